@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+	"go_crud/utils"
+)
+
+// runCommand runs a command line subcommand and returns its exit code.
+func runCommand(name string, args []string) int {
+	switch name {
+	case "import":
+		return runImport(args)
+	case "migrate-addresses":
+		return runMigrateAddresses(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		return 2
+	}
+}
+
+// runImport imports users from a CSV or NDJSON file, the same way as
+// POST /api/users/import.
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "CSV or NDJSON file to import, stdin when omitted")
+	format := flags.String("format", "", "csv or ndjson, detected from the file name when omitted")
+	onDuplicate := flags.String("on-duplicate", models.ImportOnDuplicateSkip, "skip or upsert rows whose email already exists, upserts keep the password of existing users")
+	dryRun := flags.Bool("dry-run", false, "validate the file without writing anything")
+	chunkSize := flags.Int("chunk-size", 0, "number of rows committed at once")
+	reportPath := flags.String("report", "", "write the per-row report as CSV to this file")
+	tenantID := flags.String("tenant", "", "import into this tenant rather than the default one")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	app := defaultApp
+	if *tenantID != "" && *tenantID != models.DefaultTenantID {
+		if !multiTenant {
+			fmt.Fprintln(os.Stderr, "-tenant needs GO_CRUD_MULTI_TENANT to be set")
+			return 2
+		}
+
+		tenant, err := tenantService.FindTenant(*tenantID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		app = tenants.app(tenant)
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+			if *format == "jsonl" {
+				*format = models.ImportFormatNDJSON
+			}
+		}
+	}
+
+	report, importErr := app.userImportService.ImportUsers(input, models.ImportUsersOptions{
+		Format:      *format,
+		OnDuplicate: *onDuplicate,
+		DryRun:      *dryRun,
+		ChunkSize:   *chunkSize,
+	})
+	if report == nil {
+		fmt.Fprintln(os.Stderr, importErr)
+		return 1
+	}
+
+	fmt.Printf("rows: %d, created: %d, updated: %d, skipped: %d, failed: %d", report.Total, report.Created, report.Updated, report.Skipped, report.Failed)
+	if report.DryRun {
+		fmt.Print(" (dry run)")
+	}
+	fmt.Println()
+
+	var out io.Writer = os.Stdout
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := utils.WriteImportReportCSV(out, report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if importErr != nil {
+		fmt.Fprintln(os.Stderr, importErr)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runMigrateAddresses stores the plain string addresses of existing users as
+// structured addresses, in the shared database and in every tenant database.
+func runMigrateAddresses(args []string) int {
+	flags := flag.NewFlagSet("migrate-addresses", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// The shared collection holds the users of every tenant without a
+	// dedicated database, so it is migrated as a whole
+	databases := []string{"go_crud"}
+	if multiTenant {
+		all, err := tenantService.FindTenants()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, tenant := range all {
+			if tenant.Database != "" {
+				databases = append(databases, tenant.Database)
+			}
+		}
+	}
+
+	for _, database := range databases {
+		migrated, err := services.MigrateAddresses(ctx, mongoclient.Database(database).Collection("users"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", database, err)
+			return 1
+		}
+		fmt.Printf("%s: %d addresses migrated\n", database, migrated)
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService services.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService services.APIKeyService) APIKeyController {
+	return APIKeyController{apiKeyService}
+}
+
+// CreateKey creates an API key.
+// @Summary Create an API key
+// @Description Create an API key, sent as "Authorization: ApiKey <key>" or as a bearer token. The key is only shown in this response. Keys belong to the caller, admins can create keys for other users and service accounts.
+// @Tags API keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAPIKeyRequest true "Name, scopes and expiry of the key"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/api-keys [post]
+func (kc *APIKeyController) CreateKey(ctx *gin.Context) {
+	if usesAPIKey(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "API keys cannot manage API keys"})
+		return
+	}
+
+	var req *models.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if req.UserID == "" && req.ServiceAccount == "" {
+		req.UserID = callerID(ctx)
+	}
+	if !isAdmin(ctx) && (req.ServiceAccount != "" || req.UserID != callerID(ctx)) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can create keys for others"})
+		return
+	}
+
+	apiKey, err := kc.apiKeyService.CreateKey(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": apiKey})
+}
+
+// FindKeys lists API keys.
+// @Summary List API keys
+// @Description List the API keys of the caller, revoked ones included. Admins list every key, or those of the given user or service account.
+// @Tags API keys
+// @Security BearerAuth
+// @Produce json
+// @Param userId query string false "Only keys of this user, for admins"
+// @Param serviceAccount query string false "Only keys of this service account, for admins"
+// @Success 200 {object} models.FindAPIKeysResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/api-keys [get]
+func (kc *APIKeyController) FindKeys(ctx *gin.Context) {
+	if usesAPIKey(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "API keys cannot manage API keys"})
+		return
+	}
+
+	filter := models.APIKeyFilter{UserID: ctx.Query("userId"), ServiceAccount: ctx.Query("serviceAccount")}
+	if !isAdmin(ctx) {
+		if filter.ServiceAccount != "" || (filter.UserID != "" && filter.UserID != callerID(ctx)) {
+			ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can list the keys of others"})
+			return
+		}
+		filter.UserID = callerID(ctx)
+	}
+
+	keys, err := kc.apiKeyService.FindKeys(filter)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(keys), "data": keys})
+}
+
+// RevokeKey revokes an API key.
+// @Summary Revoke an API key
+// @Description Stop an API key from working. Users can revoke their own keys, admins any key.
+// @Tags API keys
+// @Security BearerAuth
+// @Param keyId path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/api-keys/{keyId} [delete]
+func (kc *APIKeyController) RevokeKey(ctx *gin.Context) {
+	if usesAPIKey(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "API keys cannot manage API keys"})
+		return
+	}
+
+	keyId := ctx.Param("keyId")
+	if !isAdmin(ctx) {
+		apiKey, err := kc.apiKeyService.FindKey(keyId)
+		if err != nil {
+			ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+
+		// Other keys are reported missing, so their ids cannot be probed
+		if apiKey.UserID == nil || apiKey.UserID.Hex() != callerID(ctx) {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": services.ErrAPIKeyNotFound.Error()})
+			return
+		}
+	}
+
+	if err := kc.apiKeyService.RevokeKey(keyId); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockAPIKeyService is a mock implementation of the APIKeyService interface
+type MockAPIKeyService struct {
+	Keys    map[string]*models.APIKey
+	Filter  models.APIKeyFilter
+	Revoked string
+}
+
+func (m *MockAPIKeyService) CreateKey(req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	obId, _ := primitive.ObjectIDFromHex(req.UserID)
+	return &models.CreatedAPIKey{
+		APIKey: models.APIKey{ID: primitive.NewObjectID(), Name: req.Name, UserID: &obId, Prefix: "gocrud_01234567", Scopes: req.Scopes, CreatedAt: time.Now()},
+		Key:    "gocrud_0123456789abcdef",
+	}, nil
+}
+
+func (m *MockAPIKeyService) FindKeys(filter models.APIKeyFilter) ([]*models.APIKey, error) {
+	m.Filter = filter
+	return []*models.APIKey{}, nil
+}
+
+func (m *MockAPIKeyService) FindKey(id string) (*models.APIKey, error) {
+	if key, ok := m.Keys[id]; ok {
+		return key, nil
+	}
+
+	return nil, services.ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyService) RevokeKey(id string) error {
+	m.Revoked = id
+	return nil
+}
+
+func (m *MockAPIKeyService) Authenticate(key string) (*models.APIKey, *models.User, error) {
+	return nil, nil, services.ErrInvalidAPIKey
+}
+
+// apiKeyRequest calls handler as a caller with the given id and role, and
+// with an API key when apiKeyID is set.
+func apiKeyRequest(handler gin.HandlerFunc, req *http.Request, params gin.Params, callerID string, role string, apiKeyID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = params
+	c.Set(UserIDKey, callerID)
+	c.Set(RoleKey, role)
+	if apiKeyID != "" {
+		c.Set(APIKeyIDKey, apiKeyID)
+	}
+
+	handler(c)
+	c.Writer.WriteHeaderNow()
+
+	return w
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	apiKeyController := NewAPIKeyController(&MockAPIKeyService{})
+	userId := primitive.NewObjectID().Hex()
+
+	newRequest := func(body string) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/api-keys", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	w := apiKeyRequest(apiKeyController.CreateKey, newRequest(`{"name": "nightly sync", "scopes": ["users:read"]}`), nil, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.CreateAPIKeyResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "gocrud_0123456789abcdef", response.Data.Key)
+	assert.Equal(t, userId, response.Data.UserID.Hex())
+	assert.Equal(t, []string{"users:read"}, response.Data.Scopes)
+
+	tests := []struct {
+		name     string
+		body     string
+		role     string
+		apiKeyID string
+		status   int
+	}{
+		{"unknown scope", `{"name": "sync", "scopes": ["admin"]}`, models.RoleUser, "", http.StatusBadRequest},
+		{"service account by a user", `{"name": "sync", "serviceAccount": "batch"}`, models.RoleUser, "", http.StatusForbidden},
+		{"other user by a user", `{"name": "sync", "userId": "` + primitive.NewObjectID().Hex() + `"}`, models.RoleUser, "", http.StatusForbidden},
+		{"other user by an admin", `{"name": "sync", "userId": "` + primitive.NewObjectID().Hex() + `"}`, models.RoleAdmin, "", http.StatusCreated},
+		{"with an API key", `{"name": "sync"}`, models.RoleUser, primitive.NewObjectID().Hex(), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		w := apiKeyRequest(apiKeyController.CreateKey, newRequest(tt.body), nil, userId, tt.role, tt.apiKeyID)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+	}
+}
+
+func TestFindAPIKeys(t *testing.T) {
+	mockAPIKeyService := &MockAPIKeyService{}
+	apiKeyController := NewAPIKeyController(mockAPIKeyService)
+	userId := primitive.NewObjectID().Hex()
+
+	req, _ := http.NewRequest("GET", "/api/api-keys", nil)
+	w := apiKeyRequest(apiKeyController.FindKeys, req, nil, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.APIKeyFilter{UserID: userId}, mockAPIKeyService.Filter)
+
+	req, _ = http.NewRequest("GET", "/api/api-keys?serviceAccount=batch", nil)
+	w = apiKeyRequest(apiKeyController.FindKeys, req, nil, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/api-keys?serviceAccount=batch", nil)
+	w = apiKeyRequest(apiKeyController.FindKeys, req, nil, userId, models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.APIKeyFilter{ServiceAccount: "batch"}, mockAPIKeyService.Filter)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	userId := primitive.NewObjectID()
+	ownKey := &models.APIKey{ID: primitive.NewObjectID(), UserID: &userId}
+	serviceKey := &models.APIKey{ID: primitive.NewObjectID(), ServiceAccount: "batch"}
+	mockAPIKeyService := &MockAPIKeyService{Keys: map[string]*models.APIKey{
+		ownKey.ID.Hex():     ownKey,
+		serviceKey.ID.Hex(): serviceKey,
+	}}
+	apiKeyController := NewAPIKeyController(mockAPIKeyService)
+
+	revoke := func(keyId string, role string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("DELETE", "/api/api-keys/"+keyId, nil)
+		return apiKeyRequest(apiKeyController.RevokeKey, req, gin.Params{{Key: "keyId", Value: keyId}}, userId.Hex(), role, "")
+	}
+
+	assert.Equal(t, http.StatusNotFound, revoke(serviceKey.ID.Hex(), models.RoleUser).Code)
+	assert.Empty(t, mockAPIKeyService.Revoked)
+
+	assert.Equal(t, http.StatusNoContent, revoke(ownKey.ID.Hex(), models.RoleUser).Code)
+	assert.Equal(t, ownKey.ID.Hex(), mockAPIKeyService.Revoked)
+
+	assert.Equal(t, http.StatusNoContent, revoke(serviceKey.ID.Hex(), models.RoleAdmin).Code)
+	assert.Equal(t, serviceKey.ID.Hex(), mockAPIKeyService.Revoked)
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeController struct {
+	attributeService services.AttributeService
+}
+
+func NewAttributeController(attributeService services.AttributeService) AttributeController {
+	return AttributeController{attributeService}
+}
+
+// CreateAttribute defines a custom user attribute.
+// @Summary Define a custom user attribute
+// @Description Define an attribute users keep in their attributes, for admins. String attributes can be limited to an enum or a pattern, unique ones cannot be defined while users share values of them.
+// @Tags attributes
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAttributeRequest true "Name, type and rules of the attribute"
+// @Success 201 {object} models.AttributeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/attributes [post]
+func (ac *AttributeController) CreateAttribute(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage attributes"})
+		return
+	}
+
+	var req *models.CreateAttributeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	attribute, err := ac.attributeService.CreateAttribute(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": attribute})
+}
+
+// FindAttributes lists the custom user attributes.
+// @Summary List custom user attributes
+// @Description List the attributes users can have, for admins.
+// @Tags attributes
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.FindAttributesResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/attributes [get]
+func (ac *AttributeController) FindAttributes(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage attributes"})
+		return
+	}
+
+	attributes, err := ac.attributeService.FindAttributes()
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(attributes), "data": attributes})
+}
+
+// FindAttribute finds a custom user attribute.
+// @Summary Find a custom user attribute
+// @Description Find an attribute by its name, for admins.
+// @Tags attributes
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Attribute name"
+// @Success 200 {object} models.AttributeResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/attributes/{name} [get]
+func (ac *AttributeController) FindAttribute(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage attributes"})
+		return
+	}
+
+	attribute, err := ac.attributeService.FindAttribute(ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": attribute})
+}
+
+// UpdateAttribute changes the rules of a custom user attribute.
+// @Summary Update a custom user attribute
+// @Description Replace the rules of an attribute, for admins. Its name and type cannot change, and values users already have are only checked against the new rules when they are written.
+// @Tags attributes
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Attribute name"
+// @Param request body models.UpdateAttributeRequest true "New rules of the attribute"
+// @Success 200 {object} models.AttributeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/attributes/{name} [put]
+func (ac *AttributeController) UpdateAttribute(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage attributes"})
+		return
+	}
+
+	var req *models.UpdateAttributeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	attribute, err := ac.attributeService.UpdateAttribute(ctx.Param("name"), req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": attribute})
+}
+
+// DeleteAttribute deletes a custom user attribute.
+// @Summary Delete a custom user attribute
+// @Description Delete an attribute and remove its values from every user, for admins.
+// @Tags attributes
+// @Security BearerAuth
+// @Param name path string true "Attribute name"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/attributes/{name} [delete]
+func (ac *AttributeController) DeleteAttribute(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage attributes"})
+		return
+	}
+
+	if err := ac.attributeService.DeleteAttribute(ctx.Param("name")); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockAttributeService is a mock implementation of the AttributeService interface
+type MockAttributeService struct {
+	services.AttributeService
+	Updated *models.UpdateAttributeRequest
+}
+
+func (m *MockAttributeService) CreateAttribute(req *models.CreateAttributeRequest) (*models.Attribute, error) {
+	if req.Name == "department" {
+		return nil, services.ErrAttributeExists
+	}
+
+	return &models.Attribute{Name: req.Name, Type: req.Type, Required: req.Required, Unique: req.Unique, CreatedAt: time.Now()}, nil
+}
+
+func (m *MockAttributeService) UpdateAttribute(name string, req *models.UpdateAttributeRequest) (*models.Attribute, error) {
+	if name != "department" {
+		return nil, services.ErrAttributeNotFound
+	}
+
+	m.Updated = req
+	return &models.Attribute{Name: name, Type: models.AttributeTypeString, Enum: req.Enum}, nil
+}
+
+func TestCreateAttribute(t *testing.T) {
+	attributeController := NewAttributeController(&MockAttributeService{})
+
+	tests := []struct {
+		name   string
+		role   string
+		body   string
+		status int
+	}{
+		{"Created", models.RoleAdmin, `{"name": "employeeNumber", "type": "string", "required": true, "unique": true}`, http.StatusCreated},
+		{"Not an admin", models.RoleUser, `{"name": "employeeNumber", "type": "string"}`, http.StatusForbidden},
+		{"Unknown type", models.RoleAdmin, `{"name": "employeeNumber", "type": "list"}`, http.StatusBadRequest},
+		{"Existing", models.RoleAdmin, `{"name": "department", "type": "string"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/attributes", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := apiKeyRequest(attributeController.CreateAttribute, req, nil, "", tt.role, "")
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusCreated {
+				assert.Contains(t, w.Body.String(), `"unique":true`)
+			}
+		})
+	}
+}
+
+func TestUpdateAttribute(t *testing.T) {
+	attributeService := &MockAttributeService{}
+	attributeController := NewAttributeController(attributeService)
+
+	body := `{"enum": ["Sales", "HR"]}`
+	req, _ := http.NewRequest("PUT", "/api/attributes/department", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := apiKeyRequest(attributeController.UpdateAttribute, req, gin.Params{{Key: "name", Value: "department"}}, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Sales", "HR"}, attributeService.Updated.Enum)
+
+	req, _ = http.NewRequest("PUT", "/api/attributes/team", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = apiKeyRequest(attributeController.UpdateAttribute, req, gin.Params{{Key: "name", Value: "team"}}, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
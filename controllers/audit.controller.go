@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	auditService services.AuditService
+}
+
+func NewAuditController(auditService services.AuditService) AuditController {
+	return AuditController{auditService}
+}
+
+// FindAuditEntries lists the audit log.
+// @Summary List the audit log
+// @Description List the audit log, newest first, for admins. Exports and erasures of user data are recorded in it.
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param userId query string false "Only list the entries about this user"
+// @Param page query int false "Page number" Default(1)
+// @Param limit query int false "Number of entries per page" Default(10)
+// @Success 200 {object} models.FindAuditEntriesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/audit [get]
+func (ac *AuditController) FindAuditEntries(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can read the audit log"})
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	entries, err := ac.auditService.FindEntries(ctx.Query("userId"), page, limit)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(entries), "data": entries})
+}
+
+// auditActor returns who is calling, for the audit log.
+func auditActor(ctx *gin.Context) models.AuditActor {
+	return models.AuditActor{UserID: callerID(ctx), APIKeyID: ctx.GetString(APIKeyIDKey)}
+}
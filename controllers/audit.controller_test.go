@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockAuditService is a mock implementation of the AuditService interface
+type MockAuditService struct {
+	services.AuditService
+	UserID string
+}
+
+func (m *MockAuditService) FindEntries(userID string, page int, limit int) ([]*models.AuditEntry, error) {
+	m.UserID = userID
+	return []*models.AuditEntry{{Action: models.AuditActionUserErased}}, nil
+}
+
+func TestFindAuditEntries(t *testing.T) {
+	auditService := &MockAuditService{}
+	auditController := NewAuditController(auditService)
+	userId := primitive.NewObjectID().Hex()
+
+	req, _ := http.NewRequest("GET", "/api/audit?userId="+userId, nil)
+	w := apiKeyRequest(auditController.FindAuditEntries, req, nil, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, userId, auditService.UserID)
+	assert.Contains(t, w.Body.String(), `"action":"user.erased"`)
+
+	w = apiKeyRequest(auditController.FindAuditEntries, req, nil, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct {
+	authService services.AuthService
+}
+
+func NewAuthController(authService services.AuthService) AuthController {
+	return AuthController{authService}
+}
+
+// Login signs a user in.
+// @Summary Sign in
+// @Description Sign in with an email and password. The returned token is sent as "Authorization: Bearer <token>" on later requests. Users with two-factor authentication get mfaRequired and an mfaToken instead, to finish signing in with POST /api/auth/login/mfa. Repeated failures lock the email or IP address out for a while.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Email and password"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/login [post]
+func (ac *AuthController) Login(ctx *gin.Context) {
+	var credentials *models.LoginRequest
+	if err := ctx.ShouldBindJSON(&credentials); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	result, err := ac.authService.Login(credentials, loginClient(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
+
+// loginClient describes the caller for the sign in history.
+func loginClient(ctx *gin.Context) models.LoginClient {
+	return models.LoginClient{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+}
+
+// LoginMFA finishes signing in with a second factor.
+// @Summary Finish signing in with a second factor
+// @Description Finish a sign in that returned mfaRequired, with a code from the authenticator app or a recovery code. Too many wrong codes end the sign in.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.MFALoginRequest true "Token from the sign in and a code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /api/auth/login/mfa [post]
+func (ac *AuthController) LoginMFA(ctx *gin.Context) {
+	var req *models.MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	result, err := ac.authService.LoginMFA(req, loginClient(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
+
+// Logout ends the session of the bearer token.
+// @Summary Sign out
+// @Description End the session of the bearer token
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} models.ErrorResponse
+// @Router /api/auth/logout [post]
+func (ac *AuthController) Logout(ctx *gin.Context) {
+	token := BearerToken(ctx)
+	if token == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "a bearer token is required"})
+		return
+	}
+
+	if err := ac.authService.Logout(token); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ForgotPassword emails a password reset link.
+// @Summary Ask for a password reset
+// @Description Email a single use password reset link to the account with that email. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Email of the account"
+// @Success 202 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/auth/forgot-password [post]
+func (ac *AuthController) ForgotPassword(ctx *gin.Context) {
+	var req *models.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := ac.authService.ForgotPassword(req.Email); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "success", "message": "if an account uses that email, a reset link has been sent to it"})
+}
+
+// ResetPassword sets a new password using a reset token.
+// @Summary Reset a password
+// @Description Set a new password with the token of a reset link. The token can only be used once and every session of the user is ended.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/auth/reset-password [post]
+func (ac *AuthController) ResetPassword(ctx *gin.Context) {
+	var req *models.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := ac.authService.ResetPassword(req); err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "the password has been reset"})
+}
@@ -0,0 +1,243 @@
+// auth.controller_test.go
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockAuthService is a mock implementation of the AuthService interface
+type MockAuthService struct {
+	LoggedOut  string
+	ResetEmail string
+}
+
+func (m *MockAuthService) Login(req *models.LoginRequest, client models.LoginClient) (*models.LoginResult, error) {
+	if req.Password != "password123" {
+		return nil, services.ErrInvalidCredentials
+	}
+
+	if req.Email == "mfa@example.com" {
+		return &models.LoginResult{MFARequired: true, MFAToken: "mfa-token", ExpiresAt: time.Now().Add(time.Minute)}, nil
+	}
+
+	return &models.LoginResult{
+		Token:     "token",
+		ExpiresAt: time.Now().Add(time.Hour),
+		User:      &models.User{ID: primitive.NewObjectID(), Email: req.Email},
+	}, nil
+}
+
+func (m *MockAuthService) LoginMFA(req *models.MFALoginRequest, client models.LoginClient) (*models.LoginResult, error) {
+	if req.MFAToken != "mfa-token" || req.Code != "123456" {
+		return nil, services.ErrInvalidMFALoginCode
+	}
+
+	return &models.LoginResult{
+		Token:     "token",
+		ExpiresAt: time.Now().Add(time.Hour),
+		User:      &models.User{ID: primitive.NewObjectID(), Email: "mfa@example.com", MFAEnabled: true},
+	}, nil
+}
+
+func (m *MockAuthService) LoginOIDC(provider string, code string, state string, client models.LoginClient) (*models.LoginResult, error) {
+	if provider != "corp" {
+		return nil, services.ErrOIDCProviderNotFound
+	}
+	if state != "state" {
+		return nil, services.ErrInvalidOIDCState
+	}
+
+	return &models.LoginResult{
+		Token:     "token",
+		ExpiresAt: time.Now().Add(time.Hour),
+		User:      &models.User{ID: primitive.NewObjectID(), Email: "jane@example.com"},
+	}, nil
+}
+
+func (m *MockAuthService) Logout(token string) error {
+	m.LoggedOut = token
+	return nil
+}
+
+func (m *MockAuthService) Authenticate(token string) (*models.User, error) {
+	if token != "token" {
+		return nil, services.ErrInvalidSession
+	}
+
+	return &models.User{ID: primitive.NewObjectID(), Name: "John Doe"}, nil
+}
+
+func (m *MockAuthService) ForgotPassword(email string) error {
+	m.ResetEmail = email
+	return nil
+}
+
+func (m *MockAuthService) ResetPassword(req *models.ResetPasswordRequest) error {
+	if req.Token != "reset-token" {
+		return services.ErrInvalidResetToken
+	}
+
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	authController := NewAuthController(&MockAuthService{})
+
+	req, _ := http.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "john.doe@example.com", "password": "password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LoginResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", response.Data.Token)
+	assert.Equal(t, "john.doe@example.com", response.Data.User.Email)
+}
+
+func TestLoginFail401(t *testing.T) {
+	authController := NewAuthController(&MockAuthService{})
+
+	req, _ := http.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "john.doe@example.com", "password": "wrong"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.Login(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoginMFA(t *testing.T) {
+	authController := NewAuthController(&MockAuthService{})
+
+	req, _ := http.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"email": "mfa@example.com", "password": "password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LoginResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Data.MFARequired)
+	assert.Equal(t, "mfa-token", response.Data.MFAToken)
+	assert.Empty(t, response.Data.Token)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"mfaToken": "mfa-token", "code": "123456"}`, http.StatusOK},
+		{`{"mfaToken": "mfa-token", "code": "654321"}`, http.StatusUnauthorized},
+		{`{"mfaToken": "mfa-token"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/auth/login/mfa", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		authController.LoginMFA(c)
+
+		assert.Equal(t, tt.status, w.Code, tt.body)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	mockAuthService := &MockAuthService{}
+	authController := NewAuthController(mockAuthService)
+
+	req, _ := http.NewRequest("POST", "/api/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.Logout(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.Equal(t, "token", mockAuthService.LoggedOut)
+
+	// A bearer token is required
+	req, _ = http.NewRequest("POST", "/api/auth/logout", nil)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.Logout(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestForgotPassword(t *testing.T) {
+	mockAuthService := &MockAuthService{}
+	authController := NewAuthController(mockAuthService)
+
+	req, _ := http.NewRequest("POST", "/api/auth/forgot-password", strings.NewReader(`{"email": "john.doe@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	authController.ForgotPassword(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "john.doe@example.com", mockAuthService.ResetEmail)
+}
+
+func TestResetPassword(t *testing.T) {
+	authController := NewAuthController(&MockAuthService{})
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"token": "reset-token", "password": "new password"}`, http.StatusOK},
+		{`{"token": "used-token", "password": "new password"}`, http.StatusBadRequest},
+		{`{"token": "reset-token"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/auth/reset-password", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		authController.ResetPassword(c)
+
+		assert.Equal(t, tt.status, w.Code, tt.body)
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys of the gin context values describing the authenticated caller.
+const (
+	RoleKey   = "role"
+	UserIDKey = "userId"
+	// ScopesKey holds the scopes of an API key, it is unset for sessions
+	// and keys without scopes
+	ScopesKey = "scopes"
+	// APIKeyIDKey holds the id of the API key the caller signed in with
+	APIKeyIDKey = "apiKeyId"
+	// TenantKey holds the *models.Tenant a request is for, it is unset for
+	// the default tenant
+	TenantKey = "tenant"
+)
+
+// callerRole returns the role of the caller, or "" if the request was not authenticated.
+func callerRole(ctx *gin.Context) string {
+	return ctx.GetString(RoleKey)
+}
+
+// callerID returns the id of the caller, or "" if the request was not authenticated.
+func callerID(ctx *gin.Context) string {
+	return ctx.GetString(UserIDKey)
+}
+
+// CanManageUser reports whether the caller may act on the user with
+// userID: callers that can manage every user, and the user themself.
+func CanManageUser(ctx *gin.Context, userID string) bool {
+	return services.CanManageUser(callerRole(ctx), callerID(ctx), userID)
+}
+
+// canManageUsers reports whether the caller may act on every user: admins,
+// and the service accounts admins create for machine clients.
+func canManageUsers(ctx *gin.Context) bool {
+	return services.CanManageUsers(callerRole(ctx))
+}
+
+// isAdmin reports whether the caller is an admin.
+func isAdmin(ctx *gin.Context) bool {
+	return callerRole(ctx) == models.RoleAdmin
+}
+
+// usesAPIKey reports whether the caller signed in with an API key.
+func usesAPIKey(ctx *gin.Context) bool {
+	return ctx.GetString(APIKeyIDKey) != ""
+}
+
+// hasScope reports whether the caller may use scope. Sessions and API keys
+// without scopes may use any.
+func hasScope(ctx *gin.Context, scope string) bool {
+	scopes := ctx.GetStringSlice(ScopesKey)
+	if scopes == nil {
+		return true
+	}
+
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// BearerToken returns the token of an "Authorization: Bearer <token>" header, or "".
+func BearerToken(ctx *gin.Context) string {
+	return authorizationCredentials(ctx, "Bearer")
+}
+
+// APIKey returns the key of an "Authorization: ApiKey <key>" header, or "".
+func APIKey(ctx *gin.Context) string {
+	return authorizationCredentials(ctx, "ApiKey")
+}
+
+func authorizationCredentials(ctx *gin.Context, scheme string) string {
+	sent, credentials, ok := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(sent, scheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(credentials)
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerificationController struct {
+	emailVerificationService services.EmailVerificationService
+}
+
+func NewEmailVerificationController(emailVerificationService services.EmailVerificationService) EmailVerificationController {
+	return EmailVerificationController{emailVerificationService}
+}
+
+// VerifyEmail confirms an email address with the token of a verification link.
+// @Summary Verify an email address
+// @Description Confirm the email address a verification link was sent to. The token can only be used once.
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} models.FindUserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/auth/verify-email [get]
+func (vc *EmailVerificationController) VerifyEmail(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "the token query parameter is required"})
+		return
+	}
+
+	user, err := vc.emailVerificationService.VerifyEmail(token)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": toUserV1(user)})
+}
+
+// ResendVerification sends another verification email.
+// @Summary Resend the verification email
+// @Description Send another verification link to an unverified email address. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResendVerificationRequest true "Email to verify"
+// @Success 202 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/resend-verification [post]
+func (vc *EmailVerificationController) ResendVerification(ctx *gin.Context) {
+	var req *models.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := vc.emailVerificationService.ResendVerification(req.Email); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "success", "message": "if an unverified account uses that email, a verification link has been sent to it"})
+}
@@ -0,0 +1,88 @@
+// email_verification.controller_test.go
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockEmailVerificationService is a mock implementation of the EmailVerificationService interface
+type MockEmailVerificationService struct {
+	Resent string
+}
+
+func (m *MockEmailVerificationService) SendVerification(user *models.User) error {
+	return nil
+}
+
+func (m *MockEmailVerificationService) VerifyEmail(token string) (*models.User, error) {
+	if token != "verify-token" {
+		return nil, services.ErrInvalidVerificationToken
+	}
+
+	return &models.User{ID: primitive.NewObjectID(), EmailVerified: true}, nil
+}
+
+func (m *MockEmailVerificationService) ResendVerification(email string) error {
+	if m.Resent == email {
+		return &services.Error{Code: services.ErrCodeRateLimited, Message: "wait 1m0s before asking for another verification email"}
+	}
+
+	m.Resent = email
+	return nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	emailVerificationController := NewEmailVerificationController(&MockEmailVerificationService{})
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"/api/auth/verify-email?token=verify-token", http.StatusOK},
+		{"/api/auth/verify-email?token=used-token", http.StatusBadRequest},
+		{"/api/auth/verify-email", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		emailVerificationController.VerifyEmail(c)
+
+		assert.Equal(t, tt.status, w.Code, tt.url)
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	mockEmailVerificationService := &MockEmailVerificationService{}
+	emailVerificationController := NewEmailVerificationController(mockEmailVerificationService)
+
+	// The second request within the cooldown is rejected
+	for _, status := range []int{http.StatusAccepted, http.StatusTooManyRequests} {
+		req, _ := http.NewRequest("POST", "/api/auth/resend-verification", strings.NewReader(`{"email": "john.doe@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		emailVerificationController.ResendVerification(c)
+
+		assert.Equal(t, status, w.Code)
+	}
+
+	assert.Equal(t, "john.doe@example.com", mockEmailVerificationService.Resent)
+}
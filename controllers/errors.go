@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps the code of a service error to an HTTP status.
+func errorStatus(err error) int {
+	switch services.ErrorCode(err) {
+	case services.ErrCodeNotFound:
+		return http.StatusNotFound
+	case services.ErrCodeAlreadyExists, services.ErrCodeFailedPrecondition:
+		return http.StatusConflict
+	case services.ErrCodeInvalid:
+		return http.StatusBadRequest
+	case services.ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	case services.ErrCodeUnauthenticated:
+		return http.StatusUnauthorized
+	case services.ErrCodePermissionDenied:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorBody is the JSON body of an error response. Password policy errors
+// also list every broken rule.
+func errorBody(err error) gin.H {
+	body := gin.H{"status": "fail", "message": err.Error()}
+
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		body["errors"] = policyErr.Violations
+	}
+
+	return body
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+func TestErrorBody(t *testing.T) {
+	assert.Equal(t, gin.H{"status": "fail", "message": "boom"}, errorBody(errors.New("boom")))
+
+	violations := []models.PasswordViolation{{Rule: models.PasswordRuleMinLength, Message: "must be at least 8 characters long"}}
+	err := &services.PasswordPolicyError{Violations: violations}
+
+	assert.Equal(t, gin.H{
+		"status":  "fail",
+		"message": "the password must be at least 8 characters long",
+		"errors":  violations,
+	}, errorBody(err))
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go_crud/graphql"
+	"go_crud/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGraphQLRequestSize is the largest POST body accepted, the document and
+// the variables together.
+const maxGraphQLRequestSize = 1 << 20
+
+type GraphQLController struct {
+	schema *graphql.Schema
+}
+
+func NewGraphQLController(schema *graphql.Schema) GraphQLController {
+	return GraphQLController{schema}
+}
+
+// Execute runs a GraphQL query or mutation.
+// @Summary Run a GraphQL request
+// @Description Run a query or mutation of the user schema, printed by GET /api/graphql/schema and open to introspection. Lookups of users by ID are batched. Requests that are too large or nest too deep are rejected, and fields past the complexity limit fail. Queries need the users:read scope and mutations users:write. Field errors are returned with a 200 status, along with the data.
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param request body models.GraphQLRequest true "GraphQL request"
+// @Success 200 {object} models.GraphQLResponse
+// @Failure 400 {object} models.GraphQLResponse
+// @Failure 403 {object} models.GraphQLResponse
+// @Router /api/graphql [post]
+func (gc *GraphQLController) Execute(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxGraphQLRequestSize)
+
+	var req models.GraphQLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, graphql.ErrorResponse(err))
+		return
+	}
+
+	gc.execute(ctx, &req)
+}
+
+// ExecuteQuery runs a GraphQL query sent in the URL.
+// @Summary Run a GraphQL query
+// @Description Run a query of the user schema, mutations must be sent with POST
+// @Tags GraphQL
+// @Produce json
+// @Param query query string true "GraphQL query"
+// @Param operationName query string false "Operation to run when the query has several"
+// @Param variables query string false "Variables as a JSON object"
+// @Success 200 {object} models.GraphQLResponse
+// @Failure 400 {object} models.GraphQLResponse
+// @Failure 403 {object} models.GraphQLResponse
+// @Failure 405 {object} models.GraphQLResponse
+// @Router /api/graphql [get]
+func (gc *GraphQLController) ExecuteQuery(ctx *gin.Context) {
+	req := models.GraphQLRequest{Query: ctx.Query("query"), OperationName: ctx.Query("operationName")}
+	if variables := ctx.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			ctx.JSON(http.StatusBadRequest, graphql.ErrorResponse(errors.New("variables must be a JSON object")))
+			return
+		}
+	}
+
+	gc.execute(ctx, &req)
+}
+
+// graphQLRequestError fails a whole GraphQL request with status, such as a
+// mutation the API key has no scope for.
+type graphQLRequestError struct {
+	status  int
+	message string
+}
+
+func (e *graphQLRequestError) Error() string {
+	return e.message
+}
+
+func (gc *GraphQLController) execute(ctx *gin.Context, req *models.GraphQLRequest) {
+	authorize := func(operation string) error {
+		required := models.ScopeUsersRead
+		if operation == "mutation" {
+			if ctx.Request.Method == http.MethodGet {
+				return &graphQLRequestError{http.StatusMethodNotAllowed, "mutations must be sent with POST"}
+			}
+			required = models.ScopeUsersWrite
+		}
+		if !hasScope(ctx, required) {
+			return &graphQLRequestError{http.StatusForbidden, "the API key needs the " + required + " scope"}
+		}
+		return nil
+	}
+
+	res := gc.schema.Execute(graphql.WithCaller(ctx.Request.Context(), callerID(ctx), callerRole(ctx), authorize), req.Query, req.OperationName, req.Variables)
+
+	// Root fields are authorized before doing any work, so nothing ran
+	for _, err := range res.Errors {
+		var reqErr *graphQLRequestError
+		if errors.As(err, &reqErr) {
+			ctx.JSON(reqErr.status, graphql.ErrorResponse(reqErr))
+			return
+		}
+	}
+
+	if res.Data == nil {
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Schema prints the GraphQL schema.
+// @Summary GraphQL schema
+// @Description The user schema in the GraphQL schema definition language, for code generators and clients
+// @Tags GraphQL
+// @Produce plain
+// @Success 200 {string} string
+// @Router /api/graphql/schema [get]
+func (gc *GraphQLController) Schema(ctx *gin.Context) {
+	ctx.String(http.StatusOK, gc.schema.SDL())
+}
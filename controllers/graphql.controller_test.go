@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/graphql"
+	"go_crud/models"
+)
+
+// newGraphQLTestRouter serves the GraphQL routes to a caller with the given role and scopes.
+func newGraphQLTestRouter(role string, scopes []string) *gin.Engine {
+	graphqlController := NewGraphQLController(graphql.NewUserSchema(&MockUserService{}, 10, 1000))
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if role != "" {
+			ctx.Set(RoleKey, role)
+		}
+		if scopes != nil {
+			ctx.Set(ScopesKey, scopes)
+		}
+	})
+
+	router.GET("/api/graphql", graphqlController.ExecuteQuery)
+	router.POST("/api/graphql", graphqlController.Execute)
+	router.GET("/api/graphql/schema", graphqlController.Schema)
+
+	return router
+}
+
+func graphqlRequest(router *gin.Engine, method string, target string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req, _ := http.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestGraphQLExecute(t *testing.T) {
+	router := newGraphQLTestRouter("", nil)
+
+	w, response := graphqlRequest(router, "POST", "/api/graphql", `{
+		"query": "query Find($first: Int) { users(first: $first) { edges { node { name } } } }",
+		"operationName": "Find",
+		"variables": {"first": 1}
+	}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	edges := response["data"].(map[string]interface{})["users"].(map[string]interface{})["edges"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"name": "John Doe"}}, edges[0])
+	assert.NotContains(t, response, "errors")
+}
+
+func TestGraphQLExecuteQuery(t *testing.T) {
+	router := newGraphQLTestRouter("", nil)
+
+	w, response := graphqlRequest(router, "GET", "/api/graphql?query="+url.QueryEscape("{ users(first: 2) { edges { node { name } } } }"), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, response, "data")
+
+	w, _ = graphqlRequest(router, "GET", "/api/graphql?query="+url.QueryEscape(`mutation { deleteUser(id: "1") }`), "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w, _ = graphqlRequest(router, "GET", "/api/graphql?query=%7B+id+%7D&variables=nope", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphQLExecute_Fail(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		body    string
+		status  int
+		message string
+	}{
+		{"Missing query", nil, `{}`, http.StatusBadRequest, ""},
+		{"Syntax error", nil, `{"query": "{ user(id: 1) "}`, http.StatusBadRequest, `syntax error: unexpected "", expecting Ident`},
+		{"Too large", nil, `{"query": "` + strings.Repeat(" ", maxGraphQLRequestSize) + `{ user(id: 1) { id } }"}`, http.StatusBadRequest, ""},
+		{"Invalid query", nil, `{"query": "{ user(id: 1) { password } }"}`, http.StatusBadRequest, `Cannot query field "password" on type "User".`},
+		{"Read scope", []string{models.ScopeUsersRead}, `{"query": "mutation { deleteUser(id: 1) }"}`, http.StatusForbidden, "the API key needs the users:write scope"},
+		{"Write scope", []string{models.ScopeUsersWrite}, `{"query": "{ user(id: 1) { id } }"}`, http.StatusForbidden, "the API key needs the users:read scope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newGraphQLTestRouter(models.RoleService, tt.scopes)
+
+			w, response := graphqlRequest(router, "POST", "/api/graphql", tt.body)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.NotContains(t, response, "data")
+			errors := response["errors"].([]interface{})
+			if tt.message != "" {
+				assert.Equal(t, tt.message, errors[0].(map[string]interface{})["message"])
+			}
+		})
+	}
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	router := newGraphQLTestRouter("", nil)
+
+	w, _ := graphqlRequest(router, "GET", "/api/graphql/schema", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "type Query {")
+}
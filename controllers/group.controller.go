@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupController struct {
+	groupService services.GroupService
+}
+
+func NewGroupController(groupService services.GroupService) GroupController {
+	return GroupController{groupService}
+}
+
+// CreateGroup creates a group.
+// @Summary Create a group
+// @Description Create a group users and other groups can be members of, for admins.
+// @Tags groups
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateGroupRequest true "Name and description of the group"
+// @Success 201 {object} models.GroupResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/groups [post]
+func (gc *GroupController) CreateGroup(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage groups"})
+		return
+	}
+
+	var req *models.CreateGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	group, err := gc.groupService.CreateGroup(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": group})
+}
+
+// FindGroups lists groups.
+// @Summary List groups
+// @Description List the groups by name, or only those nested directly in a parent group.
+// @Tags groups
+// @Security BearerAuth
+// @Produce json
+// @Param parent query string false "ID of the group the groups are nested in"
+// @Success 200 {object} models.FindGroupsResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups [get]
+func (gc *GroupController) FindGroups(ctx *gin.Context) {
+	groups, err := gc.groupService.FindGroups(ctx.Query("parent"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(groups), "data": groups})
+}
+
+// FindGroup finds a group.
+// @Summary Find a group
+// @Description Find a group by its ID.
+// @Tags groups
+// @Security BearerAuth
+// @Produce json
+// @Param groupId path string true "Group ID"
+// @Success 200 {object} models.GroupResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups/{groupId} [get]
+func (gc *GroupController) FindGroup(ctx *gin.Context) {
+	group, err := gc.groupService.FindGroup(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": group})
+}
+
+// UpdateGroup renames a group.
+// @Summary Update a group
+// @Description Replace the name and description of a group, for admins.
+// @Tags groups
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID"
+// @Param request body models.UpdateGroupRequest true "New name and description of the group"
+// @Success 200 {object} models.GroupResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/groups/{groupId} [put]
+func (gc *GroupController) UpdateGroup(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage groups"})
+		return
+	}
+
+	var req *models.UpdateGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	group, err := gc.groupService.UpdateGroup(ctx.Param("groupId"), req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": group})
+}
+
+// DeleteGroup deletes a group.
+// @Summary Delete a group
+// @Description Delete a group, for admins. Its members are removed from it, and groups nested only in it are left at the top.
+// @Tags groups
+// @Security BearerAuth
+// @Param groupId path string true "Group ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups/{groupId} [delete]
+func (gc *GroupController) DeleteGroup(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage groups"})
+		return
+	}
+
+	if err := gc.groupService.DeleteGroup(ctx.Param("groupId")); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AddMembers adds users and groups to a group.
+// @Summary Add members to a group
+// @Description Add users and nested groups to a group, for admins. Nothing is added when one of them does not exist, or nesting a group would make it a member of itself.
+// @Tags groups
+// @Security BearerAuth
+// @Accept json
+// @Param groupId path string true "Group ID"
+// @Param request body models.GroupMembersRequest true "Users and groups to add"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/groups/{groupId}/members [post]
+func (gc *GroupController) AddMembers(ctx *gin.Context) {
+	gc.changeMembers(ctx, gc.groupService.AddMembers)
+}
+
+// RemoveMembers removes users and groups from a group.
+// @Summary Remove members from a group
+// @Description Remove users and nested groups from a group, for admins. Those that are not members are ignored.
+// @Tags groups
+// @Security BearerAuth
+// @Accept json
+// @Param groupId path string true "Group ID"
+// @Param request body models.GroupMembersRequest true "Users and groups to remove"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups/{groupId}/members [delete]
+func (gc *GroupController) RemoveMembers(ctx *gin.Context) {
+	gc.changeMembers(ctx, gc.groupService.RemoveMembers)
+}
+
+// AddMember adds a user to a group.
+// @Summary Add a user to a group
+// @Description Add a single user to a group, for admins.
+// @Tags groups
+// @Security BearerAuth
+// @Param groupId path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups/{groupId}/users/{userId} [put]
+func (gc *GroupController) AddMember(ctx *gin.Context) {
+	gc.changeMember(ctx, gc.groupService.AddMembers)
+}
+
+// RemoveMember removes a user from a group.
+// @Summary Remove a user from a group
+// @Description Remove a single user from a group, for admins.
+// @Tags groups
+// @Security BearerAuth
+// @Param groupId path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/groups/{groupId}/users/{userId} [delete]
+func (gc *GroupController) RemoveMember(ctx *gin.Context) {
+	gc.changeMember(ctx, gc.groupService.RemoveMembers)
+}
+
+func (gc *GroupController) changeMembers(ctx *gin.Context, change func(string, *models.GroupMembersRequest) error) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage groups"})
+		return
+	}
+
+	var req *models.GroupMembersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := change(ctx.Param("groupId"), req); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (gc *GroupController) changeMember(ctx *gin.Context, change func(string, *models.GroupMembersRequest) error) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage groups"})
+		return
+	}
+
+	req := &models.GroupMembersRequest{UserIDs: []string{ctx.Param("userId")}}
+	if err := change(ctx.Param("groupId"), req); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// FindUserGroups lists the groups of a user.
+// @Summary List the groups of a user
+// @Description List the groups a user is a member of, and with inherited also those it is a member of through nested groups. Users can list their own groups, admins those of any user.
+// @Tags groups
+// @Security BearerAuth
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param inherited query bool false "Include the groups the user is a member of through nested groups"
+// @Success 200 {object} models.FindGroupsResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{userId}/groups [get]
+func (gc *GroupController) FindUserGroups(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "you can only list your own groups"})
+		return
+	}
+
+	groups, err := gc.groupService.UserGroups(userId, ctx.Query("inherited") == "true")
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(groups), "data": groups})
+}
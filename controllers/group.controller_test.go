@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockGroupService is a mock implementation of the GroupService interface
+type MockGroupService struct {
+	services.GroupService
+	Members   *models.GroupMembersRequest
+	Inherited bool
+}
+
+func (m *MockGroupService) CreateGroup(req *models.CreateGroupRequest) (*models.Group, error) {
+	if req.Name == "Sales" {
+		return nil, services.ErrGroupExists
+	}
+
+	return &models.Group{ID: primitive.NewObjectID(), Name: req.Name, Description: req.Description}, nil
+}
+
+func (m *MockGroupService) AddMembers(id string, req *models.GroupMembersRequest) error {
+	if len(req.GroupIDs) > 0 && req.GroupIDs[0] == id {
+		return &services.Error{Code: services.ErrCodeFailedPrecondition, Message: "nesting group would make a cycle"}
+	}
+
+	m.Members = req
+	return nil
+}
+
+func (m *MockGroupService) UserGroups(userID string, inherited bool) ([]*models.Group, error) {
+	m.Inherited = inherited
+	return []*models.Group{{ID: primitive.NewObjectID(), Name: "Sales"}}, nil
+}
+
+func TestCreateGroup(t *testing.T) {
+	groupController := NewGroupController(&MockGroupService{})
+
+	tests := []struct {
+		name   string
+		role   string
+		body   string
+		status int
+	}{
+		{"Created", models.RoleAdmin, `{"name": "Support", "description": "First line"}`, http.StatusCreated},
+		{"Not an admin", models.RoleUser, `{"name": "Support"}`, http.StatusForbidden},
+		{"No name", models.RoleAdmin, `{"description": "First line"}`, http.StatusBadRequest},
+		{"Existing", models.RoleAdmin, `{"name": "Sales"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/groups", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := apiKeyRequest(groupController.CreateGroup, req, nil, "", tt.role, "")
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestAddMembers(t *testing.T) {
+	groupService := &MockGroupService{}
+	groupController := NewGroupController(groupService)
+	groupId := primitive.NewObjectID().Hex()
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "groupId", Value: groupId}}
+
+	req, _ := http.NewRequest("POST", "/api/groups/"+groupId+"/members", strings.NewReader(`{"userIds": ["`+userId+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := apiKeyRequest(groupController.AddMembers, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{userId}, groupService.Members.UserIDs)
+
+	req, _ = http.NewRequest("POST", "/api/groups/"+groupId+"/members", strings.NewReader(`{"groupIds": ["`+groupId+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = apiKeyRequest(groupController.AddMembers, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("PUT", "/api/groups/"+groupId+"/users/"+userId, nil)
+	w = apiKeyRequest(groupController.AddMember, req, append(params, gin.Param{Key: "userId", Value: userId}), "", models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestFindUserGroups(t *testing.T) {
+	groupService := &MockGroupService{}
+	groupController := NewGroupController(groupService)
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "userId", Value: userId}}
+
+	req, _ := http.NewRequest("GET", "/api/users/"+userId+"/groups?inherited=true", nil)
+	w := apiKeyRequest(groupController.FindUserGroups, req, params, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, groupService.Inherited)
+	assert.Contains(t, w.Body.String(), `"name":"Sales"`)
+
+	req, _ = http.NewRequest("GET", "/api/users/"+userId+"/groups", nil)
+	w = apiKeyRequest(groupController.FindUserGroups, req, params, primitive.NewObjectID().Hex(), models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
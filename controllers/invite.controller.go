@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteController struct {
+	inviteService services.InviteService
+}
+
+func NewInviteController(inviteService services.InviteService) InviteController {
+	return InviteController{inviteService}
+}
+
+// CreateInvite invites someone to create an account.
+// @Summary Invite someone
+// @Description Email a link to create an account with the given email and role, for admins. The link expires at expiresAt, or after the default validity.
+// @Tags invites
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateInviteRequest true "Email, role and expiry of the invite"
+// @Success 201 {object} models.InviteResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/invites [post]
+func (ic *InviteController) CreateInvite(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage invites"})
+		return
+	}
+
+	var req *models.CreateInviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	invite, err := ic.inviteService.CreateInvite(req, callerID(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": invite})
+}
+
+// FindInvites lists invites.
+// @Summary List invites
+// @Description List the invites, newest first, for admins.
+// @Tags invites
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Only list the invites with this status: pending, accepted, revoked or expired"
+// @Success 200 {object} models.FindInvitesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/invites [get]
+func (ic *InviteController) FindInvites(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage invites"})
+		return
+	}
+
+	invites, err := ic.inviteService.FindInvites(ctx.Query("status"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(invites), "data": invites})
+}
+
+// ResendInvite sends an invite again.
+// @Summary Resend an invite
+// @Description Email a new link for an invite that was not accepted or revoked, for admins. The link sent before stops working, and the new one is valid as long as it was.
+// @Tags invites
+// @Security BearerAuth
+// @Produce json
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} models.InviteResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/invites/{inviteId}/resend [post]
+func (ic *InviteController) ResendInvite(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage invites"})
+		return
+	}
+
+	invite, err := ic.inviteService.ResendInvite(ctx.Param("inviteId"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": invite})
+}
+
+// RevokeInvite revokes an invite.
+// @Summary Revoke an invite
+// @Description Revoke an invite that was not accepted, for admins. Its link stops working.
+// @Tags invites
+// @Security BearerAuth
+// @Param inviteId path string true "Invite ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/invites/{inviteId} [delete]
+func (ic *InviteController) RevokeInvite(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage invites"})
+		return
+	}
+
+	if err := ic.inviteService.RevokeInvite(ctx.Param("inviteId")); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AcceptInvite creates the account of an invitee.
+// @Summary Accept an invite
+// @Description Create the account an invite link was sent for, with the password the invitee chooses. The email is verified by the link, and each invite can only be accepted once.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptInviteRequest true "Token of the invite link, profile and password"
+// @Success 201 {object} models.FindUserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/invites/accept [post]
+func (ic *InviteController) AcceptInvite(ctx *gin.Context) {
+	var req *models.AcceptInviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	user, err := ic.inviteService.AcceptInvite(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": toUserV1(user)})
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockInviteService is a mock implementation of the InviteService interface
+type MockInviteService struct {
+	services.InviteService
+	InvitedBy string
+}
+
+func (m *MockInviteService) CreateInvite(req *models.CreateInviteRequest, invitedBy string) (*models.Invite, error) {
+	if req.Email == "jane@example.com" {
+		return nil, services.ErrInviteExists
+	}
+
+	m.InvitedBy = invitedBy
+	return &models.Invite{ID: primitive.NewObjectID(), Email: req.Email, Role: req.Role, ExpiresAt: time.Now().Add(time.Hour), Status: models.InviteStatusPending}, nil
+}
+
+func (m *MockInviteService) AcceptInvite(req *models.AcceptInviteRequest) (*models.User, error) {
+	switch req.Token {
+	case "used":
+		return nil, services.ErrInviteAccepted
+	case "expired":
+		return nil, services.ErrInviteExpired
+	case "unknown":
+		return nil, services.ErrInvalidInviteToken
+	}
+
+	return &models.User{ID: primitive.NewObjectID(), Name: req.Name, Email: "john@example.com", EmailVerified: true}, nil
+}
+
+func TestCreateInvite(t *testing.T) {
+	inviteService := &MockInviteService{}
+	inviteController := NewInviteController(inviteService)
+	adminId := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name   string
+		role   string
+		body   string
+		status int
+	}{
+		{"Created", models.RoleAdmin, `{"email": "john@example.com", "role": "admin"}`, http.StatusCreated},
+		{"Not an admin", models.RoleUser, `{"email": "john@example.com"}`, http.StatusForbidden},
+		{"Unknown role", models.RoleAdmin, `{"email": "john@example.com", "role": "owner"}`, http.StatusBadRequest},
+		{"Invalid email", models.RoleAdmin, `{"email": "john"}`, http.StatusBadRequest},
+		{"Pending", models.RoleAdmin, `{"email": "jane@example.com"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/invites", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := apiKeyRequest(inviteController.CreateInvite, req, nil, adminId, tt.role, "")
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusCreated {
+				assert.Equal(t, adminId, inviteService.InvitedBy)
+				assert.Contains(t, w.Body.String(), `"status":"pending"`)
+				assert.NotContains(t, w.Body.String(), "tokenHash")
+			}
+		})
+	}
+}
+
+func TestAcceptInvite(t *testing.T) {
+	inviteController := NewInviteController(&MockInviteService{})
+	profile := `"name": "John Doe", "age": 30, "password": "Correct-Horse-9", "address": {"formatted": "123 Main St"}`
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"Accepted", "valid", http.StatusCreated},
+		{"Already used", "used", http.StatusConflict},
+		{"Expired", "expired", http.StatusConflict},
+		{"Unknown", "unknown", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/invites/accept", strings.NewReader(`{"token": "`+tt.token+`", `+profile+`}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := apiKeyRequest(inviteController.AcceptInvite, req, nil, "", "", "")
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	req, _ := http.NewRequest("POST", "/api/invites/accept", strings.NewReader(`{"token": "valid"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := apiKeyRequest(inviteController.AcceptInvite, req, nil, "", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
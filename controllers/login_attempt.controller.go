@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginAttemptController struct {
+	loginAttemptService services.LoginAttemptService
+}
+
+func NewLoginAttemptController(loginAttemptService services.LoginAttemptService) LoginAttemptController {
+	return LoginAttemptController{loginAttemptService}
+}
+
+// FindLogins finds the sign in history of a user.
+// @Summary Find the sign in history of a user
+// @Description Find the sign in attempts of a user, newest first. Users can read their own history, admins anyone's.
+// @Tags Users
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param page query int false "Page number" Default(1)
+// @Param limit query int false "Number of items per page" Default(10)
+// @Success 200 {object} models.LoginHistoryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/users/{userId}/logins [get]
+func (lc *LoginAttemptController) FindLogins(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can read the sign ins of other users"})
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	attempts, err := lc.loginAttemptService.FindLogins(userId, page, limit)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(attempts), "data": attempts})
+}
+
+// LockoutStatus tells whether a user is locked out.
+// @Summary Find the lockout status of a user
+// @Description Tell whether a user is locked out after failed sign ins, and until when
+// @Tags Users
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {object} models.LockoutStatusResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{userId}/lockout [get]
+func (lc *LoginAttemptController) LockoutStatus(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can read the lockout of other users"})
+		return
+	}
+
+	status, err := lc.loginAttemptService.LockoutStatus(userId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": status})
+}
+
+// Unlock ends the lockout of a user.
+// @Summary Unlock a user
+// @Description End the lockout of a user and reset their failed sign in count. Only admins can unlock users.
+// @Tags Users
+// @Param userId path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{userId}/lockout [delete]
+func (lc *LoginAttemptController) Unlock(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can unlock users"})
+		return
+	}
+
+	if err := lc.loginAttemptService.Unlock(ctx.Param("userId")); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
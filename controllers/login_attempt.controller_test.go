@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+)
+
+// MockLoginAttemptService is a mock implementation of the LoginAttemptService interface
+type MockLoginAttemptService struct {
+	Unlocked string
+}
+
+func (m *MockLoginAttemptService) Check(email string, client models.LoginClient) error {
+	return nil
+}
+
+func (m *MockLoginAttemptService) Record(attempt *models.LoginAttempt) error {
+	return nil
+}
+
+func (m *MockLoginAttemptService) FindLogins(userID string, page int, limit int) ([]*models.LoginAttempt, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+	return []*models.LoginAttempt{
+		{ID: primitive.NewObjectID(), UserID: &obId, Email: "john.doe@example.com", IP: "192.0.2.1", Success: true, CreatedAt: time.Now()},
+		{ID: primitive.NewObjectID(), UserID: &obId, Email: "john.doe@example.com", IP: "192.0.2.1", Reason: models.LoginFailureInvalidCredentials, CreatedAt: time.Now()},
+	}, nil
+}
+
+func (m *MockLoginAttemptService) LockoutStatus(userID string) (*models.LockoutStatus, error) {
+	lockedUntil := time.Now().Add(time.Minute)
+	return &models.LockoutStatus{Locked: true, LockedUntil: &lockedUntil, Lockouts: 1}, nil
+}
+
+func (m *MockLoginAttemptService) Unlock(userID string) error {
+	m.Unlocked = userID
+	return nil
+}
+
+// userRequest calls handler for userId, as a caller with the given id and role.
+func userRequest(handler gin.HandlerFunc, method string, userId string, callerID string, role string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/api/users/"+userId, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "userId", Value: userId}}
+	if role != "" {
+		c.Set(UserIDKey, callerID)
+		c.Set(RoleKey, role)
+	}
+
+	handler(c)
+	c.Writer.WriteHeaderNow()
+
+	return w
+}
+
+func TestFindLogins(t *testing.T) {
+	loginAttemptController := NewLoginAttemptController(&MockLoginAttemptService{})
+	userId := primitive.NewObjectID().Hex()
+
+	w := userRequest(loginAttemptController.FindLogins, "GET", userId, userId, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LoginHistoryResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Results)
+	assert.True(t, response.Data[0].Success)
+	assert.Equal(t, models.LoginFailureInvalidCredentials, response.Data[1].Reason)
+
+	w = userRequest(loginAttemptController.FindLogins, "GET", userId, primitive.NewObjectID().Hex(), models.RoleUser)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = userRequest(loginAttemptController.FindLogins, "GET", userId, primitive.NewObjectID().Hex(), models.RoleAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLockoutStatus(t *testing.T) {
+	loginAttemptController := NewLoginAttemptController(&MockLoginAttemptService{})
+	userId := primitive.NewObjectID().Hex()
+
+	w := userRequest(loginAttemptController.LockoutStatus, "GET", userId, userId, models.RoleUser)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LockoutStatusResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Data.Locked)
+	assert.NotNil(t, response.Data.LockedUntil)
+}
+
+func TestUnlock(t *testing.T) {
+	mockLoginAttemptService := &MockLoginAttemptService{}
+	loginAttemptController := NewLoginAttemptController(mockLoginAttemptService)
+	userId := primitive.NewObjectID().Hex()
+
+	// Users cannot unlock themselves
+	w := userRequest(loginAttemptController.Unlock, "DELETE", userId, userId, models.RoleUser)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mockLoginAttemptService.Unlocked)
+
+	w = userRequest(loginAttemptController.Unlock, "DELETE", userId, primitive.NewObjectID().Hex(), models.RoleAdmin)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, userId, mockLoginAttemptService.Unlocked)
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController struct {
+	mfaService services.MFAService
+}
+
+func NewMFAController(mfaService services.MFAService) MFAController {
+	return MFAController{mfaService}
+}
+
+// Enroll creates a TOTP secret for the caller.
+// @Summary Enroll in two-factor authentication
+// @Description Create a TOTP secret for the signed in user. It is only used once confirmed with a code from the authenticator app.
+// @Tags MFA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.MFAEnrollmentResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/mfa/enroll [post]
+func (mc *MFAController) Enroll(ctx *gin.Context) {
+	enrollment, err := mc.mfaService.Enroll(callerID(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": enrollment})
+}
+
+// Confirm turns two-factor authentication on.
+// @Summary Confirm two-factor enrollment
+// @Description Turn two-factor authentication on with a code from the authenticator app. The recovery codes are only shown in this response.
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.MFACodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/mfa/confirm [post]
+func (mc *MFAController) Confirm(ctx *gin.Context) {
+	var req *models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	codes, err := mc.mfaService.ConfirmEnrollment(callerID(ctx), req.Code)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": codes})
+}
+
+// Disable turns two-factor authentication off.
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off with a code from the authenticator app or a recovery code
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Param request body models.MFACodeRequest true "TOTP or recovery code"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/mfa/disable [post]
+func (mc *MFAController) Disable(ctx *gin.Context) {
+	var req *models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if err := mc.mfaService.Disable(callerID(ctx), req.Code); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the caller.
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code, with a code from the authenticator app or a recovery code. The new codes are only shown in this response.
+// @Tags MFA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/mfa/recovery-codes [post]
+func (mc *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req *models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	codes, err := mc.mfaService.RegenerateRecoveryCodes(callerID(ctx), req.Code)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": codes})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockMFAService is a mock implementation of the MFAService interface
+type MockMFAService struct {
+	Enabled map[string]bool
+}
+
+func (m *MockMFAService) Enroll(userID string) (*models.MFAEnrollment, error) {
+	if m.Enabled[userID] {
+		return nil, services.ErrMFAAlreadyEnabled
+	}
+
+	return &models.MFAEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/go_crud:john.doe@example.com?secret=JBSWY3DPEHPK3PXP"}, nil
+}
+
+func (m *MockMFAService) ConfirmEnrollment(userID string, code string) (*models.RecoveryCodes, error) {
+	if code != "123456" {
+		return nil, services.ErrInvalidMFACode
+	}
+
+	m.Enabled[userID] = true
+	return &models.RecoveryCodes{Codes: []string{"abcde-12345"}}, nil
+}
+
+func (m *MockMFAService) Disable(userID string, code string) error {
+	if !m.Enabled[userID] {
+		return services.ErrMFANotEnabled
+	}
+
+	m.Enabled[userID] = false
+	return nil
+}
+
+func (m *MockMFAService) RegenerateRecoveryCodes(userID string, code string) (*models.RecoveryCodes, error) {
+	return &models.RecoveryCodes{Codes: []string{"fghij-67890"}}, nil
+}
+
+func (m *MockMFAService) StartChallenge(userID primitive.ObjectID) (string, time.Time, error) {
+	return "mfa-token", time.Now().Add(time.Minute), nil
+}
+
+func (m *MockMFAService) CompleteChallenge(token string, code string) (primitive.ObjectID, error) {
+	return primitive.NewObjectID(), nil
+}
+
+// mfaRequest calls handler as the signed in user userID.
+func mfaRequest(handler gin.HandlerFunc, userID string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/auth/mfa", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(UserIDKey, userID)
+
+	handler(c)
+	c.Writer.WriteHeaderNow()
+
+	return w
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	mfaController := NewMFAController(&MockMFAService{Enabled: map[string]bool{}})
+	userID := primitive.NewObjectID().Hex()
+
+	w := mfaRequest(mfaController.Enroll, userID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var enrollment models.MFAEnrollmentResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", enrollment.Data.Secret)
+	assert.True(t, strings.HasPrefix(enrollment.Data.URI, "otpauth://totp/"))
+
+	w = mfaRequest(mfaController.Confirm, userID, `{"code": "000000"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = mfaRequest(mfaController.Confirm, userID, `{"code": "123456"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var codes models.RecoveryCodesResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &codes))
+	assert.Equal(t, []string{"abcde-12345"}, codes.Data.Codes)
+
+	// Enrolling again needs two-factor authentication disabled first
+	w = mfaRequest(mfaController.Enroll, userID, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = mfaRequest(mfaController.RegenerateRecoveryCodes, userID, `{"code": "123456"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = mfaRequest(mfaController.Disable, userID, `{"code": "abcde-12345"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = mfaRequest(mfaController.Disable, userID, `{"code": "123456"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = mfaRequest(mfaController.Disable, userID, `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCController struct {
+	oidcService services.OIDCService
+	authService services.AuthService
+}
+
+func NewOIDCController(oidcService services.OIDCService, authService services.AuthService) OIDCController {
+	return OIDCController{oidcService, authService}
+}
+
+// Providers lists the identity providers.
+// @Summary List identity providers
+// @Description List the identity providers users can sign in with
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} models.OIDCProvidersResponse
+// @Router /api/auth/oidc [get]
+func (oc *OIDCController) Providers(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": oc.oidcService.Providers()})
+}
+
+// Login starts a sign in with an identity provider.
+// @Summary Sign in with an identity provider
+// @Description Redirect to the identity provider, which redirects back to the callback once the user has signed in
+// @Tags Auth
+// @Param provider path string true "Identity provider name"
+// @Success 302 "Found"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/auth/oidc/{provider}/login [get]
+func (oc *OIDCController) Login(ctx *gin.Context) {
+	link, err := oc.oidcService.AuthURL(ctx.Param("provider"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, link)
+}
+
+// Link starts linking an identity provider to the caller.
+// @Summary Link an identity provider
+// @Description Start linking an identity provider to the signed in user, who can then sign in with it. Send the user to the returned URL, the callback finishes the link and signs in.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Param provider path string true "Identity provider name"
+// @Success 200 {object} models.OIDCLinkResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/auth/oidc/{provider}/link [post]
+func (oc *OIDCController) Link(ctx *gin.Context) {
+	link, err := oc.oidcService.LinkURL(ctx.Param("provider"), callerID(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.OIDCLink{URL: link}})
+}
+
+// Callback finishes a sign in with an identity provider.
+// @Summary Finish signing in with an identity provider
+// @Description The page identity providers redirect back to. The user is created on their first sign in, unless an account already uses their email: its owner links the provider with POST /api/auth/oidc/{provider}/link first. Users with two-factor authentication get a challenge, like with a password.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Identity provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State of the sign in"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/oidc/{provider}/callback [get]
+func (oc *OIDCController) Callback(ctx *gin.Context) {
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		message := "the identity provider refused the sign in: " + providerErr
+		if description := ctx.Query("error_description"); description != "" {
+			message += ", " + description
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": message})
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "the code and state query parameters are required"})
+		return
+	}
+
+	result, err := oc.authService.LoginOIDC(ctx.Param("provider"), code, state, loginClient(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": result})
+}
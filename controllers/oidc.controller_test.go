@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockOIDCService is a mock implementation of the OIDCService interface
+type MockOIDCService struct{}
+
+func (m *MockOIDCService) Providers() []string {
+	return []string{"corp"}
+}
+
+func (m *MockOIDCService) AuthURL(provider string) (string, error) {
+	if provider != "corp" {
+		return "", services.ErrOIDCProviderNotFound
+	}
+
+	return "https://login.example.com/authorize?client_id=go_crud", nil
+}
+
+func (m *MockOIDCService) LinkURL(provider string, userID string) (string, error) {
+	if userID == "" {
+		return "", &services.Error{Code: services.ErrCodeInvalid, Message: "invalid user Id"}
+	}
+
+	return m.AuthURL(provider)
+}
+
+func (m *MockOIDCService) Callback(provider string, code string, state string) (*models.DBUser, error) {
+	return nil, services.ErrInvalidOIDCState
+}
+
+func oidcRequest(handler gin.HandlerFunc, target string, provider string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", target, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "provider", Value: provider}}
+
+	handler(c)
+
+	return w
+}
+
+func TestOIDCProviders(t *testing.T) {
+	oidcController := NewOIDCController(&MockOIDCService{}, &MockAuthService{})
+
+	w := oidcRequest(oidcController.Providers, "/api/auth/oidc", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.OIDCProvidersResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"corp"}, response.Data)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	oidcController := NewOIDCController(&MockOIDCService{}, &MockAuthService{})
+
+	w := oidcRequest(oidcController.Login, "/api/auth/oidc/corp/login", "corp")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://login.example.com/authorize?client_id=go_crud", w.Header().Get("Location"))
+
+	w = oidcRequest(oidcController.Login, "/api/auth/oidc/other/login", "other")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOIDCLink(t *testing.T) {
+	oidcController := NewOIDCController(&MockOIDCService{}, &MockAuthService{})
+
+	req, _ := http.NewRequest("POST", "/api/auth/oidc/corp/link", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "provider", Value: "corp"}}
+	c.Set(UserIDKey, "64b7f0c2a1b2c3d4e5f60718")
+
+	oidcController.Link(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.OIDCLinkResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "https://login.example.com/authorize?client_id=go_crud", response.Data.URL)
+
+	// Only users link providers, not API keys without one
+	w = oidcRequest(oidcController.Link, "/api/auth/oidc/corp/link", "corp")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	oidcController := NewOIDCController(&MockOIDCService{}, &MockAuthService{})
+
+	w := oidcRequest(oidcController.Callback, "/api/auth/oidc/corp/callback?code=abc&state=state", "corp")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LoginResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "token", response.Data.Token)
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/api/auth/oidc/corp/callback?code=abc&state=expired", http.StatusUnauthorized},
+		{"/api/auth/oidc/corp/callback?error=access_denied&error_description=cancelled", http.StatusUnauthorized},
+		{"/api/auth/oidc/corp/callback?state=state", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := oidcRequest(oidcController.Callback, tt.target, "corp")
+		assert.Equal(t, tt.status, w.Code, tt.target)
+	}
+}
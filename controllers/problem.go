@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionKey is the gin context key holding the API version a request
+// was routed to, which picks the format of its errors.
+const APIVersionKey = "apiVersion"
+
+// ProblemContentType is the media type of RFC 7807 problems.
+const ProblemContentType = "application/problem+json"
+
+// AbortWithProblem replies with err as an RFC 7807 problem, the error format
+// of the v2 API. The status is picked from the code of err.
+func AbortWithProblem(ctx *gin.Context, err error) {
+	status := errorStatus(err)
+	problem := models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: ctx.Request.URL.Path,
+		Code:     services.ErrorCode(err),
+	}
+
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		problem.Errors = policyErr.Violations
+	}
+
+	// gin keeps a content type that is already set
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(status, problem)
+}
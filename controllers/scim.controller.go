@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SCIMController struct {
+	scimService services.SCIMService
+}
+
+func NewSCIMController(scimService services.SCIMService) SCIMController {
+	return SCIMController{scimService}
+}
+
+// Authorize only lets admins and service accounts through, with the
+// users:read scope to read and users:write for anything else when their
+// API key has scopes.
+func (sc *SCIMController) Authorize(ctx *gin.Context) {
+	role := callerRole(ctx)
+	if role == "" {
+		sc.abort(ctx, http.StatusUnauthorized, "", "authentication is required")
+		return
+	}
+	if role != models.RoleAdmin && role != models.RoleService {
+		sc.abort(ctx, http.StatusForbidden, "", "only admins and service accounts can provision users")
+		return
+	}
+
+	required := models.ScopeUsersWrite
+	if ctx.Request.Method == http.MethodGet {
+		required = models.ScopeUsersRead
+	}
+	if !hasScope(ctx, required) {
+		sc.abort(ctx, http.StatusForbidden, "", "the API key needs the "+required+" scope")
+		return
+	}
+
+	ctx.Next()
+}
+
+// FindUsers finds users with a SCIM filter.
+// @Summary Find SCIM users
+// @Description Find users with a SCIM filter such as userName eq "jane@example.com". Every operator is supported, on id, userName, emails, displayName, name.formatted, addresses, active and the age of the go_crud extension.
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Param filter query string false "SCIM filter"
+// @Param startIndex query int false "Index of the first user, from 1" Default(1)
+// @Param count query int false "Number of users to return, at most 1000" Default(100)
+// @Success 200 {object} models.SCIMListResponse
+// @Failure 400 {object} models.SCIMError
+// @Failure 401 {object} models.SCIMError
+// @Failure 403 {object} models.SCIMError
+// @Router /scim/v2/Users [get]
+func (sc *SCIMController) FindUsers(ctx *gin.Context) {
+	startIndex, err := strconv.Atoi(ctx.DefaultQuery("startIndex", "1"))
+	if err != nil {
+		sc.abort(ctx, http.StatusBadRequest, services.SCIMTypeInvalidValue, "startIndex must be a number")
+		return
+	}
+
+	count, err := strconv.Atoi(ctx.DefaultQuery("count", "100"))
+	if err != nil {
+		sc.abort(ctx, http.StatusBadRequest, services.SCIMTypeInvalidValue, "count must be a number")
+		return
+	}
+
+	res, err := sc.scimService.FindUsers(ctx.Query("filter"), startIndex, count)
+	if err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	sc.respond(ctx, http.StatusOK, res)
+}
+
+// FindUser finds a user by ID.
+// @Summary Find a SCIM user
+// @Description Find a user by ID as a SCIM resource
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {object} models.SCIMUser
+// @Failure 404 {object} models.SCIMError
+// @Router /scim/v2/Users/{userId} [get]
+func (sc *SCIMController) FindUser(ctx *gin.Context) {
+	user, err := sc.scimService.FindUser(ctx.Param("userId"))
+	if err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", user.Meta.Location)
+	sc.respond(ctx, http.StatusOK, user)
+}
+
+// CreateUser provisions a user.
+// @Summary Create a SCIM user
+// @Description Create a user. The userName is the email and the age, set in the go_crud extension, is required like for POST /api/users. Users without a password get a random one.
+// @Tags SCIM
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param user body models.SCIMUser true "User to create"
+// @Success 201 {object} models.SCIMUser
+// @Failure 400 {object} models.SCIMError
+// @Failure 409 {object} models.SCIMError
+// @Router /scim/v2/Users [post]
+func (sc *SCIMController) CreateUser(ctx *gin.Context) {
+	var user *models.SCIMUser
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		sc.abort(ctx, http.StatusBadRequest, services.SCIMTypeInvalidSyntax, err.Error())
+		return
+	}
+
+	created, err := sc.scimService.CreateUser(user)
+	if err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", created.Meta.Location)
+	sc.respond(ctx, http.StatusCreated, created)
+}
+
+// ReplaceUser replaces a user.
+// @Summary Replace a SCIM user
+// @Description Replace every field of a user, the age and address are removed when left out and the password is kept unless one is given
+// @Tags SCIM
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param user body models.SCIMUser true "User to replace with"
+// @Success 200 {object} models.SCIMUser
+// @Failure 400 {object} models.SCIMError
+// @Failure 404 {object} models.SCIMError
+// @Failure 409 {object} models.SCIMError
+// @Router /scim/v2/Users/{userId} [put]
+func (sc *SCIMController) ReplaceUser(ctx *gin.Context) {
+	var user *models.SCIMUser
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		sc.abort(ctx, http.StatusBadRequest, services.SCIMTypeInvalidSyntax, err.Error())
+		return
+	}
+
+	replaced, err := sc.scimService.ReplaceUser(ctx.Param("userId"), user)
+	if err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	sc.respond(ctx, http.StatusOK, replaced)
+}
+
+// PatchUser applies SCIM PATCH operations to a user.
+// @Summary Patch a SCIM user
+// @Description Apply add, replace and remove operations to a user. Only the age and addresses can be removed. Setting active to false disables the user, and true reactivates them.
+// @Tags SCIM
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param request body models.SCIMPatchRequest true "PATCH operations"
+// @Success 200 {object} models.SCIMUser
+// @Failure 400 {object} models.SCIMError
+// @Failure 404 {object} models.SCIMError
+// @Failure 409 {object} models.SCIMError
+// @Router /scim/v2/Users/{userId} [patch]
+func (sc *SCIMController) PatchUser(ctx *gin.Context) {
+	var req *models.SCIMPatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		sc.abort(ctx, http.StatusBadRequest, services.SCIMTypeInvalidSyntax, err.Error())
+		return
+	}
+
+	patched, err := sc.scimService.PatchUser(ctx.Param("userId"), req)
+	if err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	sc.respond(ctx, http.StatusOK, patched)
+}
+
+// DeleteUser deprovisions a user.
+// @Summary Delete a SCIM user
+// @Description Delete a user by ID
+// @Tags SCIM
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 404 {object} models.SCIMError
+// @Router /scim/v2/Users/{userId} [delete]
+func (sc *SCIMController) DeleteUser(ctx *gin.Context) {
+	if err := sc.scimService.DeleteUser(ctx.Param("userId")); err != nil {
+		sc.error(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig describes the supported SCIM features.
+// @Summary SCIM service provider configuration
+// @Description The SCIM features this server supports
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.SCIMServiceProviderConfig
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (sc *SCIMController) ServiceProviderConfig(ctx *gin.Context) {
+	sc.respond(ctx, http.StatusOK, sc.scimService.ServiceProviderConfig())
+}
+
+// ResourceTypes lists the SCIM resource types.
+// @Summary SCIM resource types
+// @Description The kinds of SCIM resources, only users
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.SCIMResourceTypesResponse
+// @Router /scim/v2/ResourceTypes [get]
+func (sc *SCIMController) ResourceTypes(ctx *gin.Context) {
+	resourceTypes := sc.scimService.ResourceTypes()
+	sc.respond(ctx, http.StatusOK, models.SCIMResourceTypesResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+// ResourceType finds a SCIM resource type.
+// @Summary SCIM resource type
+// @Description A kind of SCIM resource by ID
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Param resourceTypeId path string true "Resource type ID"
+// @Success 200 {object} models.SCIMResourceType
+// @Failure 404 {object} models.SCIMError
+// @Router /scim/v2/ResourceTypes/{resourceTypeId} [get]
+func (sc *SCIMController) ResourceType(ctx *gin.Context) {
+	for _, resourceType := range sc.scimService.ResourceTypes() {
+		if resourceType.ID == ctx.Param("resourceTypeId") {
+			sc.respond(ctx, http.StatusOK, resourceType)
+			return
+		}
+	}
+
+	sc.abort(ctx, http.StatusNotFound, "", "no resource type with that Id exists")
+}
+
+// Schemas lists the SCIM schemas.
+// @Summary SCIM schemas
+// @Description The attributes of the user schemas
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.SCIMSchemasResponse
+// @Router /scim/v2/Schemas [get]
+func (sc *SCIMController) Schemas(ctx *gin.Context) {
+	schemas := sc.scimService.Schemas()
+	sc.respond(ctx, http.StatusOK, models.SCIMSchemasResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: len(schemas),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+		Resources:    schemas,
+	})
+}
+
+// Schema finds a SCIM schema.
+// @Summary SCIM schema
+// @Description The attributes of a schema by its URN
+// @Tags SCIM
+// @Security BearerAuth
+// @Produce json
+// @Param schemaId path string true "Schema URN"
+// @Success 200 {object} models.SCIMSchema
+// @Failure 404 {object} models.SCIMError
+// @Router /scim/v2/Schemas/{schemaId} [get]
+func (sc *SCIMController) Schema(ctx *gin.Context) {
+	for _, schema := range sc.scimService.Schemas() {
+		if schema.ID == ctx.Param("schemaId") {
+			sc.respond(ctx, http.StatusOK, schema)
+			return
+		}
+	}
+
+	sc.abort(ctx, http.StatusNotFound, "", "no schema with that Id exists")
+}
+
+// respond writes body as application/scim+json.
+func (sc *SCIMController) respond(ctx *gin.Context, status int, body interface{}) {
+	ctx.Header("Content-Type", "application/scim+json")
+	ctx.JSON(status, body)
+}
+
+// error responds with a SCIM error for a service error.
+func (sc *SCIMController) error(ctx *gin.Context, err error) {
+	scimType := ""
+	var scimErr *services.SCIMError
+	switch {
+	case errors.As(err, &scimErr):
+		scimType = scimErr.ScimType
+	case services.ErrorCode(err) == services.ErrCodeAlreadyExists:
+		scimType = services.SCIMTypeUniqueness
+	case services.ErrorCode(err) == services.ErrCodeInvalid:
+		scimType = services.SCIMTypeInvalidValue
+	}
+
+	sc.abort(ctx, errorStatus(err), scimType, err.Error())
+}
+
+func (sc *SCIMController) abort(ctx *gin.Context, status int, scimType string, detail string) {
+	ctx.Header("Content-Type", "application/scim+json")
+	ctx.AbortWithStatusJSON(status, models.SCIMError{
+		Schemas:  []string{models.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockSCIMService is a mock implementation of the SCIMService interface
+type MockSCIMService struct {
+	services.SCIMService
+	Filter string
+}
+
+func (m *MockSCIMService) FindUsers(filter string, startIndex int, count int) (*models.SCIMListResponse, error) {
+	if _, err := services.ParseSCIMFilter(filter); err != nil {
+		return nil, err
+	}
+
+	m.Filter = filter
+	return &models.SCIMListResponse{Schemas: []string{models.SCIMListResponseSchema}, StartIndex: startIndex, Resources: []models.SCIMUser{}}, nil
+}
+
+func (m *MockSCIMService) CreateUser(user *models.SCIMUser) (*models.SCIMUser, error) {
+	if user.UserName == "taken@example.com" {
+		return nil, services.ErrEmailExists
+	}
+
+	user.ID = "64b64c2f8f1e4a3b2c1d0e9f"
+	user.Meta = &models.SCIMMeta{ResourceType: "User", Location: "http://localhost:8080/scim/v2/Users/" + user.ID}
+	return user, nil
+}
+
+func (m *MockSCIMService) DeleteUser(id string) error {
+	return services.ErrUserNotFound
+}
+
+func (m *MockSCIMService) Schemas() []models.SCIMSchema {
+	return []models.SCIMSchema{{ID: models.SCIMUserSchema, Name: "User"}}
+}
+
+// newSCIMTestRouter serves the SCIM routes to a caller with the given role and scopes.
+func newSCIMTestRouter(scimService services.SCIMService, role string, scopes []string) *gin.Engine {
+	scimController := NewSCIMController(scimService)
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if role != "" {
+			ctx.Set(RoleKey, role)
+		}
+		if scopes != nil {
+			ctx.Set(ScopesKey, scopes)
+		}
+	})
+
+	scim := router.Group("/scim/v2", scimController.Authorize)
+	scim.GET("/Users", scimController.FindUsers)
+	scim.POST("/Users", scimController.CreateUser)
+	scim.DELETE("/Users/:userId", scimController.DeleteUser)
+	scim.GET("/Schemas/:schemaId", scimController.Schema)
+
+	return router
+}
+
+func scimRequest(router *gin.Engine, method string, target string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req, _ := http.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestSCIMAuthorize(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string
+		scopes []string
+		method string
+		status int
+	}{
+		{"anonymous", "", nil, "GET", http.StatusUnauthorized},
+		{"user", models.RoleUser, nil, "GET", http.StatusForbidden},
+		{"admin", models.RoleAdmin, nil, "GET", http.StatusOK},
+		{"service account", models.RoleService, nil, "POST", http.StatusCreated},
+		{"read scope", models.RoleService, []string{models.ScopeUsersRead}, "GET", http.StatusOK},
+		{"read scope writing", models.RoleService, []string{models.ScopeUsersRead}, "POST", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newSCIMTestRouter(&MockSCIMService{}, tt.role, tt.scopes)
+
+			w, response := scimRequest(router, tt.method, "/scim/v2/Users", `{"userName": "jane@example.com"}`)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "application/scim+json", w.Header().Get("Content-Type"))
+			if tt.status >= 400 {
+				assert.Equal(t, []interface{}{models.SCIMErrorSchema}, response["schemas"])
+			}
+		})
+	}
+}
+
+func TestSCIMFindUsers(t *testing.T) {
+	scimService := &MockSCIMService{}
+	router := newSCIMTestRouter(scimService, models.RoleAdmin, nil)
+
+	w, response := scimRequest(router, "GET", `/scim/v2/Users?filter=userName+eq+%22jane%40example.com%22&startIndex=3`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `userName eq "jane@example.com"`, scimService.Filter)
+	assert.Equal(t, float64(3), response["startIndex"])
+
+	w, response = scimRequest(router, "GET", `/scim/v2/Users?filter=nickName+eq+%22jd%22`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, services.SCIMTypeInvalidFilter, response["scimType"])
+	assert.Equal(t, "400", response["status"])
+
+	w, _ = scimRequest(router, "GET", `/scim/v2/Users?count=many`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSCIMCreateUser(t *testing.T) {
+	router := newSCIMTestRouter(&MockSCIMService{}, models.RoleAdmin, nil)
+
+	w, response := scimRequest(router, "POST", "/scim/v2/Users", `{"userName": "jane@example.com"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "http://localhost:8080/scim/v2/Users/64b64c2f8f1e4a3b2c1d0e9f", w.Header().Get("Location"))
+	assert.Equal(t, "64b64c2f8f1e4a3b2c1d0e9f", response["id"])
+
+	w, response = scimRequest(router, "POST", "/scim/v2/Users", `{"userName": "taken@example.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, services.SCIMTypeUniqueness, response["scimType"])
+
+	w, response = scimRequest(router, "POST", "/scim/v2/Users", `{"userName": `)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, services.SCIMTypeInvalidSyntax, response["scimType"])
+}
+
+func TestSCIMDeleteUser(t *testing.T) {
+	router := newSCIMTestRouter(&MockSCIMService{}, models.RoleAdmin, nil)
+
+	w, response := scimRequest(router, "DELETE", "/scim/v2/Users/64b64c2f8f1e4a3b2c1d0e9f", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404", response["status"])
+}
+
+func TestSCIMSchema(t *testing.T) {
+	router := newSCIMTestRouter(&MockSCIMService{}, models.RoleAdmin, nil)
+
+	w, response := scimRequest(router, "GET", "/scim/v2/Schemas/"+models.SCIMUserSchema, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "User", response["name"])
+
+	w, _ = scimRequest(router, "GET", "/scim/v2/Schemas/urn:unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
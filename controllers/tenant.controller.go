@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TenantController struct {
+	tenantService services.TenantService
+}
+
+func NewTenantController(tenantService services.TenantService) TenantController {
+	return TenantController{tenantService}
+}
+
+// CreateTenant creates a tenant.
+// @Summary Create a tenant
+// @Description Create a tenant, for admins of the default tenant. Its users are stored in the shared database, or in a database of their own when dedicatedDatabase is set.
+// @Tags tenants
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateTenantRequest true "Id, name and storage of the tenant"
+// @Success 201 {object} models.TenantResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/tenants [post]
+func (tc *TenantController) CreateTenant(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage tenants"})
+		return
+	}
+
+	var req *models.CreateTenantRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	tenant, err := tc.tenantService.CreateTenant(req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": tenant})
+}
+
+// FindTenants lists the tenants.
+// @Summary List tenants
+// @Description List the tenants, for admins of the default tenant.
+// @Tags tenants
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.FindTenantsResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/tenants [get]
+func (tc *TenantController) FindTenants(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage tenants"})
+		return
+	}
+
+	tenants, err := tc.tenantService.FindTenants()
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(tenants), "data": tenants})
+}
+
+// FindTenant finds a tenant.
+// @Summary Find a tenant
+// @Description Find a tenant by its Id, for admins of the default tenant.
+// @Tags tenants
+// @Security BearerAuth
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Success 200 {object} models.TenantResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/tenants/{tenantId} [get]
+func (tc *TenantController) FindTenant(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage tenants"})
+		return
+	}
+
+	tenant, err := tc.tenantService.FindTenant(ctx.Param("tenantId"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": tenant})
+}
+
+// UpdateTenant renames a tenant.
+// @Summary Update a tenant
+// @Description Update the name of a tenant, for admins of the default tenant. Its Id and storage cannot change.
+// @Tags tenants
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param tenantId path string true "Tenant ID"
+// @Param request body models.UpdateTenantRequest true "New name of the tenant"
+// @Success 200 {object} models.TenantResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/tenants/{tenantId} [patch]
+func (tc *TenantController) UpdateTenant(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage tenants"})
+		return
+	}
+
+	var req *models.UpdateTenantRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	tenant, err := tc.tenantService.UpdateTenant(ctx.Param("tenantId"), req)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": tenant})
+}
+
+// DeleteTenant deletes a tenant.
+// @Summary Delete a tenant
+// @Description Stop serving a tenant, for admins of the default tenant. Its users are kept, so creating the tenant again with the same Id and storage brings them back.
+// @Tags tenants
+// @Security BearerAuth
+// @Param tenantId path string true "Tenant ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/tenants/{tenantId} [delete]
+func (tc *TenantController) DeleteTenant(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can manage tenants"})
+		return
+	}
+
+	if err := tc.tenantService.DeleteTenant(ctx.Param("tenantId")); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
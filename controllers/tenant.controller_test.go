@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockTenantService is a mock implementation of the TenantService interface
+type MockTenantService struct {
+	services.TenantService
+	Deleted string
+}
+
+func (m *MockTenantService) CreateTenant(req *models.CreateTenantRequest) (*models.Tenant, error) {
+	if req.ID == "acme" {
+		return nil, services.ErrTenantExists
+	}
+
+	tenant := &models.Tenant{ID: req.ID, Name: req.Name, CreatedAt: time.Now()}
+	if req.DedicatedDatabase {
+		tenant.Database = "go_crud_" + req.ID
+	}
+	return tenant, nil
+}
+
+func (m *MockTenantService) DeleteTenant(id string) error {
+	if id != "acme" {
+		return services.ErrTenantNotFound
+	}
+
+	m.Deleted = id
+	return nil
+}
+
+func TestCreateTenant(t *testing.T) {
+	tenantController := NewTenantController(&MockTenantService{})
+
+	tests := []struct {
+		name   string
+		role   string
+		body   string
+		status int
+	}{
+		{"Created", models.RoleAdmin, `{"id": "globex", "name": "Globex", "dedicatedDatabase": true}`, http.StatusCreated},
+		{"Not an admin", models.RoleUser, `{"id": "globex", "name": "Globex"}`, http.StatusForbidden},
+		{"Missing name", models.RoleAdmin, `{"id": "globex"}`, http.StatusBadRequest},
+		{"Existing", models.RoleAdmin, `{"id": "acme", "name": "Acme"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/tenants", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := apiKeyRequest(tenantController.CreateTenant, req, nil, "", tt.role, "")
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusCreated {
+				assert.Contains(t, w.Body.String(), `"database":"go_crud_globex"`)
+			}
+		})
+	}
+}
+
+func TestDeleteTenant(t *testing.T) {
+	tenantService := &MockTenantService{}
+	tenantController := NewTenantController(tenantService)
+
+	req, _ := http.NewRequest("DELETE", "/api/tenants/acme", nil)
+	w := apiKeyRequest(tenantController.DeleteTenant, req, gin.Params{{Key: "tenantId", Value: "acme"}}, "", models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, tenantService.Deleted)
+
+	w = apiKeyRequest(tenantController.DeleteTenant, req, gin.Params{{Key: "tenantId", Value: "acme"}}, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "acme", tenantService.Deleted)
+
+	req, _ = http.NewRequest("DELETE", "/api/tenants/initech", nil)
+	w = apiKeyRequest(tenantController.DeleteTenant, req, gin.Params{{Key: "tenantId", Value: "initech"}}, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -27,7 +27,7 @@ func NewUserController(userService services.UserService) UserController {
 // @Produce json
 // @Param user body models.CreateUserRequest true "User data to create"
 // @Success 201 {object} models.CreateUserResponse
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid user data, a password rejected by the policy lists the broken rules in errors"
 // @Failure 409 {object} models.ErrorResponse
 // @Router /api/users [post]
 func (pc *UserController) CreateUser(ctx *gin.Context) {
@@ -46,26 +46,44 @@ func (pc *UserController) CreateUser(ctx *gin.Context) {
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		ctx.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": newUser})
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": toUserV1(newUser)})
 }
 
 // UpdateUser updates an existing user by user ID.
 // @Summary Update an existing user
-// @Description Update an existing user with the provided user data
+// @Description Update an existing user with the provided user data. With application/json the fields that are set are updated,
+// @Description with application/merge-patch+json (RFC 7396) null removes the age or address, and with application/json-patch+json
+// @Description the body is a list of RFC 6902 operations, applied to {name, age, email, address}. The password can only be added.
 // @Tags Users
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
 // @Param userId path string true "User ID"
 // @Param user body models.UpdateUser true "User data to update"
 // @Success 200 {object} models.UpdateUserResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Router /api/users/{userId} [patch]
 func (pc *UserController) UpdateUser(ctx *gin.Context) {
+	if !CanManageUser(ctx, ctx.Param("userId")) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can update other users"})
+		return
+	}
+
+	switch ctx.ContentType() {
+	case "application/merge-patch+json":
+		pc.mergePatchUser(ctx)
+		return
+	case "application/json-patch+json":
+		pc.jsonPatchUser(ctx)
+		return
+	}
+
 	userId := ctx.Param("userId")
 
 	var user *models.UpdateUser
@@ -76,50 +94,145 @@ func (pc *UserController) UpdateUser(ctx *gin.Context) {
 
 	updatedUser, err := pc.userService.UpdateUser(userId, user)
 	if err != nil {
-		if strings.Contains(err.Error(), "Id exists") {
-			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": err.Error()})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		ctx.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": updatedUser})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": toUserV1(updatedUser)})
+}
+
+func (pc *UserController) mergePatchUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	patch, err := services.ParseUserMergePatch(body)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	updatedUser, err := pc.userService.PatchUser(userId, patch)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": toUserV1(updatedUser)})
+}
+
+func (pc *UserController) jsonPatchUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	var ops []models.JSONPatchOperation
+	if err := ctx.ShouldBindJSON(&ops); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	updatedUser, err := pc.userService.JSONPatchUser(userId, ops)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": toUserV1(updatedUser)})
+}
+
+// ReplaceUser replaces an existing user by user ID.
+// @Summary Replace an existing user
+// @Description Replace every field of an existing user. The age and address are removed when left out, the password is kept unless one is given.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param user body models.ReplaceUserRequest true "User data to replace with"
+// @Success 200 {object} models.UpdateUserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users/{userId} [put]
+func (pc *UserController) ReplaceUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can update other users"})
+		return
+	}
+
+	var user *models.ReplaceUserRequest
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	updatedUser, err := pc.userService.ReplaceUser(userId, user)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": toUserV1(updatedUser)})
 }
 
 // FindUserById finds a user by user ID.
 // @Summary Find a user by ID
-// @Description Find a user by the provided user ID
+// @Description Find a user by the provided user ID, users can only find themselves
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param fields query string false "Comma separated fields to return: id, name, age, email, address"
 // @Success 200 {object} models.FindUserResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Router /api/users/{userId} [get]
 func (pc *UserController) FindUserById(ctx *gin.Context) {
 	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can read other users"})
+		return
+	}
 
-	user, err := pc.userService.FindUserById(userId)
+	err := checkFieldsV1(ctx.Query("fields"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
 
+	fields, err := services.UserReadFields(callerRole(ctx), ctx.Query("fields"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	user, err := pc.userService.FindUserById(userId, fields...)
+
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": user})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": selectUserFields(user, fields)})
 }
 
 // FindUsers finds a list of users with pagination.
 // @Summary Find users with pagination
-// @Description Find users with pagination based on page and limit query parameters
+// @Description Find users with pagination based on page and limit query parameters. Callers that cannot manage users only read their id and name.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" Default(1)
 // @Param limit query int false "Number of items per page" Default(10)
+// @Param fields query string false "Comma separated fields to return: id, name, age, email, address"
 // @Success 200 {object} models.FindUsersResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Router /api/users [get]
 func (pc *UserController) FindUsers(ctx *gin.Context) {
 	var page = ctx.DefaultQuery("page", "1")
@@ -137,13 +250,30 @@ func (pc *UserController) FindUsers(ctx *gin.Context) {
 		return
 	}
 
-	users, err := pc.userService.FindUsers(intPage, intLimit)
+	err = checkFieldsV1(ctx.Query("fields"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(users), "data": users})
+	fields, err := services.UserListFields(callerRole(ctx), ctx.Query("fields"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	users, err := pc.userService.FindUsers(intPage, intLimit, fields...)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	data := make([]interface{}, len(users))
+	for i, user := range users {
+		data[i] = selectUserFields(user, fields)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(users), "data": data})
 }
 
 // DeleteUser deletes a user by user ID.
@@ -155,10 +285,15 @@ func (pc *UserController) FindUsers(ctx *gin.Context) {
 // @Param userId path string true "User ID"
 // @Success 204 "No Content"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /api/users/{userId} [delete]
 func (pc *UserController) DeleteUser(ctx *gin.Context) {
 	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can delete other users"})
+		return
+	}
 
 	err := pc.userService.DeleteUser(userId)
 
@@ -20,6 +20,9 @@ import (
 // MockUserService is a mock implementation of the UserService interface
 type MockUserService struct {
 	ShouldFailCreateUser409 bool
+	// The last patch and JSON Patch operations received
+	Patch *models.UserPatch
+	Ops   []models.JSONPatchOperation
 }
 
 func NewMockUserService() services.UserService {
@@ -54,7 +57,30 @@ func (m *MockUserService) UpdateUser(id string, data *models.UpdateUser) (*model
 	}, nil
 }
 
-func (m *MockUserService) FindUserById(id string) (*models.User, error) {
+func (m *MockUserService) ReplaceUser(id string, data *models.ReplaceUserRequest) (*models.User, error) {
+	// Implement the ReplaceUser method of the UserService interface
+	// Return a mock replaced user and nil error for testing purposes
+	objID, _ := primitive.ObjectIDFromHex(id)
+	return &models.User{
+		ID:      objID,
+		Name:    data.Name,
+		Age:     data.Age,
+		Email:   data.Email,
+		Address: data.Address,
+	}, nil
+}
+
+func (m *MockUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	m.Patch = patch
+	return m.UpdateUser(id, &patch.Set)
+}
+
+func (m *MockUserService) JSONPatchUser(id string, ops []models.JSONPatchOperation) (*models.User, error) {
+	m.Ops = ops
+	return m.FindUserById(id)
+}
+
+func (m *MockUserService) FindUserById(id string, fields ...string) (*models.User, error) {
 	// Implement the FindUserById method of the UserService interface
 	// Return a mock user and nil error for testing purposes
 	objID, _ := primitive.ObjectIDFromHex(id)
@@ -63,11 +89,11 @@ func (m *MockUserService) FindUserById(id string) (*models.User, error) {
 		Name:    "John Doe",
 		Age:     intPointer(30), // Use intPointer(30) to create a pointer to the integer value 30
 		Email:   "john.doe@example.com",
-		Address: "123 Main St",
+		Address: &models.Address{Formatted: "123 Main St"},
 	}, nil
 }
 
-func (m *MockUserService) FindUsers(page int, limit int) ([]*models.User, error) {
+func (m *MockUserService) FindUsers(page int, limit int, fields ...string) ([]*models.User, error) {
 	// Implement the FindUsers method of the UserService interface
 	// Return a mock list of users and nil error for testing purposes
 	users := []*models.User{
@@ -76,19 +102,23 @@ func (m *MockUserService) FindUsers(page int, limit int) ([]*models.User, error)
 			Name:    "John Doe",
 			Age:     intPointer(30), // Use intPointer(30) to create a pointer to the integer value 30
 			Email:   "john.doe@example.com",
-			Address: "123 Main St",
+			Address: &models.Address{Formatted: "123 Main St"},
 		},
 		{
 			ID:      primitive.NewObjectID(),
 			Name:    "Jane Smith",
 			Age:     intPointer(28), // Use intPointer(28) to create a pointer to the integer value 28
 			Email:   "jane.smith@example.com",
-			Address: "456 Oak St",
+			Address: &models.Address{Formatted: "456 Oak St"},
 		},
 	}
 	return users, nil
 }
 
+func (m *MockUserService) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
+	return m.FindUsers(page, limit, fields...)
+}
+
 func (m *MockUserService) DeleteUser(id string) error {
 	// Implement the DeleteUser method of the UserService interface
 	// For testing purposes, we return nil, indicating success
@@ -112,7 +142,7 @@ func TestCreateUser(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	// Convert the user request to JSON
@@ -153,7 +183,7 @@ func TestCreateUserFail409(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	// Convert the user request to JSON
@@ -193,7 +223,7 @@ func TestCreateUserFail400(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	// Convert the user request to JSON
@@ -247,6 +277,7 @@ func TestUpdateUser(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
 
 	// Call the UpdateUser handler
 	userController.UpdateUser(c)
@@ -264,6 +295,106 @@ func TestUpdateUser(t *testing.T) {
 	assert.NotNil(t, response.Data)
 }
 
+// TestMergePatchUser tests the UpdateUser handler with a JSON merge patch
+func TestMergePatchUser(t *testing.T) {
+	mockUserService := &MockUserService{}
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("PATCH", "/api/users/123", strings.NewReader(`{"name": "Updated Name", "address": null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userController.UpdateUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Updated Name", mockUserService.Patch.Set.Name)
+	assert.Equal(t, []string{"address"}, mockUserService.Patch.Unset)
+}
+
+// TestMergePatchUserFail400 tests that a merge patch cannot remove a required field
+func TestMergePatchUserFail400(t *testing.T) {
+	mockUserService := &MockUserService{}
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("PATCH", "/api/users/123", strings.NewReader(`{"email": null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userController.UpdateUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, mockUserService.Patch)
+}
+
+// TestJSONPatchUser tests the UpdateUser handler with a JSON Patch
+func TestJSONPatchUser(t *testing.T) {
+	mockUserService := &MockUserService{}
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("PATCH", "/api/users/123", strings.NewReader(`[{"op": "remove", "path": "/age"}]`))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userController.UpdateUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.JSONPatchOperation{{Op: "remove", Path: "/age"}}, mockUserService.Ops)
+}
+
+// TestReplaceUser tests the ReplaceUser handler
+func TestReplaceUser(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	reqBody, _ := json.Marshal(&models.ReplaceUserRequest{
+		Name:  "John Doe",
+		Email: "john.doe@example.com",
+	})
+
+	req, _ := http.NewRequest("PUT", "/api/users/123", strings.NewReader(string(reqBody)))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userController.ReplaceUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.UpdateUserResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "success", response.Status)
+	assert.Nil(t, response.Data.Age)
+
+	// The name and email are required
+	req, _ = http.NewRequest("PUT", "/api/users/123", strings.NewReader(`{"name": "John Doe"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userController.ReplaceUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // TestFindUserById tests the FindUserById handler
 func TestFindUserById(t *testing.T) {
 	// Create a mock user service
@@ -277,6 +408,7 @@ func TestFindUserById(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
 
 	// Call the FindUserById handler
 	userController.FindUserById(c)
@@ -324,6 +456,113 @@ func TestFindUsers(t *testing.T) {
 	assert.NotNil(t, response.Data)
 }
 
+// TestFindUsersFields tests the fields query parameter of the FindUsers handler
+func TestFindUsersFields(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("GET", "/api/users?fields=id,name", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	userController.FindUsers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Len(t, response.Data, 2)
+	for _, user := range response.Data {
+		assert.Len(t, user, 2)
+		assert.Contains(t, user, "id")
+		assert.Contains(t, user, "name")
+	}
+}
+
+// TestFindUsersFieldsFail403 tests that users cannot read the email of the users they list
+func TestFindUsersFieldsFail403(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("GET", "/api/users?fields=id,email", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleUser)
+	c.Set(UserIDKey, "123")
+
+	userController.FindUsers(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed to read the email field")
+}
+
+// TestFindUserByIdFieldsFail403 tests that a caller cannot read fields hidden from their role
+func TestFindUserByIdFieldsFail403(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("GET", "/api/users/123?fields=address", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "userId", Value: "123"}}
+	c.Set(RoleKey, models.RoleUser)
+	c.Set(UserIDKey, "123")
+
+	userController.FindUserById(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed to read the address field")
+}
+
+// TestFindUserByIdFieldsFail400 tests that v1 rejects the fields only v2 renders
+func TestFindUserByIdFieldsFail400(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	for _, field := range []string{"attributes", "groups"} {
+		req, _ := http.NewRequest("GET", "/api/users/123?fields=id,"+field, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = gin.Params{{Key: "userId", Value: "123"}}
+		c.Set(RoleKey, models.RoleAdmin)
+		c.Set(UserIDKey, "123")
+
+		userController.FindUserById(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, field)
+		assert.Contains(t, w.Body.String(), `unknown field \"`+field+`\"`, field)
+	}
+}
+
+// TestFindUsersFieldsFail400 tests that v1 rejects the fields only v2 renders
+func TestFindUsersFieldsFail400(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	req, _ := http.NewRequest("GET", "/api/users?fields=attributes", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+	c.Set(UserIDKey, "123")
+
+	userController.FindUsers(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // TestDeleteUser tests the DeleteUser handler
 func TestDeleteUser(t *testing.T) {
 	// Create a mock user service
@@ -337,6 +576,7 @@ func TestDeleteUser(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
 
 	// Call the DeleteUser handler
 	userController.DeleteUser(c)
@@ -344,3 +584,26 @@ func TestDeleteUser(t *testing.T) {
 	// Check the response status code
 	assert.Equal(t, http.StatusNoContent, w.Code)
 }
+
+// TestDeleteUserFail403 tests that users can only delete themselves
+func TestDeleteUserFail403(t *testing.T) {
+	mockUserService := NewMockUserService()
+	userController := NewUserController(mockUserService)
+
+	for _, callerID := range []string{"", "456"} {
+		req, _ := http.NewRequest("DELETE", "/api/users/123", nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = gin.Params{{Key: "userId", Value: "123"}}
+		if callerID != "" {
+			c.Set(RoleKey, models.RoleUser)
+			c.Set(UserIDKey, callerID)
+		}
+
+		userController.DeleteUser(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, callerID)
+	}
+}
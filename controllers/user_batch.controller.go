@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserBatchController struct {
+	userBatchService services.UserBatchService
+}
+
+func NewUserBatchController(userBatchService services.UserBatchService) UserBatchController {
+	return UserBatchController{userBatchService}
+}
+
+// BatchCreateUsers creates users in bulk.
+// @Summary Create users in bulk
+// @Description Create many users at once. Ordered batches (the default) stop at the first failing item, unordered batches process every item. The result of every item is returned in request order. Created users are sent a verification email.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param users body models.BatchCreateUsersRequest true "Users to create"
+// @Success 200 {object} models.BatchUsersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/users:batchCreate [post]
+func (bc *UserBatchController) BatchCreateUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change users in batches"})
+		return
+	}
+
+	var request *models.BatchCreateUsersRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	results, err := bc.userBatchService.BatchCreateUsers(request.Users, request.Mode != models.BatchModeUnordered)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": results})
+}
+
+// BatchUpdateUsers updates users in bulk.
+// @Summary Update users in bulk
+// @Description Update many users at once. Ordered batches (the default) stop at the first failing item, unordered batches process every item. The result of every item is returned in request order.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param users body models.BatchUpdateUsersRequest true "Users to update"
+// @Success 200 {object} models.BatchUsersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/users:batchUpdate [patch]
+func (bc *UserBatchController) BatchUpdateUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change users in batches"})
+		return
+	}
+
+	var request *models.BatchUpdateUsersRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	results, err := bc.userBatchService.BatchUpdateUsers(request.Users, request.Mode != models.BatchModeUnordered)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": results})
+}
+
+// BatchDeleteUsers deletes users in bulk.
+// @Summary Delete users in bulk
+// @Description Delete many users at once. Ordered batches (the default) stop at the first failing item, unordered batches process every item. The result of every item is returned in request order.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param ids body models.BatchDeleteUsersRequest true "IDs of the users to delete"
+// @Success 200 {object} models.BatchUsersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/users:batchDelete [post]
+func (bc *UserBatchController) BatchDeleteUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change users in batches"})
+		return
+	}
+
+	var request *models.BatchDeleteUsersRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	results, err := bc.userBatchService.BatchDeleteUsers(request.IDs, request.Mode != models.BatchModeUnordered)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": results})
+}
@@ -0,0 +1,130 @@
+// user_batch.controller_test.go
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockUserBatchService is a mock implementation of the UserBatchService interface
+type MockUserBatchService struct {
+	Ordered bool
+}
+
+func (m *MockUserBatchService) BatchCreateUsers(users []models.CreateUserRequest, ordered bool) ([]models.BatchItemResult, error) {
+	m.Ordered = ordered
+	if len(users) > 2 {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: "a batch must contain between 1 and 2 items"}
+	}
+
+	results := make([]models.BatchItemResult, len(users))
+	for i := range users {
+		results[i] = models.BatchItemResult{Index: i, Status: models.BatchItemCreated, ID: "64b7f0c2a1b2c3d4e5f60718"}
+	}
+	return results, nil
+}
+
+func (m *MockUserBatchService) BatchUpdateUsers(users []models.BatchUpdateUser, ordered bool) ([]models.BatchItemResult, error) {
+	m.Ordered = ordered
+	results := make([]models.BatchItemResult, len(users))
+	for i, user := range users {
+		results[i] = models.BatchItemResult{Index: i, Status: models.BatchItemFailed, ID: user.ID, Error: &models.BatchItemError{Code: services.ErrCodeNotFound, Message: services.ErrUserNotFound.Error()}}
+	}
+	return results, nil
+}
+
+func (m *MockUserBatchService) BatchDeleteUsers(ids []string, ordered bool) ([]models.BatchItemResult, error) {
+	m.Ordered = ordered
+	results := make([]models.BatchItemResult, len(ids))
+	for i, id := range ids {
+		results[i] = models.BatchItemResult{Index: i, Status: models.BatchItemDeleted, ID: id}
+	}
+	return results, nil
+}
+
+func performBatchRequest(controller UserBatchController, handler func(*UserBatchController, *gin.Context), method, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/api/users:batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	handler(&controller, c)
+	return w
+}
+
+// TestBatchCreateUsers tests the BatchCreateUsers handler
+func TestBatchCreateUsers(t *testing.T) {
+	mockUserBatchService := &MockUserBatchService{}
+	userBatchController := NewUserBatchController(mockUserBatchService)
+
+	w := performBatchRequest(userBatchController, (*UserBatchController).BatchCreateUsers, "POST",
+		`{"users": [{"name": "John Doe", "age": 30, "email": "john.doe@example.com", "password": "123", "address": "123 Main St"}]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mockUserBatchService.Ordered)
+
+	var response models.BatchUsersResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "success", response.Status)
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, models.BatchItemCreated, response.Data[0].Status)
+}
+
+func TestBatchCreateUsersFail400(t *testing.T) {
+	userBatchController := NewUserBatchController(&MockUserBatchService{})
+
+	// Unknown mode
+	w := performBatchRequest(userBatchController, (*UserBatchController).BatchCreateUsers, "POST", `{"mode": "random", "users": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Too many users
+	w = performBatchRequest(userBatchController, (*UserBatchController).BatchCreateUsers, "POST", `{"users": [{}, {}, {}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestBatchUpdateUsers tests the BatchUpdateUsers handler
+func TestBatchUpdateUsers(t *testing.T) {
+	mockUserBatchService := &MockUserBatchService{}
+	userBatchController := NewUserBatchController(mockUserBatchService)
+
+	w := performBatchRequest(userBatchController, (*UserBatchController).BatchUpdateUsers, "PATCH",
+		`{"mode": "unordered", "users": [{"id": "64b7f0c2a1b2c3d4e5f60718", "name": "Updated Name"}]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mockUserBatchService.Ordered)
+
+	var response models.BatchUsersResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, models.BatchItemFailed, response.Data[0].Status)
+	assert.Equal(t, services.ErrCodeNotFound, response.Data[0].Error.Code)
+}
+
+// TestBatchDeleteUsers tests the BatchDeleteUsers handler
+func TestBatchDeleteUsers(t *testing.T) {
+	userBatchController := NewUserBatchController(&MockUserBatchService{})
+
+	w := performBatchRequest(userBatchController, (*UserBatchController).BatchDeleteUsers, "POST",
+		`{"ids": ["64b7f0c2a1b2c3d4e5f60718", "64b7f0c2a1b2c3d4e5f60719"]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.BatchUsersResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, "64b7f0c2a1b2c3d4e5f60719", response.Data[1].ID)
+}
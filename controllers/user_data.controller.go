@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userDataContentTypes maps every user data export format to its content type.
+var userDataContentTypes = map[string]string{
+	models.UserDataFormatJSON: "application/json",
+	models.UserDataFormatZIP:  "application/zip",
+}
+
+type UserDataController struct {
+	userDataService services.UserDataService
+}
+
+func NewUserDataController(userDataService services.UserDataService) UserDataController {
+	return UserDataController{userDataService}
+}
+
+// ExportUserData downloads everything held about a user.
+// @Summary Export the data of a user
+// @Description Download the profile, identities, groups, sessions, login history, API keys, invites and audit log of a user, for subject access requests. Users can export their own data, admins that of any user. Every export is recorded in the audit log.
+// @Tags Users
+// @Security BearerAuth
+// @Produce json,application/zip
+// @Param userId path string true "User ID"
+// @Param format query string false "json or zip" Default(json)
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{userId}/export [get]
+func (dc *UserDataController) ExportUserData(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !CanManageUser(ctx, userId) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "you can only export your own data"})
+		return
+	}
+
+	format := ctx.DefaultQuery("format", models.UserDataFormatJSON)
+	export, err := dc.userDataService.ExportUser(userId, format, auditActor(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", userDataContentTypes[format])
+	ctx.Header("Content-Disposition", `attachment; filename="user-`+userId+`.`+format+`"`)
+	ctx.Status(http.StatusOK)
+	if err := services.WriteUserData(ctx.Writer, export, format); err != nil {
+		// The status is sent already, the client gets a truncated file
+		ctx.Error(err)
+	}
+}
+
+// EraseUser erases a user and the personal data about them.
+// @Summary Erase a user
+// @Description Delete a user with their sessions, tokens and API keys, and anonymise their login history and invites, for right to erasure requests by admins. The audit log, which only refers to users by ID, is kept and records the erasure.
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {object} models.UserErasureResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{userId}/erase [post]
+func (dc *UserDataController) EraseUser(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can erase users"})
+		return
+	}
+
+	erasure, err := dc.userDataService.EraseUser(ctx.Param("userId"), auditActor(ctx))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": erasure})
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockUserDataService is a mock implementation of the UserDataService interface
+type MockUserDataService struct {
+	services.UserDataService
+	Actor models.AuditActor
+}
+
+func (m *MockUserDataService) ExportUser(userID string, format string, actor models.AuditActor) (*models.UserDataExport, error) {
+	if format != models.UserDataFormatJSON && format != models.UserDataFormatZIP {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: "unsupported export format"}
+	}
+
+	m.Actor = actor
+	id, _ := primitive.ObjectIDFromHex(userID)
+	return &models.UserDataExport{ExportedAt: time.Now(), User: &models.User{ID: id, Name: "Jane Doe"}}, nil
+}
+
+func (m *MockUserDataService) EraseUser(userID string, actor models.AuditActor) (*models.UserErasure, error) {
+	m.Actor = actor
+	return &models.UserErasure{UserID: userID, Removed: map[string]int64{"users": 1}}, nil
+}
+
+func TestExportUserData(t *testing.T) {
+	userDataService := &MockUserDataService{}
+	userDataController := NewUserDataController(userDataService)
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "userId", Value: userId}}
+
+	req, _ := http.NewRequest("GET", "/api/users/"+userId+"/export", nil)
+	w := apiKeyRequest(userDataController.ExportUserData, req, params, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="user-`+userId+`.json"`, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), `"name": "Jane Doe"`)
+	assert.Equal(t, models.AuditActor{UserID: userId}, userDataService.Actor)
+
+	req, _ = http.NewRequest("GET", "/api/users/"+userId+"/export?format=zip", nil)
+	w = apiKeyRequest(userDataController.ExportUserData, req, params, "", models.RoleAdmin, "key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, models.AuditActor{APIKeyID: "key"}, userDataService.Actor)
+
+	req, _ = http.NewRequest("GET", "/api/users/"+userId+"/export?format=pdf", nil)
+	w = apiKeyRequest(userDataController.ExportUserData, req, params, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/users/"+userId+"/export", nil)
+	w = apiKeyRequest(userDataController.ExportUserData, req, params, primitive.NewObjectID().Hex(), models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestEraseUser(t *testing.T) {
+	userDataController := NewUserDataController(&MockUserDataService{})
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "userId", Value: userId}}
+
+	req, _ := http.NewRequest("POST", "/api/users/"+userId+"/erase", nil)
+	w := apiKeyRequest(userDataController.EraseUser, req, params, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = apiKeyRequest(userDataController.EraseUser, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"removed":{"users":1}`)
+}
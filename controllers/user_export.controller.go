@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportContentTypes maps every export format to its content type.
+var exportContentTypes = map[string]string{
+	models.ExportFormatCSV:    "text/csv",
+	models.ExportFormatNDJSON: "application/x-ndjson",
+	models.ExportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type UserExportController struct {
+	userExportService services.UserExportService
+}
+
+func NewUserExportController(userExportService services.UserExportService) UserExportController {
+	return UserExportController{userExportService}
+}
+
+// ExportUsers exports users as CSV, NDJSON or XLSX.
+// @Summary Export users
+// @Description Stream users as a CSV, NDJSON or XLSX download, selected with the filters of GET /api/v2/users. The password hash is never exported, and callers only export the fields their role may read. With async=true the export runs in the background and a job is returned instead.
+// @Tags Users
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,json
+// @Param format query string false "csv, ndjson or xlsx" Default(csv)
+// @Param columns query string false "Comma separated columns out of id, name, age, email and address" Default(id,name,age,email,address)
+// @Param page query int false "Page number" Default(1)
+// @Param limit query int false "Number of users per page, every user is exported when omitted"
+// @Param async query bool false "Run the export in the background" Default(false)
+// @Param city query string false "City of the address, ignoring case"
+// @Param country query string false "ISO 3166-1 alpha-2 country code of the address"
+// @Param near query string false "Latitude and longitude, such as 52.37,4.89, to only export users with an address close to it"
+// @Param radius query number false "Distance in meters from near, at most 1000000" Default(10000)
+// @Param attributes[name] query string false "Value of the custom attribute name, a date without a time matches the whole day"
+// @Param group query string false "ID of a group to only export its members, including the members of groups nested in it"
+// @Param status query string false "Only export users with this status: pending, active, suspended or disabled"
+// @Param sort query string false "Custom attribute to sort by, from the highest value when it starts with -"
+// @Success 200 {file} file
+// @Success 202 {object} models.ExportJobResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Not an admin, or a column the role may not read"
+// @Router /api/users/export [get]
+func (ec *UserExportController) ExportUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can export users"})
+		return
+	}
+
+	opts := models.ExportUsersOptions{Format: ctx.DefaultQuery("format", models.ExportFormatCSV)}
+	if columns := ctx.Query("columns"); columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(column))
+		}
+	}
+
+	var err error
+	if opts.Page, err = strconv.Atoi(ctx.DefaultQuery("page", "1")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if opts.Limit, err = strconv.Atoi(ctx.DefaultQuery("limit", "0")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	async, err := strconv.ParseBool(ctx.DefaultQuery("async", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if opts.Filter, err = UserFilter(ctx); err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	opts.Role = ctx.GetString(RoleKey)
+
+	if async {
+		job, err := ec.userExportService.StartExportJob(opts)
+		if err != nil {
+			ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+
+		ctx.Header("Location", "/api/users/export/jobs/"+job.ID)
+		ctx.JSON(http.StatusAccepted, gin.H{"status": "success", "data": job})
+		return
+	}
+
+	ctx.Header("Content-Type", exportContentTypes[opts.Format])
+	ctx.Header("Content-Disposition", `attachment; filename="users.`+opts.Format+`"`)
+
+	if err := ec.userExportService.ExportUsers(ctx.Request.Context(), ctx.Writer, opts); err != nil {
+		if ctx.Writer.Written() {
+			// Too late to send an error response, cut the download short
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Writer.Header().Del("Content-Disposition")
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+	}
+}
+
+// FindExportJob finds a background export by job ID.
+// @Summary Find an export job
+// @Description Find a background export by job ID. The download URL is set once the export is done.
+// @Tags Users
+// @Produce json
+// @Param jobId path string true "Export job ID"
+// @Success 200 {object} models.ExportJobResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/export/jobs/{jobId} [get]
+func (ec *UserExportController) FindExportJob(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can export users"})
+		return
+	}
+
+	job, err := ec.userExportService.FindExportJob(ctx.Param("jobId"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if job.Status == models.ExportJobDone {
+		job.DownloadURL = "/api/users/export/jobs/" + job.ID + "/download"
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": job})
+}
+
+// DownloadExportJob downloads the file of a finished background export.
+// @Summary Download an export
+// @Description Download the file written by a finished background export.
+// @Tags Users
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,json
+// @Param jobId path string true "Export job ID"
+// @Success 200 {file} file
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users/export/jobs/{jobId}/download [get]
+func (ec *UserExportController) DownloadExportJob(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can export users"})
+		return
+	}
+
+	path, job, err := ec.userExportService.ExportJobFile(ctx.Param("jobId"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", exportContentTypes[job.Format])
+	ctx.FileAttachment(path, "users."+job.Format)
+}
@@ -0,0 +1,192 @@
+// user_export.controller_test.go
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockUserExportService is a mock implementation of the UserExportService interface
+type MockUserExportService struct {
+	Opts     models.ExportUsersOptions
+	FilePath string
+	Job      *models.ExportJob
+}
+
+func (m *MockUserExportService) ExportUsers(ctx context.Context, w io.Writer, opts models.ExportUsersOptions) error {
+	m.Opts = opts
+	if opts.Format == "pdf" {
+		return &services.Error{Code: services.ErrCodeInvalid, Message: `unsupported export format "pdf"`}
+	}
+
+	_, err := io.WriteString(w, "id,name\n1,John Doe\n")
+	return err
+}
+
+func (m *MockUserExportService) StartExportJob(opts models.ExportUsersOptions) (*models.ExportJob, error) {
+	m.Opts = opts
+	return &models.ExportJob{ID: "job-1", Status: models.ExportJobPending, Format: opts.Format, CreatedAt: time.Now()}, nil
+}
+
+func (m *MockUserExportService) FindExportJob(id string) (*models.ExportJob, error) {
+	if m.Job == nil || m.Job.ID != id {
+		return nil, services.ErrExportJobNotFound
+	}
+
+	job := *m.Job
+	return &job, nil
+}
+
+func (m *MockUserExportService) ExportJobFile(id string) (string, *models.ExportJob, error) {
+	job, err := m.FindExportJob(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return m.FilePath, job, nil
+}
+
+// TestExportUsers tests the ExportUsers handler
+func TestExportUsers(t *testing.T) {
+	mockUserExportService := &MockUserExportService{}
+	userExportController := NewUserExportController(mockUserExportService)
+
+	req, _ := http.NewRequest("GET", "/api/users/export?format=csv&columns=id,%20name&limit=100&group=g1&status=suspended&country=NL", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleService)
+
+	userExportController.ExportUsers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="users.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, []string{"id", "name"}, mockUserExportService.Opts.Columns)
+	assert.Equal(t, 1, mockUserExportService.Opts.Page)
+	assert.Equal(t, 100, mockUserExportService.Opts.Limit)
+	assert.Equal(t, models.UserFilter{Group: "g1", Status: models.UserStatusSuspended, Country: "NL", Attributes: map[string]string{}}, mockUserExportService.Opts.Filter)
+	assert.Equal(t, models.RoleService, mockUserExportService.Opts.Role)
+	assert.Equal(t, "id,name\n1,John Doe\n", w.Body.String())
+}
+
+func TestExportUsersFail400(t *testing.T) {
+	userExportController := NewUserExportController(&MockUserExportService{})
+
+	req, _ := http.NewRequest("GET", "/api/users/export?format=pdf", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userExportController.ExportUsers(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	// Only admins and service accounts export users
+	req, _ = http.NewRequest("GET", "/api/users/export", nil)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleUser)
+
+	userExportController.ExportUsers(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestExportUsersAsync tests starting a background export
+func TestExportUsersAsync(t *testing.T) {
+	userExportController := NewUserExportController(&MockUserExportService{})
+
+	req, _ := http.NewRequest("GET", "/api/users/export?format=xlsx&async=true", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userExportController.ExportUsers(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/api/users/export/jobs/job-1", w.Header().Get("Location"))
+
+	var response models.ExportJobResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, models.ExportJobPending, response.Data.Status)
+	assert.Equal(t, models.ExportFormatXLSX, response.Data.Format)
+}
+
+// TestFindExportJob tests the FindExportJob handler
+func TestFindExportJob(t *testing.T) {
+	userExportController := NewUserExportController(&MockUserExportService{
+		Job: &models.ExportJob{ID: "job-1", Status: models.ExportJobDone, Format: models.ExportFormatCSV},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/users/export/jobs/job-1", nil)
+	c.Params = gin.Params{{Key: "jobId", Value: "job-1"}}
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userExportController.FindExportJob(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ExportJobResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "/api/users/export/jobs/job-1/download", response.Data.DownloadURL)
+
+	// Unknown job
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/users/export/jobs/job-2", nil)
+	c.Params = gin.Params{{Key: "jobId", Value: "job-2"}}
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userExportController.FindExportJob(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestDownloadExportJob tests the DownloadExportJob handler
+func TestDownloadExportJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users-job-1.csv")
+	os.WriteFile(path, []byte("id,name\n"), 0o600)
+
+	userExportController := NewUserExportController(&MockUserExportService{
+		FilePath: path,
+		Job:      &models.ExportJob{ID: "job-1", Status: models.ExportJobDone, Format: models.ExportFormatCSV},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/users/export/jobs/job-1/download", nil)
+	c.Params = gin.Params{{Key: "jobId", Value: "job-1"}}
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userExportController.DownloadExportJob(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "users.csv")
+	assert.Equal(t, "id,name\n", w.Body.String())
+}
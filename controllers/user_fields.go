@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userFieldsV1 are the fields v1 can select, the attributes and groups are
+// only rendered by v2.
+var userFieldsV1 = []string{"id", "name", "age", "email", "address"}
+
+// checkFieldsV1 rejects a fields= value selecting a field v1 does not render.
+func checkFieldsV1(query string) error {
+	for _, field := range strings.Split(query, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		known := false
+		for _, v1Field := range userFieldsV1 {
+			known = known || field == v1Field
+		}
+		if !known {
+			return &services.Error{Code: services.ErrCodeInvalid, Message: fmt.Sprintf("unknown field %q", field)}
+		}
+	}
+
+	return nil
+}
+
+// toUserV1 maps user to the frozen v1 representation.
+func toUserV1(user *models.User) *models.UserV1 {
+	return &models.UserV1{
+		ID:            user.ID,
+		Name:          user.Name,
+		Age:           user.Age,
+		Email:         user.Email,
+		Address:       user.Address.String(),
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		VerifiedAt:    user.VerifiedAt,
+		MFAEnabled:    user.MFAEnabled,
+	}
+}
+
+// selectUserFields renders only the given fields of user, or the whole user
+// when fields is nil.
+func selectUserFields(user *models.User, fields []string) interface{} {
+	if fields == nil {
+		return toUserV1(user)
+	}
+
+	selected := gin.H{}
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected["id"] = user.ID
+		case "name":
+			selected["name"] = user.Name
+		case "age":
+			selected["age"] = user.Age
+		case "email":
+			selected["email"] = user.Email
+		case "address":
+			selected["address"] = user.Address.String()
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultRadius and MaxRadius bound the distance in meters of the users
+// found near a location.
+const (
+	DefaultRadius = 10000
+	MaxRadius     = 1000000
+)
+
+// UserFilter reads the conditions selecting users from the query: the
+// address, custom attributes, group, status and sort order. Callers can
+// only filter on the fields they may read of the users listed.
+func UserFilter(ctx *gin.Context) (models.UserFilter, error) {
+	filter, err := addressFilter(ctx)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.Attributes = ctx.QueryMap("attributes")
+	filter.Group = ctx.Query("group")
+	filter.Status = ctx.Query("status")
+	filter.Sort = ctx.Query("sort")
+
+	return filter, services.CheckUserFilter(callerRole(ctx), filter)
+}
+
+// addressFilter reads the address conditions of the query.
+func addressFilter(ctx *gin.Context) (models.UserFilter, error) {
+	filter := models.UserFilter{City: ctx.Query("city"), Country: ctx.Query("country")}
+
+	near, ok := ctx.GetQuery("near")
+	if !ok {
+		if _, ok := ctx.GetQuery("radius"); ok {
+			return filter, &services.Error{Code: services.ErrCodeInvalid, Message: "radius needs near"}
+		}
+		return filter, nil
+	}
+
+	lat, lng, found := strings.Cut(near, ",")
+	latitude, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	longitude, lngErr := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if !found || latErr != nil || lngErr != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return filter, &services.Error{Code: services.ErrCodeInvalid, Message: "near must be a latitude and longitude, such as 52.37,4.89"}
+	}
+
+	radius, err := strconv.ParseFloat(ctx.DefaultQuery("radius", strconv.Itoa(DefaultRadius)), 64)
+	if err != nil || radius <= 0 || radius > MaxRadius {
+		return filter, &services.Error{Code: services.ErrCodeInvalid, Message: "radius must be a number of meters between 0 and " + strconv.Itoa(MaxRadius)}
+	}
+
+	filter.Near = &models.GeoNear{Location: models.GeoPoint{Latitude: latitude, Longitude: longitude}, Radius: radius}
+	return filter, nil
+}
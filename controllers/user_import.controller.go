@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/services"
+	"go_crud/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserImportController struct {
+	userImportService services.UserImportService
+}
+
+func NewUserImportController(userImportService services.UserImportService) UserImportController {
+	return UserImportController{userImportService}
+}
+
+// ImportUsers imports users from a CSV or NDJSON file.
+// @Summary Import users from CSV or NDJSON
+// @Description Stream users from a CSV file (with a header row naming the columns) or an NDJSON file. Every row is validated like a single create, and created users are sent the verification email. The response is a downloadable report listing every row that failed or was skipped.
+// @Tags Users
+// @Accept text/csv,application/x-ndjson,mpfd
+// @Produce json,text/csv
+// @Param file formData file false "File to import, the request body is read when omitted"
+// @Param format query string false "csv or ndjson, detected from the content type or file name when omitted"
+// @Param onDuplicate query string false "skip or upsert rows whose email already exists, upserts keep the password of existing users" Default(skip)
+// @Param dryRun query bool false "Validate the file without writing anything" Default(false)
+// @Param chunkSize query int false "Number of rows committed at once" Default(500)
+// @Param report query string false "Report format, json or csv" Default(json)
+// @Success 200 {object} models.ImportUsersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/users/import [post]
+func (ic *UserImportController) ImportUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can import users"})
+		return
+	}
+
+	opts := models.ImportUsersOptions{
+		Format:      ctx.Query("format"),
+		OnDuplicate: ctx.DefaultQuery("onDuplicate", models.ImportOnDuplicateSkip),
+	}
+
+	var err error
+	if opts.DryRun, err = strconv.ParseBool(ctx.DefaultQuery("dryRun", "false")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if opts.ChunkSize, err = strconv.Atoi(ctx.DefaultQuery("chunkSize", "0")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	reportFormat := ctx.DefaultQuery("report", "json")
+	if reportFormat != "json" && reportFormat != "csv" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "report must be json or csv"})
+		return
+	}
+
+	// Read an uploaded file or the raw request body
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		body = file
+		if opts.Format == "" {
+			opts.Format = importFormat(mime.TypeByExtension(filepath.Ext(header.Filename)), filepath.Ext(header.Filename))
+		}
+	} else if opts.Format == "" {
+		opts.Format = importFormat(ctx.ContentType(), "")
+	}
+
+	report, err := ic.userImportService.ImportUsers(body, opts)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if reportFormat == "csv" {
+		ctx.Header("Content-Disposition", `attachment; filename="user-import-report.csv"`)
+		ctx.Header("Content-Type", "text/csv")
+		ctx.Status(http.StatusOK)
+		if err := utils.WriteImportReportCSV(ctx.Writer, report); err != nil {
+			ctx.Error(err)
+		}
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="user-import-report.json"`)
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": report})
+}
+
+// importFormat guesses the import format from a content type or file extension.
+func importFormat(contentType string, ext string) string {
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	switch {
+	case contentType == "text/csv" || ext == ".csv":
+		return models.ImportFormatCSV
+	case contentType == "application/x-ndjson" || contentType == "application/ndjson" || ext == ".ndjson" || ext == ".jsonl":
+		return models.ImportFormatNDJSON
+	}
+
+	return ""
+}
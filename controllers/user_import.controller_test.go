@@ -0,0 +1,118 @@
+// user_import.controller_test.go
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockUserImportService is a mock implementation of the UserImportService interface
+type MockUserImportService struct {
+	Opts models.ImportUsersOptions
+	Body string
+}
+
+func (m *MockUserImportService) ImportUsers(r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersReport, error) {
+	m.Opts = opts
+	body, _ := io.ReadAll(r)
+	m.Body = string(body)
+
+	if opts.Format == "" {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: "the import format could not be detected"}
+	}
+
+	return &models.ImportUsersReport{
+		DryRun:  opts.DryRun,
+		Total:   2,
+		Created: 1,
+		Failed:  1,
+		Rows: []models.ImportRowResult{
+			{Row: 3, Status: models.ImportRowFailed, Email: "jane.smith@example.com", Error: &models.BatchItemError{Code: services.ErrCodeInvalid, Message: "name is required"}},
+		},
+	}, nil
+}
+
+// TestImportUsers tests the ImportUsers handler with a raw CSV body
+func TestImportUsers(t *testing.T) {
+	mockUserImportService := &MockUserImportService{}
+	userImportController := NewUserImportController(mockUserImportService)
+
+	req, _ := http.NewRequest("POST", "/api/users/import?dryRun=true&onDuplicate=upsert", strings.NewReader("name,email\n"))
+	req.Header.Set("Content-Type", "text/csv")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userImportController.ImportUsers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, models.ImportFormatCSV, mockUserImportService.Opts.Format)
+	assert.Equal(t, models.ImportOnDuplicateUpsert, mockUserImportService.Opts.OnDuplicate)
+	assert.True(t, mockUserImportService.Opts.DryRun)
+	assert.Equal(t, "name,email\n", mockUserImportService.Body)
+
+	var response models.ImportUsersResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, 1, response.Data.Failed)
+	assert.Len(t, response.Data.Rows, 1)
+}
+
+// TestImportUsersMultipartCSVReport tests uploading a file and downloading a CSV report
+func TestImportUsersMultipartCSVReport(t *testing.T) {
+	mockUserImportService := &MockUserImportService{}
+	userImportController := NewUserImportController(mockUserImportService)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "users.ndjson")
+	part.Write([]byte(`{"name": "John Doe"}` + "\n"))
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/api/users/import?report=csv", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userImportController.ImportUsers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ImportFormatNDJSON, mockUserImportService.Opts.Format)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "row,status,email,code,message\n3,failed,jane.smith@example.com,invalid_argument,name is required\n", w.Body.String())
+}
+
+func TestImportUsersFail400(t *testing.T) {
+	userImportController := NewUserImportController(&MockUserImportService{})
+
+	req, _ := http.NewRequest("POST", "/api/users/import", strings.NewReader("???"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userImportController.ImportUsers(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserStatusController struct {
+	userStatusService services.UserStatusService
+}
+
+func NewUserStatusController(userStatusService services.UserStatusService) UserStatusController {
+	return UserStatusController{userStatusService}
+}
+
+// SuspendUser suspends a user.
+// @Summary Suspend a user
+// @Description Stop a pending, active or suspended user from signing in and sign them out, for admins. Suspensions with an until date are lifted once it passes.
+// @Tags Users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param request body models.SuspendUserRequest true "Reason and optional end of the suspension"
+// @Success 200 {object} models.UserStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users/{userId}/suspend [post]
+func (sc *UserStatusController) SuspendUser(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change the status of users"})
+		return
+	}
+
+	var req *models.SuspendUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	user, err := sc.userStatusService.SuspendUser(ctx.Param("userId"), req)
+	sc.respond(ctx, user, err)
+}
+
+// DisableUser disables a user.
+// @Summary Disable a user
+// @Description Stop a user from signing in until they are reactivated and sign them out, for admins.
+// @Tags Users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param request body models.DisableUserRequest false "Reason for disabling the user"
+// @Success 200 {object} models.UserStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users/{userId}/disable [post]
+func (sc *UserStatusController) DisableUser(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change the status of users"})
+		return
+	}
+
+	req := &models.DisableUserRequest{}
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+			return
+		}
+	}
+
+	user, err := sc.userStatusService.DisableUser(ctx.Param("userId"), req)
+	sc.respond(ctx, user, err)
+}
+
+// ReactivateUser reactivates a user.
+// @Summary Reactivate a user
+// @Description Make a pending, suspended or disabled user active again, for admins.
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {object} models.UserStatusResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users/{userId}/reactivate [post]
+func (sc *UserStatusController) ReactivateUser(ctx *gin.Context) {
+	if !isAdmin(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can change the status of users"})
+		return
+	}
+
+	user, err := sc.userStatusService.ReactivateUser(ctx.Param("userId"))
+	sc.respond(ctx, user, err)
+}
+
+func (sc *UserStatusController) respond(ctx *gin.Context, user *models.User, err error) {
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.UserStatus{
+		UserID:         user.ID.Hex(),
+		Status:         user.Status,
+		Reason:         user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+	}})
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockUserStatusService is a mock implementation of the UserStatusService interface
+type MockUserStatusService struct {
+	services.UserStatusService
+	Suspended *models.SuspendUserRequest
+	Disabled  *models.DisableUserRequest
+}
+
+func (m *MockUserStatusService) SuspendUser(id string, req *models.SuspendUserRequest) (*models.User, error) {
+	m.Suspended = req
+	obId, _ := primitive.ObjectIDFromHex(id)
+	return &models.User{ID: obId, Status: models.UserStatusSuspended, StatusReason: req.Reason, SuspendedUntil: req.Until}, nil
+}
+
+func (m *MockUserStatusService) DisableUser(id string, req *models.DisableUserRequest) (*models.User, error) {
+	m.Disabled = req
+	obId, _ := primitive.ObjectIDFromHex(id)
+	return &models.User{ID: obId, Status: models.UserStatusDisabled, StatusReason: req.Reason}, nil
+}
+
+func (m *MockUserStatusService) ReactivateUser(id string) (*models.User, error) {
+	return nil, &services.Error{Code: services.ErrCodeFailedPrecondition, Message: "a active user cannot become active"}
+}
+
+func TestSuspendUser(t *testing.T) {
+	userStatusService := &MockUserStatusService{}
+	userStatusController := NewUserStatusController(userStatusService)
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "userId", Value: userId}}
+
+	body := `{"reason": "Chargeback", "until": "2030-01-01T00:00:00Z"}`
+	req, _ := http.NewRequest("POST", "/api/users/"+userId+"/suspend", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := apiKeyRequest(userStatusController.SuspendUser, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC), *userStatusService.Suspended.Until)
+	assert.JSONEq(t, `{"status": "success", "data": {"userId": "`+userId+`", "status": "suspended", "reason": "Chargeback", "suspendedUntil": "2030-01-01T00:00:00Z"}}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/api/users/"+userId+"/suspend", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = apiKeyRequest(userStatusController.SuspendUser, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/users/"+userId+"/suspend", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = apiKeyRequest(userStatusController.SuspendUser, req, params, userId, models.RoleUser, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestDisableAndReactivateUser(t *testing.T) {
+	userStatusService := &MockUserStatusService{}
+	userStatusController := NewUserStatusController(userStatusService)
+	userId := primitive.NewObjectID().Hex()
+	params := gin.Params{{Key: "userId", Value: userId}}
+
+	// The reason is optional, and so is the body
+	req, _ := http.NewRequest("POST", "/api/users/"+userId+"/disable", nil)
+	w := apiKeyRequest(userStatusController.DisableUser, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", userStatusService.Disabled.Reason)
+
+	req, _ = http.NewRequest("POST", "/api/users/"+userId+"/reactivate", nil)
+	w = apiKeyRequest(userStatusController.ReactivateUser, req, params, "", models.RoleAdmin, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"go_crud/services"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle connections from being closed by proxies.
+const heartbeatInterval = 15 * time.Second
+
+type UserStreamController struct {
+	userStreamService services.UserStreamService
+}
+
+func NewUserStreamController(userStreamService services.UserStreamService) UserStreamController {
+	return UserStreamController{userStreamService}
+}
+
+// StreamUsers streams user changes as Server-Sent Events.
+// @Summary Stream user changes
+// @Description Push create, update and delete events for users as Server-Sent Events. Send Last-Event-ID to resume after a given event.
+// @Tags Users
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {object} models.UserEvent
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/users/stream [get]
+func (sc *UserStreamController) StreamUsers(ctx *gin.Context) {
+	if !canManageUsers(ctx) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "only admins can stream user changes"})
+		return
+	}
+
+	events, err := sc.userStreamService.Watch(ctx.Request.Context(), ctx.GetHeader("Last-Event-ID"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", sse.ContentType)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// user_stream.controller_test.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+)
+
+// MockUserStreamService is a mock implementation of the UserStreamService interface
+type MockUserStreamService struct {
+	Events      []models.UserEvent
+	ShouldFail  bool
+	LastEventID string
+}
+
+func (m *MockUserStreamService) Watch(ctx context.Context, lastEventID string) (<-chan models.UserEvent, error) {
+	if m.ShouldFail {
+		return nil, errors.New("change stream failed")
+	}
+
+	m.LastEventID = lastEventID
+	events := make(chan models.UserEvent, len(m.Events))
+	for _, event := range m.Events {
+		events <- event
+	}
+	close(events)
+
+	return events, nil
+}
+
+// closeNotifyingRecorder adds http.CloseNotifier to the recorder, which gin requires for streaming
+type closeNotifyingRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r *closeNotifyingRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+// TestStreamUsers tests the StreamUsers handler
+func TestStreamUsers(t *testing.T) {
+	mockUserStreamService := &MockUserStreamService{
+		Events: []models.UserEvent{
+			{ID: "token-1", Type: models.UserEventCreated, UserID: "1", User: &models.User{Name: "John Doe"}, Time: time.Now()},
+			{ID: "token-2", Type: models.UserEventDeleted, UserID: "1", Time: time.Now()},
+		},
+	}
+	userStreamController := NewUserStreamController(mockUserStreamService)
+
+	// Create a new HTTP GET request resuming after a previous event
+	req, _ := http.NewRequest("GET", "/api/users/stream", nil)
+	req.Header.Set("Last-Event-ID", "token-0")
+
+	w := &closeNotifyingRecorder{httptest.NewRecorder()}
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	// Call the StreamUsers handler
+	userStreamController.StreamUsers(c)
+
+	// Check the response
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "token-0", mockUserStreamService.LastEventID)
+
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "id:token-1\nevent:create\n"))
+	assert.True(t, strings.Contains(body, "id:token-2\nevent:delete\n"))
+	assert.True(t, strings.Contains(body, `"name":"John Doe"`))
+	assert.False(t, strings.Contains(body, "password"))
+}
+
+func TestStreamUsersFail(t *testing.T) {
+	userStreamController := NewUserStreamController(&MockUserStreamService{ShouldFail: true})
+
+	req, _ := http.NewRequest("GET", "/api/users/stream", nil)
+
+	w := &closeNotifyingRecorder{httptest.NewRecorder()}
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(RoleKey, models.RoleAdmin)
+
+	userStreamController.StreamUsers(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
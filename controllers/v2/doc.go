@@ -0,0 +1,16 @@
+// Package v2 serves version 2 of the REST API. Users are rendered with
+// camelCase fields, responses are wrapped in {data, meta} and errors are
+// RFC 7807 problems.
+//
+// @title Users API
+// @version 2.0
+// @description Version 2 of the Users API. Responses carry the result in data, lists also describe the page in meta. Errors are sent as application/problem+json.
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+//
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+package v2
@@ -0,0 +1,272 @@
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLimit is the largest page of users FindUsers returns
+const maxLimit = 100
+
+type UserController struct {
+	userService services.UserService
+}
+
+func NewUserController(userService services.UserService) UserController {
+	return UserController{userService}
+}
+
+// forbidden is the problem of callers acting on users other than themselves.
+var forbidden = &services.Error{Code: services.ErrCodePermissionDenied, Message: "only admins can act on other users"}
+
+// invalid wraps an error of the request body or query as a service error,
+// so that it is answered with a 400 problem.
+func invalid(err error) error {
+	return &services.Error{Code: services.ErrCodeInvalid, Message: err.Error()}
+}
+
+// CreateUser creates a new user.
+// @Summary Create a new user
+// @Description Create a new user, the Location header points to it
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param user body models.CreateUserRequest true "User data to create"
+// @Success 201 {object} models.UserV2Response
+// @Failure 400 {object} models.Problem "Invalid user data, a password rejected by the policy lists the broken rules in errors"
+// @Failure 409 {object} models.Problem
+// @Router /api/v2/users [post]
+func (uc *UserController) CreateUser(ctx *gin.Context) {
+	var user *models.CreateUserRequest
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		controllers.AbortWithProblem(ctx, invalid(err))
+		return
+	}
+
+	newUser, err := uc.userService.CreateUser(user)
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", strings.TrimSuffix(ctx.Request.URL.Path, "/")+"/"+newUser.ID.Hex())
+	ctx.JSON(http.StatusCreated, gin.H{"data": toUser(newUser)})
+}
+
+// FindUserById finds a user by user ID.
+// @Summary Find a user by ID
+// @Description Find a user by the provided user ID, users can only find themselves
+// @Tags Users
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param fields query string false "Comma separated fields to return: id, name, age, email, address, attributes, groups"
+// @Success 200 {object} models.UserV2Response
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /api/v2/users/{userId} [get]
+func (uc *UserController) FindUserById(ctx *gin.Context) {
+	if !controllers.CanManageUser(ctx, ctx.Param("userId")) {
+		controllers.AbortWithProblem(ctx, forbidden)
+		return
+	}
+
+	fields, err := services.UserReadFields(ctx.GetString(controllers.RoleKey), ctx.Query("fields"))
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	user, err := uc.userService.FindUserById(ctx.Param("userId"), fields...)
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": selectUserFields(user, fields)})
+}
+
+// FindUsers finds a page of users.
+// @Summary Find users with pagination
+// @Description Find a page of users, described in meta. Callers that cannot manage users only read their id and name, and cannot filter on the other fields.
+// @Tags Users
+// @Produce json
+// @Param page query int false "Page number" Default(1)
+// @Param limit query int false "Number of users per page, at most 100" Default(10)
+// @Param fields query string false "Comma separated fields to return: id, name, age, email, address, attributes, groups"
+// @Param city query string false "City of the address, ignoring case"
+// @Param country query string false "ISO 3166-1 alpha-2 country code of the address"
+// @Param near query string false "Latitude and longitude, such as 52.37,4.89, to only find users with an address close to it"
+// @Param radius query number false "Distance in meters from near, at most 1000000" Default(10000)
+// @Param attributes[name] query string false "Value of the custom attribute name, a date without a time matches the whole day"
+// @Param group query string false "ID of a group to only find its members, including the members of groups nested in it"
+// @Param status query string false "Only find users with this status: pending, active, suspended or disabled"
+// @Param sort query string false "Custom attribute to sort by, from the highest value when it starts with -"
+// @Success 200 {object} models.UsersV2Response
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Router /api/v2/users [get]
+func (uc *UserController) FindUsers(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		controllers.AbortWithProblem(ctx, &services.Error{Code: services.ErrCodeInvalid, Message: "page must be a positive integer"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > maxLimit {
+		controllers.AbortWithProblem(ctx, &services.Error{Code: services.ErrCodeInvalid, Message: "limit must be between 1 and " + strconv.Itoa(maxLimit)})
+		return
+	}
+
+	fields, err := services.UserListFields(ctx.GetString(controllers.RoleKey), ctx.Query("fields"))
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	filter, err := controllers.UserFilter(ctx)
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	users, err := uc.userService.SearchUsers(filter, page, limit, fields...)
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	data := make([]interface{}, len(users))
+	for i, user := range users {
+		data[i] = selectUserFields(user, fields)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": data, "meta": models.PageMeta{Page: page, Limit: limit, Count: len(users)}})
+}
+
+// ReplaceUser replaces an existing user by user ID.
+// @Summary Replace an existing user
+// @Description Replace every field of an existing user. The age and address are removed when left out, the password is kept unless one is given.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param user body models.ReplaceUserRequest true "User data to replace with"
+// @Success 200 {object} models.UserV2Response
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Router /api/v2/users/{userId} [put]
+func (uc *UserController) ReplaceUser(ctx *gin.Context) {
+	if !controllers.CanManageUser(ctx, ctx.Param("userId")) {
+		controllers.AbortWithProblem(ctx, forbidden)
+		return
+	}
+
+	var user *models.ReplaceUserRequest
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		controllers.AbortWithProblem(ctx, invalid(err))
+		return
+	}
+
+	updatedUser, err := uc.userService.ReplaceUser(ctx.Param("userId"), user)
+	if err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": toUser(updatedUser)})
+}
+
+// PatchUser updates an existing user by user ID.
+// @Summary Update an existing user
+// @Description Update an existing user with a JSON merge patch (RFC 7396), where null removes the age or address.
+// @Description With application/json-patch+json the body is a list of RFC 6902 operations, applied to {name, age, email, address}.
+// @Tags Users
+// @Accept application/merge-patch+json,json,application/json-patch+json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param user body models.UpdateUser true "User data to update"
+// @Success 200 {object} models.UserV2Response
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Router /api/v2/users/{userId} [patch]
+func (uc *UserController) PatchUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	if !controllers.CanManageUser(ctx, userId) {
+		controllers.AbortWithProblem(ctx, forbidden)
+		return
+	}
+
+	var updatedUser *models.User
+	if ctx.ContentType() == "application/json-patch+json" {
+		var ops []models.JSONPatchOperation
+		if err := ctx.ShouldBindJSON(&ops); err != nil {
+			controllers.AbortWithProblem(ctx, invalid(err))
+			return
+		}
+
+		user, err := uc.userService.JSONPatchUser(userId, ops)
+		if err != nil {
+			controllers.AbortWithProblem(ctx, err)
+			return
+		}
+		updatedUser = user
+	} else {
+		body, err := ctx.GetRawData()
+		if err != nil {
+			controllers.AbortWithProblem(ctx, invalid(err))
+			return
+		}
+
+		patch, err := services.ParseUserMergePatch(body)
+		if err != nil {
+			controllers.AbortWithProblem(ctx, err)
+			return
+		}
+
+		user, err := uc.userService.PatchUser(userId, patch)
+		if err != nil {
+			controllers.AbortWithProblem(ctx, err)
+			return
+		}
+		updatedUser = user
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": toUser(updatedUser)})
+}
+
+// DeleteUser deletes a user by user ID.
+// @Summary Delete a user by ID
+// @Description Delete a user by the provided user ID
+// @Tags Users
+// @Param userId path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /api/v2/users/{userId} [delete]
+func (uc *UserController) DeleteUser(ctx *gin.Context) {
+	if !controllers.CanManageUser(ctx, ctx.Param("userId")) {
+		controllers.AbortWithProblem(ctx, forbidden)
+		return
+	}
+
+	if err := uc.userService.DeleteUser(ctx.Param("userId")); err != nil {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
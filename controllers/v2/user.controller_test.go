@@ -0,0 +1,256 @@
+package v2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+)
+
+var janeID = primitive.NewObjectID()
+
+// MockUserService is a mock implementation of the UserService interface
+type MockUserService struct {
+	services.UserService
+	Page, Limit int
+	Filter      models.UserFilter
+	Patch       *models.UserPatch
+}
+
+func (m *MockUserService) jane() *models.User {
+	age := 30
+	return &models.User{ID: janeID, Name: "Jane Doe", Age: &age, Email: "jane@example.com", Address: &models.Address{Formatted: "1 Main St"}, EmailVerified: true}
+}
+
+func (m *MockUserService) CreateUser(user *models.CreateUserRequest) (*models.User, error) {
+	if user.Email == "taken@example.com" {
+		return nil, services.ErrEmailExists
+	}
+	if len(user.Password) < 8 {
+		return nil, &services.PasswordPolicyError{Violations: []models.PasswordViolation{{Rule: models.PasswordRuleMinLength, Message: "must be at least 8 characters"}}}
+	}
+
+	return &models.User{ID: janeID, Name: user.Name, Age: user.Age, Email: user.Email, Address: user.Address}, nil
+}
+
+func (m *MockUserService) FindUserById(id string, fields ...string) (*models.User, error) {
+	if id != janeID.Hex() {
+		return nil, services.ErrUserNotFound
+	}
+	return m.jane(), nil
+}
+
+func (m *MockUserService) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
+	m.Filter, m.Page, m.Limit = filter, page, limit
+	return []*models.User{m.jane()}, nil
+}
+
+func (m *MockUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	m.Patch = patch
+	user := m.jane()
+	user.Name = patch.Set.Name
+	return user, nil
+}
+
+func (m *MockUserService) DeleteUser(id string) error {
+	if id != janeID.Hex() {
+		return services.ErrUserNotFound
+	}
+	return nil
+}
+
+// newTestRouter serves the v2 user routes to Jane, signed in with the given role.
+func newTestRouter(userService services.UserService, role string) *gin.Engine {
+	userController := NewUserController(userService)
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if role != "" {
+			ctx.Set(controllers.RoleKey, role)
+			ctx.Set(controllers.UserIDKey, janeID.Hex())
+		}
+	})
+
+	router.GET("/api/v2/users", userController.FindUsers)
+	router.GET("/api/v2/users/:userId", userController.FindUserById)
+	router.POST("/api/v2/users", userController.CreateUser)
+	router.PATCH("/api/v2/users/:userId", userController.PatchUser)
+	router.DELETE("/api/v2/users/:userId", userController.DeleteUser)
+
+	return router
+}
+
+func request(router *gin.Engine, method string, target string, contentType string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateUser(t *testing.T) {
+	router := newTestRouter(&MockUserService{}, "")
+
+	w := request(router, "POST", "/api/v2/users", "application/json",
+		`{"name": "Jane Doe", "age": 30, "email": "jane@example.com", "password": "password123",
+		  "address": {"lines": ["1 Main St"], "city": "Springfield", "postalCode": "62701", "country": "US"}}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/users/"+janeID.Hex(), w.Header().Get("Location"))
+	assert.JSONEq(t, `{"data": {
+		"id": "`+janeID.Hex()+`",
+		"name": "Jane Doe",
+		"age": 30,
+		"email": "jane@example.com",
+		"address": {"lines": ["1 Main St"], "city": "Springfield", "postalCode": "62701", "country": "US"},
+		"role": "user",
+		"emailVerified": false,
+		"verifiedAt": null,
+		"mfaEnabled": false,
+		"status": "active",
+		"createdAt": "`+janeID.Timestamp().UTC().Format("2006-01-02T15:04:05Z07:00")+`"
+	}}`, w.Body.String())
+}
+
+func TestCreateUser_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		errors int
+	}{
+		{"Invalid body", `{"name": "Jane Doe"}`, http.StatusBadRequest, services.ErrCodeInvalid, 0},
+		{"Weak password", `{"name": "Jane", "age": 30, "email": "jane@example.com", "password": "short", "address": "1 Main St"}`, http.StatusBadRequest, services.ErrCodeInvalid, 1},
+		{"Email taken", `{"name": "Jane", "age": 30, "email": "taken@example.com", "password": "password123", "address": "1 Main St"}`, http.StatusConflict, services.ErrCodeAlreadyExists, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(&MockUserService{}, "")
+
+			w := request(router, "POST", "/api/v2/users", "application/json", tt.body)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, controllers.ProblemContentType, w.Header().Get("Content-Type"))
+
+			var problem models.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, "about:blank", problem.Type)
+			assert.Equal(t, http.StatusText(tt.status), problem.Title)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, "/api/v2/users", problem.Instance)
+			assert.Len(t, problem.Errors, tt.errors)
+		})
+	}
+}
+
+func TestFindUserById(t *testing.T) {
+	router := newTestRouter(&MockUserService{}, models.RoleUser)
+
+	w := request(router, "GET", "/api/v2/users/"+janeID.Hex(), "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	// Users can only read their id, name and email
+	assert.JSONEq(t, `{"data": {"id": "`+janeID.Hex()+`", "name": "Jane Doe", "email": "jane@example.com"}}`, w.Body.String())
+
+	w = request(router, "GET", "/api/v2/users/"+janeID.Hex()+"?fields=age", "", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// and cannot read others
+	w = request(router, "GET", "/api/v2/users/nope", "", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"permission_denied"`)
+
+	w = request(newTestRouter(&MockUserService{}, models.RoleAdmin), "GET", "/api/v2/users/nope", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"not_found"`)
+}
+
+func TestFindUsers(t *testing.T) {
+	userService := &MockUserService{}
+	router := newTestRouter(userService, models.RoleAdmin)
+
+	w := request(router, "GET", "/api/v2/users?page=2&limit=5&fields=name", "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": [{"name": "Jane Doe"}], "meta": {"page": 2, "limit": 5, "count": 1}}`, w.Body.String())
+	assert.Equal(t, 2, userService.Page)
+	assert.Equal(t, 5, userService.Limit)
+
+	w = request(router, "GET", "/api/v2/users?city=Amsterdam&country=nl&near=52.37,4.89&radius=500", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.UserFilter{City: "Amsterdam", Country: "nl", Near: &models.GeoNear{
+		Location: models.GeoPoint{Latitude: 52.37, Longitude: 4.89}, Radius: 500,
+	}, Attributes: map[string]string{}}, userService.Filter)
+
+	w = request(router, "GET", "/api/v2/users?attributes[department]=Sales&sort=-hired", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"department": "Sales"}, userService.Filter.Attributes)
+	assert.Equal(t, "-hired", userService.Filter.Sort)
+
+	w = request(router, "GET", "/api/v2/users?group=6ad5bbd9d51f80a6d3792dc9", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "6ad5bbd9d51f80a6d3792dc9", userService.Filter.Group)
+
+	w = request(router, "GET", "/api/v2/users?status=suspended", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "suspended", userService.Filter.Status)
+
+	w = request(router, "GET", "/api/v2/users?near=52.37,4.89", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(controllers.DefaultRadius), userService.Filter.Near.Radius)
+
+	for _, query := range []string{"page=0", "page=x", "limit=0", "limit=101", "near=52.37", "near=91,4.89", "near=52.37,4.89&radius=0", "radius=500"} {
+		w = request(router, "GET", "/api/v2/users?"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestFindUsersFilterForbidden(t *testing.T) {
+	router := newTestRouter(&MockUserService{}, models.RoleUser)
+
+	for query, field := range map[string]string{
+		"city=Amsterdam":               "address",
+		"country=nl":                   "address",
+		"near=52.37,4.89&radius=50":    "address",
+		"attributes[department]=Sales": "attributes",
+		"sort=-hired":                  "attributes",
+	} {
+		w := request(router, "GET", "/api/v2/users?"+query, "", "")
+		assert.Equal(t, http.StatusForbidden, w.Code, query)
+		assert.Contains(t, w.Body.String(), "not allowed to filter on the "+field+" field", query)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	userService := &MockUserService{}
+	router := newTestRouter(userService, models.RoleAdmin)
+
+	w := request(router, "PATCH", "/api/v2/users/"+janeID.Hex(), "application/merge-patch+json", `{"name": "Jane Roe", "age": null}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Jane Roe"`)
+	assert.Equal(t, &models.UserPatch{Set: models.UpdateUser{Name: "Jane Roe"}, Unset: []string{"age"}}, userService.Patch)
+}
+
+func TestDeleteUser(t *testing.T) {
+	router := newTestRouter(&MockUserService{}, models.RoleAdmin)
+
+	w := request(router, "DELETE", "/api/v2/users/"+janeID.Hex(), "", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = request(router, "DELETE", "/api/v2/users/nope", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
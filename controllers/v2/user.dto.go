@@ -0,0 +1,74 @@
+package v2
+
+import (
+	"go_crud/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// toUser maps user to the v2 representation.
+func toUser(user *models.User) *models.UserV2 {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	status := user.Status
+	if status == "" {
+		status = models.UserStatusActive
+	}
+
+	var groups []string
+	for _, group := range user.Groups {
+		groups = append(groups, group.Hex())
+	}
+
+	return &models.UserV2{
+		ID:             user.ID.Hex(),
+		Name:           user.Name,
+		Age:            user.Age,
+		Email:          user.Email,
+		Address:        user.Address,
+		Attributes:     user.Attributes,
+		Groups:         groups,
+		Role:           role,
+		EmailVerified:  user.EmailVerified,
+		VerifiedAt:     user.VerifiedAt,
+		MFAEnabled:     user.MFAEnabled,
+		Status:         status,
+		StatusReason:   user.StatusReason,
+		SuspendedUntil: user.SuspendedUntil,
+		CreatedAt:      user.ID.Timestamp().UTC(),
+	}
+}
+
+// selectUserFields renders only the given fields of user, or the whole user
+// when fields is nil.
+func selectUserFields(user *models.User, fields []string) interface{} {
+	dto := toUser(user)
+	if fields == nil {
+		return dto
+	}
+
+	selected := gin.H{}
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected["id"] = dto.ID
+		case "name":
+			selected["name"] = dto.Name
+		case "age":
+			selected["age"] = dto.Age
+		case "email":
+			selected["email"] = dto.Email
+		case "address":
+			selected["address"] = dto.Address
+		case "attributes":
+			selected["attributes"] = dto.Attributes
+		case "groups":
+			selected["groups"] = dto.Groups
+		}
+	}
+
+	return selected
+}
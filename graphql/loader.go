@@ -0,0 +1,96 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batchWait is how long a loader waits for more keys before it fetches a
+// batch. graphql-go resolves sibling fields concurrently, so the keys of a
+// level of the query are loaded at about the same time, not in turn.
+const batchWait = 2 * time.Millisecond
+
+// Thunk is a value computed later, see Loader.
+type Thunk func() (interface{}, error)
+
+// Loader batches lookups by key. Keys loaded within batchWait of the first
+// key of a batch are fetched with a single call, and values are cached for
+// the rest of the request. Loaders are safe for concurrent use.
+type Loader struct {
+	fetch func(keys []string) (map[string]interface{}, error)
+
+	mu      sync.Mutex
+	pending *loaderBatch
+	batches map[string]*loaderBatch
+}
+
+// loaderBatch is a set of keys fetched with one call. done is closed once
+// values or err is set.
+type loaderBatch struct {
+	keys   []string
+	values map[string]interface{}
+	err    error
+	done   chan struct{}
+}
+
+// NewLoader creates a loader fetching values with fetch. Keys missing from
+// the map fetch returns load nil.
+func NewLoader(fetch func(keys []string) (map[string]interface{}, error)) *Loader {
+	return &Loader{fetch: fetch, batches: map[string]*loaderBatch{}}
+}
+
+// Load queues key and returns a thunk waiting for its value.
+func (l *Loader) Load(key string) Thunk {
+	l.mu.Lock()
+	batch, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &loaderBatch{done: make(chan struct{})}
+			time.AfterFunc(batchWait, l.dispatch)
+		}
+		batch = l.pending
+		batch.keys = append(batch.keys, key)
+		l.batches[key] = batch
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		<-batch.done
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		return batch.values[key], nil
+	}
+}
+
+func (l *Loader) dispatch() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	batch.values, batch.err = l.fetch(batch.keys)
+	close(batch.done)
+}
+
+// LoaderFor returns the loader named name of the execution running with
+// ctx, creating it with fetch on first use. Every execution has its own
+// loaders, so nothing is cached across requests.
+func LoaderFor(ctx context.Context, name string, fetch func(keys []string) (map[string]interface{}, error)) *Loader {
+	e, _ := ctx.Value(executionKey{}).(*execution)
+	if e == nil {
+		// Outside of an execution, nothing can be batched
+		return NewLoader(fetch)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	loader, ok := e.loaders[name]
+	if !ok {
+		loader = NewLoader(fetch)
+		e.loaders[name] = loader
+	}
+	return loader
+}
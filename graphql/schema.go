@@ -0,0 +1,104 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	gql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"go_crud/services"
+)
+
+// MaxDocumentSize is the length of the longest query document accepted, in bytes.
+const MaxDocumentSize = 64 << 10
+
+// Schema is an executable GraphQL schema run by graph-gophers/graphql-go,
+// along with the complexity limit of its requests.
+type Schema struct {
+	schema *gql.Schema
+	sdl    string
+	// maxComplexity caps the number of fields a query may resolve, with the
+	// selections of list fields counted once per item, 0 for no limit
+	maxComplexity int
+}
+
+// Response is the result of a request. Data is left out when the request
+// failed before it could run.
+type Response = gql.Response
+
+// NewSchema parses sdl, written with string descriptions, and binds the
+// methods of resolver to its root fields. Selections may nest maxDepth
+// levels deep and queries resolve at most maxComplexity fields, 0 for no
+// limit.
+func NewSchema(sdl string, resolver interface{}, maxDepth int, maxComplexity int) (*Schema, error) {
+	schema, err := gql.ParseSchema(sdl, resolver,
+		gql.UseStringDescriptions(),
+		gql.UseFieldResolvers(),
+		gql.MaxDepth(maxDepth),
+		gql.MaxQueryLength(MaxDocumentSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{schema, sdl, maxComplexity}, nil
+}
+
+// ErrorResponse returns the response of a request that could not run.
+func ErrorResponse(err error) *Response {
+	return &Response{Errors: []*gqlerrors.QueryError{{Message: err.Error()}}}
+}
+
+// Execute runs the operation of query named operationName, or its only
+// operation. Invalid requests, such as those nesting too deep, do not run.
+// The errors resolvers return carry their code in the extensions.
+func (s *Schema) Execute(ctx context.Context, query string, operationName string, variables map[string]interface{}) *Response {
+	ctx = context.WithValue(ctx, executionKey{}, &execution{loaders: map[string]*Loader{}, maxComplexity: s.maxComplexity})
+
+	res := s.schema.Exec(ctx, query, operationName, variables)
+	for _, err := range res.Errors {
+		if err.ResolverError != nil && err.Extensions == nil {
+			err.Extensions = errorExtensions(err.ResolverError)
+		}
+	}
+
+	return res
+}
+
+// SDL prints the schema in the GraphQL schema definition language.
+func (s *Schema) SDL() string {
+	return s.sdl
+}
+
+// execution is the state the resolvers of a request share. graphql-go
+// resolves sibling fields concurrently, so it is guarded by mu.
+type execution struct {
+	mu            sync.Mutex
+	loaders       map[string]*Loader
+	complexity    int
+	maxComplexity int
+}
+
+type executionKey struct{}
+
+// addComplexity counts the fields resolving the field of ctx takes: the
+// field itself and its selections, once for each of items. It fails once
+// the request resolves more fields than allowed, so root fields call it
+// before doing any work.
+func addComplexity(ctx context.Context, items int) error {
+	e, _ := ctx.Value(executionKey{}).(*execution)
+	if e == nil || e.maxComplexity <= 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.complexity += 1 + items*len(gql.SelectedFieldNames(ctx))
+	if e.complexity > e.maxComplexity {
+		return &services.Error{Code: services.ErrCodeInvalid, Message: fmt.Sprintf("the query has a complexity of %d, more than the %d allowed", e.complexity, e.maxComplexity)}
+	}
+	return nil
+}
@@ -0,0 +1,529 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin/binding"
+	gql "github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxPageSize is the largest page users returns
+const maxPageSize = 100
+
+// caller is the authenticated caller of a request.
+type caller struct {
+	ID   string
+	Role string
+	// authorize tells whether the caller may run an operation
+	authorize func(operation string) error
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context for executing the request of the caller
+// with id and role. Like the REST API, an empty role was not authenticated
+// and only reads the public fields. authorize, which may be nil, is called
+// with "query" or "mutation" before a root field of that operation does
+// any work, and its error fails the field.
+func WithCaller(ctx context.Context, id string, role string, authorize func(operation string) error) context.Context {
+	return context.WithValue(ctx, callerKey{}, &caller{id, role, authorize})
+}
+
+func callerRole(ctx context.Context) string {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		return c.Role
+	}
+	return ""
+}
+
+// canManageUser reports whether the caller may act on the user with id.
+func canManageUser(ctx context.Context, id string) error {
+	var callerID string
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		callerID = c.ID
+	}
+
+	if !services.CanManageUser(callerRole(ctx), callerID, id) {
+		return &services.Error{Code: services.ErrCodePermissionDenied, Message: "only admins can act on other users"}
+	}
+	return nil
+}
+
+// rootField starts resolving a root field of operation: it checks that the
+// caller may run the operation and counts the complexity of the field,
+// whose selections are resolved once for each of items.
+func rootField(ctx context.Context, operation string, items int) error {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok && c.authorize != nil {
+		if err := c.authorize(operation); err != nil {
+			return err
+		}
+	}
+
+	return addComplexity(ctx, items)
+}
+
+// userSDL is the schema of the user API.
+const userSDL = `schema {
+  query: Query
+  mutation: Mutation
+}
+
+"A user. Fields the caller may not read resolve to an error."
+type User {
+  id: ID!
+  name: String!
+  age: Int
+  email: String
+  "The address on one line"
+  address: String
+  "Only readable by callers that can read every field"
+  emailVerified: Boolean
+  "Only readable by callers that can read every field"
+  mfaEnabled: Boolean
+  "Only readable by callers that can read every field"
+  role: String
+}
+
+type UserConnection {
+  edges: [UserEdge!]!
+  pageInfo: PageInfo!
+}
+
+type UserEdge {
+  cursor: String!
+  node: User!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  endCursor: String
+}
+
+type Query {
+  "The user with the given ID, or null. Users can only look up themselves, lookups in the same request are batched."
+  user(id: ID!): User
+  "Users in the order they were created, first is at most 100. Callers that cannot manage users only read their id and name, and cannot filter on the other fields."
+  users(filter: UserFilter, first: Int = 10, after: String): UserConnection!
+}
+
+type Mutation {
+  createUser(input: CreateUserInput!): User!
+  "Updates the fields of input, null removes the age or address"
+  updateUser(id: ID!, input: UpdateUserInput!): User!
+  "Deletes a user and returns its ID"
+  deleteUser(id: ID!): ID!
+}
+
+input UserFilter {
+  name: String
+  email: String
+  minAge: Int
+  maxAge: Int
+  ids: [ID!]
+}
+
+input CreateUserInput {
+  name: String!
+  age: Int!
+  email: String!
+  password: String!
+  address: String!
+}
+
+input UpdateUserInput {
+  name: String
+  age: Int
+  email: String
+  password: String
+  address: String
+}
+`
+
+// userConnection is a Relay connection over users.
+type userConnection struct {
+	Edges    []*userEdge
+	PageInfo *pageInfo
+}
+
+type userEdge struct {
+	Cursor string
+	Node   *userNode
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   *string
+}
+
+// userNode resolves the fields of user. fields are the fields the caller
+// may read, which user was found with, nil for every field.
+type userNode struct {
+	user   *models.User
+	fields []string
+}
+
+// readable fails unless the caller may read field. An empty field needs
+// the caller to read every field.
+func (u *userNode) readable(field string) error {
+	if u.fields == nil || (field != "" && containsString(u.fields, field)) {
+		return nil
+	}
+
+	name := field
+	if name == "" {
+		name = "this"
+	}
+	return &services.Error{Code: services.ErrCodePermissionDenied, Message: fmt.Sprintf("not allowed to read the %s field", name)}
+}
+
+func (u *userNode) ID() (gql.ID, error) {
+	return gql.ID(u.user.ID.Hex()), u.readable("id")
+}
+
+func (u *userNode) Name() (string, error) {
+	return u.user.Name, u.readable("name")
+}
+
+func (u *userNode) Age() (*int32, error) {
+	if err := u.readable("age"); err != nil || u.user.Age == nil {
+		return nil, err
+	}
+	age := int32(*u.user.Age)
+	return &age, nil
+}
+
+func (u *userNode) Email() (*string, error) {
+	if err := u.readable("email"); err != nil {
+		return nil, err
+	}
+	return &u.user.Email, nil
+}
+
+func (u *userNode) Address() (*string, error) {
+	if err := u.readable("address"); err != nil || u.user.Address == nil {
+		return nil, err
+	}
+	address := u.user.Address.String()
+	return &address, nil
+}
+
+func (u *userNode) EmailVerified() (*bool, error) {
+	if err := u.readable(""); err != nil {
+		return nil, err
+	}
+	return &u.user.EmailVerified, nil
+}
+
+func (u *userNode) MfaEnabled() (*bool, error) {
+	if err := u.readable(""); err != nil {
+		return nil, err
+	}
+	return &u.user.MFAEnabled, nil
+}
+
+func (u *userNode) Role() (*string, error) {
+	if err := u.readable(""); err != nil {
+		return nil, err
+	}
+	role := u.user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	return &role, nil
+}
+
+type userResolver struct {
+	userService services.UserService
+}
+
+// NewUserSchema returns the schema of the user API, with the given depth
+// and complexity limits. Resolvers call userService, so the same rules
+// apply as for the REST API.
+func NewUserSchema(userService services.UserService, maxDepth int, maxComplexity int) *Schema {
+	schema, err := NewSchema(userSDL, &userResolver{userService}, maxDepth, maxComplexity)
+	if err != nil {
+		panic(err)
+	}
+
+	return schema
+}
+
+// User loads the user through the loader of the request, so the users
+// asked for in one request are found with a single query.
+func (r *userResolver) User(ctx context.Context, args struct{ ID gql.ID }) (*userNode, error) {
+	if err := rootField(ctx, "query", 1); err != nil {
+		return nil, err
+	}
+	if err := canManageUser(ctx, string(args.ID)); err != nil {
+		return nil, err
+	}
+
+	fields, err := services.UserReadFields(callerRole(ctx), "")
+	if err != nil {
+		return nil, err
+	}
+
+	loader := LoaderFor(ctx, "user", func(ids []string) (map[string]interface{}, error) {
+		users, err := r.userService.SearchUsers(models.UserFilter{IDs: ids}, 1, len(ids), fields...)
+		if err != nil {
+			return nil, err
+		}
+
+		found := map[string]interface{}{}
+		for _, user := range users {
+			found[user.ID.Hex()] = user
+		}
+		return found, nil
+	})
+
+	user, err := loader.Load(string(args.ID))()
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &userNode{user.(*models.User), fields}, nil
+}
+
+type usersArgs struct {
+	Filter *userFilterInput
+	First  int32
+	After  *string
+}
+
+type userFilterInput struct {
+	Name   *string
+	Email  *string
+	MinAge *int32
+	MaxAge *int32
+	IDs    *[]gql.ID
+}
+
+func (r *userResolver) Users(ctx context.Context, args usersArgs) (*userConnection, error) {
+	first := int(args.First)
+	if first < 0 || first > maxPageSize {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: fmt.Sprintf("first must be between 0 and %d", maxPageSize)}
+	}
+
+	items := first
+	if items < 1 {
+		items = 1
+	}
+	if err := rootField(ctx, "query", items); err != nil {
+		return nil, err
+	}
+
+	fields, err := services.UserListFields(callerRole(ctx), "")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := userFilter(args.Filter)
+	if err := services.CheckUserFilter(callerRole(ctx), filter); err != nil {
+		return nil, err
+	}
+	if args.After != nil {
+		if filter.AfterID, err = decodeCursor(*args.After); err != nil {
+			return nil, err
+		}
+	}
+
+	// One more user tells whether there is a next page
+	users, err := r.userService.SearchUsers(filter, 1, first+1, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &userConnection{Edges: []*userEdge{}, PageInfo: &pageInfo{HasNextPage: len(users) > first}}
+	if len(users) > first {
+		users = users[:first]
+	}
+	for _, user := range users {
+		res.Edges = append(res.Edges, &userEdge{Cursor: encodeCursor(user.ID.Hex()), Node: &userNode{user, fields}})
+	}
+	if len(res.Edges) > 0 {
+		res.PageInfo.EndCursor = &res.Edges[len(res.Edges)-1].Cursor
+	}
+
+	return res, nil
+}
+
+// userFilter reads the filter argument of users.
+func userFilter(input *userFilterInput) models.UserFilter {
+	var filter models.UserFilter
+	if input == nil {
+		return filter
+	}
+
+	if input.Name != nil {
+		filter.Name = *input.Name
+	}
+	if input.Email != nil {
+		filter.Email = *input.Email
+	}
+	if input.MinAge != nil {
+		minAge := int(*input.MinAge)
+		filter.MinAge = &minAge
+	}
+	if input.MaxAge != nil {
+		maxAge := int(*input.MaxAge)
+		filter.MaxAge = &maxAge
+	}
+	if input.IDs != nil {
+		filter.IDs = []string{}
+		for _, id := range *input.IDs {
+			filter.IDs = append(filter.IDs, string(id))
+		}
+	}
+
+	return filter
+}
+
+// Cursors are opaque to clients, they hold the ID of the user of the edge.
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !primitive.IsValidObjectID(string(id)) {
+		return "", &services.Error{Code: services.ErrCodeInvalid, Message: "invalid cursor"}
+	}
+	return string(id), nil
+}
+
+type createUserInput struct {
+	Name     string
+	Age      int32
+	Email    string
+	Password string
+	Address  string
+}
+
+func (r *userResolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userNode, error) {
+	if err := rootField(ctx, "mutation", 1); err != nil {
+		return nil, err
+	}
+
+	age := int(args.Input.Age)
+	req := &models.CreateUserRequest{
+		Name:     args.Input.Name,
+		Age:      &age,
+		Email:    args.Input.Email,
+		Password: args.Input.Password,
+		Address:  &models.Address{Formatted: args.Input.Address},
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: err.Error()}
+	}
+
+	return r.userResult(ctx, func() (*models.User, error) { return r.userService.CreateUser(req) })
+}
+
+// updateUserInput tells the fields set to null, which are removed, from
+// those left out.
+type updateUserInput struct {
+	Name     gql.NullString
+	Age      gql.NullInt
+	Email    gql.NullString
+	Password gql.NullString
+	Address  gql.NullString
+}
+
+// UpdateUser applies input as a merge patch, like PATCH /api/users/{userId}.
+func (r *userResolver) UpdateUser(ctx context.Context, args struct {
+	ID    gql.ID
+	Input updateUserInput
+}) (*userNode, error) {
+	if err := rootField(ctx, "mutation", 1); err != nil {
+		return nil, err
+	}
+	if err := canManageUser(ctx, string(args.ID)); err != nil {
+		return nil, err
+	}
+
+	input := map[string]interface{}{}
+	if args.Input.Name.Set {
+		input["name"] = args.Input.Name.Value
+	}
+	if args.Input.Age.Set {
+		input["age"] = args.Input.Age.Value
+	}
+	if args.Input.Email.Set {
+		input["email"] = args.Input.Email.Value
+	}
+	if args.Input.Password.Set {
+		input["password"] = args.Input.Password.Value
+	}
+	if args.Input.Address.Set {
+		input["address"] = args.Input.Address.Value
+	}
+
+	body, _ := json.Marshal(input)
+	patch, err := services.ParseUserMergePatch(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := binding.Validator.ValidateStruct(&patch.Set); err != nil {
+		return nil, &services.Error{Code: services.ErrCodeInvalid, Message: err.Error()}
+	}
+
+	return r.userResult(ctx, func() (*models.User, error) { return r.userService.PatchUser(string(args.ID), patch) })
+}
+
+func (r *userResolver) DeleteUser(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	if err := rootField(ctx, "mutation", 1); err != nil {
+		return "", err
+	}
+	if err := canManageUser(ctx, string(args.ID)); err != nil {
+		return "", err
+	}
+	if err := r.userService.DeleteUser(string(args.ID)); err != nil {
+		return "", err
+	}
+
+	return args.ID, nil
+}
+
+// userResult returns the user a mutation wrote, with the fields the caller
+// may read of it.
+func (r *userResolver) userResult(ctx context.Context, write func() (*models.User, error)) (*userNode, error) {
+	fields, err := services.UserReadFields(callerRole(ctx), "")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := write()
+	if err != nil {
+		return nil, err
+	}
+	return &userNode{user, fields}, nil
+}
+
+// errorExtensions adds the code of service errors to GraphQL errors, and
+// the broken rules of password policy errors.
+func errorExtensions(err error) map[string]interface{} {
+	extensions := map[string]interface{}{"code": services.ErrorCode(err)}
+
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		extensions["violations"] = policyErr.Violations
+	}
+
+	return extensions
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/models"
+	"go_crud/services"
+)
+
+var (
+	janeID = primitive.NewObjectID()
+	johnID = primitive.NewObjectID()
+)
+
+// MockUserService is a mock implementation of the UserService interface
+type MockUserService struct {
+	services.UserService
+	Searches []models.UserFilter
+	Limit    int
+	Fields   []string
+	Patch    *models.UserPatch
+}
+
+func (m *MockUserService) users() []*models.User {
+	age := 30
+	return []*models.User{
+		{ID: janeID, Name: "Jane Doe", Age: &age, Email: "jane@example.com", Address: &models.Address{Formatted: "1 Main St"}, EmailVerified: true},
+		{ID: johnID, Name: "John Doe", Email: "john@example.com"},
+	}
+}
+
+func (m *MockUserService) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
+	m.Searches = append(m.Searches, filter)
+	m.Limit, m.Fields = limit, fields
+
+	var users []*models.User
+	for _, user := range m.users() {
+		if filter.IDs != nil && !containsString(filter.IDs, user.ID.Hex()) {
+			continue
+		}
+		if filter.AfterID != "" && user.ID.Hex() <= filter.AfterID {
+			continue
+		}
+		if len(users) < limit {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (m *MockUserService) CreateUser(user *models.CreateUserRequest) (*models.User, error) {
+	if len(user.Password) < 8 {
+		return nil, &services.PasswordPolicyError{Violations: []models.PasswordViolation{{Rule: models.PasswordRuleMinLength, Message: "must be at least 8 characters"}}}
+	}
+
+	return &models.User{ID: janeID, Name: user.Name, Age: user.Age, Email: user.Email, Address: user.Address}, nil
+}
+
+func (m *MockUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	if id != janeID.Hex() {
+		return nil, services.ErrUserNotFound
+	}
+
+	m.Patch = patch
+	return &models.User{ID: janeID, Name: patch.Set.Name}, nil
+}
+
+func (m *MockUserService) DeleteUser(id string) error {
+	if id != janeID.Hex() {
+		return services.ErrUserNotFound
+	}
+	return nil
+}
+
+// execute runs query as a caller with role and returns the response as JSON.
+func execute(t *testing.T, schema *Schema, role string, query string, variables map[string]interface{}) string {
+	res, err := json.Marshal(schema.Execute(WithCaller(context.Background(), janeID.Hex(), role, nil), query, "", variables))
+	assert.NoError(t, err)
+	return string(res)
+}
+
+func TestUserSchema_User(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 1000)
+
+	res := execute(t, schema, models.RoleAdmin, `query ($id: ID!) {
+		jane: user(id: $id) { ...Fields }
+		john: user(id: "`+johnID.Hex()+`") { ...Fields __typename }
+		missing: user(id: "nope") { id }
+	}
+	fragment Fields on User { id name age }`, map[string]interface{}{"id": janeID.Hex()})
+
+	assert.JSONEq(t, `{"data": {
+		"jane": {"id": "`+janeID.Hex()+`", "name": "Jane Doe", "age": 30},
+		"john": {"id": "`+johnID.Hex()+`", "name": "John Doe", "age": null, "__typename": "User"},
+		"missing": null
+	}}`, res)
+	// The three lookups are batched, in the order they were resolved
+	assert.Len(t, userService.Searches, 1)
+	assert.ElementsMatch(t, []string{janeID.Hex(), johnID.Hex(), "nope"}, userService.Searches[0].IDs)
+}
+
+func TestUserSchema_UserRestrictedFields(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 1000)
+
+	res := execute(t, schema, models.RoleUser, `{ user(id: "`+janeID.Hex()+`") { name email age } }`, nil)
+
+	assert.JSONEq(t, `{
+		"data": {"user": {"name": "Jane Doe", "email": "jane@example.com", "age": null}},
+		"errors": [{
+			"message": "not allowed to read the age field",
+			"path": ["user", "age"],
+			"extensions": {"code": "permission_denied"}
+		}]
+	}`, res)
+	assert.Equal(t, []string{"id", "name", "email"}, userService.Fields)
+
+	// Only the public fields of listed users are found, so the others fail
+	// rather than come back empty
+	res = execute(t, schema, models.RoleUser, `{ users(first: 1) { edges { node { name email } } } }`, nil)
+	assert.Contains(t, res, `"node":{"name":"Jane Doe","email":null}`)
+	assert.Contains(t, res, `"message":"not allowed to read the email field"`)
+	assert.Equal(t, []string{"id", "name"}, userService.Fields)
+}
+
+func TestUserSchema_OtherUsers(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 1000)
+
+	// Users only act on themselves
+	res := execute(t, schema, models.RoleUser, `mutation {
+		updateUser(id: "`+johnID.Hex()+`", input: {name: "John Roe"}) { name }
+	}`, nil)
+
+	assert.JSONEq(t, `{
+		"data": null,
+		"errors": [{
+			"message": "only admins can act on other users",
+			"path": ["updateUser"],
+			"extensions": {"code": "permission_denied"}
+		}]
+	}`, res)
+	assert.Nil(t, userService.Patch)
+}
+
+func TestUserSchema_Users(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 1000)
+
+	res := execute(t, schema, models.RoleAdmin, `{ users(first: 1, filter: {name: "Doe", minAge: 18}) {
+		edges { cursor node { name } }
+		pageInfo { hasNextPage endCursor }
+	} }`, nil)
+
+	cursor := encodeCursor(janeID.Hex())
+	assert.JSONEq(t, `{"data": {"users": {
+		"edges": [{"cursor": "`+cursor+`", "node": {"name": "Jane Doe"}}],
+		"pageInfo": {"hasNextPage": true, "endCursor": "`+cursor+`"}
+	}}}`, res)
+
+	minAge := 18
+	assert.Equal(t, models.UserFilter{Name: "Doe", MinAge: &minAge}, userService.Searches[0])
+	assert.Equal(t, 2, userService.Limit)
+
+	res = execute(t, schema, models.RoleAdmin, `{ users(after: "`+cursor+`") { edges { node { name } } pageInfo { hasNextPage } } }`, nil)
+
+	assert.JSONEq(t, `{"data": {"users": {
+		"edges": [{"node": {"name": "John Doe"}}],
+		"pageInfo": {"hasNextPage": false}
+	}}}`, res)
+	assert.Equal(t, janeID.Hex(), userService.Searches[1].AfterID)
+}
+
+func TestUserSchema_UsersFail(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		args    string
+		message string
+	}{
+		{"Page too large", models.RoleAdmin, "first: 101", "first must be between 0 and 100"},
+		{"Invalid cursor", models.RoleAdmin, `after: "nope"`, "invalid cursor"},
+		{"Restricted filter", models.RoleUser, "filter: {maxAge: 65}", "not allowed to filter on the age field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := NewUserSchema(&MockUserService{}, 10, 100000)
+
+			res := execute(t, schema, tt.role, `{ users(`+tt.args+`) { edges { cursor } } }`, nil)
+
+			var body struct {
+				Data   map[string]interface{}
+				Errors []struct{ Message string }
+			}
+			assert.NoError(t, json.Unmarshal([]byte(res), &body))
+			// users is not nullable, so data is null
+			assert.Nil(t, body.Data)
+			assert.Equal(t, tt.message, body.Errors[0].Message)
+		})
+	}
+}
+
+func TestUserSchema_Mutations(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 1000)
+
+	res := execute(t, schema, models.RoleAdmin, `mutation ($input: CreateUserInput!) {
+		createUser(input: $input) { id name }
+	}`, map[string]interface{}{"input": map[string]interface{}{
+		"name": "Jane Doe", "age": float64(30), "email": "jane@example.com", "password": "password123", "address": "1 Main St",
+	}})
+	assert.JSONEq(t, `{"data": {"createUser": {"id": "`+janeID.Hex()+`", "name": "Jane Doe"}}}`, res)
+
+	res = execute(t, schema, models.RoleAdmin, `mutation {
+		updateUser(id: "`+janeID.Hex()+`", input: {name: "Jane Roe", age: null}) { name }
+		deleteUser(id: "`+janeID.Hex()+`")
+	}`, nil)
+	assert.JSONEq(t, `{"data": {"updateUser": {"name": "Jane Roe"}, "deleteUser": "`+janeID.Hex()+`"}}`, res)
+	assert.Equal(t, &models.UserPatch{Set: models.UpdateUser{Name: "Jane Roe"}, Unset: []string{"age"}}, userService.Patch)
+}
+
+func TestUserSchema_MutationsFail(t *testing.T) {
+	schema := NewUserSchema(&MockUserService{}, 10, 1000)
+
+	res := execute(t, schema, models.RoleAdmin, `mutation {
+		createUser(input: {name: "Jane", age: 30, email: "jane@example.com", password: "short", address: "1 Main St"}) { id }
+	}`, nil)
+	assert.JSONEq(t, `{"data": null, "errors": [{
+		"message": "the password must be at least 8 characters",
+		"path": ["createUser"],
+		"extensions": {"code": "invalid_argument", "violations": [{"rule": "min_length", "message": "must be at least 8 characters"}]}
+	}]}`, res)
+
+	res = execute(t, schema, models.RoleAdmin, `mutation { updateUser(id: "nope", input: {email: "not an email"}) { id } }`, nil)
+	assert.Contains(t, res, `"extensions":{"code":"invalid_argument"}`)
+
+	res = execute(t, schema, models.RoleAdmin, `mutation { deleteUser(id: "nope") }`, nil)
+	assert.Contains(t, res, `"extensions":{"code":"not_found"}`)
+}
+
+func TestUserSchema_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		message   string
+	}{
+		{"Unknown field", `{ user(id: "1") { password } }`, nil, `Cannot query field "password" on type "User".`},
+		{"Unknown argument", `{ user(id: "1", name: "Jane") { id } }`, nil, `Unknown argument "name" on field "Query.user".`},
+		{"Missing argument", `{ user { id } }`, nil, `Field "user" argument "id" of type "ID!" is required, but it was not provided.`},
+		{"Invalid argument", `{ users(first: "ten") { edges { cursor } } }`, nil, "Argument \"first\" has invalid value \"ten\".\nExpected type \"Int\", found \"ten\"."},
+		{"Missing selection", `{ user(id: "1") }`, nil, `Field "user" of type "User" must have a selection of subfields. Did you mean "user { ... }"?`},
+		{"Scalar selection", `{ user(id: "1") { id { x } } }`, nil, `Field "id" must not have a selection since type "ID!" has no subfields.`},
+		{"Missing variable", `query ($id: ID!) { user(id: $id) { id } }`, nil, "Variable \"id\" has invalid value null.\nExpected type \"ID!\", found null."},
+		{"Undefined variable", `{ user(id: $id) { id } }`, nil, `Variable "$id" is not defined.`},
+		{"Unknown fragment", `{ user(id: "1") { ...Fields } }`, nil, `Unknown fragment "Fields". Unable to evaluate depth.`},
+		{"Fragment cycle", `{ user(id: "1") { ...A } } fragment A on User { ...A }`, nil, `Cannot spread fragment "A" within itself.`},
+		{"Too deep", `{ users { edges { node { id } } } }`, nil, `Field "id" has depth 4 that exceeds max depth 3`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService := &MockUserService{}
+			maxDepth := 10
+			if tt.name == "Too deep" {
+				maxDepth = 3
+			}
+			schema := NewUserSchema(userService, maxDepth, 200)
+
+			res := schema.Execute(context.Background(), tt.query, "", tt.variables)
+
+			assert.Nil(t, res.Data)
+			assert.Equal(t, tt.message, res.Errors[0].Message)
+			assert.Empty(t, userService.Searches)
+		})
+	}
+}
+
+func TestUserSchema_Complexity(t *testing.T) {
+	userService := &MockUserService{}
+	schema := NewUserSchema(userService, 10, 200)
+
+	// The fields of each of the 100 users count
+	res := execute(t, schema, models.RoleAdmin, `{ users(first: 100) { edges { node { id name } } } }`, nil)
+	assert.JSONEq(t, `{"data": null, "errors": [{
+		"message": "the query has a complexity of 401, more than the 200 allowed",
+		"path": ["users"],
+		"extensions": {"code": "invalid_argument"}
+	}]}`, res)
+	assert.Empty(t, userService.Searches)
+
+	// Root fields count together, the ones past the limit fail
+	res = execute(t, schema, models.RoleAdmin, `{
+		a: users(first: 40) { edges { node { id name } } }
+		b: users(first: 40) { edges { node { id name } } }
+	}`, nil)
+	assert.Contains(t, res, "the query has a complexity of 322, more than the 200 allowed")
+	assert.Len(t, userService.Searches, 1)
+}
+
+func TestUserSchema_DocumentSize(t *testing.T) {
+	schema := NewUserSchema(&MockUserService{}, 10, 1000)
+
+	res := schema.Execute(context.Background(), "{ user(id: \"1\") { id } }"+strings.Repeat(" ", MaxDocumentSize), "", nil)
+
+	assert.Nil(t, res.Data)
+	assert.Contains(t, res.Errors[0].Message, "exceeds the maximum allowed query length")
+}
+
+func TestUserSchema_Introspection(t *testing.T) {
+	schema := NewUserSchema(&MockUserService{}, 10, 1000)
+
+	res := execute(t, schema, "", `{ __type(name: "UserFilter") { inputFields { name } } }`, nil)
+
+	assert.JSONEq(t, `{"data": {"__type": {"inputFields": [
+		{"name": "name"}, {"name": "email"}, {"name": "minAge"}, {"name": "maxAge"}, {"name": "ids"}
+	]}}}`, res)
+}
+
+func TestUserSchema_SDL(t *testing.T) {
+	sdl := NewUserSchema(&MockUserService{}, 10, 1000).SDL()
+
+	assert.Contains(t, sdl, "schema {\n  query: Query\n  mutation: Mutation\n}\n")
+	assert.Contains(t, sdl, "  users(filter: UserFilter, first: Int = 10, after: String): UserConnection!\n")
+	assert.Contains(t, sdl, "input UpdateUserInput {\n  name: String\n")
+}
@@ -2,20 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"go_crud/controllers"
+	controllersv2 "go_crud/controllers/v2"
 	"go_crud/docs"
+	// Registers the v2 swagger document
+	_ "go_crud/docs/v2"
+	"go_crud/graphql"
+	"go_crud/middleware"
+	"go_crud/models"
 	"go_crud/routes"
+	"go_crud/rpc"
 	"go_crud/services"
+	"go_crud/utils"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"golang.org/x/crypto/bcrypt"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -26,12 +44,105 @@ var (
 	ctx    context.Context
 
 	mongoclient *mongo.Client
+	mailer      services.Mailer
+
+	// multiTenant scopes the data of the default tenant too, and serves the
+	// other tenants
+	multiTenant bool
+
+	tenantService         services.TenantService
+	TenantController      controllers.TenantController
+	TenantRouteController routes.TenantRouteController
+
+	// tenants holds the app of every tenant served so far
+	tenants *tenantApps
+	// defaultApp serves the default tenant, which the gRPC API and the
+	// commands use
+	defaultApp *tenantApp
+)
+
+// tenantApp holds the services of a tenant and the engine serving its routes.
+type tenantApp struct {
+	engine *gin.Engine
 
 	userService         services.UserService
 	UserController      controllers.UserController
-	userCollection      *mongo.Collection
 	UserRouteController routes.UserRouteController
-)
+
+	attributeService         services.AttributeService
+	AttributeController      controllers.AttributeController
+	AttributeRouteController routes.AttributeRouteController
+
+	groupService         services.GroupService
+	GroupController      controllers.GroupController
+	GroupRouteController routes.GroupRouteController
+
+	inviteService         services.InviteService
+	InviteController      controllers.InviteController
+	InviteRouteController routes.InviteRouteController
+
+	auditService         services.AuditService
+	AuditController      controllers.AuditController
+	AuditRouteController routes.AuditRouteController
+
+	userDataService         services.UserDataService
+	UserDataController      controllers.UserDataController
+	UserDataRouteController routes.UserDataRouteController
+
+	userStatusService         services.UserStatusService
+	UserStatusController      controllers.UserStatusController
+	UserStatusRouteController routes.UserStatusRouteController
+
+	UserV2Controller      controllersv2.UserController
+	UserV2RouteController routes.UserV2RouteController
+
+	userStreamService         services.UserStreamService
+	UserStreamController      controllers.UserStreamController
+	UserStreamRouteController routes.UserStreamRouteController
+
+	userBatchService         services.UserBatchService
+	UserBatchController      controllers.UserBatchController
+	UserBatchRouteController routes.UserBatchRouteController
+
+	userImportService         services.UserImportService
+	UserImportController      controllers.UserImportController
+	UserImportRouteController routes.UserImportRouteController
+
+	userExportService         services.UserExportService
+	UserExportController      controllers.UserExportController
+	UserExportRouteController routes.UserExportRouteController
+
+	authService         services.AuthService
+	AuthController      controllers.AuthController
+	AuthRouteController routes.AuthRouteController
+
+	emailVerificationService         services.EmailVerificationService
+	EmailVerificationController      controllers.EmailVerificationController
+	EmailVerificationRouteController routes.EmailVerificationRouteController
+
+	mfaService         services.MFAService
+	MFAController      controllers.MFAController
+	MFARouteController routes.MFARouteController
+
+	loginAttemptService         services.LoginAttemptService
+	LoginAttemptController      controllers.LoginAttemptController
+	LoginAttemptRouteController routes.LoginAttemptRouteController
+
+	apiKeyService         services.APIKeyService
+	APIKeyController      controllers.APIKeyController
+	APIKeyRouteController routes.APIKeyRouteController
+
+	oidcService         services.OIDCService
+	OIDCController      controllers.OIDCController
+	OIDCRouteController routes.OIDCRouteController
+
+	scimService         services.SCIMService
+	SCIMController      controllers.SCIMController
+	SCIMRouteController routes.SCIMRouteController
+
+	GraphQLController      controllers.GraphQLController
+	GraphQLRouteController routes.GraphQLRouteController
+}
 
 func init() {
 
@@ -40,7 +151,8 @@ func init() {
 	// Connect to MongoDB
 	DBUri := os.Getenv("GO_CRUD_MONGO_URI")
 	mongoconn := options.Client().ApplyURI(DBUri)
-	mongoclient, err := mongo.Connect(ctx, mongoconn)
+	var err error
+	mongoclient, err = mongo.Connect(ctx, mongoconn)
 
 	if err != nil {
 		panic(err)
@@ -52,33 +164,586 @@ func init() {
 
 	fmt.Println("MongoDB successfully connected...")
 
+	services.SetPasswordPolicy(newPasswordPolicy())
+	utils.SetPasswordHasher(newPasswordHasher())
+
 	// 👇 Instantiate the Constructors
-	userCollection = mongoclient.Database("go_crud").Collection("users")
-	userService = services.NewUserService(userCollection, ctx)
-	UserController = controllers.NewUserController(userService)
-	UserRouteController = routes.NewUserControllerRoute(UserController)
+	mailer = newMailer()
+
+	multiTenant = envBool("GO_CRUD_MULTI_TENANT", false)
+	if multiTenant {
+		dropGlobalUniqueIndexes(mongoclient.Database("go_crud"))
+	}
+
+	tenantCollection := mongoclient.Database("go_crud").Collection("tenants")
+	tenantService = services.NewTenantService(tenantCollection, ctx, envDuration("GO_CRUD_TENANT_CACHE_TTL", time.Minute))
+	TenantController = controllers.NewTenantController(tenantService)
+	TenantRouteController = routes.NewTenantControllerRoute(TenantController)
+
+	tenants = newTenantApps(newTenantApp)
+	defaultApp = tenants.app(nil)
 
 	server = gin.Default()
+	trustProxies(server)
 }
 
-func main() {
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"*"}
-	corsConfig.AllowCredentials = true
+// newTenantApp builds the services and routes of tenant, the default tenant
+// when nil. Tenants with a dedicated database get their indexes created
+// here, on their first request.
+func newTenantApp(tenant *models.Tenant) *tenantApp {
+	app := &tenantApp{}
 
-	server.Use(cors.New(corsConfig))
+	tenantID := ""
+	if tenant != nil {
+		tenantID = tenant.ID
+	}
+	collection := tenantCollections(tenant)
+
+	userCollection := collection("users")
+
+	verificationCollection := collection("email_verifications")
+	app.emailVerificationService = services.NewEmailVerificationService(userCollection, verificationCollection, ctx, mailer, services.EmailVerificationConfig{
+		TokenTTL:       envDuration("GO_CRUD_VERIFY_TOKEN_TTL", 48*time.Hour),
+		ResendCooldown: envDuration("GO_CRUD_VERIFY_RESEND_COOLDOWN", time.Minute),
+		VerifyURL:      tenantURL(envString("GO_CRUD_VERIFY_URL", "http://localhost:8080/api/auth/verify-email"), tenant),
+	})
+	app.EmailVerificationController = controllers.NewEmailVerificationController(app.emailVerificationService)
+	app.EmailVerificationRouteController = routes.NewEmailVerificationControllerRoute(app.EmailVerificationController)
+
+	app.attributeService = services.NewAttributeService(collection("attributes"), userCollection, ctx)
+	app.AttributeController = controllers.NewAttributeController(app.attributeService)
+	app.AttributeRouteController = routes.NewAttributeControllerRoute(app.AttributeController)
+
+	app.groupService = services.NewGroupService(collection("groups"), userCollection, ctx)
+	app.GroupController = controllers.NewGroupController(app.groupService)
+	app.GroupRouteController = routes.NewGroupControllerRoute(app.GroupController)
+
+	app.userService = services.NewUserService(userCollection, ctx, app.emailVerificationService, app.attributeService, app.groupService)
+	app.UserController = controllers.NewUserController(app.userService)
+	app.UserRouteController = routes.NewUserControllerRoute(app.UserController)
+	app.UserV2Controller = controllersv2.NewUserController(app.userService)
+	app.UserV2RouteController = routes.NewUserV2ControllerRoute(app.UserV2Controller)
+
+	app.inviteService = services.NewInviteService(collection("invites"), app.userService, ctx, mailer, services.InviteConfig{
+		TokenTTL:  envDuration("GO_CRUD_INVITE_TTL", 7*24*time.Hour),
+		AcceptURL: tenantURL(envString("GO_CRUD_INVITE_URL", "http://localhost:8080/accept-invite"), tenant),
+	})
+	app.InviteController = controllers.NewInviteController(app.inviteService)
+	app.InviteRouteController = routes.NewInviteControllerRoute(app.InviteController)
+
+	// Polling interval used when change streams are unavailable
+	pollInterval := envDuration("GO_CRUD_STREAM_POLL_INTERVAL", 5*time.Second)
+	app.userStreamService = services.NewUserStreamService(userCollection, pollInterval)
+	app.UserStreamController = controllers.NewUserStreamController(app.userStreamService)
+	app.UserStreamRouteController = routes.NewUserStreamControllerRoute(app.UserStreamController)
+
+	maxBatchSize := envInt("GO_CRUD_BATCH_MAX_SIZE", 1000)
+	hashWorkers := envInt("GO_CRUD_HASH_WORKERS", runtime.NumCPU())
+	app.userBatchService = services.NewUserBatchService(userCollection, ctx, maxBatchSize, hashWorkers, app.emailVerificationService, app.attributeService)
+	app.UserBatchController = controllers.NewUserBatchController(app.userBatchService)
+	app.UserBatchRouteController = routes.NewUserBatchControllerRoute(app.UserBatchController)
+
+	app.userImportService = services.NewUserImportService(userCollection, ctx, hashWorkers, app.emailVerificationService, app.attributeService)
+	app.UserImportController = controllers.NewUserImportController(app.userImportService)
+	app.UserImportRouteController = routes.NewUserImportControllerRoute(app.UserImportController)
+
+	// Directory holding the files of background exports, the other tenants
+	// get a directory of their own in it
+	exportDir := os.Getenv("GO_CRUD_EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "go_crud_exports")
+	}
+	if tenantID != "" {
+		exportDir = filepath.Join(exportDir, "tenants", tenantID)
+	}
+	app.userExportService = services.NewUserExportService(userCollection, exportDir, app.attributeService, app.groupService)
+	app.UserExportController = controllers.NewUserExportController(app.userExportService)
+	app.UserExportRouteController = routes.NewUserExportControllerRoute(app.UserExportController)
+
+	challengeCollection := collection("mfa_challenges")
+	app.mfaService = services.NewMFAService(userCollection, challengeCollection, ctx, newSecretBox(), services.MFAConfig{
+		Issuer:       envString("GO_CRUD_MFA_ISSUER", "go_crud"),
+		Skew:         envInt("GO_CRUD_MFA_SKEW", 1),
+		ChallengeTTL: envDuration("GO_CRUD_MFA_CHALLENGE_TTL", 5*time.Minute),
+	})
+	app.MFAController = controllers.NewMFAController(app.mfaService)
+	app.MFARouteController = routes.NewMFAControllerRoute(app.MFAController)
+
+	historyCollection := collection("login_history")
+	throttleCollection := throttleCollection(tenant)
+	app.loginAttemptService = services.NewLoginAttemptService(userCollection, historyCollection, throttleCollection, ctx, services.LockoutConfig{
+		MaxFailures:      envInt("GO_CRUD_LOCKOUT_MAX_FAILURES", 5),
+		IPMaxFailures:    envInt("GO_CRUD_LOCKOUT_IP_MAX_FAILURES", 20),
+		BaseDuration:     envDuration("GO_CRUD_LOCKOUT_DURATION", time.Minute),
+		MaxDuration:      envDuration("GO_CRUD_LOCKOUT_MAX_DURATION", time.Hour),
+		ResetAfter:       envDuration("GO_CRUD_LOCKOUT_RESET_AFTER", 24*time.Hour),
+		HistoryRetention: envDuration("GO_CRUD_LOGIN_HISTORY_RETENTION", 90*24*time.Hour),
+	})
+	app.LoginAttemptController = controllers.NewLoginAttemptController(app.loginAttemptService)
+	app.LoginAttemptRouteController = routes.NewLoginAttemptControllerRoute(app.LoginAttemptController)
+
+	oidcStateCollection := collection("oidc_states")
+	app.oidcService = services.NewOIDCService(userCollection, oidcStateCollection, ctx, newOIDCProviders(), envDuration("GO_CRUD_OIDC_STATE_TTL", 10*time.Minute))
+
+	sessionCollection := collection("sessions")
+	resetCollection := collection("password_resets")
+	app.authService = services.NewAuthService(userCollection, sessionCollection, resetCollection, ctx, mailer, app.mfaService, app.loginAttemptService, app.oidcService, services.AuthConfig{
+		SessionTTL:    envDuration("GO_CRUD_SESSION_TTL", 24*time.Hour),
+		ResetTokenTTL: envDuration("GO_CRUD_RESET_TOKEN_TTL", time.Hour),
+		ResetURL:      tenantURL(envString("GO_CRUD_RESET_URL", "http://localhost:8080/reset-password"), tenant),
+		// Unverified users cannot sign in when set
+		RequireVerifiedEmail: envBool("GO_CRUD_REQUIRE_VERIFIED_EMAIL", false),
+		TokenPrefix:          services.TenantTokenPrefix(tenantID),
+	})
+	app.AuthController = controllers.NewAuthController(app.authService)
+	app.AuthRouteController = routes.NewAuthControllerRoute(app.AuthController)
+	app.OIDCController = controllers.NewOIDCController(app.oidcService, app.authService)
+	app.OIDCRouteController = routes.NewOIDCControllerRoute(app.OIDCController)
+
+	apiKeyCollection := collection("api_keys")
+	app.apiKeyService = services.NewAPIKeyService(userCollection, apiKeyCollection, ctx, services.TenantTokenPrefix(tenantID))
+	app.APIKeyController = controllers.NewAPIKeyController(app.apiKeyService)
+	app.APIKeyRouteController = routes.NewAPIKeyControllerRoute(app.APIKeyController)
+
+	auditCollection := collection("audit_log")
+	app.auditService = services.NewAuditService(auditCollection, ctx)
+	app.AuditController = controllers.NewAuditController(app.auditService)
+	app.AuditRouteController = routes.NewAuditControllerRoute(app.AuditController)
+
+	// Erasing a user reaches every collection holding data about them
+	app.userDataService = services.NewUserDataService(services.UserDataCollections{
+		Users:              userCollection,
+		Groups:             collection("groups"),
+		Sessions:           sessionCollection,
+		PasswordResets:     resetCollection,
+		EmailVerifications: verificationCollection,
+		MFAChallenges:      challengeCollection,
+		LoginHistory:       historyCollection,
+		LoginThrottles:     throttleCollection,
+		APIKeys:            apiKeyCollection,
+		Invites:            collection("invites"),
+		Audit:              auditCollection,
+	}, app.auditService, ctx)
+	app.UserDataController = controllers.NewUserDataController(app.userDataService)
+	app.UserDataRouteController = routes.NewUserDataControllerRoute(app.UserDataController)
+
+	app.userStatusService = services.NewUserStatusService(userCollection, sessionCollection, ctx)
+	app.UserStatusController = controllers.NewUserStatusController(app.userStatusService)
+	app.UserStatusRouteController = routes.NewUserStatusControllerRoute(app.UserStatusController)
+	// Ended suspensions no longer stop sign ins, this only updates the status
+	app.userStatusService.RunReactivation(envDuration("GO_CRUD_SUSPENSION_CHECK_INTERVAL", time.Minute))
+
+	app.scimService = services.NewSCIMService(app.userService, app.userStatusService, userCollection, ctx, tenantURL(envString("GO_CRUD_SCIM_BASE_URL", "http://localhost:8080/scim/v2"), tenant))
+	app.SCIMController = controllers.NewSCIMController(app.scimService)
+	app.SCIMRouteController = routes.NewSCIMControllerRoute(app.SCIMController)
+
+	userSchema := graphql.NewUserSchema(app.userService, envInt("GO_CRUD_GRAPHQL_MAX_DEPTH", 10), envInt("GO_CRUD_GRAPHQL_MAX_COMPLEXITY", 1000))
+	app.GraphQLController = controllers.NewGraphQLController(userSchema)
+	app.GraphQLRouteController = routes.NewGraphQLControllerRoute(app.GraphQLController)
+
+	// Logging and recovery are left to the server passing requests on
+	app.engine = gin.New()
+	trustProxies(app.engine)
+	app.routes(tenant == nil && multiTenant)
+
+	return app
+}
+
+// tenantCollections returns a function opening the collections of tenant.
+// Tenants with a dedicated database own all of its documents, the others
+// only see their own documents of the shared database.
+func tenantCollections(tenant *models.Tenant) func(name string) services.Collection {
+	return func(name string) services.Collection {
+		switch {
+		case tenant != nil && tenant.Database != "":
+			return mongoclient.Database(tenant.Database).Collection(name)
+		case tenant != nil:
+			return services.TenantCollection(mongoclient.Database("go_crud").Collection(name), tenant.ID)
+		case multiTenant:
+			return services.TenantCollection(mongoclient.Database("go_crud").Collection(name), "")
+		default:
+			return mongoclient.Database("go_crud").Collection(name)
+		}
+	}
+}
+
+// throttleCollection returns the sign in throttles of tenant. Throttles are
+// upserted by email and IP address in their _id, which the tenants of a
+// shared collection would collide on, so each tenant of the shared
+// database has a collection of its own.
+func throttleCollection(tenant *models.Tenant) services.Collection {
+	switch {
+	case tenant != nil && tenant.Database != "":
+		return mongoclient.Database(tenant.Database).Collection("login_throttles")
+	case tenant != nil:
+		return mongoclient.Database("go_crud").Collection("login_throttles." + tenant.ID)
+	default:
+		return mongoclient.Database("go_crud").Collection("login_throttles")
+	}
+}
+
+// tenantURL replaces {tenant} in a link sent to users with the ID of
+// tenant, so it reaches the tenant in a subdomain, path or query.
+func tenantURL(link string, tenant *models.Tenant) string {
+	tenantID := models.DefaultTenantID
+	if tenant != nil {
+		tenantID = tenant.ID
+	}
+	return strings.ReplaceAll(link, "{tenant}", tenantID)
+}
+
+// dropGlobalUniqueIndexes drops the unique indexes of the users made before
+// multi-tenancy was turned on. The indexes unique per tenant replace them,
+// so the same email or identity can be used in several tenants.
+func dropGlobalUniqueIndexes(db *mongo.Database) {
+	for _, name := range []string{"email_1", "identities.provider_1_identities.subject_1"} {
+		_, err := db.Collection("users").Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound")) {
+			panic(err)
+		}
+	}
+}
+
+// newPasswordPolicy reads the password policy settings. Breached passwords
+// are only checked when GO_CRUD_BREACHED_PASSWORDS_FILE is set.
+func newPasswordPolicy() *services.PasswordPolicy {
+	policy := &services.PasswordPolicy{
+		MinLength:  envInt("GO_CRUD_PASSWORD_MIN_LENGTH", 8),
+		MaxBytes:   envInt("GO_CRUD_PASSWORD_MAX_BYTES", 72),
+		MinClasses: envInt("GO_CRUD_PASSWORD_MIN_CLASSES", 2),
+	}
+
+	if path := os.Getenv("GO_CRUD_BREACHED_PASSWORDS_FILE"); path != "" {
+		breached, err := utils.LoadBloomFilter(path, 0.001)
+		if err != nil {
+			panic(err)
+		}
+		policy.Breached = breached
+	}
+
+	return policy
+}
+
+// newPasswordHasher reads the algorithm new passwords are hashed with,
+// argon2id unless GO_CRUD_PASSWORD_HASHER is "bcrypt". Hashes made by the
+// other algorithm or weaker parameters are replaced when users sign in.
+func newPasswordHasher() utils.PasswordHasher {
+	switch algorithm := envString("GO_CRUD_PASSWORD_HASHER", "argon2id"); algorithm {
+	case "argon2id":
+		return &utils.Argon2idHasher{
+			Memory:      uint32(envInt("GO_CRUD_ARGON2_MEMORY_KIB", 19456)),
+			Iterations:  uint32(envInt("GO_CRUD_ARGON2_ITERATIONS", 2)),
+			Parallelism: uint8(envInt("GO_CRUD_ARGON2_PARALLELISM", 1)),
+			SaltLength:  16,
+			KeyLength:   32,
+		}
+	case "bcrypt":
+		return &utils.BcryptHasher{Cost: envInt("GO_CRUD_BCRYPT_COST", bcrypt.DefaultCost)}
+	default:
+		panic(fmt.Errorf("GO_CRUD_PASSWORD_HASHER: unknown algorithm %q", algorithm))
+	}
+}
+
+// newOIDCProviders reads the identity providers named in the comma
+// separated GO_CRUD_OIDC_PROVIDERS. Each one is configured with
+// GO_CRUD_OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and
+// _SCOPES.
+func newOIDCProviders() []*services.OIDCProvider {
+	var providers []*services.OIDCProvider
+	for _, name := range strings.Split(os.Getenv("GO_CRUD_OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "GO_CRUD_OIDC_" + strings.ToUpper(name) + "_"
+		issuer := os.Getenv(prefix + "ISSUER")
+		clientID := os.Getenv(prefix + "CLIENT_ID")
+		if issuer == "" || clientID == "" {
+			panic(fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix))
+		}
+
+		providers = append(providers, services.NewOIDCProvider(services.OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    issuer,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  envString(prefix+"REDIRECT_URL", "http://localhost:8080/api/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(envString(prefix+"SCOPES", "email profile")),
+		}, nil))
+	}
 
-	router := server.Group("/api")
+	return providers
+}
+
+// newSecretBox reads the key TOTP secrets are encrypted with, a base64
+// encoded 32 byte key. Two-factor authentication is unavailable without it.
+func newSecretBox() *utils.SecretBox {
+	value := os.Getenv("GO_CRUD_MFA_ENCRYPTION_KEY")
+	if value == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		panic(fmt.Errorf("GO_CRUD_MFA_ENCRYPTION_KEY: %w", err))
+	}
+
+	box, err := utils.NewSecretBox(key)
+	if err != nil {
+		panic(fmt.Errorf("GO_CRUD_MFA_ENCRYPTION_KEY: %w", err))
+	}
+	return box
+}
+
+// newMailer sends emails through SMTP when GO_CRUD_SMTP_HOST is set, and
+// otherwise writes them to GO_CRUD_MAIL_FILE or stdout.
+func newMailer() services.Mailer {
+	if host := os.Getenv("GO_CRUD_SMTP_HOST"); host != "" {
+		return services.NewSMTPMailer(services.SMTPConfig{
+			Host:     host,
+			Port:     envInt("GO_CRUD_SMTP_PORT", 587),
+			Username: os.Getenv("GO_CRUD_SMTP_USERNAME"),
+			Password: os.Getenv("GO_CRUD_SMTP_PASSWORD"),
+			From:     envString("GO_CRUD_MAIL_FROM", "no-reply@localhost"),
+		})
+	}
+
+	var w io.Writer = os.Stdout
+	if path := os.Getenv("GO_CRUD_MAIL_FILE"); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			panic(err)
+		}
+		w = file
+	}
+
+	return services.NewLogMailer(w)
+}
+
+// envString reads a setting, falling back to def when it is unset.
+func envString(name string, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return def
+}
+
+// envBool reads a boolean setting, falling back to def when it is unset.
+func envBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", name, err))
+	}
+	return b
+}
+
+// envInt reads an integer setting, falling back to def when it is unset.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", name, err))
+	}
+	return n
+}
+
+// envTime reads a date setting, either an RFC 3339 time or a day such as
+// 2006-01-02, falling back to def when it is unset.
+func envTime(name string, def time.Time) time.Time {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			panic(fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return t
+}
+
+// envDuration reads a duration setting, falling back to def when it is unset.
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", name, err))
+	}
+	return d
+}
+
+// routes serves the API of the tenant on its engine. Tenants are managed
+// through the default tenant, when manageTenants is set.
+func (app *tenantApp) routes(manageTenants bool) {
+	// The user routes need a signed in caller unless GO_CRUD_AUTH_REQUIRED=false,
+	// which lets anonymous callers read the public fields and sign up
+	authRequired := envBool("GO_CRUD_AUTH_REQUIRED", true)
+
+	// v1 is frozen and deprecated, the unversioned /api routes are kept as
+	// an alias of it
+	deprecated := middleware.Deprecated(middleware.DeprecationConfig{
+		DeprecatedAt: envTime("GO_CRUD_V1_DEPRECATED_AT", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)),
+		Sunset:       envTime("GO_CRUD_V1_SUNSET", time.Time{}),
+		Successor:    "/api/v2",
+	})
+
+	app.v1Routes(app.engine.Group("/api"), deprecated, authRequired, manageTenants)
+	app.v1Routes(app.engine.Group("/api/v1"), deprecated, authRequired, manageTenants)
+	app.v2Routes(app.engine.Group("/api/v2", middleware.APIVersion("v2")), authRequired)
+
+	// SCIM clients expect the endpoints outside of /api, and always sign in
+	app.SCIMRouteController.SCIMRoute(app.engine.Group("/scim/v2", middleware.Authenticate(app.authService, app.apiKeyService)))
+}
+
+// v1Routes serves the v1 API under router. deprecated marks its responses,
+// the health check aside.
+func (app *tenantApp) v1Routes(router *gin.RouterGroup, deprecated gin.HandlerFunc, authRequired bool, manageTenants bool) {
 	router.GET("/healthchecker", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"status": "success"})
 	})
 
-	UserRouteController.UserRoute(router)
+	router.Use(deprecated, middleware.Authenticate(app.authService, app.apiKeyService))
+	app.AuthRouteController.AuthRoute(router)
+	app.OIDCRouteController.OIDCRoute(router)
+	app.EmailVerificationRouteController.EmailVerificationRoute(router)
+	app.InviteRouteController.AcceptInviteRoute(router)
+	app.MFARouteController.MFARoute(router.Group("", middleware.RequireAuth()))
+	app.OIDCRouteController.OIDCLinkRoute(router.Group("", middleware.RequireAuth()))
+
+	signedIn := router.Group("")
+	if authRequired {
+		signedIn.Use(middleware.RequireAuth())
+	}
+
+	// GraphQL checks the scopes itself, as queries and mutations are both POSTs
+	app.GraphQLRouteController.GraphQLRoute(signedIn)
+
+	// API keys only reach the routes their scopes allow
+	scoped := signedIn.Group("", middleware.RequireUserScopes())
+
+	app.APIKeyRouteController.APIKeyRoute(scoped)
+	if manageTenants {
+		TenantRouteController.TenantRoute(scoped)
+	}
+	app.AttributeRouteController.AttributeRoute(scoped)
+	app.InviteRouteController.InviteRoute(scoped)
+	app.AuditRouteController.AuditRoute(scoped)
+
+	app.UserRouteController.UserRoute(scoped)
+	app.UserStreamRouteController.UserStreamRoute(scoped)
+	app.UserBatchRouteController.UserBatchRoute(scoped)
+	app.UserImportRouteController.UserImportRoute(scoped)
+	app.UserExportRouteController.UserExportRoute(scoped)
+	app.LoginAttemptRouteController.LoginAttemptRoute(scoped)
+	app.GroupRouteController.GroupRoute(scoped)
+	app.UserDataRouteController.UserDataRoute(scoped)
+	app.UserStatusRouteController.UserStatusRoute(scoped)
+
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+}
+
+// v2Routes serves the v2 API under router.
+func (app *tenantApp) v2Routes(router *gin.RouterGroup, authRequired bool) {
+	router.Use(middleware.Authenticate(app.authService, app.apiKeyService))
+
+	signedIn := router.Group("")
+	if authRequired {
+		signedIn.Use(middleware.RequireAuth())
+	}
+	app.UserV2RouteController.UserV2Route(signedIn.Group("", middleware.RequireUserScopes()))
+
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName("v2")))
+}
+
+// serveTenant passes the request to the app of its tenant.
+func serveTenant(ctx *gin.Context) {
+	var tenant *models.Tenant
+	if value, ok := ctx.Get(controllers.TenantKey); ok {
+		tenant = value.(*models.Tenant)
+	}
+
+	tenants.app(tenant).engine.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// trustProxies only trusts X-Forwarded-For from the proxies listed in
+// GO_CRUD_TRUSTED_PROXIES, as client IPs drive the sign in lockouts. Without
+// it the client IP is the address of the connection.
+func trustProxies(engine *gin.Engine) {
+	var proxies []string
+	if list := os.Getenv("GO_CRUD_TRUSTED_PROXIES"); list != "" {
+		proxies = strings.Split(list, ",")
+	}
+
+	if err := engine.SetTrustedProxies(proxies); err != nil {
+		panic(err)
+	}
+}
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+//
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+func main() {
+	// Subcommands such as "import" run instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = []string{"*"}
+	corsConfig.AllowCredentials = true
+
+	server.Use(cors.New(corsConfig))
 
 	// SWAGGER
 	docs.SwaggerInfo.Title = "Users API"
 	docs.SwaggerInfo.Description = "Users API"
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Each tenant is served by its own app, the default tenant's alone
+	// unless GO_CRUD_MULTI_TENANT is set
+	if multiTenant {
+		resolveTenant := middleware.ResolveTenant(middleware.TenantConfig{
+			Header: envString("GO_CRUD_TENANT_HEADER", "X-Tenant-ID"),
+			Domain: os.Getenv("GO_CRUD_TENANT_DOMAIN"),
+		}, tenantService)
+		server.Any("/api/v2/*path", middleware.APIVersion("v2"), resolveTenant, serveTenant)
+		server.NoRoute(resolveTenant, serveTenant)
+	} else {
+		server.NoRoute(serveTenant)
+	}
+
+	// The gRPC API is served on its own port, with the same authentication,
+	// for the default tenant
+	grpcListener, err := net.Listen("tcp", ":"+envString("GO_CRUD_GRPC_PORT", "9090"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	userServer := rpc.NewUserServer(defaultApp.userService, defaultApp.userStreamService)
+	grpcServer := rpc.NewServer(userServer, defaultApp.authService, defaultApp.apiKeyService, envBool("GO_CRUD_AUTH_REQUIRED", true))
+	go func() {
+		log.Fatal(grpcServer.Serve(grpcListener))
+	}()
 
 	log.Fatal(server.Run(":" + os.Getenv("PORT")))
 }
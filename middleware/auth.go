@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate signs in callers sending an "Authorization: Bearer <token>"
+// or "Authorization: ApiKey <key>" header, setting their id and role in the
+// gin context. API keys can also be sent as bearer tokens, for clients such
+// as SCIM provisioning that only support those. Requests without the header
+// carry on unauthenticated, invalid credentials are rejected.
+func Authenticate(authService services.AuthService, apiKeyService services.APIKeyService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, key := controllers.BearerToken(ctx), controllers.APIKey(ctx)
+		if services.IsAPIKey(token) {
+			token, key = "", token
+		}
+
+		if token != "" {
+			user, err := authService.Authenticate(token)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+
+			setCaller(ctx, user)
+		} else if key != "" {
+			apiKey, user, err := apiKeyService.Authenticate(key)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+
+			if user != nil {
+				setCaller(ctx, user)
+			} else {
+				ctx.Set(controllers.RoleKey, models.RoleService)
+			}
+			ctx.Set(controllers.APIKeyIDKey, apiKey.ID.Hex())
+			if len(apiKey.Scopes) > 0 {
+				ctx.Set(controllers.ScopesKey, apiKey.Scopes)
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+func setCaller(ctx *gin.Context, user *models.User) {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	ctx.Set(controllers.UserIDKey, user.ID.Hex())
+	ctx.Set(controllers.RoleKey, role)
+}
+
+func abortWithError(ctx *gin.Context, err error) {
+	// The v2 routes reply with problems, which pick the status from the code
+	if ctx.GetString(controllers.APIVersionKey) == "v2" {
+		controllers.AbortWithProblem(ctx, err)
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch services.ErrorCode(err) {
+	case services.ErrCodeInvalid:
+		status = http.StatusBadRequest
+	case services.ErrCodeNotFound:
+		status = http.StatusNotFound
+	case services.ErrCodeUnauthenticated:
+		status = http.StatusUnauthorized
+	case services.ErrCodePermissionDenied:
+		status = http.StatusForbidden
+	}
+	ctx.AbortWithStatusJSON(status, gin.H{"status": "fail", "message": err.Error()})
+}
+
+// RequireAuth rejects requests that Authenticate did not sign in.
+func RequireAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString(controllers.RoleKey) == "" {
+			abortWithError(ctx, &services.Error{Code: services.ErrCodeUnauthenticated, Message: "authentication is required"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireUserScopes rejects API keys without the scope a request needs:
+// users:read to read and users:write for anything else. Sessions and keys
+// without scopes are let through.
+func RequireUserScopes() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scopes := ctx.GetStringSlice(controllers.ScopesKey)
+		if scopes == nil {
+			ctx.Next()
+			return
+		}
+
+		required := models.ScopeUsersWrite
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			required = models.ScopeUsersRead
+		}
+
+		for _, scope := range scopes {
+			if scope == required {
+				ctx.Next()
+				return
+			}
+		}
+
+		abortWithError(ctx, &services.Error{Code: services.ErrCodePermissionDenied, Message: "the API key needs the " + required + " scope"})
+	}
+}
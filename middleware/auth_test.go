@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockAuthService is a mock implementation of the AuthService interface
+type MockAuthService struct {
+	services.AuthService
+}
+
+func (m *MockAuthService) Authenticate(token string) (*models.User, error) {
+	switch token {
+	case "user-token":
+		return &models.User{ID: primitive.NewObjectID()}, nil
+	case "admin-token":
+		return &models.User{ID: primitive.NewObjectID(), Role: models.RoleAdmin}, nil
+	}
+
+	return nil, services.ErrInvalidSession
+}
+
+// MockAPIKeyService is a mock implementation of the APIKeyService interface
+type MockAPIKeyService struct {
+	services.APIKeyService
+}
+
+func (m *MockAPIKeyService) Authenticate(key string) (*models.APIKey, *models.User, error) {
+	switch key {
+	case "user-key":
+		return &models.APIKey{ID: primitive.NewObjectID()}, &models.User{ID: primitive.NewObjectID()}, nil
+	case "service-key":
+		return &models.APIKey{ID: primitive.NewObjectID(), ServiceAccount: "batch"}, nil, nil
+	case "gocrud_scim-key":
+		return &models.APIKey{ID: primitive.NewObjectID(), ServiceAccount: "scim"}, nil, nil
+	case "read-key":
+		return &models.APIKey{ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}}, &models.User{ID: primitive.NewObjectID()}, nil
+	}
+
+	return nil, nil, services.ErrInvalidAPIKey
+}
+
+func newTestRouter(required bool) *gin.Engine {
+	router := gin.New()
+	router.Use(Authenticate(&MockAuthService{}, &MockAPIKeyService{}))
+	if required {
+		router.Use(RequireAuth())
+	}
+
+	router.Use(RequireUserScopes())
+	router.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(controllers.RoleKey))
+	})
+	router.POST("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.GetString(controllers.RoleKey))
+	})
+
+	return router
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		required      bool
+		status        int
+		role          string
+	}{
+		{"anonymous", "", false, http.StatusOK, ""},
+		{"user", "Bearer user-token", false, http.StatusOK, models.RoleUser},
+		{"admin", "bearer admin-token", false, http.StatusOK, models.RoleAdmin},
+		{"invalid token", "Bearer expired", false, http.StatusUnauthorized, ""},
+		{"required", "", true, http.StatusUnauthorized, ""},
+		{"required and signed in", "Bearer user-token", true, http.StatusOK, models.RoleUser},
+		{"user API key", "ApiKey user-key", true, http.StatusOK, models.RoleUser},
+		{"service account API key", "apikey service-key", true, http.StatusOK, models.RoleService},
+		{"invalid API key", "ApiKey revoked", false, http.StatusUnauthorized, ""},
+		{"scoped API key", "ApiKey read-key", true, http.StatusOK, models.RoleUser},
+		{"API key as bearer token", "Bearer gocrud_scim-key", true, http.StatusOK, models.RoleService},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			newTestRouter(tt.required).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.role, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireUserScopes(t *testing.T) {
+	tests := []struct {
+		method        string
+		authorization string
+		status        int
+	}{
+		{"GET", "ApiKey read-key", http.StatusOK},
+		{"POST", "ApiKey read-key", http.StatusForbidden},
+		{"POST", "ApiKey user-key", http.StatusOK},
+		{"POST", "Bearer user-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "/", nil)
+		req.Header.Set("Authorization", tt.authorization)
+
+		w := httptest.NewRecorder()
+		newTestRouter(true).ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.method+" "+tt.authorization)
+	}
+}
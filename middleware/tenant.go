@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantConfig tells where requests name their tenant.
+type TenantConfig struct {
+	// Header names the tenant, such as X-Tenant-ID
+	Header string
+	// Domain serves each tenant on a subdomain of it when set, such as
+	// acme.users.example.com for "users.example.com"
+	Domain string
+}
+
+// ResolveTenant finds the tenant a request is for and sets it in the gin
+// context, leaving it unset for the default tenant. Requests name it in the
+// header or subdomain of cfg, or through the prefix of their session token
+// or API key. Requests naming two different tenants are rejected, as are
+// unknown tenants.
+func ResolveTenant(cfg TenantConfig, tenantService services.TenantService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantID, err := requestTenant(ctx, cfg)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		if tenantID != "" {
+			tenant, err := tenantService.ResolveTenant(tenantID)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+			ctx.Set(controllers.TenantKey, tenant)
+		}
+
+		ctx.Next()
+	}
+}
+
+// requestTenant returns the ID of the tenant named by the request, "" for
+// the default tenant.
+func requestTenant(ctx *gin.Context, cfg TenantConfig) (string, error) {
+	var named []string
+	if cfg.Header != "" {
+		named = append(named, ctx.GetHeader(cfg.Header))
+	}
+	if cfg.Domain != "" {
+		named = append(named, subdomain(ctx.Request.Host, cfg.Domain))
+	}
+
+	credentials := controllers.BearerToken(ctx)
+	if credentials == "" {
+		credentials = controllers.APIKey(ctx)
+	}
+	named = append(named, services.TokenTenant(credentials))
+
+	tenantID := ""
+	for _, id := range named {
+		if id == "" {
+			continue
+		}
+		if tenantID != "" && id != tenantID {
+			return "", &services.Error{Code: services.ErrCodeInvalid, Message: "the request names more than one tenant"}
+		}
+		tenantID = id
+	}
+
+	if tenantID == models.DefaultTenantID {
+		return "", nil
+	}
+	return tenantID, nil
+}
+
+// subdomain returns the label of host right under domain, or "".
+func subdomain(host string, domain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(domain))
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
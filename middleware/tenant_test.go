@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/controllers"
+	"go_crud/models"
+	"go_crud/services"
+)
+
+// MockTenantService is a mock implementation of the TenantService interface
+type MockTenantService struct {
+	services.TenantService
+}
+
+func (m *MockTenantService) ResolveTenant(id string) (*models.Tenant, error) {
+	if id == "acme" || id == "globex" {
+		return &models.Tenant{ID: id}, nil
+	}
+
+	return nil, services.ErrTenantNotFound
+}
+
+func TestResolveTenant(t *testing.T) {
+	router := gin.New()
+	router.Use(ResolveTenant(TenantConfig{Header: "X-Tenant-ID", Domain: "users.example.com"}, &MockTenantService{}))
+	router.GET("/", func(ctx *gin.Context) {
+		tenantID := ""
+		if tenant, ok := ctx.Get(controllers.TenantKey); ok {
+			tenantID = tenant.(*models.Tenant).ID
+		}
+		ctx.String(http.StatusOK, tenantID)
+	})
+
+	tests := []struct {
+		name          string
+		host          string
+		header        string
+		authorization string
+		status        int
+		tenantID      string
+	}{
+		{"default", "localhost:8080", "", "", http.StatusOK, ""},
+		{"header", "localhost:8080", "acme", "", http.StatusOK, "acme"},
+		{"default header", "localhost:8080", "default", "", http.StatusOK, ""},
+		{"subdomain", "acme.users.example.com", "", "", http.StatusOK, "acme"},
+		{"subdomain with port", "Acme.Users.Example.com:443", "", "", http.StatusOK, "acme"},
+		{"nested subdomain", "www.acme.users.example.com", "", "", http.StatusOK, ""},
+		{"session token", "localhost:8080", "", "Bearer acme.0123abcd", http.StatusOK, "acme"},
+		{"API key", "localhost:8080", "", "ApiKey gocrud_acme.0123abcd", http.StatusOK, "acme"},
+		{"default tenant token", "localhost:8080", "acme", "Bearer 0123abcd", http.StatusOK, "acme"},
+		{"agreeing", "acme.users.example.com", "acme", "Bearer acme.0123abcd", http.StatusOK, "acme"},
+		{"conflicting", "acme.users.example.com", "globex", "", http.StatusBadRequest, ""},
+		{"conflicting token", "localhost:8080", "default", "Bearer acme.0123abcd", http.StatusBadRequest, ""},
+		{"unknown", "localhost:8080", "initech", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.tenantID, w.Body.String())
+			}
+		})
+	}
+}
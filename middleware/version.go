@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion records the API version a route group serves, so that the
+// middlewares reply to its errors in the format of that version.
+func APIVersion(version string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(controllers.APIVersionKey, version)
+		ctx.Next()
+	}
+}
+
+// DeprecationConfig describes the retirement of an API version.
+type DeprecationConfig struct {
+	// DeprecatedAt is when the version was deprecated
+	DeprecatedAt time.Time
+	// Sunset is when the version stops being served, the zero value if it
+	// has not been decided
+	Sunset time.Time
+	// Successor is the path of the version replacing it, such as /api/v2
+	Successor string
+}
+
+// Deprecated marks the responses of a deprecated API version with the
+// Deprecation header of RFC 9745, the Sunset header of RFC 8594 once a date
+// is set, and a link to the successor version.
+func Deprecated(config DeprecationConfig) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(config.DeprecatedAt.Unix(), 10)
+	var sunset string
+	if !config.Sunset.IsZero() {
+		sunset = config.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var link string
+	if config.Successor != "" {
+		link = "<" + config.Successor + `>; rel="successor-version"`
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		if sunset != "" {
+			ctx.Header("Sunset", sunset)
+		}
+		if link != "" {
+			ctx.Header("Link", link)
+		}
+
+		ctx.Next()
+	}
+}
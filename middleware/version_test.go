@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/controllers"
+	"go_crud/models"
+)
+
+func TestDeprecated(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DeprecationConfig
+		headers map[string]string
+	}{
+		{"Deprecated", DeprecationConfig{DeprecatedAt: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)}, map[string]string{
+			"Deprecation": "@1792368000",
+			"Sunset":      "",
+			"Link":        "",
+		}},
+		{"Sunset", DeprecationConfig{
+			DeprecatedAt: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			Sunset:       time.Date(2027, time.April, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			Successor:    "/api/v2",
+		}, map[string]string{
+			"Deprecation": "@1792368000",
+			"Sunset":      "Thu, 01 Apr 2027 10:00:00 GMT",
+			"Link":        `</api/v2>; rel="successor-version"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Deprecated(tt.config))
+			router.GET("/", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			for name, value := range tt.headers {
+				assert.Equal(t, value, w.Header().Get(name), name)
+			}
+		})
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	router := gin.New()
+	router.Use(APIVersion("v2"), Authenticate(&MockAuthService{}, &MockAPIKeyService{}), RequireAuth(), RequireUserScopes())
+	router.POST("/users", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		authorization string
+		status        int
+		code          string
+	}{
+		{"", http.StatusUnauthorized, "unauthenticated"},
+		{"Bearer expired", http.StatusUnauthorized, "unauthenticated"},
+		{"ApiKey read-key", http.StatusForbidden, "permission_denied"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/users", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// The errors are problems on the v2 routes
+		assert.Equal(t, tt.status, w.Code, tt.authorization)
+		assert.Equal(t, controllers.ProblemContentType, w.Header().Get("Content-Type"))
+
+		var problem models.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, tt.status, problem.Status)
+		assert.Equal(t, tt.code, problem.Code)
+		assert.Equal(t, "/users", problem.Instance)
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Address is the postal address of a user. Addresses sent as a plain
+// string, as before addresses had parts, are kept as their Formatted line.
+// @Name Address
+// @Description Postal address of a user, a plain string is read as the formatted address.
+type Address struct {
+	// Lines are the street lines, such as the street, number and apartment
+	Lines      []string `json:"lines,omitempty" bson:"lines,omitempty"`
+	City       string   `json:"city,omitempty" bson:"city,omitempty"`
+	Region     string   `json:"region,omitempty" bson:"region,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty" bson:"postal_code,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code, such as NL
+	Country  string    `json:"country,omitempty" bson:"country,omitempty"`
+	Location *GeoPoint `json:"location,omitempty" bson:"location,omitempty"`
+	// Formatted is the whole address on one line, when it was given as such
+	Formatted string `json:"formatted,omitempty" bson:"formatted,omitempty"`
+}
+
+// IsZero reports whether the address has no parts, which removes it from a user.
+func (a *Address) IsZero() bool {
+	return a == nil || (len(a.Lines) == 0 && a.City == "" && a.Region == "" && a.PostalCode == "" &&
+		a.Country == "" && a.Location == nil && a.Formatted == "")
+}
+
+// String returns the address on one line: the formatted address, or its
+// parts joined when it has none. It is "" for nil addresses.
+func (a *Address) String() string {
+	if a == nil {
+		return ""
+	}
+	if a.Formatted != "" {
+		return a.Formatted
+	}
+
+	parts := append([]string{}, a.Lines...)
+	parts = append(parts, strings.TrimSpace(a.PostalCode+" "+a.City), a.Region, a.Country)
+
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// address has the fields of Address without its methods, to decode objects.
+type address Address
+
+// UnmarshalJSON reads an address object, or a plain string as the
+// formatted address.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	*a = Address{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &a.Formatted)
+	}
+
+	return json.Unmarshal(data, (*address)(a))
+}
+
+// UnmarshalBSONValue reads a stored address, or the plain string stored
+// for users created before addresses had parts.
+func (a *Address) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	*a = Address{}
+	switch t {
+	case bsontype.String:
+		return bson.RawValue{Type: t, Value: data}.Unmarshal(&a.Formatted)
+	case bsontype.EmbeddedDocument:
+		return bson.Unmarshal(data, (*address)(a))
+	case bsontype.Null, bsontype.Undefined:
+		return nil
+	default:
+		return fmt.Errorf("cannot read an address from a BSON %s", t)
+	}
+}
+
+// GeoPoint is a position on Earth, stored as a GeoJSON point so users can
+// be searched by distance.
+// @Name GeoPoint
+// @Description Latitude and longitude of an address.
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// geoJSONPoint is the GeoJSON form of a GeoPoint, longitude first.
+type geoJSONPoint struct {
+	Type        string     `bson:"type"`
+	Coordinates [2]float64 `bson:"coordinates"`
+}
+
+func (p GeoPoint) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(geoJSONPoint{Type: "Point", Coordinates: [2]float64{p.Longitude, p.Latitude}})
+}
+
+func (p *GeoPoint) UnmarshalBSON(data []byte) error {
+	var point geoJSONPoint
+	if err := bson.Unmarshal(data, &point); err != nil {
+		return err
+	}
+
+	p.Longitude, p.Latitude = point.Coordinates[0], point.Coordinates[1]
+	return nil
+}
+
+// GeoNear selects the users whose address is within Radius meters of Location.
+type GeoNear struct {
+	Location GeoPoint
+	Radius   float64
+}
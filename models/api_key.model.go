@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes an API key can be limited to. Keys without scopes can do anything
+// their owner can.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// APIKey is a key machine clients send as "Authorization: ApiKey <key>". It
+// belongs to a user, or to a named service account. Only its hash is stored.
+type APIKey struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name           string              `json:"name" bson:"name"`
+	UserID         *primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty"`
+	ServiceAccount string              `json:"serviceAccount,omitempty" bson:"serviceAccount,omitempty"`
+	// Prefix is the start of the key, to tell keys apart
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	KeyHash    string     `json:"-" bson:"keyHash"`
+	Scopes     []string   `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the request model for creating an API key.
+// Keys belong to the caller unless an admin names another user or a
+// service account.
+// @Name CreateAPIKeyRequest
+// @Description Request model for creating an API key.
+type CreateAPIKeyRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	Scopes         []string   `json:"scopes" binding:"omitempty,dive,oneof=users:read users:write"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	UserID         string     `json:"userId"`
+	ServiceAccount string     `json:"serviceAccount"`
+}
+
+// CreatedAPIKey is a new API key, the only time the key itself is shown.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyFilter selects API keys, the zero value selects every key.
+type APIKeyFilter struct {
+	UserID         string
+	ServiceAccount string
+}
+
+// CreateAPIKeyResponse represents the response model for creating an API key.
+// @Name CreateAPIKeyResponse
+// @Description Response model carrying a new API key.
+type CreateAPIKeyResponse struct {
+	Data   CreatedAPIKey `json:"data"`
+	Status string        `json:"status"`
+}
+
+// FindAPIKeysResponse represents the response model for listing API keys.
+// @Name FindAPIKeysResponse
+// @Description Response model for a list of API keys.
+type FindAPIKeysResponse struct {
+	Data    []APIKey `json:"data"`
+	Results int      `json:"results"`
+	Status  string   `json:"status"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Types of the custom attributes of users.
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	// AttributeTypeDate values are RFC 3339 timestamps, stored as dates so
+	// they sort in time order
+	AttributeTypeDate = "date"
+)
+
+// Attribute defines a custom attribute admins add to the users of their
+// tenant, such as an employee number or cost center. Users keep its values
+// in their attributes, which are checked against the definitions when they
+// are written.
+type Attribute struct {
+	// Name is the key of the attribute in the attributes of users
+	Name     string `json:"name" bson:"_id"`
+	Type     string `json:"type" bson:"type"`
+	Required bool   `json:"required" bson:"required"`
+	// Unique attributes cannot have the same value for two users
+	Unique bool `json:"unique" bson:"unique"`
+	// Enum lists the values a string attribute can have, when set
+	Enum []string `json:"enum,omitempty" bson:"enum,omitempty"`
+	// Pattern is a regular expression string values must match, when set
+	Pattern   string    `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// CreateAttributeRequest represents the request model for defining a custom attribute.
+// @Name CreateAttributeRequest
+// @Description Request model for defining a custom user attribute.
+type CreateAttributeRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Type     string   `json:"type" binding:"required,oneof=string number boolean date"`
+	Required bool     `json:"required"`
+	Unique   bool     `json:"unique"`
+	Enum     []string `json:"enum"`
+	Pattern  string   `json:"pattern"`
+}
+
+// UpdateAttributeRequest represents the request model for changing a custom
+// attribute. The name and type cannot change.
+// @Name UpdateAttributeRequest
+// @Description Request model for changing a custom user attribute.
+type UpdateAttributeRequest struct {
+	Required bool     `json:"required"`
+	Unique   bool     `json:"unique"`
+	Enum     []string `json:"enum"`
+	Pattern  string   `json:"pattern"`
+}
+
+// AttributeResponse represents the response model for a custom attribute.
+// @Name AttributeResponse
+// @Description Response model carrying a custom user attribute.
+type AttributeResponse struct {
+	Data   Attribute `json:"data"`
+	Status string    `json:"status"`
+}
+
+// FindAttributesResponse represents the response model for listing custom attributes.
+// @Name FindAttributesResponse
+// @Description Response model for a list of custom user attributes.
+type FindAttributesResponse struct {
+	Data    []Attribute `json:"data"`
+	Results int         `json:"results"`
+	Status  string      `json:"status"`
+}
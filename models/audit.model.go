@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditActionUserExported = "user.exported"
+	AuditActionUserErased   = "user.erased"
+)
+
+// AuditActor is who did an audited action. Both fields are empty for
+// deployments without authentication.
+type AuditActor struct {
+	UserID   string
+	APIKeyID string
+}
+
+// AuditEntry records an action on a user. Entries only refer to users by
+// ID, so they outlive the erasure of the user.
+type AuditEntry struct {
+	ID       primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Action   string              `json:"action" bson:"action"`
+	UserID   primitive.ObjectID  `json:"userId" bson:"userId"`
+	ActorID  *primitive.ObjectID `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	APIKeyID string              `json:"apiKeyId,omitempty" bson:"apiKeyId,omitempty"`
+	// Details depend on the action, such as the format of an export
+	Details   map[string]interface{} `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time              `json:"createdAt" bson:"createdAt"`
+}
+
+// FindAuditEntriesResponse represents the response model for listing audit entries.
+// @Name FindAuditEntriesResponse
+// @Description Response model for a list of audit entries.
+type FindAuditEntriesResponse struct {
+	Data    []AuditEntry `json:"data"`
+	Results int          `json:"results"`
+	Status  string       `json:"status"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a signed in user, stored with the hash of its bearer token.
+type Session struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	TokenHash string             `bson:"tokenHash"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// PasswordReset is a single use password reset token, stored hashed.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	TokenHash string             `bson:"tokenHash"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty"`
+}
+
+// LoginRequest represents the request model for signing in.
+// @Name LoginRequest
+// @Description Request model for signing in with an email and password.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResult is a new session and the bearer token to use it. When the
+// user has two-factor authentication, it is instead the token to finish
+// signing in with, and ExpiresAt is when that token expires.
+type LoginResult struct {
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user,omitempty"`
+
+	MFARequired bool   `json:"mfaRequired,omitempty"`
+	MFAToken    string `json:"mfaToken,omitempty"`
+}
+
+// LoginResponse represents the response model for the Login API.
+// @Name LoginResponse
+// @Description Response model for signing in.
+type LoginResponse struct {
+	Data   LoginResult `json:"data"`
+	Status string      `json:"status"`
+}
+
+// ForgotPasswordRequest represents the request model for asking for a password reset.
+// @Name ForgotPasswordRequest
+// @Description Request model for asking for a password reset email.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest represents the request model for resetting a password.
+// @Name ResetPasswordRequest
+// @Description Request model for setting a new password with a reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// MessageResponse represents a response carrying only a message.
+// @Name MessageResponse
+// @Description Response model carrying a message.
+type MessageResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
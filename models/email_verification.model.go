@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailVerification is a pending email verification token, stored hashed.
+// It only verifies the email it was sent to.
+type EmailVerification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	Email     string             `bson:"email"`
+	TokenHash string             `bson:"tokenHash"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// ResendVerificationRequest represents the request model for asking for another verification email.
+// @Name ResendVerificationRequest
+// @Description Request model for asking for another verification email.
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
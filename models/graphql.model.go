@@ -0,0 +1,26 @@
+package models
+
+// GraphQLRequest represents a GraphQL request.
+// @Name GraphQLRequest
+// @Description Request model for a GraphQL query or mutation.
+type GraphQLRequest struct {
+	Query string `json:"query" binding:"required"`
+	// OperationName picks the operation to run when the query has several
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse represents a GraphQL response.
+// @Name GraphQLResponse
+// @Description Response model of a GraphQL request, data is left out when the request could not run.
+type GraphQLResponse struct {
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Errors []GraphQLError         `json:"errors,omitempty"`
+}
+
+// GraphQLError represents an error of a GraphQL response.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
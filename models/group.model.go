@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group organises users, for permissions and reporting. Groups can be
+// nested in other groups, whose members their members then are too.
+type Group struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	// Groups are the groups this group is nested in
+	Groups    []primitive.ObjectID `json:"groups,omitempty" bson:"groups,omitempty"`
+	CreatedAt time.Time            `json:"createdAt" bson:"createdAt"`
+}
+
+// CreateGroupRequest represents the request model for creating a group.
+// @Name CreateGroupRequest
+// @Description Request model for creating a group.
+type CreateGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateGroupRequest represents the request model for replacing the name
+// and description of a group.
+// @Name UpdateGroupRequest
+// @Description Request model for updating a group.
+type UpdateGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// GroupMembersRequest represents the request model for adding members to,
+// or removing them from, a group.
+// @Name GroupMembersRequest
+// @Description Users and nested groups to add to or remove from a group.
+type GroupMembersRequest struct {
+	UserIDs  []string `json:"userIds"`
+	GroupIDs []string `json:"groupIds"`
+}
+
+// GroupResponse represents the response model for a group.
+// @Name GroupResponse
+// @Description Response model carrying a group.
+type GroupResponse struct {
+	Data   Group  `json:"data"`
+	Status string `json:"status"`
+}
+
+// FindGroupsResponse represents the response model for listing groups.
+// @Name FindGroupsResponse
+// @Description Response model for a list of groups.
+type FindGroupsResponse struct {
+	Data    []Group `json:"data"`
+	Results int     `json:"results"`
+	Status  string  `json:"status"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses of an invite.
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusRevoked  = "revoked"
+	InviteStatusExpired  = "expired"
+)
+
+// Invite asks someone to create an account with the email it was sent to.
+// Only the hash of its token is stored, the link sent is the only copy.
+type Invite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email     string             `json:"email" bson:"email"`
+	Role      string             `json:"role,omitempty" bson:"role,omitempty"`
+	TokenHash string             `json:"-" bson:"tokenHash"`
+	// InvitedBy is the user who created the invite, unset for callers
+	// without a user
+	InvitedBy  *primitive.ObjectID `json:"invitedBy,omitempty" bson:"invitedBy,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+	SentAt     time.Time           `json:"sentAt" bson:"sentAt"`
+	ExpiresAt  time.Time           `json:"expiresAt" bson:"expiresAt"`
+	AcceptedAt *time.Time          `json:"acceptedAt,omitempty" bson:"acceptedAt,omitempty"`
+	// UserID is the user who accepted the invite
+	UserID    *primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty"`
+	RevokedAt *time.Time          `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	Status    string              `json:"status" bson:"-"`
+}
+
+// CreateInviteRequest represents the request model for inviting someone.
+// The invite expires after the default validity unless ExpiresAt is set.
+// @Name CreateInviteRequest
+// @Description Request model for inviting someone to create an account.
+type CreateInviteRequest struct {
+	Email     string     `json:"email" binding:"required,email"`
+	Role      string     `json:"role" binding:"omitempty,oneof=admin user"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// AcceptInviteRequest represents the request model for accepting an invite,
+// with the profile and the password of the new account.
+// @Name AcceptInviteRequest
+// @Description Request model for accepting an invite.
+type AcceptInviteRequest struct {
+	Token      string                 `json:"token" binding:"required"`
+	Name       string                 `json:"name" binding:"required"`
+	Age        *int                   `json:"age" binding:"required"`
+	Password   string                 `json:"password" binding:"required"`
+	Address    *Address               `json:"address" binding:"required"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// InviteResponse represents the response model for an invite.
+// @Name InviteResponse
+// @Description Response model carrying an invite.
+type InviteResponse struct {
+	Data   Invite `json:"data"`
+	Status string `json:"status"`
+}
+
+// FindInvitesResponse represents the response model for listing invites.
+// @Name FindInvitesResponse
+// @Description Response model for a list of invites.
+type FindInvitesResponse struct {
+	Data    []Invite `json:"data"`
+	Results int      `json:"results"`
+	Status  string   `json:"status"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a sign in attempt failed.
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureInvalidMFACode     = "invalid_mfa_code"
+	LoginFailureLockedOut          = "locked_out"
+	LoginFailureEmailNotVerified   = "email_not_verified"
+	LoginFailureAccountSuspended   = "account_suspended"
+	LoginFailureAccountDisabled    = "account_disabled"
+)
+
+// LoginAttempt is an entry of the sign in history.
+type LoginAttempt struct {
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// UserID is unset when no user has the email
+	UserID    *primitive.ObjectID `json:"userId,omitempty" bson:"userId,omitempty"`
+	Email     string              `json:"email" bson:"email"`
+	IP        string              `json:"ip" bson:"ip"`
+	UserAgent string              `json:"userAgent" bson:"userAgent"`
+	Success   bool                `json:"success" bson:"success"`
+	// Reason is one of the LoginFailure constants when the attempt failed
+	Reason    string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// LoginClient describes where a sign in attempt came from.
+type LoginClient struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginThrottle counts the failed sign ins of an email or IP address.
+type LoginThrottle struct {
+	Key         string     `bson:"_id"`
+	Failures    int        `bson:"failures"`
+	Lockouts    int        `bson:"lockouts"`
+	LockedUntil *time.Time `bson:"lockedUntil,omitempty"`
+	UpdatedAt   time.Time  `bson:"updatedAt"`
+}
+
+// LockoutStatus tells whether a user is locked out after failed sign ins.
+type LockoutStatus struct {
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	// FailedAttempts counts the failures since the last lockout or success
+	FailedAttempts int `json:"failedAttempts"`
+	// Lockouts counts the lockouts in a row, each one lasts twice as long
+	Lockouts int `json:"lockouts"`
+}
+
+// LockoutStatusResponse represents the response model for the lockout status API.
+// @Name LockoutStatusResponse
+// @Description Response model for the lockout status of a user.
+type LockoutStatusResponse struct {
+	Data   LockoutStatus `json:"data"`
+	Status string        `json:"status"`
+}
+
+// LoginHistoryResponse represents the response model for the sign in history API.
+// @Name LoginHistoryResponse
+// @Description Response model for a page of the sign in history of a user.
+type LoginHistoryResponse struct {
+	Data    []LoginAttempt `json:"data"`
+	Results int            `json:"results"`
+	Status  string         `json:"status"`
+}
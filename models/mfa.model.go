@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserMFA is the two-factor state stored in the "mfa" field of a user. The
+// secrets are encrypted and the recovery codes hashed.
+type UserMFA struct {
+	Secret string `bson:"secret,omitempty"`
+	// PendingSecret is set between enrolling and confirming
+	PendingSecret string `bson:"pending_secret,omitempty"`
+	// LastCounter is the time step of the last code used, older codes are replays
+	LastCounter   int64    `bson:"last_counter,omitempty"`
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"`
+}
+
+// MFAChallenge is the second step of a sign in, stored with the hash of its token.
+type MFAChallenge struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	TokenHash string             `bson:"tokenHash"`
+	Attempts  int                `bson:"attempts"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+// MFAEnrollment is a new TOTP secret, to be added to an authenticator app.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to show as a QR code
+	URI string `json:"otpauthUri"`
+}
+
+// MFAEnrollmentResponse represents the response model for enrolling in two-factor authentication.
+// @Name MFAEnrollmentResponse
+// @Description Response model carrying a new TOTP secret.
+type MFAEnrollmentResponse struct {
+	Data   MFAEnrollment `json:"data"`
+	Status string        `json:"status"`
+}
+
+// RecoveryCodes are single use codes replacing a TOTP code when the
+// authenticator is lost. They are only shown once.
+type RecoveryCodes struct {
+	Codes []string `json:"recoveryCodes"`
+}
+
+// RecoveryCodesResponse represents the response model carrying recovery codes.
+// @Name RecoveryCodesResponse
+// @Description Response model carrying new recovery codes.
+type RecoveryCodesResponse struct {
+	Data   RecoveryCodes `json:"data"`
+	Status string        `json:"status"`
+}
+
+// MFACodeRequest represents the request model carrying a TOTP or recovery code.
+// @Name MFACodeRequest
+// @Description Request model carrying a code from the authenticator app, or a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginRequest represents the request model for the second step of signing in.
+// @Name MFALoginRequest
+// @Description Request model for finishing a sign in with a TOTP or recovery code.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
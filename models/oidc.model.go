@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExternalIdentity links a user to their account at an identity provider.
+type ExternalIdentity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	Email    string    `json:"email,omitempty" bson:"email,omitempty"`
+	LinkedAt time.Time `json:"linkedAt" bson:"linkedAt"`
+}
+
+// OIDCState is a sign in started with an identity provider, stored with the
+// hash of its state parameter until the provider redirects back.
+type OIDCState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	StateHash    string             `bson:"stateHash"`
+	Provider     string             `bson:"provider"`
+	Nonce        string             `bson:"nonce"`
+	CodeVerifier string             `bson:"codeVerifier"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+	ExpiresAt    time.Time          `bson:"expiresAt"`
+	// LinkUserID is the signed in user the identity is linked to, unset
+	// for sign ins
+	LinkUserID primitive.ObjectID `bson:"linkUserId,omitempty"`
+}
+
+// OIDCLink is the page of the identity provider to send the user to.
+// @Name OIDCLink
+// @Description Page of the identity provider to send the user to.
+type OIDCLink struct {
+	URL string `json:"url"`
+}
+
+// OIDCLinkResponse represents the response model for linking an identity provider.
+// @Name OIDCLinkResponse
+// @Description Response model for linking an identity provider.
+type OIDCLinkResponse struct {
+	Data   OIDCLink `json:"data"`
+	Status string   `json:"status"`
+}
+
+// OIDCProvidersResponse represents the response model listing the identity providers.
+// @Name OIDCProvidersResponse
+// @Description Response model listing the identity providers users can sign in with.
+type OIDCProvidersResponse struct {
+	Data   []string `json:"data"`
+	Status string   `json:"status"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// Rules of the password policy.
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleMaxLength        = "max_length"
+	PasswordRuleCharacterClasses = "character_classes"
+	PasswordRuleNotEmail         = "not_email"
+	PasswordRuleNotName          = "not_name"
+	PasswordRuleBreached         = "breached"
+)
+
+// PasswordViolation is a password policy rule a password breaks.
+// @Name PasswordViolation
+// @Description A password policy rule the password breaks.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// Roles a caller can have. Requests without a role were not authenticated,
+// which GO_CRUD_AUTH_REQUIRED=false allows, and only get the rights every
+// caller has.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+	// RoleService is the role of API keys of service accounts
+	RoleService = "service"
+)
@@ -0,0 +1,219 @@
+package models
+
+import "time"
+
+// SCIM 2.0 schema and message URNs (RFC 7643, RFC 7644).
+const (
+	SCIMUserSchema            = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMUserExtensionSchema   = "urn:go_crud:params:scim:schemas:extension:2.0:User"
+	SCIMListResponseSchema    = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema         = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema           = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMServiceProviderSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMResourceTypeSchema    = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCIMSchemaSchema          = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+// SCIMUser is a user as SCIM represents it. The userName is the email, the
+// name is read from displayName, name.formatted or the given and family
+// names, the address is the first of addresses and the age lives in the
+// go_crud extension. Suspended and disabled users are inactive, which SCIM
+// cannot change, and externalId is not stored.
+// @Name SCIMUser
+// @Description A user as a SCIM resource.
+type SCIMUser struct {
+	Schemas     []string           `json:"schemas"`
+	ID          string             `json:"id,omitempty"`
+	ExternalID  string             `json:"externalId,omitempty"`
+	UserName    string             `json:"userName"`
+	Name        *SCIMName          `json:"name,omitempty"`
+	DisplayName string             `json:"displayName,omitempty"`
+	Emails      []SCIMEmail        `json:"emails,omitempty"`
+	Addresses   []SCIMAddress      `json:"addresses,omitempty"`
+	Active      *bool              `json:"active,omitempty"`
+	Password    string             `json:"password,omitempty"`
+	Extension   *SCIMUserExtension `json:"urn:go_crud:params:scim:schemas:extension:2.0:User,omitempty"`
+	Meta        *SCIMMeta          `json:"meta,omitempty"`
+}
+
+// SCIMName is the name of a SCIM user.
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail is one of the emails of a SCIM user.
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMAddress is one of the addresses of a SCIM user. The formatted address
+// is returned for every address, joining its parts when it was not given.
+type SCIMAddress struct {
+	Formatted     string `json:"formatted,omitempty"`
+	StreetAddress string `json:"streetAddress,omitempty"`
+	Locality      string `json:"locality,omitempty"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postalCode,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Primary       bool   `json:"primary,omitempty"`
+}
+
+// SCIMUserExtension holds the user fields SCIM has no attribute for.
+type SCIMUserExtension struct {
+	Age *int `json:"age,omitempty"`
+}
+
+// SCIMMeta describes a SCIM resource.
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Location     string     `json:"location,omitempty"`
+	Created      *time.Time `json:"created,omitempty"`
+}
+
+// SCIMListResponse is a page of SCIM resources.
+// @Name SCIMListResponse
+// @Description A page of SCIM users.
+type SCIMListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int64      `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []SCIMUser `json:"Resources"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH request.
+// @Name SCIMPatchRequest
+// @Description Request model for a SCIM PATCH.
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas" binding:"required"`
+	Operations []SCIMPatchOperation `json:"Operations" binding:"required,min=1,dive"`
+}
+
+// SCIMPatchOperation is one operation of a SCIM PATCH request. Without a
+// path the value is an object of the attributes to change.
+type SCIMPatchOperation struct {
+	Op    string      `json:"op" binding:"required"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// SCIMError is the body of a SCIM error response.
+// @Name SCIMError
+// @Description SCIM error response.
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// SCIMServiceProviderConfig describes the SCIM features this server supports.
+// @Name SCIMServiceProviderConfig
+// @Description The SCIM features of the server.
+type SCIMServiceProviderConfig struct {
+	Schemas               []string                 `json:"schemas"`
+	DocumentationURI      string                   `json:"documentationUri,omitempty"`
+	Patch                 SCIMSupported            `json:"patch"`
+	Bulk                  SCIMBulkSupport          `json:"bulk"`
+	Filter                SCIMFilterSupport        `json:"filter"`
+	ChangePassword        SCIMSupported            `json:"changePassword"`
+	Sort                  SCIMSupported            `json:"sort"`
+	ETag                  SCIMSupported            `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationType `json:"authenticationSchemes"`
+	Meta                  SCIMMeta                 `json:"meta"`
+}
+
+// SCIMSupported tells whether a SCIM feature is supported.
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+// SCIMBulkSupport describes the support for bulk requests.
+type SCIMBulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// SCIMFilterSupport describes the support for filters.
+type SCIMFilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// SCIMAuthenticationType describes a way to authenticate SCIM requests.
+type SCIMAuthenticationType struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// SCIMResourceType describes a kind of SCIM resource.
+// @Name SCIMResourceType
+// @Description A kind of SCIM resource.
+type SCIMResourceType struct {
+	Schemas          []string              `json:"schemas"`
+	ID               string                `json:"id"`
+	Name             string                `json:"name"`
+	Endpoint         string                `json:"endpoint"`
+	Description      string                `json:"description"`
+	Schema           string                `json:"schema"`
+	SchemaExtensions []SCIMSchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             SCIMMeta              `json:"meta"`
+}
+
+// SCIMSchemaExtension names a schema extending a resource type.
+type SCIMSchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// SCIMSchema describes the attributes of a SCIM schema.
+// @Name SCIMSchema
+// @Description The attributes of a SCIM schema.
+type SCIMSchema struct {
+	Schemas     []string        `json:"schemas,omitempty"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Attributes  []SCIMAttribute `json:"attributes"`
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+// SCIMAttribute describes an attribute of a SCIM schema.
+type SCIMAttribute struct {
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	MultiValued   bool            `json:"multiValued"`
+	Description   string          `json:"description,omitempty"`
+	Required      bool            `json:"required"`
+	CaseExact     bool            `json:"caseExact"`
+	Mutability    string          `json:"mutability"`
+	Returned      string          `json:"returned"`
+	Uniqueness    string          `json:"uniqueness"`
+	SubAttributes []SCIMAttribute `json:"subAttributes,omitempty"`
+}
+
+// SCIMSchemasResponse is the list of the supported SCIM schemas.
+type SCIMSchemasResponse struct {
+	Schemas      []string     `json:"schemas"`
+	TotalResults int          `json:"totalResults"`
+	StartIndex   int          `json:"startIndex"`
+	ItemsPerPage int          `json:"itemsPerPage"`
+	Resources    []SCIMSchema `json:"Resources"`
+}
+
+// SCIMResourceTypesResponse is the list of the supported SCIM resource types.
+type SCIMResourceTypesResponse struct {
+	Schemas      []string           `json:"schemas"`
+	TotalResults int                `json:"totalResults"`
+	StartIndex   int                `json:"startIndex"`
+	ItemsPerPage int                `json:"itemsPerPage"`
+	Resources    []SCIMResourceType `json:"Resources"`
+}
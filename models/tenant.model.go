@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DefaultTenantID names the default tenant in requests. It owns the data
+// created before multi-tenancy was turned on and is stored without an ID.
+const DefaultTenantID = "default"
+
+// Tenant is a customer whose users are kept apart from those of the others.
+// Its users live in the shared database unless it has a dedicated one.
+type Tenant struct {
+	// ID is a slug, used in subdomains, headers and the prefix of tokens
+	ID   string `json:"id" bson:"_id"`
+	Name string `json:"name" bson:"name"`
+	// Database is the dedicated database of the tenant, if it has one
+	Database  string    `json:"database,omitempty" bson:"database,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// CreateTenantRequest represents the request model for creating a tenant.
+// @Name CreateTenantRequest
+// @Description Request model for creating a tenant.
+type CreateTenantRequest struct {
+	ID   string `json:"id" binding:"required"`
+	Name string `json:"name" binding:"required"`
+	// DedicatedDatabase stores the users of the tenant in a database of its own
+	DedicatedDatabase bool `json:"dedicatedDatabase"`
+}
+
+// UpdateTenantRequest represents the request model for updating a tenant.
+// @Name UpdateTenantRequest
+// @Description Request model for updating a tenant.
+type UpdateTenantRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TenantResponse represents the response model for a tenant.
+// @Name TenantResponse
+// @Description Response model carrying a tenant.
+type TenantResponse struct {
+	Data   Tenant `json:"data"`
+	Status string `json:"status"`
+}
+
+// FindTenantsResponse represents the response model for listing tenants.
+// @Name FindTenantsResponse
+// @Description Response model for a list of tenants.
+type FindTenantsResponse struct {
+	Data    []Tenant `json:"data"`
+	Results int      `json:"results"`
+	Status  string   `json:"status"`
+}
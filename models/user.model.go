@@ -1,18 +1,30 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreateUserRequest represents the request model for creating a new user.
+// Users are created with an unverified email, unless they accepted an
+// invite sent to it.
 // @Name CreateUserRequest
 // @Description Request model for creating a new user.
 type CreateUserRequest struct {
-	Name     string `json:"name" bson:"name" binding:"required"`
-	Age      *int   `json:"age" bson:"age" binding:"required"`
-	Email    string `json:"email" bson:"email" binding:"required"`
-	Password string `json:"password" bson:"password" binding:"required"`
-	Address  string `json:"address" bson:"address" binding:"required"`
+	Name     string   `json:"name" bson:"name" binding:"required"`
+	Age      *int     `json:"age" bson:"age" binding:"required"`
+	Email    string   `json:"email" bson:"email" binding:"required,email"`
+	Password string   `json:"password" bson:"password" binding:"required"`
+	Address  *Address `json:"address" bson:"address" binding:"required"`
+	// Attributes are the values of the custom attributes defined by admins
+	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+
+	// Role and the verified email cannot be requested, they are set for
+	// users created from an invite
+	Role          string     `json:"-" bson:"role,omitempty"`
+	EmailVerified bool       `json:"-" bson:"email_verified,omitempty"`
+	VerifiedAt    *time.Time `json:"-" bson:"verified_at,omitempty"`
 }
 
 // DBUser represents the user model stored in the database.
@@ -24,7 +36,23 @@ type DBUser struct {
 	Age      *int               `json:"age" bson:"age" binding:"required"`
 	Email    string             `json:"email" bson:"email" binding:"required"`
 	Password string             `json:"password" bson:"password" binding:"required"`
-	Address  string             `json:"address" bson:"address" binding:"required"`
+	Address  *Address           `json:"address" bson:"address,omitempty" binding:"required"`
+	Role     string             `json:"role,omitempty" bson:"role,omitempty"`
+
+	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+
+	EmailVerified bool       `json:"email_verified" bson:"email_verified"`
+	VerifiedAt    *time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+
+	MFAEnabled bool     `json:"mfa_enabled" bson:"mfa_enabled"`
+	MFA        *UserMFA `json:"-" bson:"mfa,omitempty"`
+
+	Status         string     `json:"status,omitempty" bson:"status,omitempty"`
+	StatusReason   string     `json:"status_reason,omitempty" bson:"status_reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty" bson:"suspended_until,omitempty"`
+
+	// Identities are the identity provider accounts the user signs in with
+	Identities []ExternalIdentity `json:"identities,omitempty" bson:"identities,omitempty"`
 }
 
 // User represents the basic user details.
@@ -33,7 +61,22 @@ type User struct {
 	Name    string             `json:"name" bson:"name" binding:"required"`
 	Age     *int               `json:"age" bson:"age" binding:"required"`
 	Email   string             `json:"email" bson:"email" binding:"required"`
-	Address string             `json:"address" bson:"address" binding:"required"`
+	Address *Address           `json:"address" bson:"address,omitempty" binding:"required"`
+	// Role is only set for users with more rights than models.RoleUser
+	Role string `json:"role,omitempty" bson:"role,omitempty"`
+	// Attributes are the values of the custom attributes defined by admins
+	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"attributes,omitempty"`
+	// Groups are the groups the user is a direct member of
+	Groups []primitive.ObjectID `json:"groups,omitempty" bson:"groups,omitempty"`
+	// EmailVerified is reset whenever the email changes
+	EmailVerified bool       `json:"email_verified" bson:"email_verified"`
+	VerifiedAt    *time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+	// MFAEnabled is set once a TOTP authenticator has been confirmed
+	MFAEnabled bool `json:"mfa_enabled" bson:"mfa_enabled"`
+	// Status is one of the UserStatus constants, unset for active users
+	Status         string     `json:"status,omitempty" bson:"status,omitempty"`
+	StatusReason   string     `json:"status_reason,omitempty" bson:"status_reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty" bson:"suspended_until,omitempty"`
 	// Add any other fields as needed for the response
 }
 
@@ -41,18 +84,53 @@ type User struct {
 // @Name UpdateUser
 // @Description Request model for updating a user.
 type UpdateUser struct {
-	Name     string `json:"name,omitempty" bson:"name,omitempty"`
-	Age      *int   `json:"age,omitempty" bson:"age,omitempty"`
-	Email    string `json:"email,omitempty" bson:"email,omitempty"`
-	Password string `json:"password,omitempty" bson:"password,omitempty"`
-	Address  string `json:"address,omitempty" bson:"address,omitempty"`
+	Name     string   `json:"name,omitempty" bson:"name,omitempty"`
+	Age      *int     `json:"age,omitempty" bson:"age,omitempty"`
+	Email    string   `json:"email,omitempty" bson:"email,omitempty" binding:"omitempty,email"`
+	Password string   `json:"password,omitempty" bson:"password,omitempty"`
+	Address  *Address `json:"address,omitempty" bson:"address,omitempty"`
+	// Attributes are written one by one, the others the user has are kept
+	Attributes map[string]interface{} `json:"attributes,omitempty" bson:"-"`
+}
+
+// UserFilter selects users, the zero value selects every user.
+type UserFilter struct {
+	// Name matches names containing it, ignoring case
+	Name string
+	// Email matches the email, ignoring case
+	Email  string
+	MinAge *int
+	MaxAge *int
+	// IDs only selects the users with these IDs when set
+	IDs []string
+	// AfterID only selects the users created after the user with this ID,
+	// to page through users in the order they were created
+	AfterID string
+	// City matches the city of the address, ignoring case
+	City string
+	// Country matches the ISO 3166-1 alpha-2 country code of the address
+	Country string
+	// Near only selects the users whose address is close to a location
+	Near *GeoNear
+	// Attributes match the custom attributes with these names, the values
+	// are read according to the type of the attribute
+	Attributes map[string]string
+	// Group only selects the members of the group with this ID, including
+	// the members of the groups nested in it
+	Group string
+	// Status is one of the UserStatus constants, active includes users
+	// without a status
+	Status string
+	// Sort orders the users by a custom attribute, from the highest value
+	// when it starts with "-", and then in the order they were created
+	Sort string
 }
 
 // CreateUserResponse represents the response model for the CreateUser API.
 // @Name CreateUserResponse
 // @Description Response model for creating a new user.
 type CreateUserResponse struct {
-	Data   User   `json:"data"`
+	Data   UserV1 `json:"data"`
 	Status string `json:"status"`
 }
 
@@ -60,7 +138,7 @@ type CreateUserResponse struct {
 // @Name UpdateUserResponse
 // @Description Response model for updating an existing user.
 type UpdateUserResponse struct {
-	Data   User   `json:"data"`
+	Data   UserV1 `json:"data"`
 	Status string `json:"status"`
 }
 
@@ -68,7 +146,7 @@ type UpdateUserResponse struct {
 // @Name FindUserResponse
 // @Description Response model for finding a user by ID.
 type FindUserResponse struct {
-	Data   User   `json:"data"`
+	Data   UserV1 `json:"data"`
 	Status string `json:"status"`
 }
 
@@ -76,9 +154,9 @@ type FindUserResponse struct {
 // @Name FindUsersResponse
 // @Description Response model for finding users with pagination.
 type FindUsersResponse struct {
-	Data    []User `json:"data"`
-	Results int    `json:"results"`
-	Status  string `json:"status"`
+	Data    []UserV1 `json:"data"`
+	Results int      `json:"results"`
+	Status  string   `json:"status"`
 }
 
 // ErrorResponse represents the response model for error responses.
@@ -87,5 +165,7 @@ type FindUsersResponse struct {
 type ErrorResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Errors lists the broken rules when a password is rejected by the policy
+	Errors []PasswordViolation `json:"errors,omitempty"`
 	// Add any other fields as needed for the error response
 }
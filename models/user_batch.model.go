@@ -0,0 +1,75 @@
+package models
+
+// Batch modes. Ordered batches stop at the first failing item, unordered
+// batches process every item.
+const (
+	BatchModeOrdered   = "ordered"
+	BatchModeUnordered = "unordered"
+)
+
+// Batch item statuses.
+const (
+	BatchItemCreated = "created"
+	BatchItemUpdated = "updated"
+	BatchItemDeleted = "deleted"
+	BatchItemFailed  = "failed"
+	BatchItemSkipped = "skipped"
+)
+
+// BatchCreateUsersRequest represents the request model for creating users in bulk.
+// @Name BatchCreateUsersRequest
+// @Description Request model for creating users in bulk.
+type BatchCreateUsersRequest struct {
+	Mode  string              `json:"mode" binding:"omitempty,oneof=ordered unordered"`
+	Users []CreateUserRequest `json:"users" binding:"required"`
+}
+
+// BatchUpdateUser represents a single update in a bulk update request.
+// @Name BatchUpdateUser
+// @Description A user ID along with the fields to update.
+type BatchUpdateUser struct {
+	ID string `json:"id"`
+	UpdateUser
+}
+
+// BatchUpdateUsersRequest represents the request model for updating users in bulk.
+// @Name BatchUpdateUsersRequest
+// @Description Request model for updating users in bulk.
+type BatchUpdateUsersRequest struct {
+	Mode  string            `json:"mode" binding:"omitempty,oneof=ordered unordered"`
+	Users []BatchUpdateUser `json:"users" binding:"required"`
+}
+
+// BatchDeleteUsersRequest represents the request model for deleting users in bulk.
+// @Name BatchDeleteUsersRequest
+// @Description Request model for deleting users in bulk.
+type BatchDeleteUsersRequest struct {
+	Mode string   `json:"mode" binding:"omitempty,oneof=ordered unordered"`
+	IDs  []string `json:"ids" binding:"required"`
+}
+
+// BatchItemError describes why a batch item failed.
+// @Name BatchItemError
+// @Description Error code and message of a failed batch item.
+type BatchItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchItemResult represents the outcome of a single batch item.
+// @Name BatchItemResult
+// @Description Outcome of a single batch item, in request order.
+type BatchItemResult struct {
+	Index  int             `json:"index"`
+	Status string          `json:"status"`
+	ID     string          `json:"id,omitempty"`
+	Error  *BatchItemError `json:"error,omitempty"`
+}
+
+// BatchUsersResponse represents the response model for the bulk APIs.
+// @Name BatchUsersResponse
+// @Description Response model for creating, updating or deleting users in bulk.
+type BatchUsersResponse struct {
+	Data   []BatchItemResult `json:"data"`
+	Status string            `json:"status"`
+}
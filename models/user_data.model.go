@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Formats of a user data export. A ZIP holds a JSON file per part of the
+// export.
+const (
+	UserDataFormatJSON = "json"
+	UserDataFormatZIP  = "zip"
+)
+
+// UserDataExport is everything held about a user, for subject access
+// requests. Secrets such as password hashes and tokens are left out.
+type UserDataExport struct {
+	ExportedAt   time.Time          `json:"exportedAt"`
+	User         *User              `json:"user"`
+	Identities   []ExternalIdentity `json:"identities"`
+	Groups       []*Group           `json:"groups"`
+	Sessions     []*ExportedSession `json:"sessions"`
+	LoginHistory []*LoginAttempt    `json:"loginHistory"`
+	APIKeys      []*APIKey          `json:"apiKeys"`
+	Invites      []*Invite          `json:"invites"`
+	AuditLog     []*AuditEntry      `json:"auditLog"`
+}
+
+// ExportedSession is a session of an export, without its token.
+type ExportedSession struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+}
+
+// UserErasure tells what was done to erase a user. Removed counts the
+// documents deleted per collection, Anonymised those kept without the
+// personal data in them.
+type UserErasure struct {
+	UserID     string           `json:"userId"`
+	ErasedAt   time.Time        `json:"erasedAt"`
+	Removed    map[string]int64 `json:"removed"`
+	Anonymised map[string]int64 `json:"anonymised"`
+}
+
+// UserErasureResponse represents the response model for erasing a user.
+// @Name UserErasureResponse
+// @Description Response model telling what was erased.
+type UserErasureResponse struct {
+	Data   UserErasure `json:"data"`
+	Status string      `json:"status"`
+}
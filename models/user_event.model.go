@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// User event types pushed to stream subscribers.
+const (
+	UserEventCreated = "create"
+	UserEventUpdated = "update"
+	UserEventDeleted = "delete"
+	// UserEventReset tells subscribers that events may have been missed and
+	// the full list should be fetched again.
+	UserEventReset = "reset"
+)
+
+// UserEvent represents a change to a user pushed to stream subscribers.
+// @Name UserEvent
+// @Description Change event sent on the users stream.
+type UserEvent struct {
+	ID     string    `json:"id"`
+	Type   string    `json:"type"`
+	UserID string    `json:"user_id,omitempty"`
+	User   *User     `json:"user,omitempty"`
+	Time   time.Time `json:"time"`
+}
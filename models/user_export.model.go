@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Supported export file formats.
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+// ExportColumns lists the user fields that can be exported, in their
+// default order. The password hash is never exported.
+var ExportColumns = []string{"id", "name", "age", "email", "address"}
+
+// ExportUsersOptions selects the users and columns to export.
+type ExportUsersOptions struct {
+	Format  string
+	Columns []string
+	// Role limits the columns to the fields the role may read, like
+	// fields= on FindUsers
+	Role string
+	// Filter selects the users like the filters of FindUsers
+	Filter UserFilter
+	// Page and Limit paginate like FindUsers, a zero limit exports every user
+	Page  int
+	Limit int
+}
+
+// Export job statuses.
+const (
+	ExportJobPending = "pending"
+	ExportJobRunning = "running"
+	ExportJobDone    = "done"
+	ExportJobFailed  = "failed"
+)
+
+// ExportJob represents an export running in the background.
+// @Name ExportJob
+// @Description An export running in the background, downloadable once done.
+type ExportJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// ExportJobResponse represents the response model for the export job APIs.
+// @Name ExportJobResponse
+// @Description Response model for starting or checking an export job.
+type ExportJobResponse struct {
+	Data   ExportJob `json:"data"`
+	Status string    `json:"status"`
+}
@@ -0,0 +1,63 @@
+package models
+
+// Supported import file formats.
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// What to do with rows whose email already exists.
+const (
+	ImportOnDuplicateSkip   = "skip"
+	ImportOnDuplicateUpsert = "upsert"
+)
+
+// Import row statuses. Dry runs report the status a row would get.
+const (
+	ImportRowCreated = "created"
+	ImportRowUpdated = "updated"
+	ImportRowSkipped = "skipped"
+	ImportRowFailed  = "failed"
+)
+
+// ImportUsersOptions controls how an import file is read and committed.
+type ImportUsersOptions struct {
+	Format      string
+	OnDuplicate string
+	DryRun      bool
+	ChunkSize   int
+	// Columns maps CSV headers to CreateUserRequest JSON field names, for
+	// headers that do not already match a field name.
+	Columns map[string]string
+}
+
+// ImportRowResult represents a row of an import file that was not imported.
+// @Name ImportRowResult
+// @Description A row that failed or was skipped, numbered as in the file.
+type ImportRowResult struct {
+	Row    int             `json:"row"`
+	Status string          `json:"status"`
+	Email  string          `json:"email,omitempty"`
+	Error  *BatchItemError `json:"error,omitempty"`
+}
+
+// ImportUsersReport summarises an import and lists every row that failed or was skipped.
+// @Name ImportUsersReport
+// @Description Summary of an import with a per-row error report.
+type ImportUsersReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// ImportUsersResponse represents the response model for the ImportUsers API.
+// @Name ImportUsersResponse
+// @Description Response model for importing users.
+type ImportUsersResponse struct {
+	Data   ImportUsersReport `json:"data"`
+	Status string            `json:"status"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// ReplaceUserRequest represents the request model for replacing a user with PUT.
+// Age and address are cleared when left out, the password and custom
+// attributes are kept.
+// @Name ReplaceUserRequest
+// @Description Request model for replacing a user.
+type ReplaceUserRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Age      *int     `json:"age"`
+	Email    string   `json:"email" binding:"required,email"`
+	Password string   `json:"password,omitempty"`
+	Address  *Address `json:"address,omitempty"`
+	// Attributes replace all the custom attributes of the user when set,
+	// an empty object removes them
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// UserPatch is a partial update of a user: the fields of Set that are not
+// empty are written and the fields named in Unset are removed. Unset can
+// name a single custom attribute as "attributes.<name>".
+type UserPatch struct {
+	Set   UpdateUser
+	Unset []string
+	// ReplaceAttributes writes the attributes of Set as all the custom
+	// attributes of the user, rather than adding them to the others
+	ReplaceAttributes bool
+}
+
+// JSONPatchOperation represents a single RFC 6902 JSON Patch operation.
+// @Name JSONPatchOperation
+// @Description A single JSON Patch operation.
+type JSONPatchOperation struct {
+	Op    string      `json:"op" binding:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Statuses of a user account. Users without a status are active.
+const (
+	// UserStatusPending users have not verified the email they signed up with
+	UserStatusPending   = "pending"
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDisabled  = "disabled"
+)
+
+// SuspendUserRequest represents the request model for suspending a user.
+// Suspensions without an until date last until the user is reactivated.
+// @Name SuspendUserRequest
+// @Description Request model for suspending a user.
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"`
+}
+
+// DisableUserRequest represents the request model for disabling a user.
+// @Name DisableUserRequest
+// @Description Request model for disabling a user.
+type DisableUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UserStatus is the status of the account of a user.
+type UserStatus struct {
+	UserID         string     `json:"userId"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+}
+
+// UserStatusResponse represents the response model for the status of a user.
+// @Name UserStatusResponse
+// @Description Response model carrying the status of the account of a user.
+type UserStatusResponse struct {
+	Data   UserStatus `json:"data"`
+	Status string     `json:"status"`
+}
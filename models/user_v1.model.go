@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserV1 is a user as rendered by the v1 API. Its fields are frozen, new
+// fields of User only reach clients through v2.
+// @Name UserV1
+// @Description User rendered by the v1 API.
+type UserV1 struct {
+	ID    primitive.ObjectID `json:"id,omitempty"`
+	Name  string             `json:"name"`
+	Age   *int               `json:"age"`
+	Email string             `json:"email"`
+	// Address is the address on one line
+	Address       string     `json:"address"`
+	Role          string     `json:"role,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	VerifiedAt    *time.Time `json:"verified_at,omitempty"`
+	MFAEnabled    bool       `json:"mfa_enabled"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// UserV2 is a user as rendered by the v2 API, with camelCase fields.
+// @Name UserV2
+// @Description User rendered by the v2 API.
+type UserV2 struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Age     *int     `json:"age"`
+	Email   string   `json:"email"`
+	Address *Address `json:"address"`
+	// Attributes are the values of the custom attributes defined by admins
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// Groups are the IDs of the groups the user is a direct member of
+	Groups []string `json:"groups,omitempty"`
+	// Role is always set, users without more rights have RoleUser
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"emailVerified"`
+	VerifiedAt    *time.Time `json:"verifiedAt"`
+	MFAEnabled    bool       `json:"mfaEnabled"`
+	// Status is always set, users without one are active
+	Status         string     `json:"status"`
+	StatusReason   string     `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	// CreatedAt is read from the user ID
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PageMeta describes a page of a v2 list.
+// @Name PageMeta
+// @Description Page of a list returned by the v2 API.
+type PageMeta struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	// Count is the number of items on the page
+	Count int `json:"count"`
+}
+
+// UserV2Response represents the v2 response model carrying a user.
+// @Name UserV2Response
+// @Description Response model of the v2 API carrying a user.
+type UserV2Response struct {
+	Data UserV2 `json:"data"`
+}
+
+// UsersV2Response represents the v2 response model carrying a page of users.
+// @Name UsersV2Response
+// @Description Response model of the v2 API carrying a page of users.
+type UsersV2Response struct {
+	Data []UserV2 `json:"data"`
+	Meta PageMeta `json:"meta"`
+}
+
+// Problem represents an RFC 7807 problem, the error format of the v2 API.
+// @Name Problem
+// @Description Error response of the v2 API, sent as application/problem+json.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	// Instance is the path of the request that failed
+	Instance string `json:"instance,omitempty"`
+	// Code is the machine readable code of the error, such as not_found
+	Code string `json:"code"`
+	// Errors lists the broken rules when a password is rejected by the policy
+	Errors []PasswordViolation `json:"errors,omitempty"`
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyRouteController struct {
+	apiKeyController controllers.APIKeyController
+}
+
+func NewAPIKeyControllerRoute(apiKeyController controllers.APIKeyController) APIKeyRouteController {
+	return APIKeyRouteController{apiKeyController}
+}
+
+func (r *APIKeyRouteController) APIKeyRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/api-keys")
+
+	router.GET("/", r.apiKeyController.FindKeys)
+	router.POST("/", r.apiKeyController.CreateKey)
+	router.DELETE("/:keyId", r.apiKeyController.RevokeKey)
+}
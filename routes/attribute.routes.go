@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeRouteController struct {
+	attributeController controllers.AttributeController
+}
+
+func NewAttributeControllerRoute(attributeController controllers.AttributeController) AttributeRouteController {
+	return AttributeRouteController{attributeController}
+}
+
+func (r *AttributeRouteController) AttributeRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/attributes")
+
+	router.GET("/", r.attributeController.FindAttributes)
+	router.POST("/", r.attributeController.CreateAttribute)
+	router.GET("/:name", r.attributeController.FindAttribute)
+	router.PUT("/:name", r.attributeController.UpdateAttribute)
+	router.DELETE("/:name", r.attributeController.DeleteAttribute)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRouteController struct {
+	auditController controllers.AuditController
+}
+
+func NewAuditControllerRoute(auditController controllers.AuditController) AuditRouteController {
+	return AuditRouteController{auditController}
+}
+
+func (r *AuditRouteController) AuditRoute(rg *gin.RouterGroup) {
+	rg.GET("/audit", r.auditController.FindAuditEntries)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthRouteController struct {
+	authController controllers.AuthController
+}
+
+func NewAuthControllerRoute(authController controllers.AuthController) AuthRouteController {
+	return AuthRouteController{authController}
+}
+
+func (r *AuthRouteController) AuthRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth")
+
+	router.POST("/login", r.authController.Login)
+	router.POST("/login/mfa", r.authController.LoginMFA)
+	router.POST("/logout", r.authController.Logout)
+	router.POST("/forgot-password", r.authController.ForgotPassword)
+	router.POST("/reset-password", r.authController.ResetPassword)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerificationRouteController struct {
+	emailVerificationController controllers.EmailVerificationController
+}
+
+func NewEmailVerificationControllerRoute(emailVerificationController controllers.EmailVerificationController) EmailVerificationRouteController {
+	return EmailVerificationRouteController{emailVerificationController}
+}
+
+func (r *EmailVerificationRouteController) EmailVerificationRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth")
+
+	router.GET("/verify-email", r.emailVerificationController.VerifyEmail)
+	router.POST("/resend-verification", r.emailVerificationController.ResendVerification)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLRouteController struct {
+	graphqlController controllers.GraphQLController
+}
+
+func NewGraphQLControllerRoute(graphqlController controllers.GraphQLController) GraphQLRouteController {
+	return GraphQLRouteController{graphqlController}
+}
+
+func (r *GraphQLRouteController) GraphQLRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/graphql")
+
+	router.GET("", r.graphqlController.ExecuteQuery)
+	router.POST("", r.graphqlController.Execute)
+	router.GET("/schema", r.graphqlController.Schema)
+}
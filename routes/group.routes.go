@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupRouteController struct {
+	groupController controllers.GroupController
+}
+
+func NewGroupControllerRoute(groupController controllers.GroupController) GroupRouteController {
+	return GroupRouteController{groupController}
+}
+
+func (r *GroupRouteController) GroupRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/groups")
+
+	router.GET("/", r.groupController.FindGroups)
+	router.POST("/", r.groupController.CreateGroup)
+	router.GET("/:groupId", r.groupController.FindGroup)
+	router.PUT("/:groupId", r.groupController.UpdateGroup)
+	router.DELETE("/:groupId", r.groupController.DeleteGroup)
+	router.POST("/:groupId/members", r.groupController.AddMembers)
+	router.DELETE("/:groupId/members", r.groupController.RemoveMembers)
+	router.PUT("/:groupId/users/:userId", r.groupController.AddMember)
+	router.DELETE("/:groupId/users/:userId", r.groupController.RemoveMember)
+
+	rg.GET("/users/:userId/groups", r.groupController.FindUserGroups)
+}
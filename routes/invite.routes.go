@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteRouteController struct {
+	inviteController controllers.InviteController
+}
+
+func NewInviteControllerRoute(inviteController controllers.InviteController) InviteRouteController {
+	return InviteRouteController{inviteController}
+}
+
+func (r *InviteRouteController) InviteRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/invites")
+
+	router.GET("/", r.inviteController.FindInvites)
+	router.POST("/", r.inviteController.CreateInvite)
+	router.POST("/:inviteId/resend", r.inviteController.ResendInvite)
+	router.DELETE("/:inviteId", r.inviteController.RevokeInvite)
+}
+
+// AcceptInviteRoute serves accepting invites, which invitees do before
+// they can sign in.
+func (r *InviteRouteController) AcceptInviteRoute(rg *gin.RouterGroup) {
+	rg.POST("/invites/accept", r.inviteController.AcceptInvite)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginAttemptRouteController struct {
+	loginAttemptController controllers.LoginAttemptController
+}
+
+func NewLoginAttemptControllerRoute(loginAttemptController controllers.LoginAttemptController) LoginAttemptRouteController {
+	return LoginAttemptRouteController{loginAttemptController}
+}
+
+func (r *LoginAttemptRouteController) LoginAttemptRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.GET("/:userId/logins", r.loginAttemptController.FindLogins)
+	router.GET("/:userId/lockout", r.loginAttemptController.LockoutStatus)
+	router.DELETE("/:userId/lockout", r.loginAttemptController.Unlock)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFARouteController struct {
+	mfaController controllers.MFAController
+}
+
+func NewMFAControllerRoute(mfaController controllers.MFAController) MFARouteController {
+	return MFARouteController{mfaController}
+}
+
+// MFARoute registers the two-factor routes, rg must only let signed in callers through.
+func (r *MFARouteController) MFARoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth/mfa")
+
+	router.POST("/enroll", r.mfaController.Enroll)
+	router.POST("/confirm", r.mfaController.Confirm)
+	router.POST("/disable", r.mfaController.Disable)
+	router.POST("/recovery-codes", r.mfaController.RegenerateRecoveryCodes)
+}
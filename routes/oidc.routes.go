@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCRouteController struct {
+	oidcController controllers.OIDCController
+}
+
+func NewOIDCControllerRoute(oidcController controllers.OIDCController) OIDCRouteController {
+	return OIDCRouteController{oidcController}
+}
+
+func (r *OIDCRouteController) OIDCRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth/oidc")
+
+	router.GET("/", r.oidcController.Providers)
+	router.GET("/:provider/login", r.oidcController.Login)
+	router.GET("/:provider/callback", r.oidcController.Callback)
+}
+
+// OIDCLinkRoute registers the route linking identity providers to users, rg
+// must only let signed in callers through.
+func (r *OIDCRouteController) OIDCLinkRoute(rg *gin.RouterGroup) {
+	rg.POST("/auth/oidc/:provider/link", r.oidcController.Link)
+}
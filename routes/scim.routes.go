@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SCIMRouteController struct {
+	scimController controllers.SCIMController
+}
+
+func NewSCIMControllerRoute(scimController controllers.SCIMController) SCIMRouteController {
+	return SCIMRouteController{scimController}
+}
+
+// SCIMRoute registers the SCIM endpoints on rg, which should be served at
+// /scim/v2. Paths have no trailing slash, as SCIM clients do not follow redirects.
+func (r *SCIMRouteController) SCIMRoute(rg *gin.RouterGroup) {
+	router := rg.Group("", r.scimController.Authorize)
+
+	router.GET("/ServiceProviderConfig", r.scimController.ServiceProviderConfig)
+	router.GET("/ResourceTypes", r.scimController.ResourceTypes)
+	router.GET("/ResourceTypes/:resourceTypeId", r.scimController.ResourceType)
+	router.GET("/Schemas", r.scimController.Schemas)
+	router.GET("/Schemas/:schemaId", r.scimController.Schema)
+
+	router.GET("/Users", r.scimController.FindUsers)
+	router.POST("/Users", r.scimController.CreateUser)
+	router.GET("/Users/:userId", r.scimController.FindUser)
+	router.PUT("/Users/:userId", r.scimController.ReplaceUser)
+	router.PATCH("/Users/:userId", r.scimController.PatchUser)
+	router.DELETE("/Users/:userId", r.scimController.DeleteUser)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TenantRouteController struct {
+	tenantController controllers.TenantController
+}
+
+func NewTenantControllerRoute(tenantController controllers.TenantController) TenantRouteController {
+	return TenantRouteController{tenantController}
+}
+
+func (r *TenantRouteController) TenantRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/tenants")
+
+	router.GET("/", r.tenantController.FindTenants)
+	router.POST("/", r.tenantController.CreateTenant)
+	router.GET("/:tenantId", r.tenantController.FindTenant)
+	router.PATCH("/:tenantId", r.tenantController.UpdateTenant)
+	router.DELETE("/:tenantId", r.tenantController.DeleteTenant)
+}
@@ -20,6 +20,7 @@ func (r *UserRouteController) UserRoute(rg *gin.RouterGroup) {
 	router.GET("/", r.userController.FindUsers)
 	router.GET("/:userId", r.userController.FindUserById)
 	router.POST("/", r.userController.CreateUser)
+	router.PUT("/:userId", r.userController.ReplaceUser)
 	router.PATCH("/:userId", r.userController.UpdateUser)
 	router.DELETE("/:userId", r.userController.DeleteUser)
 }
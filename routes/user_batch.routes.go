@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"net/http"
+
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserBatchRouteController struct {
+	userBatchController controllers.UserBatchController
+}
+
+func NewUserBatchControllerRoute(userBatchController controllers.UserBatchController) UserBatchRouteController {
+	return UserBatchRouteController{userBatchController}
+}
+
+func (r *UserBatchRouteController) UserBatchRoute(rg *gin.RouterGroup) {
+	// Custom methods are addressed as /users:<method>, gin hands us the
+	// method including its leading colon
+	rg.POST("/users:method", customMethods(map[string]gin.HandlerFunc{
+		":batchCreate": r.userBatchController.BatchCreateUsers,
+		":batchDelete": r.userBatchController.BatchDeleteUsers,
+	}))
+	rg.PATCH("/users:method", customMethods(map[string]gin.HandlerFunc{
+		":batchUpdate": r.userBatchController.BatchUpdateUsers,
+	}))
+}
+
+func customMethods(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		handler, ok := handlers[ctx.Param("method")]
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "unknown method " + ctx.Param("method")})
+			return
+		}
+
+		handler(ctx)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserDataRouteController struct {
+	userDataController controllers.UserDataController
+}
+
+func NewUserDataControllerRoute(userDataController controllers.UserDataController) UserDataRouteController {
+	return UserDataRouteController{userDataController}
+}
+
+func (r *UserDataRouteController) UserDataRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.GET("/:userId/export", r.userDataController.ExportUserData)
+	router.POST("/:userId/erase", r.userDataController.EraseUser)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserExportRouteController struct {
+	userExportController controllers.UserExportController
+}
+
+func NewUserExportControllerRoute(userExportController controllers.UserExportController) UserExportRouteController {
+	return UserExportRouteController{userExportController}
+}
+
+func (r *UserExportRouteController) UserExportRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users/export")
+
+	router.GET("", r.userExportController.ExportUsers)
+	router.GET("/jobs/:jobId", r.userExportController.FindExportJob)
+	router.GET("/jobs/:jobId/download", r.userExportController.DownloadExportJob)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserImportRouteController struct {
+	userImportController controllers.UserImportController
+}
+
+func NewUserImportControllerRoute(userImportController controllers.UserImportController) UserImportRouteController {
+	return UserImportRouteController{userImportController}
+}
+
+func (r *UserImportRouteController) UserImportRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.POST("/import", r.userImportController.ImportUsers)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserStatusRouteController struct {
+	userStatusController controllers.UserStatusController
+}
+
+func NewUserStatusControllerRoute(userStatusController controllers.UserStatusController) UserStatusRouteController {
+	return UserStatusRouteController{userStatusController}
+}
+
+func (r *UserStatusRouteController) UserStatusRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.POST("/:userId/suspend", r.userStatusController.SuspendUser)
+	router.POST("/:userId/reactivate", r.userStatusController.ReactivateUser)
+	router.POST("/:userId/disable", r.userStatusController.DisableUser)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"go_crud/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserStreamRouteController struct {
+	userStreamController controllers.UserStreamController
+}
+
+func NewUserStreamControllerRoute(userStreamController controllers.UserStreamController) UserStreamRouteController {
+	return UserStreamRouteController{userStreamController}
+}
+
+func (r *UserStreamRouteController) UserStreamRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.GET("/stream", r.userStreamController.StreamUsers)
+}
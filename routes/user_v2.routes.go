@@ -0,0 +1,26 @@
+package routes
+
+import (
+	controllersv2 "go_crud/controllers/v2"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserV2RouteController struct {
+	userController controllersv2.UserController
+}
+
+func NewUserV2ControllerRoute(userController controllersv2.UserController) UserV2RouteController {
+	return UserV2RouteController{userController}
+}
+
+func (r *UserV2RouteController) UserV2Route(rg *gin.RouterGroup) {
+	router := rg.Group("/users")
+
+	router.GET("", r.userController.FindUsers)
+	router.GET("/:userId", r.userController.FindUserById)
+	router.POST("", r.userController.CreateUser)
+	router.PUT("/:userId", r.userController.ReplaceUser)
+	router.PATCH("/:userId", r.userController.PatchUser)
+	router.DELETE("/:userId", r.userController.DeleteUser)
+}
@@ -0,0 +1,188 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/pb"
+	"go_crud/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// caller is the authenticated caller of a request.
+type caller struct {
+	ID   string
+	Role string
+	// Scopes are the scopes of an API key, nil for sessions and keys without scopes
+	Scopes []string
+}
+
+type callerKey struct{}
+
+// callerRole returns the role of the caller, or "" if the request was not authenticated.
+func callerRole(ctx context.Context) string {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		return c.Role
+	}
+
+	return ""
+}
+
+// canManageUser returns a PermissionDenied error unless the caller may act
+// on the user with id, like on the REST API.
+func canManageUser(ctx context.Context, id string) error {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	if c == nil || !services.CanManageUser(c.Role, c.ID, id) {
+		return status.Error(codes.PermissionDenied, "only admins can act on other users")
+	}
+	return nil
+}
+
+// canManageUsers returns a PermissionDenied error unless the caller may act
+// on every user.
+func canManageUsers(ctx context.Context) error {
+	if !services.CanManageUsers(callerRole(ctx)) {
+		return status.Error(codes.PermissionDenied, "only admins can watch every user")
+	}
+	return nil
+}
+
+// readMethods are the user service methods that only need the users:read scope.
+var readMethods = map[string]bool{
+	pb.UserService_GetUser_FullMethodName:    true,
+	pb.UserService_ListUsers_FullMethodName:  true,
+	pb.UserService_WatchUsers_FullMethodName: true,
+}
+
+// authenticator signs in callers from the "authorization" metadata, which
+// holds "Bearer <token>" or "ApiKey <key>" like the HTTP header.
+type authenticator struct {
+	authService   services.AuthService
+	apiKeyService services.APIKeyService
+	// required rejects unauthenticated calls to the user service
+	required bool
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ss, ctx})
+}
+
+// authenticate returns ctx with the caller of method. Health checks and
+// reflection are open to anyone.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, "/"+pb.UserService_ServiceDesc.ServiceName+"/") {
+		return ctx, nil
+	}
+
+	c, err := a.caller(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if c == nil {
+		if a.required {
+			return nil, status.Error(codes.Unauthenticated, "authentication is required")
+		}
+		return ctx, nil
+	}
+
+	if c.Scopes != nil {
+		required := models.ScopeUsersWrite
+		if readMethods[method] {
+			required = models.ScopeUsersRead
+		}
+		if !containsString(c.Scopes, required) {
+			return nil, status.Errorf(codes.PermissionDenied, "the API key needs the %s scope", required)
+		}
+	}
+
+	return context.WithValue(ctx, callerKey{}, c), nil
+}
+
+// caller reads the credentials of the call, it returns nil when there are none.
+func (a *authenticator) caller(ctx context.Context) (*caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	scheme, credentials, _ := strings.Cut(values[0], " ")
+	credentials = strings.TrimSpace(credentials)
+	if strings.EqualFold(scheme, "Bearer") && services.IsAPIKey(credentials) {
+		scheme = "ApiKey"
+	}
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer") && credentials != "":
+		user, err := a.authService.Authenticate(credentials)
+		if err != nil {
+			return nil, err
+		}
+		return userCaller(user), nil
+
+	case strings.EqualFold(scheme, "ApiKey") && credentials != "":
+		apiKey, user, err := a.apiKeyService.Authenticate(credentials)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &caller{Role: models.RoleService}
+		if user != nil {
+			c = userCaller(user)
+		}
+		if len(apiKey.Scopes) > 0 {
+			c.Scopes = apiKey.Scopes
+		}
+		return c, nil
+	}
+
+	return nil, nil
+}
+
+func userCaller(user *models.User) *caller {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	return &caller{ID: user.ID.Hex(), Role: role}
+}
+
+// authenticatedStream is a server stream carrying the context of the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"errors"
+
+	"go_crud/services"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps the code of a service error to a gRPC status. Password
+// policy errors also list every broken rule as a BadRequest detail.
+func statusError(err error) error {
+	st := status.New(statusCode(err), err.Error())
+
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		details := &errdetails.BadRequest{}
+		for _, violation := range policyErr.Violations {
+			details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       "password",
+				Description: violation.Message,
+			})
+		}
+
+		if withDetails, err := st.WithDetails(details); err == nil {
+			st = withDetails
+		}
+	}
+
+	return st.Err()
+}
+
+func statusCode(err error) codes.Code {
+	switch services.ErrorCode(err) {
+	case services.ErrCodeNotFound:
+		return codes.NotFound
+	case services.ErrCodeAlreadyExists:
+		return codes.AlreadyExists
+	case services.ErrCodeFailedPrecondition:
+		return codes.FailedPrecondition
+	case services.ErrCodeInvalid:
+		return codes.InvalidArgument
+	case services.ErrCodeRateLimited:
+		return codes.ResourceExhausted
+	case services.ErrCodeUnauthenticated:
+		return codes.Unauthenticated
+	case services.ErrCodePermissionDenied:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"go_crud/pb"
+	"go_crud/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer creates the gRPC server with the user service, health checking
+// and reflection. Callers authenticate like on the REST API, and must when
+// authRequired is set.
+func NewServer(userServer *UserServer, authService services.AuthService, apiKeyService services.APIKeyService, authRequired bool) *grpc.Server {
+	auth := &authenticator{authService, apiKeyService, authRequired}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+
+	pb.RegisterUserServiceServer(server, userServer)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server
+}
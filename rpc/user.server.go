@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"go_crud/models"
+	"go_crud/pb"
+	"go_crud/services"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxPageSize is the largest page ListUsers returns
+	maxPageSize = 1000
+	// defaultPageSize is the page size when the caller asks for none
+	defaultPageSize = 10
+)
+
+// UserServer serves the gRPC user service on top of services.UserService,
+// so calls follow the same rules as the REST API.
+type UserServer struct {
+	pb.UnimplementedUserServiceServer
+	userService       services.UserService
+	userStreamService services.UserStreamService
+}
+
+func NewUserServer(userService services.UserService, userStreamService services.UserStreamService) *UserServer {
+	return &UserServer{userService: userService, userStreamService: userStreamService}
+}
+
+func (s *UserServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	age := int(req.Age)
+	user := &models.CreateUserRequest{
+		Name:     req.Name,
+		Age:      &age,
+		Email:    req.Email,
+		Password: req.Password,
+		Address:  plainAddress(req.Address),
+	}
+	if err := binding.Validator.ValidateStruct(user); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	newUser, err := s.userService.CreateUser(user)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return userMessage(newUser, nil), nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	if err := canManageUser(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	fields, err := services.UserReadFields(callerRole(ctx), "")
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	user, err := s.userService.FindUserById(req.Id, fields...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return userMessage(user, fields), nil
+}
+
+// ListUsers lists a page of users. Page tokens hold the next page number,
+// so keep the page size the same across pages. Callers can only filter on
+// the fields they read of listed users.
+func (s *UserServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	page := 1
+	if req.PageToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(req.PageToken)
+		if err == nil {
+			page, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || page < 1 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	fields, err := services.UserListFields(callerRole(ctx), "")
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	filter := models.UserFilter{Name: req.Name, Email: req.Email}
+	if req.MinAge != nil {
+		minAge := int(*req.MinAge)
+		filter.MinAge = &minAge
+	}
+	if req.MaxAge != nil {
+		maxAge := int(*req.MaxAge)
+		filter.MaxAge = &maxAge
+	}
+	if err := services.CheckUserFilter(callerRole(ctx), filter); err != nil {
+		return nil, statusError(err)
+	}
+
+	users, err := s.userService.SearchUsers(filter, page, pageSize, fields...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	res := &pb.ListUsersResponse{Users: make([]*pb.User, len(users))}
+	for i, user := range users {
+		res.Users[i] = userMessage(user, fields)
+	}
+	if len(users) == pageSize {
+		res.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(page + 1)))
+	}
+
+	return res, nil
+}
+
+// UpdateUser writes the fields named in the update mask, or the fields
+// that are set when there is no mask.
+func (s *UserServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	if req.User == nil || req.User.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "user.id is required")
+	}
+	if err := canManageUser(ctx, req.User.Id); err != nil {
+		return nil, err
+	}
+
+	patch, err := userPatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.PatchUser(req.User.Id, patch)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return userMessage(user, nil), nil
+}
+
+// plainAddress reads the one line addresses of the messages, nil when empty.
+func plainAddress(address string) *models.Address {
+	if address == "" {
+		return nil
+	}
+	return &models.Address{Formatted: address}
+}
+
+// userPatch turns an update request into a UserPatch.
+func userPatch(req *pb.UpdateUserRequest) (*models.UserPatch, error) {
+	patch := &models.UserPatch{}
+	user := req.User
+
+	paths := req.UpdateMask.GetPaths()
+	if len(paths) == 0 {
+		patch.Set = models.UpdateUser{Name: user.Name, Email: user.Email, Address: plainAddress(user.Address), Password: req.Password}
+		if user.Age != nil {
+			age := int(*user.Age)
+			patch.Set.Age = &age
+		}
+		return patch, nil
+	}
+
+	required := func(path string, value string, set *string) error {
+		if value == "" {
+			return status.Errorf(codes.InvalidArgument, "%s is required and cannot be removed", path)
+		}
+		*set = value
+		return nil
+	}
+
+	for _, path := range paths {
+		var err error
+		switch path {
+		case "name":
+			err = required(path, user.Name, &patch.Set.Name)
+		case "email":
+			err = required(path, user.Email, &patch.Set.Email)
+		case "password":
+			err = required(path, req.Password, &patch.Set.Password)
+		case "age":
+			if user.Age == nil {
+				patch.Unset = append(patch.Unset, "age")
+			} else {
+				age := int(*user.Age)
+				patch.Set.Age = &age
+			}
+		case "address":
+			if user.Address == "" {
+				patch.Unset = append(patch.Unset, "address")
+			} else {
+				patch.Set.Address = plainAddress(user.Address)
+			}
+		default:
+			err = status.Errorf(codes.InvalidArgument, "cannot update %q", path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return patch, nil
+}
+
+func (s *UserServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := canManageUser(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.userService.DeleteUser(req.Id); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// WatchUsers streams user changes until the caller goes away.
+func (s *UserServer) WatchUsers(req *pb.WatchUsersRequest, stream pb.UserService_WatchUsersServer) error {
+	if err := canManageUsers(stream.Context()); err != nil {
+		return err
+	}
+	fields, err := services.UserReadFields(callerRole(stream.Context()), "")
+	if err != nil {
+		return statusError(err)
+	}
+
+	events, err := s.userStreamService.Watch(stream.Context(), req.LastEventId)
+	if err != nil {
+		return statusError(err)
+	}
+
+	for event := range events {
+		message := &pb.UserEvent{
+			Id:     event.ID,
+			Type:   event.Type,
+			UserId: event.UserID,
+			Time:   timestamppb.New(event.Time),
+		}
+		if event.User != nil {
+			message.User = userMessage(event.User, fields)
+		}
+
+		if err := stream.Send(message); err != nil {
+			return err
+		}
+	}
+
+	return stream.Context().Err()
+}
+
+// userMessage converts user to its protobuf message, with only the given
+// fields when fields is not nil.
+func userMessage(user *models.User, fields []string) *pb.User {
+	readable := func(field string) bool {
+		return fields == nil || containsString(fields, field)
+	}
+
+	message := &pb.User{}
+	if readable("id") {
+		message.Id = user.ID.Hex()
+	}
+	if readable("name") {
+		message.Name = user.Name
+	}
+	if readable("age") && user.Age != nil {
+		age := int32(*user.Age)
+		message.Age = &age
+	}
+	if readable("email") {
+		message.Email = user.Email
+	}
+	if readable("address") {
+		message.Address = user.Address.String()
+	}
+	if fields == nil {
+		message.Role = user.Role
+		message.EmailVerified = user.EmailVerified
+		message.MfaEnabled = user.MFAEnabled
+	}
+
+	return message
+}
@@ -0,0 +1,302 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"go_crud/models"
+	"go_crud/pb"
+	"go_crud/services"
+)
+
+var testUserID = primitive.NewObjectID()
+
+// MockUserService is a mock implementation of the UserService interface
+type MockUserService struct {
+	services.UserService
+	Filter models.UserFilter
+	Page   int
+	Patch  *models.UserPatch
+}
+
+func (m *MockUserService) CreateUser(user *models.CreateUserRequest) (*models.User, error) {
+	if len(user.Password) < 8 {
+		return nil, &services.PasswordPolicyError{Violations: []models.PasswordViolation{{Rule: models.PasswordRuleMinLength, Message: "must be at least 8 characters"}}}
+	}
+
+	return &models.User{ID: testUserID, Name: user.Name, Age: user.Age, Email: user.Email, Address: user.Address}, nil
+}
+
+func (m *MockUserService) FindUserById(id string, fields ...string) (*models.User, error) {
+	if id != testUserID.Hex() {
+		return nil, services.ErrUserNotFound
+	}
+
+	age := 30
+	return &models.User{ID: testUserID, Name: "Jane Doe", Age: &age, Email: "jane@example.com", Address: &models.Address{Formatted: "1 Main St"}}, nil
+}
+
+func (m *MockUserService) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
+	m.Filter, m.Page = filter, page
+
+	users := make([]*models.User, 0, limit)
+	for i := 0; i < limit && page == 1; i++ {
+		users = append(users, &models.User{ID: primitive.NewObjectID(), Name: "Jane Doe"})
+	}
+	return users, nil
+}
+
+func (m *MockUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	m.Patch = patch
+	return m.FindUserById(id)
+}
+
+// MockUserStreamService is a mock implementation of the UserStreamService interface
+type MockUserStreamService struct{}
+
+func (m *MockUserStreamService) Watch(ctx context.Context, lastEventID string) (<-chan models.UserEvent, error) {
+	events := make(chan models.UserEvent, 2)
+	events <- models.UserEvent{ID: "1", Type: models.UserEventCreated, UserID: testUserID.Hex(), User: &models.User{ID: testUserID, Email: "jane@example.com"}, Time: time.Now()}
+	events <- models.UserEvent{ID: "2", Type: models.UserEventDeleted, UserID: testUserID.Hex(), Time: time.Now()}
+	close(events)
+	return events, nil
+}
+
+// MockAuthService is a mock implementation of the AuthService interface
+type MockAuthService struct {
+	services.AuthService
+}
+
+func (m *MockAuthService) Authenticate(token string) (*models.User, error) {
+	switch token {
+	case "admin-token":
+		return &models.User{ID: primitive.NewObjectID(), Role: models.RoleAdmin}, nil
+	case "user-token":
+		return &models.User{ID: primitive.NewObjectID()}, nil
+	case "jane-token":
+		return &models.User{ID: testUserID}, nil
+	}
+
+	return nil, services.ErrInvalidSession
+}
+
+// MockAPIKeyService is a mock implementation of the APIKeyService interface
+type MockAPIKeyService struct {
+	services.APIKeyService
+}
+
+func (m *MockAPIKeyService) Authenticate(key string) (*models.APIKey, *models.User, error) {
+	if key == "gocrud_read-key" {
+		return &models.APIKey{ID: primitive.NewObjectID(), Scopes: []string{models.ScopeUsersRead}}, nil, nil
+	}
+
+	return nil, nil, services.ErrInvalidAPIKey
+}
+
+// newTestClient serves userService over an in-memory connection.
+func newTestClient(t *testing.T, userService services.UserService, authRequired bool) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(NewUserServer(userService, &MockUserStreamService{}), &MockAuthService{}, &MockAPIKeyService{}, authRequired)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func withAuthorization(authorization string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", authorization)
+}
+
+func TestCreateUser(t *testing.T) {
+	client := pb.NewUserServiceClient(newTestClient(t, &MockUserService{}, false))
+
+	user, err := client.CreateUser(context.Background(), &pb.CreateUserRequest{Name: "Jane Doe", Age: 30, Email: "jane@example.com", Password: "password123", Address: "1 Main St"})
+	assert.NoError(t, err)
+	assert.Equal(t, testUserID.Hex(), user.Id)
+	assert.Equal(t, int32(30), user.GetAge())
+
+	_, err = client.CreateUser(context.Background(), &pb.CreateUserRequest{Name: "Jane Doe", Age: 30, Email: "not an email", Password: "password123", Address: "1 Main St"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreateUser(context.Background(), &pb.CreateUserRequest{Name: "Jane Doe", Age: 30, Email: "jane@example.com", Password: "short", Address: "1 Main St"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	details := status.Convert(err).Details()
+	if assert.Len(t, details, 1) {
+		badRequest := details[0].(*errdetails.BadRequest)
+		assert.Equal(t, "password", badRequest.FieldViolations[0].Field)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	client := pb.NewUserServiceClient(newTestClient(t, &MockUserService{}, false))
+
+	admin := withAuthorization("Bearer admin-token")
+
+	user, err := client.GetUser(admin, &pb.GetUserRequest{Id: testUserID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "1 Main St", user.Address)
+
+	// Users only read the id, name and email of themselves
+	user, err = client.GetUser(withAuthorization("Bearer jane-token"), &pb.GetUserRequest{Id: testUserID.Hex()})
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "", user.Address)
+	assert.Nil(t, user.Age)
+
+	// and cannot read others, nor can unauthenticated callers
+	_, err = client.GetUser(withAuthorization("Bearer user-token"), &pb.GetUserRequest{Id: testUserID.Hex()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetUser(context.Background(), &pb.GetUserRequest{Id: testUserID.Hex()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetUser(admin, &pb.GetUserRequest{Id: primitive.NewObjectID().Hex()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestListUsers(t *testing.T) {
+	userService := &MockUserService{}
+	client := pb.NewUserServiceClient(newTestClient(t, userService, false))
+
+	minAge := int32(18)
+	res, err := client.ListUsers(withAuthorization("Bearer admin-token"), &pb.ListUsersRequest{PageSize: 2, Name: "jane", MinAge: &minAge})
+	assert.NoError(t, err)
+	assert.Len(t, res.Users, 2)
+	assert.NotEmpty(t, res.NextPageToken)
+	assert.Equal(t, "jane", userService.Filter.Name)
+	assert.Equal(t, 18, *userService.Filter.MinAge)
+	assert.Nil(t, userService.Filter.MaxAge)
+
+	res, err = client.ListUsers(context.Background(), &pb.ListUsersRequest{PageSize: 2, PageToken: res.NextPageToken})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, userService.Page)
+	assert.Empty(t, res.Users)
+	assert.Empty(t, res.NextPageToken)
+
+	_, err = client.ListUsers(context.Background(), &pb.ListUsersRequest{PageToken: "not a token"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Users cannot filter on the fields they do not read of listed users
+	for _, req := range []*pb.ListUsersRequest{{MinAge: &minAge}, {MaxAge: &minAge}, {Email: "jane@"}} {
+		userService.Filter = models.UserFilter{}
+		_, err = client.ListUsers(withAuthorization("Bearer jane-token"), req)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, models.UserFilter{}, userService.Filter)
+	}
+
+	_, err = client.ListUsers(withAuthorization("Bearer jane-token"), &pb.ListUsersRequest{Name: "jane"})
+	assert.NoError(t, err)
+}
+
+func TestUpdateUser(t *testing.T) {
+	userService := &MockUserService{}
+	client := pb.NewUserServiceClient(newTestClient(t, userService, false))
+
+	admin := withAuthorization("Bearer admin-token")
+
+	_, err := client.UpdateUser(admin, &pb.UpdateUserRequest{
+		User:       &pb.User{Id: testUserID.Hex(), Name: "Jane Smith", Email: "ignored@example.com"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "age", "address"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserPatch{Set: models.UpdateUser{Name: "Jane Smith"}, Unset: []string{"age", "address"}}, *userService.Patch)
+
+	// Without a mask the fields that are set are written
+	_, err = client.UpdateUser(admin, &pb.UpdateUserRequest{User: &pb.User{Id: testUserID.Hex(), Email: "jane.smith@example.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserPatch{Set: models.UpdateUser{Email: "jane.smith@example.com"}}, *userService.Patch)
+
+	// Users only update themselves
+	_, err = client.UpdateUser(withAuthorization("Bearer user-token"), &pb.UpdateUserRequest{User: &pb.User{Id: testUserID.Hex(), Name: "Jane Smith"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	tests := []*pb.UpdateUserRequest{
+		{User: &pb.User{Name: "Jane"}},
+		{User: &pb.User{Id: testUserID.Hex()}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}},
+		{User: &pb.User{Id: testUserID.Hex()}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"role"}}},
+	}
+	for _, req := range tests {
+		_, err := client.UpdateUser(admin, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+	}
+}
+
+func TestWatchUsers(t *testing.T) {
+	client := pb.NewUserServiceClient(newTestClient(t, &MockUserService{}, false))
+
+	stream, err := client.WatchUsers(withAuthorization("Bearer admin-token"), &pb.WatchUsersRequest{})
+	assert.NoError(t, err)
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserEventCreated, event.Type)
+	assert.Equal(t, "jane@example.com", event.User.Email)
+
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserEventDeleted, event.Type)
+	assert.Nil(t, event.User)
+
+	// Only admins watch every user
+	stream, err = client.WatchUsers(withAuthorization("Bearer user-token"), &pb.WatchUsersRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthentication(t *testing.T) {
+	conn := newTestClient(t, &MockUserService{}, true)
+	client := pb.NewUserServiceClient(conn)
+
+	tests := []struct {
+		authorization string
+		code          codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"Bearer expired", codes.Unauthenticated},
+		{"Bearer jane-token", codes.OK},
+		{"ApiKey gocrud_read-key", codes.OK},
+		{"Bearer gocrud_read-key", codes.OK},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.authorization != "" {
+			ctx = withAuthorization(tt.authorization)
+		}
+
+		_, err := client.GetUser(ctx, &pb.GetUserRequest{Id: testUserID.Hex()})
+		assert.Equal(t, tt.code, status.Code(err), tt.authorization)
+	}
+
+	// The read scope cannot write
+	_, err := client.DeleteUser(withAuthorization("ApiKey gocrud_read-key"), &pb.DeleteUserRequest{Id: testUserID.Hex()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Health checks need no credentials
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.UserService_ServiceDesc.ServiceName})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+}
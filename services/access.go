@@ -0,0 +1,16 @@
+package services
+
+import "go_crud/models"
+
+// CanManageUsers reports whether callers with role may act on every user:
+// admins, and the service accounts admins create for machine clients.
+func CanManageUsers(role string) bool {
+	return role == models.RoleAdmin || role == models.RoleService
+}
+
+// CanManageUser reports whether the caller with callerID and role may act
+// on the user with userID: callers that can manage every user, and the
+// user themself.
+func CanManageUser(role string, callerID string, userID string) bool {
+	return CanManageUsers(role) || (callerID != "" && callerID == userID)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go_crud/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// maxAddressLines is the number of street lines an address can have
+	maxAddressLines = 4
+	// earthRadius is the mean radius of the Earth in meters, which turns
+	// distances into the radians $centerSphere takes
+	earthRadius = 6378100.0
+)
+
+// postalCodes are the formats of the postal codes of the countries that
+// have a fixed one. The codes of other countries are not checked.
+var postalCodes = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// checkAddress checks the parts of address, which may be nil, after
+// trimming them and upper casing the country and postal code in place.
+func checkAddress(address *models.Address) error {
+	if address == nil {
+		return nil
+	}
+
+	for i, line := range address.Lines {
+		address.Lines[i] = strings.TrimSpace(line)
+	}
+	address.City = strings.TrimSpace(address.City)
+	address.Region = strings.TrimSpace(address.Region)
+	address.PostalCode = strings.ToUpper(strings.TrimSpace(address.PostalCode))
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	address.Formatted = strings.TrimSpace(address.Formatted)
+
+	if len(address.Lines) > maxAddressLines {
+		return &Error{ErrCodeInvalid, fmt.Sprintf("an address has at most %d lines", maxAddressLines)}
+	}
+
+	if address.Country != "" {
+		if err := binding.Validator.Engine().(*validator.Validate).Var(address.Country, "iso3166_1_alpha2"); err != nil {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("%q is not an ISO 3166-1 alpha-2 country code", address.Country)}
+		}
+
+		if format, ok := postalCodes[address.Country]; ok && address.PostalCode != "" && !format.MatchString(address.PostalCode) {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("%q is not a postal code of %s", address.PostalCode, address.Country)}
+		}
+	}
+
+	if location := address.Location; location != nil {
+		if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+			return &Error{ErrCodeInvalid, "the latitude must be between -90 and 90, and the longitude between -180 and 180"}
+		}
+	}
+
+	return nil
+}
+
+// plainAddress reports whether address only has a formatted line, like the
+// plain string addresses stored before addresses had parts.
+func plainAddress(address *models.Address) bool {
+	return len(address.Lines) == 0 && address.City == "" && address.Region == "" &&
+		address.PostalCode == "" && address.Country == "" && address.Location == nil
+}
+
+// addressFilterQuery adds the address conditions of filter to query.
+func addressFilterQuery(query bson.M, filter models.UserFilter) {
+	if filter.City != "" {
+		query["address.city"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.City) + "$", Options: "i"}
+	}
+	if filter.Country != "" {
+		query["address.country"] = strings.ToUpper(filter.Country)
+	}
+
+	if near := filter.Near; near != nil {
+		// $geoWithin keeps the users in the order they were created, so
+		// they can be paged through, which $near would not
+		center := bson.A{near.Location.Longitude, near.Location.Latitude}
+		query["address.location"] = bson.M{"$geoWithin": bson.M{"$centerSphere": bson.A{center, near.Radius / earthRadius}}}
+	}
+}
+
+// MigrateAddresses stores the plain string addresses of the users created
+// before addresses had parts as the formatted line of an address, so they
+// can be found by the address queries. Empty ones are removed. It returns
+// the number of users it changed.
+func MigrateAddresses(ctx context.Context, userCollection Collection) (int64, error) {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"address": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$trim": bson.M{"input": "$address"}}, ""}},
+			"$$REMOVE",
+			bson.M{"formatted": bson.M{"$trim": bson.M{"input": "$address"}}},
+		}},
+	}}}}
+
+	res, err := userCollection.UpdateMany(ctx, bson.M{"address": bson.M{"$type": "string"}}, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckAddress(t *testing.T) {
+	address := &models.Address{Lines: []string{" 1 Main St "}, City: "Springfield ", PostalCode: "62701", Country: "us"}
+	assert.NoError(t, checkAddress(address))
+	assert.Equal(t, &models.Address{Lines: []string{"1 Main St"}, City: "Springfield", PostalCode: "62701", Country: "US"}, address)
+
+	assert.NoError(t, checkAddress(nil))
+	assert.NoError(t, checkAddress(&models.Address{Formatted: "1 Main St"}))
+	assert.NoError(t, checkAddress(&models.Address{PostalCode: "1012 ab", Country: "NL"}))
+	// Postal codes of countries without a known format are not checked
+	assert.NoError(t, checkAddress(&models.Address{PostalCode: "anything", Country: "AE"}))
+
+	for _, address := range []*models.Address{
+		{Lines: []string{"1", "2", "3", "4", "5"}},
+		{Country: "XX"},
+		{Country: "USA"},
+		{PostalCode: "1234", Country: "US"},
+		{PostalCode: "62701", Country: "NL"},
+		{Location: &models.GeoPoint{Latitude: 91}},
+		{Location: &models.GeoPoint{Longitude: -181}},
+	} {
+		assert.Equal(t, ErrCodeInvalid, ErrorCode(checkAddress(address)), address)
+	}
+}
+
+func TestAddressJSON(t *testing.T) {
+	var user models.CreateUserRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"address": "1 Main St"}`), &user))
+	assert.Equal(t, &models.Address{Formatted: "1 Main St"}, user.Address)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"address": {"lines": ["1 Main St"], "city": "Springfield", "location": {"latitude": 39.8, "longitude": -89.6}}}`), &user))
+	assert.Equal(t, &models.Address{Lines: []string{"1 Main St"}, City: "Springfield", Location: &models.GeoPoint{Latitude: 39.8, Longitude: -89.6}}, user.Address)
+	assert.Equal(t, "1 Main St, Springfield", user.Address.String())
+}
+
+func TestAddressBSON(t *testing.T) {
+	address := &models.Address{City: "Amsterdam", Location: &models.GeoPoint{Latitude: 52.37, Longitude: 4.89}}
+	data, err := bson.Marshal(bson.M{"address": address})
+	assert.NoError(t, err)
+
+	var stored bson.M
+	assert.NoError(t, bson.Unmarshal(data, &stored))
+	assert.Equal(t, bson.M{"type": "Point", "coordinates": bson.A{4.89, 52.37}}, stored["address"].(bson.M)["location"])
+
+	var user models.User
+	assert.NoError(t, bson.Unmarshal(data, &user))
+	assert.Equal(t, address, user.Address)
+
+	// Users created before addresses had parts have a plain string
+	data, _ = bson.Marshal(bson.M{"address": "1 Main St"})
+	assert.NoError(t, bson.Unmarshal(data, &user))
+	assert.Equal(t, &models.Address{Formatted: "1 Main St"}, user.Address)
+}
+
+func TestAddressFilterQuery(t *testing.T) {
+	query := bson.M{}
+	addressFilterQuery(query, models.UserFilter{City: "st. louis", Country: "us", Near: &models.GeoNear{
+		Location: models.GeoPoint{Latitude: 38.6, Longitude: -90.2},
+		Radius:   earthRadius / 100,
+	}})
+
+	assert.Equal(t, bson.M{
+		"address.city":     primitive.Regex{Pattern: `^st\. louis$`, Options: "i"},
+		"address.country":  "US",
+		"address.location": bson.M{"$geoWithin": bson.M{"$centerSphere": bson.A{bson.A{-90.2, 38.6}, 0.01}}},
+	}, query)
+}
@@ -0,0 +1,13 @@
+package services
+
+import "go_crud/models"
+
+type APIKeyService interface {
+	CreateKey(*models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	FindKeys(models.APIKeyFilter) ([]*models.APIKey, error)
+	FindKey(id string) (*models.APIKey, error)
+	RevokeKey(id string) error
+	// Authenticate returns the key and its user, which is nil for the keys
+	// of service accounts
+	Authenticate(key string) (*models.APIKey, *models.User, error)
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// apiKeyPrefix starts every key, so leaked keys are easy to scan for
+	apiKeyPrefix = "gocrud_"
+	// lastUsedInterval limits how often the last use of a key is written
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrAPIKeyNotFound = &Error{ErrCodeNotFound, "no API key with that Id exists"}
+	ErrInvalidAPIKey  = &Error{ErrCodeUnauthenticated, "the API key is invalid, expired or revoked"}
+)
+
+// IsAPIKey reports whether credentials look like an API key rather than a
+// session token.
+func IsAPIKey(credentials string) bool {
+	return strings.HasPrefix(credentials, apiKeyPrefix)
+}
+
+type APIKeyServiceImpl struct {
+	userCollection   Collection
+	apiKeyCollection Collection
+	ctx              context.Context
+	tokenPrefix      string
+}
+
+// NewAPIKeyService creates the API key service. New keys start with
+// tokenPrefix after the key prefix, see TenantTokenPrefix.
+func NewAPIKeyService(userCollection Collection, apiKeyCollection Collection, ctx context.Context, tokenPrefix string) APIKeyService {
+	err := createIndexes(ctx, apiKeyCollection, []mongo.IndexModel{
+		{Keys: bson.M{"keyHash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"userId": 1}},
+		{Keys: bson.M{"serviceAccount": 1}},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &APIKeyServiceImpl{userCollection, apiKeyCollection, ctx, tokenPrefix}
+}
+
+// CreateKey creates a key for a user or a service account, exactly one of
+// which must be set.
+func (p *APIKeyServiceImpl) CreateKey(req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	if (req.UserID == "") == (req.ServiceAccount == "") {
+		return nil, &Error{ErrCodeInvalid, "an API key belongs to either a user or a service account"}
+	}
+
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, &Error{ErrCodeInvalid, "expiresAt must be in the future"}
+	}
+
+	apiKey := models.APIKey{
+		Name:           req.Name,
+		ServiceAccount: req.ServiceAccount,
+		Scopes:         req.Scopes,
+		CreatedAt:      now,
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	if req.UserID != "" {
+		obId, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+
+		if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": obId}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err(); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+		apiKey.UserID = &obId
+	}
+
+	secret, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := apiKeyPrefix + p.tokenPrefix + secret
+	apiKey.Prefix = key[:len(apiKeyPrefix)+len(p.tokenPrefix)+8]
+	apiKey.KeyHash = utils.HashToken(key)
+
+	res, err := p.apiKeyCollection.InsertOne(p.ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.ID = res.InsertedID.(primitive.ObjectID)
+
+	return &models.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// FindKeys finds the keys matching filter, newest first, revoked ones included.
+func (p *APIKeyServiceImpl) FindKeys(filter models.APIKeyFilter) ([]*models.APIKey, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		obId, _ := primitive.ObjectIDFromHex(filter.UserID)
+		query["userId"] = obId
+	}
+	if filter.ServiceAccount != "" {
+		query["serviceAccount"] = filter.ServiceAccount
+	}
+
+	cursor, err := p.apiKeyCollection.Find(p.ctx, query, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*models.APIKey{}
+	if err := cursor.All(p.ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (p *APIKeyServiceImpl) FindKey(id string) (*models.APIKey, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+
+	var apiKey *models.APIKey
+	if err := p.apiKeyCollection.FindOne(p.ctx, bson.M{"_id": obId}).Decode(&apiKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAPIKeyNotFound
+		}
+
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// RevokeKey stops a key from working. It stays listed with its revocation time.
+func (p *APIKeyServiceImpl) RevokeKey(id string) error {
+	obId, _ := primitive.ObjectIDFromHex(id)
+
+	query := bson.M{"_id": obId, "revokedAt": bson.M{"$exists": false}}
+	res, err := p.apiKeyCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}})
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		// Revoking twice is fine, unknown keys are not
+		if _, err := p.FindKey(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *APIKeyServiceImpl) Authenticate(key string) (*models.APIKey, *models.User, error) {
+	now := time.Now().UTC()
+	query := bson.M{
+		"keyHash":   utils.HashToken(key),
+		"revokedAt": bson.M{"$exists": false},
+		"$or":       bson.A{bson.M{"expiresAt": bson.M{"$exists": false}}, bson.M{"expiresAt": bson.M{"$gt": now}}},
+	}
+
+	var apiKey models.APIKey
+	if err := p.apiKeyCollection.FindOne(p.ctx, query).Decode(&apiKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInvalidAPIKey
+		}
+
+		return nil, nil, err
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > lastUsedInterval {
+		if _, err := p.apiKeyCollection.UpdateOne(p.ctx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}}); err != nil {
+			log.Printf("could not record the use of API key %s: %v", apiKey.ID.Hex(), err)
+		}
+	}
+
+	if apiKey.UserID == nil {
+		return &apiKey, nil, nil
+	}
+
+	var user *models.User
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": apiKey.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInvalidAPIKey
+		}
+
+		return nil, nil, err
+	}
+
+	if err := checkUserStatus(user.Status, user.SuspendedUntil, now); err != nil {
+		return nil, nil, err
+	}
+
+	return &apiKey, user, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// Attribute names are used in query parameters and Mongo field paths
+	attributeNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+	// attributeIndexPattern reads the attribute from the duplicate key
+	// errors of the unique attribute indexes
+	attributeIndexPattern = regexp.MustCompile(`index: attributes\.(\w+)-unique`)
+)
+
+// attributeIndexName is the name of the index keeping the values of a
+// unique attribute unique.
+func attributeIndexName(name string) string {
+	return "attributes." + name + "-unique"
+}
+
+// checkAttributeDefinition checks the parts of an attribute definition.
+func checkAttributeDefinition(attribute *models.Attribute) error {
+	switch attribute.Type {
+	case models.AttributeTypeString, models.AttributeTypeNumber, models.AttributeTypeBoolean, models.AttributeTypeDate:
+	default:
+		return &Error{ErrCodeInvalid, fmt.Sprintf("unknown attribute type %q", attribute.Type)}
+	}
+
+	if attribute.Type != models.AttributeTypeString && (len(attribute.Enum) > 0 || attribute.Pattern != "") {
+		return &Error{ErrCodeInvalid, "only string attributes can have an enum or a pattern"}
+	}
+	if attribute.Type == models.AttributeTypeBoolean && attribute.Unique {
+		return &Error{ErrCodeInvalid, "boolean attributes cannot be unique"}
+	}
+	for _, value := range attribute.Enum {
+		if value == "" {
+			return &Error{ErrCodeInvalid, "the enum cannot have empty values"}
+		}
+	}
+	if attribute.Pattern != "" {
+		if _, err := regexp.Compile(attribute.Pattern); err != nil {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("invalid pattern: %s", err)}
+		}
+	}
+
+	return nil
+}
+
+// attributeDefinitions returns the attributes defined through service by
+// name. There are none when service is nil.
+func attributeDefinitions(service AttributeService) (map[string]*models.Attribute, error) {
+	definitions := map[string]*models.Attribute{}
+	if service == nil {
+		return definitions, nil
+	}
+
+	attributes, err := service.FindAttributes()
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range attributes {
+		definitions[attribute.Name] = attribute
+	}
+
+	return definitions, nil
+}
+
+// checkAttributes checks values against the definitions and returns them
+// converted to the type they are stored as. All the attributes of a user
+// are checked when whole is set, so the required ones must be present.
+func checkAttributes(definitions map[string]*models.Attribute, values map[string]interface{}, whole bool) (map[string]interface{}, error) {
+	var converted map[string]interface{}
+	if values != nil {
+		converted = make(map[string]interface{}, len(values))
+	}
+	for name, value := range values {
+		attribute, ok := definitions[name]
+		if !ok {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unknown attribute %q", name)}
+		}
+		if value == nil {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute cannot be null", name)}
+		}
+
+		v, err := attributeValue(attribute, value)
+		if err != nil {
+			return nil, err
+		}
+		converted[name] = v
+	}
+
+	if whole {
+		for name, attribute := range definitions {
+			if _, ok := values[name]; attribute.Required && !ok {
+				return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute is required", name)}
+			}
+		}
+	}
+
+	return converted, nil
+}
+
+// attributeValue checks value against attribute and returns it as stored.
+func attributeValue(attribute *models.Attribute, value interface{}) (interface{}, error) {
+	switch attribute.Type {
+	case models.AttributeTypeString:
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must be a string that is not empty", attribute.Name)}
+		}
+		if len(attribute.Enum) > 0 && !containsString(attribute.Enum, s) {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must be one of %s", attribute.Name, strings.Join(attribute.Enum, ", "))}
+		}
+		if attribute.Pattern != "" && !regexp.MustCompile(attribute.Pattern).MatchString(s) {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must match %s", attribute.Name, attribute.Pattern)}
+		}
+		return s, nil
+	case models.AttributeTypeNumber:
+		switch n := value.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case int32:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		}
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must be a number", attribute.Name)}
+	case models.AttributeTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must be true or false", attribute.Name)}
+		}
+		return b, nil
+	case models.AttributeTypeDate:
+		switch t := value.(type) {
+		case time.Time:
+			return t.UTC(), nil
+		case primitive.DateTime:
+			return t.Time().UTC(), nil
+		case string:
+			if parsed, err := time.Parse(time.RFC3339, t); err == nil {
+				return parsed.UTC(), nil
+			}
+		}
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute must be an RFC 3339 date, such as 2024-01-31T09:00:00Z", attribute.Name)}
+	default:
+		return nil, &Error{ErrCodeInternal, fmt.Sprintf("the %s attribute has the unknown type %q", attribute.Name, attribute.Type)}
+	}
+}
+
+// checkAttributeUnset checks that the custom attributes removed by unset,
+// either all of them or "attributes.<name>", are not required.
+func checkAttributeUnset(definitions map[string]*models.Attribute, unset []string) error {
+	for _, field := range unset {
+		if field == "attributes" {
+			for name, attribute := range definitions {
+				if attribute.Required {
+					return &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute is required and cannot be removed", name)}
+				}
+			}
+			continue
+		}
+
+		name, ok := strings.CutPrefix(field, "attributes.")
+		if !ok {
+			continue
+		}
+		attribute, ok := definitions[name]
+		if !ok {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("unknown attribute %q", name)}
+		}
+		if attribute.Required {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("the %s attribute is required and cannot be removed", name)}
+		}
+	}
+
+	return nil
+}
+
+// attributesDoc returns the fields of an update writing the custom
+// attributes values: each one of them, or all the attributes at once when
+// replace is set.
+func attributesDoc(values map[string]interface{}, replace bool) bson.D {
+	if len(values) == 0 {
+		return nil
+	}
+	if replace {
+		return bson.D{{Key: "attributes", Value: values}}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	doc := bson.D{}
+	for _, name := range names {
+		doc = append(doc, bson.E{Key: "attributes." + name, Value: values[name]})
+	}
+	return doc
+}
+
+// attributeFilterQuery adds the custom attribute conditions of filter to
+// query. A date without a time matches the whole day, in UTC.
+func attributeFilterQuery(definitions map[string]*models.Attribute, query bson.M, filter models.UserFilter) error {
+	for name, raw := range filter.Attributes {
+		attribute, ok := definitions[name]
+		if !ok {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("unknown attribute %q", name)}
+		}
+
+		var value interface{}
+		var err error
+		switch attribute.Type {
+		case models.AttributeTypeString:
+			value = raw
+		case models.AttributeTypeNumber:
+			value, err = strconv.ParseFloat(raw, 64)
+		case models.AttributeTypeBoolean:
+			value, err = strconv.ParseBool(raw)
+		case models.AttributeTypeDate:
+			if day, dayErr := time.Parse(time.DateOnly, raw); dayErr == nil {
+				value = bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}
+			} else {
+				value, err = attributeValue(attribute, raw)
+			}
+		}
+		if err != nil {
+			return &Error{ErrCodeInvalid, fmt.Sprintf("%q is not a %s value of the %s attribute", raw, attribute.Type, name)}
+		}
+
+		query["attributes."+name] = value
+	}
+
+	return nil
+}
+
+// userSort returns the order of the users sorted as filter asks.
+func userSort(definitions map[string]*models.Attribute, filter models.UserFilter) (bson.D, error) {
+	if filter.Sort == "" {
+		return bson.D{{Key: "_id", Value: 1}}, nil
+	}
+	if filter.AfterID != "" {
+		return nil, &Error{ErrCodeInvalid, "sorted users are paged through with pages, not a cursor"}
+	}
+
+	name, direction := filter.Sort, 1
+	if strings.HasPrefix(name, "-") {
+		name, direction = name[1:], -1
+	}
+	if _, ok := definitions[name]; !ok {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("cannot sort by %q, only by a custom attribute", filter.Sort)}
+	}
+
+	return bson.D{{Key: "attributes." + name, Value: direction}, {Key: "_id", Value: 1}}, nil
+}
+
+// duplicateUserError returns the error of a write rejected with the
+// duplicate key error message: the email, or a unique attribute, is taken.
+func duplicateUserError(message string) error {
+	if match := attributeIndexPattern.FindStringSubmatch(message); match != nil {
+		return &Error{ErrCodeAlreadyExists, fmt.Sprintf("another user has the same %s attribute", match[1])}
+	}
+
+	return ErrEmailExists
+}
@@ -0,0 +1,15 @@
+package services
+
+import "go_crud/models"
+
+// AttributeService manages the custom attributes admins define on users.
+type AttributeService interface {
+	CreateAttribute(*models.CreateAttributeRequest) (*models.Attribute, error)
+	// UpdateAttribute changes the rules of an attribute, values users
+	// already have are only checked against them when they are written
+	UpdateAttribute(name string, req *models.UpdateAttributeRequest) (*models.Attribute, error)
+	FindAttribute(name string) (*models.Attribute, error)
+	FindAttributes() ([]*models.Attribute, error)
+	// DeleteAttribute removes the attribute and its values from every user
+	DeleteAttribute(name string) error
+}
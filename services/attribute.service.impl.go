@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrAttributeNotFound = &Error{ErrCodeNotFound, "no attribute with that name exists"}
+	ErrAttributeExists   = &Error{ErrCodeAlreadyExists, "an attribute with that name already exists"}
+)
+
+type AttributeServiceImpl struct {
+	attributeCollection Collection
+	userCollection      Collection
+	ctx                 context.Context
+}
+
+// NewAttributeService creates the attribute service, which keeps the
+// indexes of unique attributes on userCollection.
+func NewAttributeService(attributeCollection Collection, userCollection Collection, ctx context.Context) AttributeService {
+	return &AttributeServiceImpl{attributeCollection, userCollection, ctx}
+}
+
+// CreateAttribute defines an attribute. Unique attributes fail to be
+// created while users share values of an attribute of the same name.
+func (p *AttributeServiceImpl) CreateAttribute(req *models.CreateAttributeRequest) (*models.Attribute, error) {
+	if !attributeNamePattern.MatchString(req.Name) {
+		return nil, &Error{ErrCodeInvalid, "the attribute name must be a letter followed by up to 63 letters, digits or underscores"}
+	}
+
+	attribute := &models.Attribute{
+		Name:      req.Name,
+		Type:      req.Type,
+		Required:  req.Required,
+		Unique:    req.Unique,
+		Enum:      req.Enum,
+		Pattern:   req.Pattern,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := checkAttributeDefinition(attribute); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.attributeCollection.InsertOne(p.ctx, attribute); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAttributeExists
+		}
+		return nil, err
+	}
+
+	if attribute.Unique {
+		if err := p.createIndex(attribute.Name); err != nil {
+			// Without its index the attribute would not be unique
+			_, _ = p.attributeCollection.DeleteOne(p.ctx, bson.M{"_id": attribute.Name})
+			return nil, err
+		}
+	}
+
+	return attribute, nil
+}
+
+func (p *AttributeServiceImpl) UpdateAttribute(name string, req *models.UpdateAttributeRequest) (*models.Attribute, error) {
+	current, err := p.FindAttribute(name)
+	if err != nil {
+		return nil, err
+	}
+
+	attribute := *current
+	attribute.Required = req.Required
+	attribute.Unique = req.Unique
+	attribute.Enum = req.Enum
+	attribute.Pattern = req.Pattern
+	if err := checkAttributeDefinition(&attribute); err != nil {
+		return nil, err
+	}
+
+	// The index is created before the attribute is unique, and dropped
+	// after it no longer is
+	if attribute.Unique && !current.Unique {
+		if err := p.createIndex(name); err != nil {
+			return nil, err
+		}
+	}
+
+	update := bson.M{"$set": bson.M{"required": attribute.Required, "unique": attribute.Unique, "enum": attribute.Enum, "pattern": attribute.Pattern}}
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated *models.Attribute
+	if err := p.attributeCollection.FindOneAndUpdate(p.ctx, bson.M{"_id": name}, update, opt).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAttributeNotFound
+		}
+		return nil, err
+	}
+
+	if current.Unique && !attribute.Unique {
+		if err := dropIndex(p.ctx, p.userCollection, attributeIndexName(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+func (p *AttributeServiceImpl) FindAttribute(name string) (*models.Attribute, error) {
+	var attribute *models.Attribute
+	if err := p.attributeCollection.FindOne(p.ctx, bson.M{"_id": name}).Decode(&attribute); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAttributeNotFound
+		}
+		return nil, err
+	}
+
+	return attribute, nil
+}
+
+func (p *AttributeServiceImpl) FindAttributes() ([]*models.Attribute, error) {
+	cursor, err := p.attributeCollection.Find(p.ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []*models.Attribute{}
+	if err := cursor.All(p.ctx, &attributes); err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+func (p *AttributeServiceImpl) DeleteAttribute(name string) error {
+	var attribute *models.Attribute
+	if err := p.attributeCollection.FindOneAndDelete(p.ctx, bson.M{"_id": name}).Decode(&attribute); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrAttributeNotFound
+		}
+		return err
+	}
+
+	field := "attributes." + name
+	if _, err := p.userCollection.UpdateMany(p.ctx, bson.M{field: bson.M{"$exists": true}}, bson.M{"$unset": bson.M{field: ""}}); err != nil {
+		return err
+	}
+
+	if attribute.Unique {
+		return dropIndex(p.ctx, p.userCollection, attributeIndexName(name))
+	}
+	return nil
+}
+
+// createIndex creates the index of a unique attribute, which fails while
+// users share values of it.
+func (p *AttributeServiceImpl) createIndex(name string) error {
+	err := createUniqueFieldIndex(p.ctx, p.userCollection, attributeIndexName(name), "attributes."+name)
+	if mongo.IsDuplicateKeyError(err) {
+		return &Error{ErrCodeFailedPrecondition, fmt.Sprintf("users share values of the %s attribute, so it cannot be unique", name)}
+	}
+
+	return err
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var testAttributes = map[string]*models.Attribute{
+	"employeeNumber": {Name: "employeeNumber", Type: models.AttributeTypeString, Required: true, Unique: true, Pattern: `^E\d+$`},
+	"department":     {Name: "department", Type: models.AttributeTypeString, Enum: []string{"Sales", "HR"}},
+	"costCenter":     {Name: "costCenter", Type: models.AttributeTypeNumber},
+	"remote":         {Name: "remote", Type: models.AttributeTypeBoolean},
+	"hired":          {Name: "hired", Type: models.AttributeTypeDate},
+}
+
+func TestCheckAttributeDefinition(t *testing.T) {
+	assert.NoError(t, checkAttributeDefinition(testAttributes["employeeNumber"]))
+	assert.NoError(t, checkAttributeDefinition(testAttributes["hired"]))
+
+	for _, attribute := range []*models.Attribute{
+		{Type: "list"},
+		{Type: models.AttributeTypeNumber, Enum: []string{"1"}},
+		{Type: models.AttributeTypeDate, Pattern: `^2024`},
+		{Type: models.AttributeTypeBoolean, Unique: true},
+		{Type: models.AttributeTypeString, Enum: []string{""}},
+		{Type: models.AttributeTypeString, Pattern: `(`},
+	} {
+		assert.Equal(t, ErrCodeInvalid, ErrorCode(checkAttributeDefinition(attribute)), attribute)
+	}
+}
+
+func TestCheckAttributes(t *testing.T) {
+	values, err := checkAttributes(testAttributes, map[string]interface{}{
+		"employeeNumber": "E42",
+		"department":     "Sales",
+		"costCenter":     float64(1200),
+		"remote":         true,
+		"hired":          "2024-01-31T10:00:00+01:00",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"employeeNumber": "E42",
+		"department":     "Sales",
+		"costCenter":     float64(1200),
+		"remote":         true,
+		"hired":          time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC),
+	}, values)
+
+	// Only the attributes given are checked in an update
+	_, err = checkAttributes(testAttributes, map[string]interface{}{"remote": false}, false)
+	assert.NoError(t, err)
+
+	for _, values := range []map[string]interface{}{
+		{"remote": false},
+		{"employeeNumber": "42"},
+		{"employeeNumber": "E42", "department": "Legal"},
+		{"employeeNumber": "E42", "costCenter": "1200"},
+		{"employeeNumber": "E42", "remote": "yes"},
+		{"employeeNumber": "E42", "hired": "31/01/2024"},
+		{"employeeNumber": "E42", "team": "Core"},
+		{"employeeNumber": nil},
+	} {
+		_, err := checkAttributes(testAttributes, values, true)
+		assert.Equal(t, ErrCodeInvalid, ErrorCode(err), values)
+	}
+}
+
+func TestCheckAttributeUnset(t *testing.T) {
+	assert.NoError(t, checkAttributeUnset(testAttributes, []string{"age", "attributes.department"}))
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(checkAttributeUnset(testAttributes, []string{"attributes.employeeNumber"})))
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(checkAttributeUnset(testAttributes, []string{"attributes.team"})))
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(checkAttributeUnset(testAttributes, []string{"attributes"})))
+}
+
+func TestAttributesDoc(t *testing.T) {
+	values := map[string]interface{}{"remote": true, "department": "HR"}
+	assert.Equal(t, bson.D{{Key: "attributes.department", Value: "HR"}, {Key: "attributes.remote", Value: true}}, attributesDoc(values, false))
+	assert.Equal(t, bson.D{{Key: "attributes", Value: values}}, attributesDoc(values, true))
+	assert.Nil(t, attributesDoc(nil, true))
+}
+
+func TestAttributeFilterQuery(t *testing.T) {
+	query := bson.M{}
+	err := attributeFilterQuery(testAttributes, query, models.UserFilter{Attributes: map[string]string{
+		"department": "Sales",
+		"costCenter": "1200",
+		"remote":     "true",
+		"hired":      "2024-01-31",
+	}})
+	assert.NoError(t, err)
+
+	day := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, bson.M{
+		"attributes.department": "Sales",
+		"attributes.costCenter": float64(1200),
+		"attributes.remote":     true,
+		"attributes.hired":      bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)},
+	}, query)
+
+	for _, attributes := range []map[string]string{{"team": "Core"}, {"costCenter": "many"}, {"hired": "yesterday"}} {
+		err := attributeFilterQuery(testAttributes, bson.M{}, models.UserFilter{Attributes: attributes})
+		assert.Equal(t, ErrCodeInvalid, ErrorCode(err), attributes)
+	}
+}
+
+func TestUserSort(t *testing.T) {
+	sort, err := userSort(testAttributes, models.UserFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "_id", Value: 1}}, sort)
+
+	sort, err = userSort(testAttributes, models.UserFilter{Sort: "-hired"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "attributes.hired", Value: -1}, {Key: "_id", Value: 1}}, sort)
+
+	_, err = userSort(testAttributes, models.UserFilter{Sort: "name"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+	_, err = userSort(testAttributes, models.UserFilter{Sort: "hired", AfterID: "6ad5bbd9d51f80a6d3792dc9"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestDuplicateUserError(t *testing.T) {
+	err := duplicateUserError(`E11000 duplicate key error collection: go_crud.users index: attributes.employeeNumber-unique-acme dup key: { attributes.employeeNumber: "E42" }`)
+	assert.Equal(t, &Error{ErrCodeAlreadyExists, "another user has the same employeeNumber attribute"}, err)
+
+	assert.Equal(t, ErrEmailExists, duplicateUserError(`E11000 duplicate key error collection: go_crud.users index: email_1 dup key: { email: "a@b.c" }`))
+}
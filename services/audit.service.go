@@ -0,0 +1,11 @@
+package services
+
+import "go_crud/models"
+
+// AuditService keeps the audit log of actions on users.
+type AuditService interface {
+	Record(action string, userID string, actor models.AuditActor, details map[string]interface{}) (*models.AuditEntry, error)
+	// FindEntries lists the entries about the user with userID, or every
+	// entry when it is empty, newest first
+	FindEntries(userID string, page int, limit int) ([]*models.AuditEntry, error)
+}
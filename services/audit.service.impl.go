@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditServiceImpl struct {
+	auditCollection Collection
+	ctx             context.Context
+}
+
+func NewAuditService(auditCollection Collection, ctx context.Context) AuditService {
+	err := createIndexes(ctx, auditCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.M{"createdAt": -1}},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &AuditServiceImpl{auditCollection, ctx}
+}
+
+func (p *AuditServiceImpl) Record(action string, userID string, actor models.AuditActor, details map[string]interface{}) (*models.AuditEntry, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+	entry := &models.AuditEntry{
+		ID:        primitive.NewObjectID(),
+		Action:    action,
+		UserID:    obId,
+		APIKeyID:  actor.APIKeyID,
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	}
+	if actorID, err := primitive.ObjectIDFromHex(actor.UserID); err == nil {
+		entry.ActorID = &actorID
+	}
+
+	if _, err := p.auditCollection.InsertOne(p.ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (p *AuditServiceImpl) FindEntries(userID string, page int, limit int) ([]*models.AuditEntry, error) {
+	if page == 0 {
+		page = 1
+	}
+
+	if limit == 0 {
+		limit = 10
+	}
+
+	query := bson.M{}
+	if userID != "" {
+		obId, _ := primitive.ObjectIDFromHex(userID)
+		query["userId"] = obId
+	}
+
+	opt := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := p.auditCollection.Find(p.ctx, query, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*models.AuditEntry{}
+	if err := cursor.All(p.ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
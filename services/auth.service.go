@@ -0,0 +1,13 @@
+package services
+
+import "go_crud/models"
+
+type AuthService interface {
+	Login(*models.LoginRequest, models.LoginClient) (*models.LoginResult, error)
+	LoginMFA(*models.MFALoginRequest, models.LoginClient) (*models.LoginResult, error)
+	LoginOIDC(provider string, code string, state string, client models.LoginClient) (*models.LoginResult, error)
+	Logout(token string) error
+	Authenticate(token string) (*models.User, error)
+	ForgotPassword(email string) error
+	ResetPassword(*models.ResetPasswordRequest) error
+}
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidCredentials = &Error{ErrCodeUnauthenticated, "invalid email or password"}
+	ErrInvalidSession     = &Error{ErrCodeUnauthenticated, "the session is invalid or has expired"}
+	ErrInvalidResetToken  = &Error{ErrCodeInvalid, "the reset token is invalid or has expired"}
+	ErrEmailNotVerified   = &Error{ErrCodePermissionDenied, "the email address has not been verified"}
+)
+
+// AuthConfig holds the settings of the sign in and password reset flows.
+type AuthConfig struct {
+	SessionTTL    time.Duration
+	ResetTokenTTL time.Duration
+	// ResetURL is the page reset emails link to, the token is added as
+	// the "token" query parameter
+	ResetURL string
+	// RequireVerifiedEmail stops users from signing in until their email is verified
+	RequireVerifiedEmail bool
+	// TokenPrefix starts the session tokens, see TenantTokenPrefix
+	TokenPrefix string
+}
+
+type AuthServiceImpl struct {
+	userCollection    Collection
+	sessionCollection Collection
+	resetCollection   Collection
+	ctx               context.Context
+	mailer            Mailer
+	mfa               MFAService
+	attempts          LoginAttemptService
+	oidc              OIDCService
+	config            AuthConfig
+}
+
+// NewAuthService creates the auth service. Users with two-factor
+// authentication finish signing in through mfa, every sign in attempt is
+// recorded and checked for lockouts through attempts, and users of
+// identity providers sign in through oidc.
+func NewAuthService(userCollection Collection, sessionCollection Collection, resetCollection Collection, ctx context.Context, mailer Mailer, mfa MFAService, attempts LoginAttemptService, oidc OIDCService, config AuthConfig) AuthService {
+	// Tokens are looked up by hash, and expired ones are removed by Mongo
+	for _, collection := range []Collection{sessionCollection, resetCollection} {
+		err := createIndexes(ctx, collection, []mongo.IndexModel{
+			{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"userId": 1}},
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		}...)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return &AuthServiceImpl{userCollection, sessionCollection, resetCollection, ctx, mailer, mfa, attempts, oidc, config}
+}
+
+// Login checks the email and password and starts a new session, or a
+// two-factor challenge if the user has it enabled.
+func (p *AuthServiceImpl) Login(req *models.LoginRequest, client models.LoginClient) (*models.LoginResult, error) {
+	attempt := &models.LoginAttempt{Email: req.Email, IP: client.IP, UserAgent: client.UserAgent}
+
+	// Locked out callers are turned away before the password is checked,
+	// so it cannot be guessed during the lockout
+	if err := p.attempts.Check(req.Email, client); err != nil {
+		if ErrorCode(err) == ErrCodeRateLimited {
+			attempt.Reason = models.LoginFailureLockedOut
+			p.recordAttempt(attempt)
+		}
+		return nil, err
+	}
+
+	var user models.DBUser
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"email": req.Email}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// Take as long as a wrong password, so emails cannot be probed
+			utils.VerifyPassword(dummyPasswordHash(), req.Password)
+			attempt.Reason = models.LoginFailureInvalidCredentials
+			p.recordAttempt(attempt)
+			return nil, ErrInvalidCredentials
+		}
+
+		return nil, err
+	}
+	attempt.UserID = &user.Id
+
+	if err := utils.VerifyPassword(user.Password, req.Password); err != nil {
+		attempt.Reason = models.LoginFailureInvalidCredentials
+		p.recordAttempt(attempt)
+		return nil, ErrInvalidCredentials
+	}
+
+	// The password is known only now, so this is when old hashes can be upgraded
+	if utils.PasswordNeedsRehash(user.Password) {
+		if err := p.rehashPassword(&user, req.Password); err != nil {
+			log.Printf("could not rehash the password of user %s: %v", user.Id.Hex(), err)
+		}
+	}
+
+	return p.finishLogin(&user, attempt)
+}
+
+// finishLogin signs in a user whose credentials are checked, unless their
+// status or unverified email keep them out. Users with two-factor
+// authentication get a challenge instead of a session.
+func (p *AuthServiceImpl) finishLogin(user *models.DBUser, attempt *models.LoginAttempt) (*models.LoginResult, error) {
+	if err := p.checkStatus(user, attempt); err != nil {
+		return nil, err
+	}
+
+	if p.config.RequireVerifiedEmail && !user.EmailVerified {
+		attempt.Reason = models.LoginFailureEmailNotVerified
+		p.recordAttempt(attempt)
+		return nil, ErrEmailNotVerified
+	}
+
+	// The attempt is recorded once the second step is done
+	if user.MFAEnabled {
+		token, expiresAt, err := p.mfa.StartChallenge(user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.LoginResult{MFARequired: true, MFAToken: token, ExpiresAt: expiresAt}, nil
+	}
+
+	attempt.Success = true
+	p.recordAttempt(attempt)
+
+	return p.startSession(user)
+}
+
+// LoginMFA finishes signing in a user with two-factor authentication.
+func (p *AuthServiceImpl) LoginMFA(req *models.MFALoginRequest, client models.LoginClient) (*models.LoginResult, error) {
+	userID, err := p.mfa.CompleteChallenge(req.MFAToken, req.Code)
+	if err != nil && err != ErrInvalidMFALoginCode {
+		return nil, err
+	}
+
+	var user models.DBUser
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	attempt := &models.LoginAttempt{UserID: &user.Id, Email: user.Email, IP: client.IP, UserAgent: client.UserAgent}
+	if err != nil {
+		attempt.Reason = models.LoginFailureInvalidMFACode
+		p.recordAttempt(attempt)
+		return nil, err
+	}
+
+	// The account may have been suspended since the first step
+	if err := p.checkStatus(&user, attempt); err != nil {
+		return nil, err
+	}
+
+	attempt.Success = true
+	p.recordAttempt(attempt)
+
+	return p.startSession(&user)
+}
+
+// LoginOIDC finishes a sign in with an identity provider. The provider
+// only stands in for the password: the status, verified email and second
+// factor of the user are checked like in Login.
+func (p *AuthServiceImpl) LoginOIDC(provider string, code string, state string, client models.LoginClient) (*models.LoginResult, error) {
+	user, err := p.oidc.Callback(provider, code, state)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &models.LoginAttempt{UserID: &user.Id, Email: user.Email, IP: client.IP, UserAgent: client.UserAgent}
+	return p.finishLogin(user, attempt)
+}
+
+// checkStatus fails the sign in attempt of a suspended or disabled user.
+func (p *AuthServiceImpl) checkStatus(user *models.DBUser, attempt *models.LoginAttempt) error {
+	err := checkUserStatus(user.Status, user.SuspendedUntil, time.Now())
+	if err == nil {
+		return nil
+	}
+
+	attempt.Reason = models.LoginFailureAccountSuspended
+	if user.Status == models.UserStatusDisabled {
+		attempt.Reason = models.LoginFailureAccountDisabled
+	}
+	p.recordAttempt(attempt)
+
+	return err
+}
+
+// recordAttempt adds attempt to the sign in history. A failure to record
+// does not fail the sign in.
+func (p *AuthServiceImpl) recordAttempt(attempt *models.LoginAttempt) {
+	if err := p.attempts.Record(attempt); err != nil {
+		log.Printf("could not record the sign in attempt of %s: %v", attempt.Email, err)
+	}
+}
+
+// startSession creates a session for user.
+func (p *AuthServiceImpl) startSession(user *models.DBUser) (*models.LoginResult, error) {
+	token, _, err := utils.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	token = p.config.TokenPrefix + token
+	tokenHash := utils.HashToken(token)
+
+	now := time.Now().UTC()
+	session := models.Session{
+		UserID:    user.Id,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(p.config.SessionTTL),
+	}
+	if _, err := p.sessionCollection.InsertOne(p.ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResult{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User: &models.User{
+			ID:      user.Id,
+			Name:    user.Name,
+			Age:     user.Age,
+			Email:   user.Email,
+			Address: user.Address,
+			Role:    user.Role,
+
+			EmailVerified: user.EmailVerified,
+			VerifiedAt:    user.VerifiedAt,
+			MFAEnabled:    user.MFAEnabled,
+			Status:        user.Status,
+		},
+	}, nil
+}
+
+// rehashPassword replaces the hash of user with one made by the current
+// hasher, unless the password was changed since the user was read.
+func (p *AuthServiceImpl) rehashPassword(user *models.DBUser, password string) error {
+	hashPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"_id": user.Id, "password": user.Password}
+	_, err = p.userCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"password": hashPassword}})
+	return err
+}
+
+// Logout ends the session of token. Unknown tokens are ignored.
+func (p *AuthServiceImpl) Logout(token string) error {
+	_, err := p.sessionCollection.DeleteOne(p.ctx, bson.M{"tokenHash": utils.HashToken(token)})
+	return err
+}
+
+// Authenticate returns the user signed in with token.
+func (p *AuthServiceImpl) Authenticate(token string) (*models.User, error) {
+	query := bson.M{"tokenHash": utils.HashToken(token), "expiresAt": bson.M{"$gt": time.Now().UTC()}}
+
+	var session models.Session
+	if err := p.sessionCollection.FindOne(p.ctx, query).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidSession
+		}
+
+		return nil, err
+	}
+
+	var user *models.User
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": session.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidSession
+		}
+
+		return nil, err
+	}
+
+	if err := checkUserStatus(user.Status, user.SuspendedUntil, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ForgotPassword emails a reset link to the user with that email, replacing
+// any link sent before. Unknown emails are ignored, so the caller cannot
+// tell whether an account exists.
+func (p *AuthServiceImpl) ForgotPassword(email string) error {
+	var user models.User
+	opt := options.FindOne().SetProjection(bson.M{"_id": 1, "email": 1})
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"email": email}, opt).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		return err
+	}
+
+	if _, err := p.resetCollection.DeleteMany(p.ctx, bson.M{"userId": user.ID}); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := utils.NewToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(p.config.ResetTokenTTL),
+	}
+	if _, err := p.resetCollection.InsertOne(p.ctx, reset); err != nil {
+		return err
+	}
+
+	link, err := tokenURL(p.config.ResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return p.mailer.Send(&MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. "+
+			"Open the link below within %s to choose a new password:\n\n%s\n\n"+
+			"If it was not you, ignore this email and your password will stay the same.",
+			p.config.ResetTokenTTL, link),
+	})
+}
+
+// ResetPassword sets a new password using a reset token, which can only be
+// used once, and signs the user out everywhere. A password rejected by the
+// policy does not use up the token.
+func (p *AuthServiceImpl) ResetPassword(req *models.ResetPasswordRequest) error {
+	now := time.Now().UTC()
+	query := bson.M{
+		"tokenHash": utils.HashToken(req.Token),
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	var reset models.PasswordReset
+	if err := p.resetCollection.FindOne(p.ctx, query).Decode(&reset); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	var user models.User
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": reset.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	if err := checkPassword(req.Password, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	hashPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	// Use up the token, unless a concurrent request already did
+	query["_id"] = reset.ID
+	if err := p.resetCollection.FindOneAndUpdate(p.ctx, query, bson.M{"$set": bson.M{"usedAt": now}}).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	if _, err := p.userCollection.UpdateOne(p.ctx, bson.M{"_id": reset.UserID}, bson.M{"$set": bson.M{"password": hashPassword}}); err != nil {
+		return err
+	}
+
+	_, err = p.sessionCollection.DeleteMany(p.ctx, bson.M{"userId": reset.UserID})
+	return err
+}
+
+// tokenURL adds token to the query of base.
+func tokenURL(base string, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is checked against when there is no user to compare with.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("dummy password")
+	})
+
+	return dummyHash
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockOIDCService signs in User with any provider
+type MockOIDCService struct {
+	OIDCService
+	User *models.DBUser
+}
+
+func (m *MockOIDCService) Callback(provider string, code string, state string) (*models.DBUser, error) {
+	return m.User, nil
+}
+
+// MockMFAService starts a challenge for every user
+type MockMFAService struct {
+	MFAService
+}
+
+func (m *MockMFAService) StartChallenge(userID primitive.ObjectID) (string, time.Time, error) {
+	return "mfa-token", time.Now().Add(5 * time.Minute), nil
+}
+
+// MockLoginAttemptService records the sign in attempts
+type MockLoginAttemptService struct {
+	LoginAttemptService
+	Attempts []*models.LoginAttempt
+}
+
+func (m *MockLoginAttemptService) Record(attempt *models.LoginAttempt) error {
+	m.Attempts = append(m.Attempts, attempt)
+	return nil
+}
+
+func TestTokenURL(t *testing.T) {
+	link, err := tokenURL("https://example.com/reset-password?lang=en", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/reset-password?lang=en&token=abc123", link)
+}
+
+func TestLoginOIDC(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   models.DBUser
+		reason string
+		code   string
+	}{
+		{"second factor", models.DBUser{EmailVerified: true, MFAEnabled: true}, "", ""},
+		{"unverified email", models.DBUser{}, models.LoginFailureEmailNotVerified, ErrCodePermissionDenied},
+		{"disabled", models.DBUser{EmailVerified: true, Status: models.UserStatusDisabled}, models.LoginFailureAccountDisabled, ErrCodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := &MockLoginAttemptService{}
+			user := tt.user
+			user.Id = primitive.NewObjectID()
+			p := &AuthServiceImpl{mfa: &MockMFAService{}, attempts: attempts, oidc: &MockOIDCService{User: &user}, config: AuthConfig{RequireVerifiedEmail: true}}
+
+			result, err := p.LoginOIDC("corp", "code", "state", models.LoginClient{IP: "192.0.2.1"})
+			if tt.code != "" {
+				assert.Equal(t, tt.code, ErrorCode(err))
+				if assert.Len(t, attempts.Attempts, 1) {
+					assert.Equal(t, tt.reason, attempts.Attempts[0].Reason)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, result.MFARequired)
+			assert.Equal(t, "mfa-token", result.MFAToken)
+			assert.Empty(t, result.Token)
+			// The attempt is recorded once the second step is done
+			assert.Empty(t, attempts.Attempts)
+		})
+	}
+}
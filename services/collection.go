@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the part of *mongo.Collection the services use. Services of
+// a tenant sharing the database get a collection from TenantCollection, so
+// they cannot read or write the documents of other tenants.
+type Collection interface {
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+// TenantField holds the tenant of the documents of a shared database.
+const TenantField = "tenant_id"
+
+// tenantCollection scopes a collection shared by several tenants to one of
+// them: filters only match its documents and new documents are tagged with
+// its ID. The default tenant, with the ID "", owns the untagged documents.
+type tenantCollection struct {
+	collection *mongo.Collection
+	tenantID   string
+}
+
+// TenantCollection scopes collection to the tenant with tenantID.
+func TenantCollection(collection *mongo.Collection, tenantID string) Collection {
+	return &tenantCollection{collection, tenantID}
+}
+
+// owner is the value of TenantField on the documents of the tenant.
+func (c *tenantCollection) owner() interface{} {
+	if c.tenantID == "" {
+		// Matches the documents without the field
+		return nil
+	}
+	return c.tenantID
+}
+
+// scope restricts filter to the documents of the tenant.
+func (c *tenantCollection) scope(filter interface{}) interface{} {
+	if filter == nil {
+		return bson.D{{Key: TenantField, Value: c.owner()}}
+	}
+	return bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: TenantField, Value: c.owner()}}}}}
+}
+
+// tag returns document as owned by the tenant.
+func (c *tenantCollection) tag(document interface{}) (interface{}, error) {
+	if c.tenantID == "" {
+		return document, nil
+	}
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	tagged := bson.D{}
+	for _, e := range doc {
+		if e.Key != TenantField {
+			tagged = append(tagged, e)
+		}
+	}
+	return append(tagged, bson.E{Key: TenantField, Value: c.tenantID}), nil
+}
+
+func (c *tenantCollection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	return c.collection.FindOne(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return c.collection.Find(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	return c.collection.CountDocuments(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := c.tag(document)
+	if err != nil {
+		return nil, err
+	}
+	return c.collection.InsertOne(ctx, doc, opts...)
+}
+
+// UpdateOne only updates a document of the tenant, upserts are tagged as
+// the tenant field is matched by equality.
+func (c *tenantCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.collection.UpdateOne(ctx, c.scope(filter), update, opts...)
+}
+
+func (c *tenantCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.collection.UpdateMany(ctx, c.scope(filter), update, opts...)
+}
+
+func (c *tenantCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return c.collection.FindOneAndUpdate(ctx, c.scope(filter), update, opts...)
+}
+
+func (c *tenantCollection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	return c.collection.FindOneAndDelete(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.collection.DeleteOne(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.collection.DeleteMany(ctx, c.scope(filter), opts...)
+}
+
+func (c *tenantCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	scoped := make([]mongo.WriteModel, len(models))
+	for i, model := range models {
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			doc, err := c.tag(m.Document)
+			if err != nil {
+				return nil, err
+			}
+			scoped[i] = mongo.NewInsertOneModel().SetDocument(doc)
+		case *mongo.UpdateOneModel:
+			copied := *m
+			copied.Filter = c.scope(m.Filter)
+			scoped[i] = &copied
+		case *mongo.DeleteOneModel:
+			copied := *m
+			copied.Filter = c.scope(m.Filter)
+			scoped[i] = &copied
+		default:
+			return nil, fmt.Errorf("%T writes are not supported on a tenant collection", model)
+		}
+	}
+
+	return c.collection.BulkWrite(ctx, scoped, opts...)
+}
+
+// Watch only streams the changes of the documents of the tenant. Deletes
+// carry no document, so they are told apart by their pre-image, which
+// Watch turns on for the collection (MongoDB 6.0 and later).
+func (c *tenantCollection) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	stages, ok := pipeline.(mongo.Pipeline)
+	if !ok {
+		return nil, fmt.Errorf("%T pipelines are not supported on a tenant collection", pipeline)
+	}
+
+	err := c.collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: c.collection.Name()},
+		{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+	}).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	opt := options.MergeChangeStreamOptions(opts...)
+	preImages := opt.FullDocumentBeforeChange != nil
+	if !preImages {
+		opt.SetFullDocumentBeforeChange(options.WhenAvailable)
+	}
+
+	return c.collection.Watch(ctx, c.watchPipeline(stages, preImages), opt)
+}
+
+// watchPipeline returns stages preceded by the match of the changes of the
+// tenant. The pre-images are dropped again unless the caller asked for them.
+func (c *tenantCollection) watchPipeline(stages mongo.Pipeline, preImages bool) mongo.Pipeline {
+	match := bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+		bson.D{
+			{Key: "fullDocument._id", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "fullDocument." + TenantField, Value: c.owner()},
+		},
+		bson.D{
+			{Key: "operationType", Value: "delete"},
+			{Key: "fullDocumentBeforeChange._id", Value: bson.D{{Key: "$exists", Value: true}}},
+			{Key: "fullDocumentBeforeChange." + TenantField, Value: c.owner()},
+		},
+	}}}}}
+
+	scoped := mongo.Pipeline{match}
+	if !preImages {
+		scoped = append(scoped, bson.D{{Key: "$unset", Value: "fullDocumentBeforeChange"}})
+	}
+	return append(scoped, stages...)
+}
+
+// createIndexes creates indexes on collection. On a tenant collection,
+// unique indexes are made unique per tenant.
+func createIndexes(ctx context.Context, collection Collection, indexes ...mongo.IndexModel) error {
+	switch c := collection.(type) {
+	case *mongo.Collection:
+		_, err := c.Indexes().CreateMany(ctx, indexes)
+		return err
+	case *tenantCollection:
+		scoped := make([]mongo.IndexModel, len(indexes))
+		for i, index := range indexes {
+			scoped[i] = index
+			if index.Options != nil && index.Options.Unique != nil && *index.Options.Unique {
+				scoped[i].Keys = append(bson.D{{Key: TenantField, Value: 1}}, indexKeys(index.Keys)...)
+			}
+		}
+		_, err := c.collection.Indexes().CreateMany(ctx, scoped)
+		return err
+	default:
+		return fmt.Errorf("cannot create indexes on a %T", collection)
+	}
+}
+
+// indexKeys returns the keys of an index as a bson.D.
+func indexKeys(keys interface{}) bson.D {
+	switch k := keys.(type) {
+	case bson.D:
+		return k
+	case bson.M:
+		names := make([]string, 0, len(k))
+		for name := range k {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		d := bson.D{}
+		for _, name := range names {
+			d = append(d, bson.E{Key: name, Value: k[name]})
+		}
+		return d
+	default:
+		panic(fmt.Sprintf("unsupported index keys %T", keys))
+	}
+}
+
+// createUniqueFieldIndex creates the index named name, which keeps the
+// values of field unique among the documents that have it. On a tenant
+// collection the index only covers the documents of the tenant, so other
+// tenants may repeat values, and its name ends with the tenant ID.
+func createUniqueFieldIndex(ctx context.Context, collection Collection, name string, field string) error {
+	filter := bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: true}}}}
+	index := options.Index().SetUnique(true)
+
+	switch c := collection.(type) {
+	case *mongo.Collection:
+		_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: index.SetName(name).SetPartialFilterExpression(filter),
+		})
+		return err
+	case *tenantCollection:
+		_, err := c.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: index.SetName(c.indexName(name)).SetPartialFilterExpression(append(filter, bson.E{Key: TenantField, Value: c.owner()})),
+		})
+		return err
+	default:
+		return fmt.Errorf("cannot create indexes on a %T", collection)
+	}
+}
+
+// dropIndex drops the index created on collection as name by
+// createUniqueFieldIndex. Missing indexes are not an error.
+func dropIndex(ctx context.Context, collection Collection, name string) error {
+	var err error
+	switch c := collection.(type) {
+	case *mongo.Collection:
+		_, err = c.Indexes().DropOne(ctx, name)
+	case *tenantCollection:
+		_, err = c.collection.Indexes().DropOne(ctx, c.indexName(name))
+	default:
+		return fmt.Errorf("cannot drop indexes on a %T", collection)
+	}
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// indexName returns name made specific to the tenant.
+func (c *tenantCollection) indexName(name string) string {
+	if c.tenantID == "" {
+		return name
+	}
+	return name + "-" + c.tenantID
+}
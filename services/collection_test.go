@@ -0,0 +1,119 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTenantCollectionScope(t *testing.T) {
+	acme := &tenantCollection{tenantID: "acme"}
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{bson.M{"email": "a@b.c"}, bson.D{{Key: TenantField, Value: "acme"}}}}}, acme.scope(bson.M{"email": "a@b.c"}))
+	assert.Equal(t, bson.D{{Key: TenantField, Value: "acme"}}, acme.scope(nil))
+
+	// The default tenant owns the documents without a tenant
+	byDefault := &tenantCollection{}
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{bson.M{}, bson.D{{Key: TenantField, Value: nil}}}}}, byDefault.scope(bson.M{}))
+}
+
+func TestTenantCollectionTag(t *testing.T) {
+	acme := &tenantCollection{tenantID: "acme"}
+	doc, err := acme.tag(struct {
+		Name   string `bson:"name"`
+		Tenant string `bson:"tenant_id"`
+	}{"Ada", "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "name", Value: "Ada"}, {Key: TenantField, Value: "acme"}}, doc)
+
+	byDefault := &tenantCollection{}
+	doc, err = byDefault.tag(bson.M{"name": "Ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": "Ada"}, doc)
+}
+
+func TestTenantCollectionWatchPipeline(t *testing.T) {
+	project := bson.D{{Key: "$project", Value: bson.M{"fullDocument.password": 0}}}
+	acme := &tenantCollection{tenantID: "acme"}
+
+	pipeline := acme.watchPipeline(mongo.Pipeline{project}, false)
+	assert.Len(t, pipeline, 3)
+	assert.Equal(t, bson.D{{Key: "$unset", Value: "fullDocumentBeforeChange"}}, pipeline[1])
+	assert.Equal(t, project, pipeline[2])
+
+	match := pipeline[0][0].Value.(bson.D)
+	tests := []struct {
+		name  string
+		event bson.M
+		match bool
+	}{
+		{"Update", bson.M{"operationType": "update", "fullDocument": bson.M{"_id": 1, TenantField: "acme"}}, true},
+		{"Update of another tenant", bson.M{"operationType": "update", "fullDocument": bson.M{"_id": 1, TenantField: "other"}}, false},
+		{"Delete", bson.M{"operationType": "delete", "documentKey": bson.M{"_id": 1}, "fullDocumentBeforeChange": bson.M{"_id": 1, TenantField: "acme"}}, true},
+		{"Delete of another tenant", bson.M{"operationType": "delete", "documentKey": bson.M{"_id": 1}, "fullDocumentBeforeChange": bson.M{"_id": 1, TenantField: "other"}}, false},
+		{"Delete without pre-image", bson.M{"operationType": "delete", "documentKey": bson.M{"_id": 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, matches(tt.event, match))
+		})
+	}
+
+	// Deletes of the default tenant are of documents without a tenant
+	byDefault := (&tenantCollection{}).watchPipeline(nil, false)[0][0].Value.(bson.D)
+	assert.True(t, matches(bson.M{"operationType": "delete", "fullDocumentBeforeChange": bson.M{"_id": 1}}, byDefault))
+	assert.False(t, matches(bson.M{"operationType": "delete", "fullDocumentBeforeChange": bson.M{"_id": 1, TenantField: "acme"}}, byDefault))
+
+	// Pre-images asked for by the caller are kept
+	assert.Equal(t, mongo.Pipeline{pipeline[0], project}, acme.watchPipeline(mongo.Pipeline{project}, true))
+}
+
+// matches evaluates the $or, $exists and equality filters of watchPipeline on doc.
+func matches(doc bson.M, filter bson.D) bool {
+	for _, e := range filter {
+		if e.Key == "$or" {
+			matched := false
+			for _, f := range e.Value.(bson.A) {
+				matched = matched || matches(doc, f.(bson.D))
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+
+		value, found := lookup(doc, e.Key)
+		if cond, ok := e.Value.(bson.D); ok && cond[0].Key == "$exists" {
+			if found != cond[0].Value.(bool) {
+				return false
+			}
+		} else if value != e.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func lookup(doc bson.M, path string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func TestIndexKeys(t *testing.T) {
+	assert.Equal(t, bson.D{{Key: "a", Value: 1}, {Key: "b", Value: -1}}, indexKeys(bson.M{"b": -1, "a": 1}))
+
+	keys := bson.D{{Key: "b", Value: 1}, {Key: "a", Value: 1}}
+	assert.Equal(t, keys, indexKeys(keys))
+}
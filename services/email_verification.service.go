@@ -0,0 +1,9 @@
+package services
+
+import "go_crud/models"
+
+type EmailVerificationService interface {
+	SendVerification(*models.User) error
+	VerifyEmail(token string) (*models.User, error)
+	ResendVerification(email string) error
+}
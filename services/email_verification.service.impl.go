@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidVerificationToken = &Error{ErrCodeInvalid, "the verification token is invalid or has expired"}
+
+// EmailVerificationConfig holds the settings of the email verification flow.
+type EmailVerificationConfig struct {
+	TokenTTL time.Duration
+	// ResendCooldown is how long users wait before asking for another email
+	ResendCooldown time.Duration
+	// VerifyURL is the link sent to users, the token is added as the
+	// "token" query parameter
+	VerifyURL string
+}
+
+type EmailVerificationServiceImpl struct {
+	userCollection         Collection
+	verificationCollection Collection
+	ctx                    context.Context
+	mailer                 Mailer
+	config                 EmailVerificationConfig
+}
+
+func NewEmailVerificationService(userCollection Collection, verificationCollection Collection, ctx context.Context, mailer Mailer, config EmailVerificationConfig) EmailVerificationService {
+	err := createIndexes(ctx, verificationCollection, []mongo.IndexModel{
+		{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"userId": 1}},
+		{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &EmailVerificationServiceImpl{userCollection, verificationCollection, ctx, mailer, config}
+}
+
+// SendVerification emails a verification link for the current email of
+// user, replacing any link sent before.
+func (p *EmailVerificationServiceImpl) SendVerification(user *models.User) error {
+	if _, err := p.verificationCollection.DeleteMany(p.ctx, bson.M{"userId": user.ID}); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := utils.NewToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(p.config.TokenTTL),
+	}
+	if _, err := p.verificationCollection.InsertOne(p.ctx, verification); err != nil {
+		return err
+	}
+
+	link, err := tokenURL(p.config.VerifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	return p.mailer.Send(&MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Open the link below within %s to verify your email address:\n\n%s\n\n"+
+			"If you did not create an account, ignore this email.",
+			p.config.TokenTTL, link),
+	})
+}
+
+// VerifyEmail marks the email a token was sent to as verified. The token
+// is used up, and fails if the user has changed their email since.
+func (p *EmailVerificationServiceImpl) VerifyEmail(token string) (*models.User, error) {
+	now := time.Now().UTC()
+	query := bson.M{"tokenHash": utils.HashToken(token), "expiresAt": bson.M{"$gt": now}}
+
+	var verification models.EmailVerification
+	if err := p.verificationCollection.FindOneAndDelete(p.ctx, query).Decode(&verification); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidVerificationToken
+		}
+
+		return nil, err
+	}
+
+	query = bson.M{"_id": verification.UserID, "email": verification.Email}
+	update := bson.M{"$set": bson.M{"email_verified": true, "verified_at": now}}
+	res := p.userCollection.FindOneAndUpdate(p.ctx, query, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	var user *models.User
+	if err := res.Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidVerificationToken
+		}
+
+		return nil, err
+	}
+
+	// Users who signed up are pending until now, not those suspended since
+	if user.Status == models.UserStatusPending {
+		query = bson.M{"_id": user.ID, "status": models.UserStatusPending}
+		if _, err := p.userCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"status": models.UserStatusActive}}); err != nil {
+			return nil, err
+		}
+		user.Status = models.UserStatusActive
+	}
+
+	return user, nil
+}
+
+// ResendVerification sends another verification link, at most once per
+// cooldown. Unknown and verified emails are ignored, so the caller cannot
+// tell whether an account exists.
+func (p *EmailVerificationServiceImpl) ResendVerification(email string) error {
+	var user models.User
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	var last models.EmailVerification
+	opt := options.FindOne().SetSort(bson.M{"createdAt": -1})
+	err := p.verificationCollection.FindOne(p.ctx, bson.M{"userId": user.ID}, opt).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if err == nil {
+		if wait := time.Until(last.CreatedAt.Add(p.config.ResendCooldown)); wait > 0 {
+			return &Error{ErrCodeRateLimited, fmt.Sprintf("wait %s before asking for another verification email", wait.Round(time.Second))}
+		}
+	}
+
+	return p.SendVerification(&user)
+}
@@ -0,0 +1,45 @@
+package services
+
+import "errors"
+
+// Error codes returned by the services, stable enough to be sent to clients.
+const (
+	ErrCodeNotFound           = "not_found"
+	ErrCodeAlreadyExists      = "already_exists"
+	ErrCodeInvalid            = "invalid_argument"
+	ErrCodeFailedPrecondition = "failed_precondition"
+	ErrCodePermissionDenied   = "permission_denied"
+	ErrCodeUnauthenticated    = "unauthenticated"
+	ErrCodeRateLimited        = "rate_limited"
+	ErrCodeInternal           = "internal"
+)
+
+// Error is a service error carrying a machine readable code.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrUserNotFound = &Error{ErrCodeNotFound, "no user with that Id exists"}
+	ErrEmailExists  = &Error{ErrCodeAlreadyExists, "user with that email already exists"}
+)
+
+// ErrorCode returns the code carried by err, or ErrCodeInternal if it has none.
+func ErrorCode(err error) string {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return ErrCodeInvalid
+	}
+
+	return ErrCodeInternal
+}
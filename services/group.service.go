@@ -0,0 +1,32 @@
+package services
+
+import (
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupService manages groups and their members. Memberships are kept on
+// the members, so they are deleted with the user and show up as user
+// changes on the users stream.
+type GroupService interface {
+	CreateGroup(*models.CreateGroupRequest) (*models.Group, error)
+	UpdateGroup(id string, req *models.UpdateGroupRequest) (*models.Group, error)
+	FindGroup(id string) (*models.Group, error)
+	// FindGroups lists the groups by name, only those nested directly in
+	// the group with parentID when it is set
+	FindGroups(parentID string) ([]*models.Group, error)
+	// DeleteGroup removes the group from its members, its nested groups
+	// are no longer nested in anything through it
+	DeleteGroup(id string) error
+	// AddMembers adds users and nested groups to a group, which fails as a
+	// whole if any of them does not exist or nesting one would make a cycle
+	AddMembers(id string, req *models.GroupMembersRequest) error
+	RemoveMembers(id string, req *models.GroupMembersRequest) error
+	// UserGroups lists the groups the user is a direct member of, and with
+	// inherited also those it is a member of through nested groups
+	UserGroups(userID string, inherited bool) ([]*models.Group, error)
+	// NestedGroups returns the ID of the group and of every group nested
+	// in it, at any depth
+	NestedGroups(id string) ([]primitive.ObjectID, error)
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrGroupNotFound = &Error{ErrCodeNotFound, "no group with that Id exists"}
+	ErrGroupExists   = &Error{ErrCodeAlreadyExists, "a group with that name already exists"}
+)
+
+type GroupServiceImpl struct {
+	groupCollection Collection
+	userCollection  Collection
+	ctx             context.Context
+}
+
+// NewGroupService creates the group service, which keeps the memberships
+// of users on userCollection.
+func NewGroupService(groupCollection Collection, userCollection Collection, ctx context.Context) GroupService {
+	err := createIndexes(ctx, groupCollection, []mongo.IndexModel{
+		{Keys: bson.M{"name": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"groups": 1}},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+	if err := createIndexes(ctx, userCollection, mongo.IndexModel{Keys: bson.M{"groups": 1}}); err != nil {
+		panic(err)
+	}
+
+	return &GroupServiceImpl{groupCollection, userCollection, ctx}
+}
+
+func (p *GroupServiceImpl) CreateGroup(req *models.CreateGroupRequest) (*models.Group, error) {
+	group := &models.Group{
+		ID:          primitive.NewObjectID(),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if group.Name == "" {
+		return nil, &Error{ErrCodeInvalid, "the group name cannot be empty"}
+	}
+
+	if _, err := p.groupCollection.InsertOne(p.ctx, group); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (p *GroupServiceImpl) UpdateGroup(id string, req *models.UpdateGroupRequest) (*models.Group, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, &Error{ErrCodeInvalid, "the group name cannot be empty"}
+	}
+
+	obId, _ := primitive.ObjectIDFromHex(id)
+	update := bson.M{"$set": bson.M{"name": name, "description": req.Description}}
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var group *models.Group
+	if err := p.groupCollection.FindOneAndUpdate(p.ctx, bson.M{"_id": obId}, update, opt).Decode(&group); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrGroupNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (p *GroupServiceImpl) FindGroup(id string) (*models.Group, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+
+	var group *models.Group
+	if err := p.groupCollection.FindOne(p.ctx, bson.M{"_id": obId}).Decode(&group); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (p *GroupServiceImpl) FindGroups(parentID string) ([]*models.Group, error) {
+	filter := bson.M{}
+	if parentID != "" {
+		parent, err := p.FindGroup(parentID)
+		if err != nil {
+			return nil, err
+		}
+		filter["groups"] = parent.ID
+	}
+
+	return p.findGroups(filter)
+}
+
+func (p *GroupServiceImpl) findGroups(filter bson.M) ([]*models.Group, error) {
+	cursor, err := p.groupCollection.Find(p.ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []*models.Group{}
+	if err := cursor.All(p.ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (p *GroupServiceImpl) DeleteGroup(id string) error {
+	obId, _ := primitive.ObjectIDFromHex(id)
+
+	res, err := p.groupCollection.DeleteOne(p.ctx, bson.M{"_id": obId})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrGroupNotFound
+	}
+
+	pull := bson.M{"$pull": bson.M{"groups": obId}}
+	if _, err := p.userCollection.UpdateMany(p.ctx, bson.M{"groups": obId}, pull); err != nil {
+		return err
+	}
+	if _, err := p.groupCollection.UpdateMany(p.ctx, bson.M{"groups": obId}, pull); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *GroupServiceImpl) AddMembers(id string, req *models.GroupMembersRequest) error {
+	group, userIDs, groupIDs, err := p.members(id, req)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkExist(p.userCollection, userIDs, "user"); err != nil {
+		return err
+	}
+	if err := p.checkExist(p.groupCollection, groupIDs, "group"); err != nil {
+		return err
+	}
+
+	if len(groupIDs) > 0 {
+		// Nesting a group in one it contains would make its members
+		// members of themselves
+		ancestors, err := p.ancestors([]primitive.ObjectID{group.ID})
+		if err != nil {
+			return err
+		}
+		for _, groupID := range groupIDs {
+			if groupID == group.ID || containsObjectID(ancestors, groupID) {
+				return &Error{ErrCodeFailedPrecondition, fmt.Sprintf("nesting group %s in group %s would make a cycle", groupID.Hex(), group.ID.Hex())}
+			}
+		}
+	}
+
+	add := bson.M{"$addToSet": bson.M{"groups": group.ID}}
+	if len(userIDs) > 0 {
+		if _, err := p.userCollection.UpdateMany(p.ctx, bson.M{"_id": bson.M{"$in": userIDs}}, add); err != nil {
+			return err
+		}
+	}
+	if len(groupIDs) > 0 {
+		if _, err := p.groupCollection.UpdateMany(p.ctx, bson.M{"_id": bson.M{"$in": groupIDs}}, add); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *GroupServiceImpl) RemoveMembers(id string, req *models.GroupMembersRequest) error {
+	group, userIDs, groupIDs, err := p.members(id, req)
+	if err != nil {
+		return err
+	}
+
+	pull := bson.M{"$pull": bson.M{"groups": group.ID}}
+	if len(userIDs) > 0 {
+		if _, err := p.userCollection.UpdateMany(p.ctx, bson.M{"_id": bson.M{"$in": userIDs}, "groups": group.ID}, pull); err != nil {
+			return err
+		}
+	}
+	if len(groupIDs) > 0 {
+		if _, err := p.groupCollection.UpdateMany(p.ctx, bson.M{"_id": bson.M{"$in": groupIDs}, "groups": group.ID}, pull); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// members finds the group with id and reads the IDs of the members of req.
+func (p *GroupServiceImpl) members(id string, req *models.GroupMembersRequest) (*models.Group, []primitive.ObjectID, []primitive.ObjectID, error) {
+	if len(req.UserIDs) == 0 && len(req.GroupIDs) == 0 {
+		return nil, nil, nil, &Error{ErrCodeInvalid, "no members given"}
+	}
+
+	userIDs, err := objectIDs(req.UserIDs, "user")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	groupIDs, err := objectIDs(req.GroupIDs, "group")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	group, err := p.FindGroup(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return group, userIDs, groupIDs, nil
+}
+
+// checkExist checks that collection has a document for each of ids.
+func (p *GroupServiceImpl) checkExist(collection Collection, ids []primitive.ObjectID, kind string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cursor, err := collection.Find(p.ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(p.ctx, &found); err != nil {
+		return err
+	}
+
+	existing := make([]primitive.ObjectID, len(found))
+	for i, doc := range found {
+		existing[i] = doc.ID
+	}
+	for _, id := range ids {
+		if !containsObjectID(existing, id) {
+			return &Error{ErrCodeNotFound, fmt.Sprintf("no %s with the Id %s exists", kind, id.Hex())}
+		}
+	}
+
+	return nil
+}
+
+func (p *GroupServiceImpl) UserGroups(userID string, inherited bool) ([]*models.Group, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+
+	var user models.User
+	opt := options.FindOne().SetProjection(bson.M{"groups": 1})
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": obId}, opt).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	ids := user.Groups
+	if inherited {
+		ancestors, err := p.ancestors(user.Groups)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ancestors...)
+	}
+	if len(ids) == 0 {
+		return []*models.Group{}, nil
+	}
+
+	return p.findGroups(bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (p *GroupServiceImpl) NestedGroups(id string) ([]primitive.ObjectID, error) {
+	group, err := p.FindGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.walk([]primitive.ObjectID{group.ID}, func(frontier []primitive.ObjectID) ([]primitive.ObjectID, error) {
+		children, err := p.findGroups(bson.M{"groups": bson.M{"$in": frontier}})
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]primitive.ObjectID, len(children))
+		for i, child := range children {
+			ids[i] = child.ID
+		}
+		return ids, nil
+	})
+}
+
+// ancestors returns the groups the groups with ids are nested in, at any
+// depth, without ids themselves unless they are nested in one another.
+func (p *GroupServiceImpl) ancestors(ids []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	found, err := p.walk(ids, func(frontier []primitive.ObjectID) ([]primitive.ObjectID, error) {
+		groups, err := p.findGroups(bson.M{"_id": bson.M{"$in": frontier}})
+		if err != nil {
+			return nil, err
+		}
+
+		var parents []primitive.ObjectID
+		for _, group := range groups {
+			parents = append(parents, group.Groups...)
+		}
+		return parents, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found[len(ids):], nil
+}
+
+// walk returns start followed by the groups reached from it through next,
+// level by level, each of them once.
+func (p *GroupServiceImpl) walk(start []primitive.ObjectID, next func(frontier []primitive.ObjectID) ([]primitive.ObjectID, error)) ([]primitive.ObjectID, error) {
+	seen := map[primitive.ObjectID]bool{}
+	var found, frontier []primitive.ObjectID
+	for _, id := range start {
+		if !seen[id] {
+			seen[id] = true
+			found = append(found, id)
+			frontier = append(frontier, id)
+		}
+	}
+
+	for len(frontier) > 0 {
+		reached, err := next(frontier)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, id := range reached {
+			if !seen[id] {
+				seen[id] = true
+				found = append(found, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// objectIDs reads the hex IDs of users or groups, without duplicates.
+func objectIDs(hexes []string, kind string) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, hex := range hexes {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("invalid %s Id %q", kind, hex)}
+		}
+		if !containsObjectID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
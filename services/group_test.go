@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGroupWalk(t *testing.T) {
+	a, b, c, d := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	// b and c are nested in a, d in both, and a in d, which walk must not loop on
+	children := map[primitive.ObjectID][]primitive.ObjectID{
+		a: {b, c},
+		b: {d},
+		c: {d},
+		d: {a},
+	}
+	next := func(frontier []primitive.ObjectID) ([]primitive.ObjectID, error) {
+		var reached []primitive.ObjectID
+		for _, id := range frontier {
+			reached = append(reached, children[id]...)
+		}
+		return reached, nil
+	}
+
+	found, err := (&GroupServiceImpl{}).walk([]primitive.ObjectID{a}, next)
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{a, b, c, d}, found)
+
+	found, err = (&GroupServiceImpl{}).walk([]primitive.ObjectID{c, c}, next)
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{c, d, a, b}, found)
+}
+
+func TestObjectIDs(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	ids, err := objectIDs([]string{id.Hex(), id.Hex()}, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{id}, ids)
+
+	_, err = objectIDs([]string{"nope"}, "group")
+	assert.Equal(t, &Error{ErrCodeInvalid, `invalid group Id "nope"`}, err)
+}
+
+func TestDiffUserSnapshots_Membership(t *testing.T) {
+	id, group := primitive.NewObjectID(), primitive.NewObjectID()
+	prev := map[primitive.ObjectID]models.User{id: {ID: id, Name: "John Doe"}}
+	next := map[primitive.ObjectID]models.User{id: {ID: id, Name: "John Doe", Groups: []primitive.ObjectID{group}}}
+
+	events := diffUserSnapshots(prev, next, time.Now())
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.UserEventUpdated, events[0].Type)
+	assert.Equal(t, []primitive.ObjectID{group}, events[0].User.Groups)
+}
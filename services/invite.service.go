@@ -0,0 +1,21 @@
+package services
+
+import "go_crud/models"
+
+// InviteService invites people to create an account with the email an
+// invite link is sent to.
+type InviteService interface {
+	// CreateInvite emails an invite link, invitedBy is the ID of the
+	// admin inviting, if any
+	CreateInvite(req *models.CreateInviteRequest, invitedBy string) (*models.Invite, error)
+	// FindInvites lists the invites, newest first, only those with status
+	// when it is set
+	FindInvites(status string) ([]*models.Invite, error)
+	// ResendInvite emails a new link for an invite that was not accepted
+	// or revoked, the link sent before stops working
+	ResendInvite(id string) (*models.Invite, error)
+	RevokeInvite(id string) error
+	// AcceptInvite creates the user the invite was for, with the password
+	// the invitee chose. Each invite can only be accepted once.
+	AcceptInvite(req *models.AcceptInviteRequest) (*models.User, error)
+}
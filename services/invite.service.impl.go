@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInviteNotFound     = &Error{ErrCodeNotFound, "no invite with that Id exists"}
+	ErrInviteExists       = &Error{ErrCodeAlreadyExists, "a pending invite was already sent to that email"}
+	ErrInvalidInviteToken = &Error{ErrCodeInvalid, "the invite token is invalid"}
+	ErrInviteAccepted     = &Error{ErrCodeFailedPrecondition, "the invite has already been accepted"}
+	ErrInviteRevoked      = &Error{ErrCodeFailedPrecondition, "the invite has been revoked"}
+	ErrInviteExpired      = &Error{ErrCodeFailedPrecondition, "the invite has expired, ask for a new one"}
+)
+
+// InviteConfig holds the settings of the invite flow.
+type InviteConfig struct {
+	// TokenTTL is how long invites are valid unless they say otherwise
+	TokenTTL time.Duration
+	// AcceptURL is the link sent to invitees, the token is added as the
+	// "token" query parameter
+	AcceptURL string
+}
+
+type InviteServiceImpl struct {
+	inviteCollection Collection
+	userService      UserService
+	ctx              context.Context
+	mailer           Mailer
+	config           InviteConfig
+}
+
+// NewInviteService creates the invite service. Accepted invites create
+// their user through userService.
+func NewInviteService(inviteCollection Collection, userService UserService, ctx context.Context, mailer Mailer, config InviteConfig) InviteService {
+	err := createIndexes(ctx, inviteCollection, []mongo.IndexModel{
+		{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"email": 1}},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &InviteServiceImpl{inviteCollection, userService, ctx, mailer, config}
+}
+
+func (p *InviteServiceImpl) CreateInvite(req *models.CreateInviteRequest, invitedBy string) (*models.Invite, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(p.config.TokenTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, &Error{ErrCodeInvalid, "expiresAt must be in the future"}
+		}
+		expiresAt = req.ExpiresAt.UTC()
+	}
+
+	users, err := p.userService.SearchUsers(models.UserFilter{Email: req.Email}, 1, 1, "id")
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		return nil, ErrEmailExists
+	}
+
+	query := inviteStatusQuery(models.InviteStatusPending, now)
+	query["email"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(req.Email) + "$", Options: "i"}
+	count, err := p.inviteCollection.CountDocuments(p.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrInviteExists
+	}
+
+	token, tokenHash, err := utils.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.Invite{
+		ID:        primitive.NewObjectID(),
+		Email:     req.Email,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		SentAt:    now,
+		ExpiresAt: expiresAt,
+	}
+	// Only roles with more rights than a user are kept
+	if req.Role != models.RoleUser {
+		invite.Role = req.Role
+	}
+	if id, err := primitive.ObjectIDFromHex(invitedBy); err == nil {
+		invite.InvitedBy = &id
+	}
+
+	if _, err := p.inviteCollection.InsertOne(p.ctx, invite); err != nil {
+		return nil, err
+	}
+
+	// An invite nobody received cannot be accepted or resent usefully
+	if err := p.send(invite, token); err != nil {
+		p.inviteCollection.DeleteOne(p.ctx, bson.M{"_id": invite.ID})
+		return nil, err
+	}
+
+	invite.Status = inviteStatus(invite, now)
+	return invite, nil
+}
+
+func (p *InviteServiceImpl) FindInvites(status string) ([]*models.Invite, error) {
+	now := time.Now().UTC()
+
+	query := bson.M{}
+	if status != "" {
+		query = inviteStatusQuery(status, now)
+		if query == nil {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unknown invite status %q", status)}
+		}
+	}
+
+	cursor, err := p.inviteCollection.Find(p.ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	invites := []*models.Invite{}
+	if err := cursor.All(p.ctx, &invites); err != nil {
+		return nil, err
+	}
+	for _, invite := range invites {
+		invite.Status = inviteStatus(invite, now)
+	}
+
+	return invites, nil
+}
+
+func (p *InviteServiceImpl) ResendInvite(id string) (*models.Invite, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+	now := time.Now().UTC()
+
+	invite, err := p.findInvite(obId)
+	if err != nil {
+		return nil, err
+	}
+	if err := inviteStatusError(inviteStatus(invite, now)); err != nil && err != ErrInviteExpired {
+		return nil, err
+	}
+
+	token, tokenHash, err := utils.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// The new link is valid as long as the one sent before was
+	query := bson.M{
+		"_id":        obId,
+		"tokenHash":  invite.TokenHash,
+		"acceptedAt": bson.M{"$exists": false},
+		"revokedAt":  bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{
+		"tokenHash": tokenHash,
+		"sentAt":    now,
+		"expiresAt": now.Add(invite.ExpiresAt.Sub(invite.SentAt)),
+	}}
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := p.inviteCollection.FindOneAndUpdate(p.ctx, query, update, opt).Decode(&invite); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &Error{ErrCodeFailedPrecondition, "the invite changed while it was resent"}
+		}
+		return nil, err
+	}
+
+	if err := p.send(invite, token); err != nil {
+		return nil, err
+	}
+
+	invite.Status = inviteStatus(invite, now)
+	return invite, nil
+}
+
+func (p *InviteServiceImpl) RevokeInvite(id string) error {
+	obId, _ := primitive.ObjectIDFromHex(id)
+
+	query := bson.M{"_id": obId, "acceptedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": false}}
+	res, err := p.inviteCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	invite, err := p.findInvite(obId)
+	if err != nil {
+		return err
+	}
+
+	return inviteStatusError(inviteStatus(invite, time.Now().UTC()))
+}
+
+func (p *InviteServiceImpl) AcceptInvite(req *models.AcceptInviteRequest) (*models.User, error) {
+	now := time.Now().UTC()
+	tokenHash := utils.HashToken(req.Token)
+
+	// Claiming the invite first keeps two requests with the same token from
+	// both creating a user
+	query := inviteStatusQuery(models.InviteStatusPending, now)
+	query["tokenHash"] = tokenHash
+	update := bson.M{"$set": bson.M{"acceptedAt": now}}
+
+	var invite *models.Invite
+	if err := p.inviteCollection.FindOneAndUpdate(p.ctx, query, update).Decode(&invite); err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		if err := p.inviteCollection.FindOne(p.ctx, bson.M{"tokenHash": tokenHash}).Decode(&invite); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrInvalidInviteToken
+			}
+			return nil, err
+		}
+		return nil, inviteStatusError(inviteStatus(invite, now))
+	}
+
+	user, err := p.userService.CreateUser(&models.CreateUserRequest{
+		Name:          req.Name,
+		Age:           req.Age,
+		Email:         invite.Email,
+		Password:      req.Password,
+		Address:       req.Address,
+		Attributes:    req.Attributes,
+		Role:          invite.Role,
+		EmailVerified: true,
+		VerifiedAt:    &now,
+	})
+	if err != nil {
+		// The invitee can try again, with a better password say
+		p.inviteCollection.UpdateOne(p.ctx, bson.M{"_id": invite.ID}, bson.M{"$unset": bson.M{"acceptedAt": ""}})
+		return nil, err
+	}
+
+	if _, err := p.inviteCollection.UpdateOne(p.ctx, bson.M{"_id": invite.ID}, bson.M{"$set": bson.M{"userId": user.ID}}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (p *InviteServiceImpl) findInvite(id primitive.ObjectID) (*models.Invite, error) {
+	var invite *models.Invite
+	if err := p.inviteCollection.FindOne(p.ctx, bson.M{"_id": id}).Decode(&invite); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (p *InviteServiceImpl) send(invite *models.Invite, token string) error {
+	link, err := tokenURL(p.config.AcceptURL, token)
+	if err != nil {
+		return err
+	}
+
+	return p.mailer.Send(&MailMessage{
+		To:      invite.Email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf("You have been invited to create an account. Open the link below before %s to choose your password:\n\n%s\n\n"+
+			"If you did not expect this invite, ignore this email.",
+			invite.ExpiresAt.Format(time.RFC1123), link),
+	})
+}
+
+// inviteStatus returns the status of invite at now.
+func inviteStatus(invite *models.Invite, now time.Time) string {
+	switch {
+	case invite.AcceptedAt != nil:
+		return models.InviteStatusAccepted
+	case invite.RevokedAt != nil:
+		return models.InviteStatusRevoked
+	case !invite.ExpiresAt.After(now):
+		return models.InviteStatusExpired
+	default:
+		return models.InviteStatusPending
+	}
+}
+
+// inviteStatusQuery returns the query selecting the invites with status at
+// now, or nil for an unknown status.
+func inviteStatusQuery(status string, now time.Time) bson.M {
+	open := bson.M{"acceptedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": false}}
+
+	switch status {
+	case models.InviteStatusPending:
+		open["expiresAt"] = bson.M{"$gt": now}
+		return open
+	case models.InviteStatusExpired:
+		open["expiresAt"] = bson.M{"$lte": now}
+		return open
+	case models.InviteStatusAccepted:
+		return bson.M{"acceptedAt": bson.M{"$exists": true}}
+	case models.InviteStatusRevoked:
+		return bson.M{"acceptedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": true}}
+	default:
+		return nil
+	}
+}
+
+// inviteStatusError returns why an invite with status cannot be used, nil
+// when it is pending.
+func inviteStatusError(status string) error {
+	switch status {
+	case models.InviteStatusAccepted:
+		return ErrInviteAccepted
+	case models.InviteStatusRevoked:
+		return ErrInviteRevoked
+	case models.InviteStatusExpired:
+		return ErrInviteExpired
+	default:
+		return nil
+	}
+}
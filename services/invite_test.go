@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestInviteStatus(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(time.Hour)
+
+	assert.Equal(t, models.InviteStatusPending, inviteStatus(&models.Invite{ExpiresAt: later}, now))
+	assert.Equal(t, models.InviteStatusExpired, inviteStatus(&models.Invite{ExpiresAt: now}, now))
+	assert.Equal(t, models.InviteStatusAccepted, inviteStatus(&models.Invite{ExpiresAt: now, AcceptedAt: &now}, now))
+	assert.Equal(t, models.InviteStatusRevoked, inviteStatus(&models.Invite{ExpiresAt: later, RevokedAt: &now}, now))
+
+	assert.Nil(t, inviteStatusError(models.InviteStatusPending))
+	assert.Equal(t, ErrInviteExpired, inviteStatusError(models.InviteStatusExpired))
+	assert.Equal(t, ErrInviteAccepted, inviteStatusError(models.InviteStatusAccepted))
+}
+
+func TestInviteStatusQuery(t *testing.T) {
+	now := time.Now().UTC()
+	open := bson.M{"acceptedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": false}}
+
+	query := inviteStatusQuery(models.InviteStatusPending, now)
+	assert.Equal(t, bson.M{"$gt": now}, query["expiresAt"])
+	delete(query, "expiresAt")
+	assert.Equal(t, open, query)
+
+	assert.Equal(t, bson.M{"$lte": now}, inviteStatusQuery(models.InviteStatusExpired, now)["expiresAt"])
+	assert.Equal(t, bson.M{"acceptedAt": bson.M{"$exists": true}}, inviteStatusQuery(models.InviteStatusAccepted, now))
+	assert.Nil(t, inviteStatusQuery("sent", now))
+}
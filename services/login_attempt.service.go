@@ -0,0 +1,14 @@
+package services
+
+import "go_crud/models"
+
+type LoginAttemptService interface {
+	// Check returns a rate limited error while the email or the IP address
+	// of client is locked out
+	Check(email string, client models.LoginClient) error
+	// Record adds an attempt to the history and counts it towards lockouts
+	Record(attempt *models.LoginAttempt) error
+	FindLogins(userID string, page int, limit int) ([]*models.LoginAttempt, error)
+	LockoutStatus(userID string) (*models.LockoutStatus, error)
+	Unlock(userID string) error
+}
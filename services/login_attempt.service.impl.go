@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockoutConfig holds the settings of sign in lockouts.
+type LockoutConfig struct {
+	// MaxFailures is how many failed sign ins lock an email out
+	MaxFailures int
+	// IPMaxFailures is how many failed sign ins, for any email, lock an IP address out
+	IPMaxFailures int
+	// BaseDuration is the length of the first lockout, each one in a row
+	// lasts twice as long as the one before, up to MaxDuration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	// ResetAfter is how long without a failure it takes for the counts to reset
+	ResetAfter time.Duration
+	// HistoryRetention is how long attempts are kept, forever when zero
+	HistoryRetention time.Duration
+}
+
+type LoginAttemptServiceImpl struct {
+	userCollection     Collection
+	historyCollection  Collection
+	throttleCollection Collection
+	ctx                context.Context
+	config             LockoutConfig
+}
+
+func NewLoginAttemptService(userCollection Collection, historyCollection Collection, throttleCollection Collection, ctx context.Context, config LockoutConfig) LoginAttemptService {
+	historyIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	}
+	if config.HistoryRetention > 0 {
+		historyIndexes = append(historyIndexes, mongo.IndexModel{
+			Keys:    bson.M{"createdAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(config.HistoryRetention.Seconds())),
+		})
+	}
+	if err := createIndexes(ctx, historyCollection, historyIndexes...); err != nil {
+		panic(err)
+	}
+
+	err := createIndexes(ctx, throttleCollection, mongo.IndexModel{
+		Keys:    bson.M{"updatedAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(config.ResetAfter.Seconds())),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &LoginAttemptServiceImpl{userCollection, historyCollection, throttleCollection, ctx, config}
+}
+
+func (p *LoginAttemptServiceImpl) Check(email string, client models.LoginClient) error {
+	now := time.Now().UTC()
+	query := bson.M{
+		"_id":         bson.M{"$in": bson.A{emailThrottleKey(email), ipThrottleKey(client.IP)}},
+		"lockedUntil": bson.M{"$gt": now},
+	}
+	opt := options.FindOne().SetSort(bson.M{"lockedUntil": -1})
+
+	var throttle models.LoginThrottle
+	if err := p.throttleCollection.FindOne(p.ctx, query, opt).Decode(&throttle); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		return err
+	}
+
+	wait := throttle.LockedUntil.Sub(now).Round(time.Second)
+	return &Error{ErrCodeRateLimited, fmt.Sprintf("too many failed sign ins, try again in %s", wait)}
+}
+
+// Record adds attempt to the history. Wrong passwords and codes count
+// towards lockouts, a successful sign in resets the count of its email but
+// not of its IP address.
+func (p *LoginAttemptServiceImpl) Record(attempt *models.LoginAttempt) error {
+	attempt.CreatedAt = time.Now().UTC()
+	if _, err := p.historyCollection.InsertOne(p.ctx, attempt); err != nil {
+		return err
+	}
+
+	if attempt.Success {
+		_, err := p.throttleCollection.DeleteOne(p.ctx, bson.M{"_id": emailThrottleKey(attempt.Email)})
+		return err
+	}
+
+	if attempt.Reason != models.LoginFailureInvalidCredentials && attempt.Reason != models.LoginFailureInvalidMFACode {
+		return nil
+	}
+
+	if err := p.addFailure(emailThrottleKey(attempt.Email), p.config.MaxFailures); err != nil {
+		return err
+	}
+
+	return p.addFailure(ipThrottleKey(attempt.IP), p.config.IPMaxFailures)
+}
+
+// addFailure counts a failure for key, locking it out once it reaches max.
+func (p *LoginAttemptServiceImpl) addFailure(key string, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	update := bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"updatedAt": now}}
+	opt := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var throttle models.LoginThrottle
+	if err := p.throttleCollection.FindOneAndUpdate(p.ctx, bson.M{"_id": key}, update, opt).Decode(&throttle); err != nil {
+		return err
+	}
+
+	if throttle.Failures < max {
+		return nil
+	}
+
+	lockedUntil := now.Add(p.lockoutDuration(throttle.Lockouts))
+	// Only the request reaching the limit starts the lockout
+	query := bson.M{"_id": key, "failures": throttle.Failures}
+	_, err := p.throttleCollection.UpdateOne(p.ctx, query, bson.M{
+		"$set": bson.M{"failures": 0, "lockedUntil": lockedUntil, "updatedAt": now},
+		"$inc": bson.M{"lockouts": 1},
+	})
+	return err
+}
+
+// lockoutDuration doubles the base duration for each earlier lockout in a row.
+func (p *LoginAttemptServiceImpl) lockoutDuration(lockouts int) time.Duration {
+	duration := p.config.BaseDuration
+	for i := 0; i < lockouts && (p.config.MaxDuration <= 0 || duration < p.config.MaxDuration); i++ {
+		duration *= 2
+	}
+
+	if p.config.MaxDuration > 0 && duration > p.config.MaxDuration {
+		return p.config.MaxDuration
+	}
+	return duration
+}
+
+// FindLogins finds a page of the sign in history of a user, newest first.
+func (p *LoginAttemptServiceImpl) FindLogins(userID string, page int, limit int) ([]*models.LoginAttempt, error) {
+	if page == 0 {
+		page = 1
+	}
+
+	if limit == 0 {
+		limit = 10
+	}
+
+	obId, _ := primitive.ObjectIDFromHex(userID)
+	opt := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := p.historyCollection.Find(p.ctx, bson.M{"userId": obId}, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := []*models.LoginAttempt{}
+	if err := cursor.All(p.ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (p *LoginAttemptServiceImpl) LockoutStatus(userID string) (*models.LockoutStatus, error) {
+	email, err := p.userEmail(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var throttle models.LoginThrottle
+	if err := p.throttleCollection.FindOne(p.ctx, bson.M{"_id": emailThrottleKey(email)}).Decode(&throttle); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &models.LockoutStatus{}, nil
+		}
+
+		return nil, err
+	}
+
+	status := &models.LockoutStatus{FailedAttempts: throttle.Failures, Lockouts: throttle.Lockouts}
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(time.Now()) {
+		status.Locked = true
+		status.LockedUntil = throttle.LockedUntil
+	}
+
+	return status, nil
+}
+
+// Unlock ends the lockout of a user and resets their failure counts. IP
+// address lockouts are left alone.
+func (p *LoginAttemptServiceImpl) Unlock(userID string) error {
+	email, err := p.userEmail(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.throttleCollection.DeleteOne(p.ctx, bson.M{"_id": emailThrottleKey(email)})
+	return err
+}
+
+func (p *LoginAttemptServiceImpl) userEmail(userID string) (string, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+
+	var user models.User
+	opt := options.FindOne().SetProjection(bson.M{"email": 1})
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": obId}, opt).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", ErrUserNotFound
+		}
+
+		return "", err
+	}
+
+	return user.Email, nil
+}
+
+// Lockouts are kept by email rather than user, so unknown emails are locked
+// out the same way and cannot be told apart. Emails are compared ignoring
+// case and surrounding spaces, so changing them does not escape a lockout.
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
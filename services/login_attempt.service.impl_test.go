@@ -0,0 +1,23 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	p := &LoginAttemptServiceImpl{config: LockoutConfig{BaseDuration: time.Minute, MaxDuration: 10 * time.Minute}}
+
+	assert.Equal(t, time.Minute, p.lockoutDuration(0))
+	assert.Equal(t, 2*time.Minute, p.lockoutDuration(1))
+	assert.Equal(t, 8*time.Minute, p.lockoutDuration(3))
+	assert.Equal(t, 10*time.Minute, p.lockoutDuration(4))
+	assert.Equal(t, 10*time.Minute, p.lockoutDuration(50))
+}
+
+func TestEmailThrottleKey(t *testing.T) {
+	assert.Equal(t, "email:jane@example.com", emailThrottleKey("jane@example.com"))
+	assert.Equal(t, "email:jane@example.com", emailThrottleKey(" Jane@Example.COM "))
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MailMessage is a plain text email.
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails sent by the services.
+type Mailer interface {
+	Send(msg *MailMessage) error
+}
+
+// LogMailer writes emails to w instead of delivering them, for local
+// development and tests.
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func (m *LogMailer) Send(msg *MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig holds the settings of the SMTP server emails are sent through.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer delivers emails through an SMTP server, using STARTTLS when
+// the server supports it.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config}
+}
+
+func (m *SMTPMailer) Send(msg *MailMessage) error {
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	if err := smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, m.message(msg)); err != nil {
+		return fmt.Errorf("could not send email to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+func (m *SMTPMailer) message(msg *MailMessage) []byte {
+	// Header values must not contain line breaks
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(m.config.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer(&buf)
+
+	err := mailer.Send(&MailMessage{To: "john.doe@example.com", Subject: "Hello", Body: "Hi John"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "To: john.doe@example.com\nSubject: Hello\n\nHi John\n")
+}
+
+func TestSMTPMailer_Message(t *testing.T) {
+	mailer := NewSMTPMailer(SMTPConfig{Host: "localhost", Port: 25, From: "no-reply@example.com"})
+
+	message := string(mailer.message(&MailMessage{
+		To:      "john.doe@example.com",
+		Subject: "Hello\r\nBcc: jane.smith@example.com",
+		Body:    "Line 1\nLine 2",
+	}))
+
+	assert.Contains(t, message, "From: no-reply@example.com\r\n")
+	assert.Contains(t, message, "Subject: HelloBcc: jane.smith@example.com\r\n")
+	assert.False(t, strings.Contains(message, "\r\nBcc:"))
+	assert.True(t, strings.HasSuffix(message, "\r\n\r\nLine 1\r\nLine 2"))
+}
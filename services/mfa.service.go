@@ -0,0 +1,22 @@
+package services
+
+import (
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MFAService interface {
+	Enroll(userID string) (*models.MFAEnrollment, error)
+	ConfirmEnrollment(userID string, code string) (*models.RecoveryCodes, error)
+	Disable(userID string, code string) error
+	RegenerateRecoveryCodes(userID string, code string) (*models.RecoveryCodes, error)
+	// StartChallenge returns the token a user with two-factor
+	// authentication finishes signing in with, and when it expires
+	StartChallenge(userID primitive.ObjectID) (string, time.Time, error)
+	// CompleteChallenge checks the code for a challenge token and returns
+	// the user it was for, also on a wrong code
+	CompleteChallenge(token string, code string) (primitive.ObjectID, error)
+}
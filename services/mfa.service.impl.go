@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxChallengeAttempts is how many wrong codes end a sign in
+	maxChallengeAttempts = 5
+)
+
+var (
+	ErrMFANotConfigured    = &Error{ErrCodeFailedPrecondition, "two-factor authentication is not configured on this server"}
+	ErrMFAAlreadyEnabled   = &Error{ErrCodeFailedPrecondition, "two-factor authentication is already enabled, disable it first"}
+	ErrMFANotEnabled       = &Error{ErrCodeFailedPrecondition, "two-factor authentication is not enabled"}
+	ErrMFANotEnrolled      = &Error{ErrCodeFailedPrecondition, "start the two-factor enrollment first"}
+	ErrInvalidMFACode      = &Error{ErrCodeInvalid, "the code is invalid or has already been used"}
+	ErrInvalidMFAToken     = &Error{ErrCodeUnauthenticated, "the sign in has expired, sign in again"}
+	ErrInvalidMFALoginCode = &Error{ErrCodeUnauthenticated, "the code is invalid or has already been used"}
+)
+
+var totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// MFAConfig holds the settings of two-factor authentication.
+type MFAConfig struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// Skew is how many time steps either side of now codes are accepted for
+	Skew         int
+	ChallengeTTL time.Duration
+}
+
+type MFAServiceImpl struct {
+	userCollection      Collection
+	challengeCollection Collection
+	ctx                 context.Context
+	// secrets encrypts TOTP secrets, two-factor authentication is
+	// unavailable when it is nil
+	secrets *utils.SecretBox
+	config  MFAConfig
+}
+
+func NewMFAService(userCollection Collection, challengeCollection Collection, ctx context.Context, secrets *utils.SecretBox, config MFAConfig) MFAService {
+	err := createIndexes(ctx, challengeCollection, []mongo.IndexModel{
+		{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &MFAServiceImpl{userCollection, challengeCollection, ctx, secrets, config}
+}
+
+// Enroll creates a TOTP secret for the user, which is only used once
+// confirmed with a code. Enrolling again replaces an unconfirmed secret.
+func (p *MFAServiceImpl) Enroll(userID string) (*models.MFAEnrollment, error) {
+	if p.secrets == nil {
+		return nil, ErrMFANotConfigured
+	}
+
+	user, err := p.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := p.secrets.Seal(secret, user.Id[:])
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"_id": user.Id, "mfa_enabled": bson.M{"$ne": true}}
+	res, err := p.userCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"mfa": models.UserMFA{PendingSecret: sealed}}})
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &models.MFAEnrollment{Secret: secret, URI: utils.TOTPURI(p.config.Issuer, user.Email, secret)}, nil
+}
+
+// ConfirmEnrollment turns two-factor authentication on once code shows the
+// authenticator has the pending secret, and returns the first recovery codes.
+func (p *MFAServiceImpl) ConfirmEnrollment(userID string, code string) (*models.RecoveryCodes, error) {
+	if p.secrets == nil {
+		return nil, ErrMFANotConfigured
+	}
+
+	user, err := p.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFA == nil || user.MFA.PendingSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	secret, err := p.secrets.Open(user.MFA.PendingSecret, user.Id[:])
+	if err != nil {
+		return nil, err
+	}
+
+	counter, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now(), p.config.Skew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only confirm the secret that was checked, in case of a concurrent enrollment
+	query := bson.M{"_id": user.Id, "mfa_enabled": bson.M{"$ne": true}, "mfa.pending_secret": user.MFA.PendingSecret}
+	update := bson.M{"$set": bson.M{
+		"mfa_enabled": true,
+		"mfa": models.UserMFA{
+			Secret:        user.MFA.PendingSecret,
+			LastCounter:   counter,
+			RecoveryCodes: hashes,
+		},
+	}}
+	res, err := p.userCollection.UpdateOne(p.ctx, query, update)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+// Disable turns two-factor authentication off, with a TOTP or recovery code.
+func (p *MFAServiceImpl) Disable(userID string, code string) error {
+	user, err := p.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := p.verifyCode(user, code); err != nil {
+		return err
+	}
+
+	_, err = p.userCollection.UpdateOne(p.ctx, bson.M{"_id": user.Id}, bson.M{
+		"$set":   bson.M{"mfa_enabled": false},
+		"$unset": bson.M{"mfa": ""},
+	})
+	return err
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user.
+func (p *MFAServiceImpl) RegenerateRecoveryCodes(userID string, code string) (*models.RecoveryCodes, error) {
+	user, err := p.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.verifyCode(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"_id": user.Id, "mfa_enabled": true}
+	res, err := p.userCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"mfa.recovery_codes": hashes}})
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, ErrMFANotEnabled
+	}
+
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+func (p *MFAServiceImpl) StartChallenge(userID primitive.ObjectID) (string, time.Time, error) {
+	token, tokenHash, err := utils.NewToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now().UTC()
+	challenge := models.MFAChallenge{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(p.config.ChallengeTTL),
+	}
+	if _, err := p.challengeCollection.InsertOne(p.ctx, challenge); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, challenge.ExpiresAt, nil
+}
+
+// CompleteChallenge uses up the challenge when the code is right. Too many
+// wrong codes end it, and the user has to sign in with their password again.
+// The user is also returned with ErrInvalidMFALoginCode.
+func (p *MFAServiceImpl) CompleteChallenge(token string, code string) (primitive.ObjectID, error) {
+	query := bson.M{
+		"tokenHash": utils.HashToken(token),
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+		"attempts":  bson.M{"$lt": maxChallengeAttempts},
+	}
+
+	var challenge models.MFAChallenge
+	if err := p.challengeCollection.FindOne(p.ctx, query).Decode(&challenge); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, ErrInvalidMFAToken
+		}
+
+		return primitive.NilObjectID, err
+	}
+
+	var user models.DBUser
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": challenge.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, ErrInvalidMFAToken
+		}
+
+		return primitive.NilObjectID, err
+	}
+
+	if err := p.verifyCode(&user, code); err != nil {
+		if err != ErrInvalidMFACode {
+			return primitive.NilObjectID, err
+		}
+
+		if _, err := p.challengeCollection.UpdateOne(p.ctx, bson.M{"_id": challenge.ID}, bson.M{"$inc": bson.M{"attempts": 1}}); err != nil {
+			return primitive.NilObjectID, err
+		}
+		return user.Id, ErrInvalidMFALoginCode
+	}
+
+	query["_id"] = challenge.ID
+	if err := p.challengeCollection.FindOneAndDelete(p.ctx, query).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, ErrInvalidMFAToken
+		}
+
+		return primitive.NilObjectID, err
+	}
+
+	return user.Id, nil
+}
+
+// verifyCode checks a TOTP code, or else a recovery code, and uses it up so
+// it cannot be replayed.
+func (p *MFAServiceImpl) verifyCode(user *models.DBUser, code string) error {
+	if !user.MFAEnabled || user.MFA == nil {
+		return ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if !totpCodePattern.MatchString(code) {
+		return p.useRecoveryCode(user, code)
+	}
+
+	if p.secrets == nil {
+		return ErrMFANotConfigured
+	}
+
+	secret, err := p.secrets.Open(user.MFA.Secret, user.Id[:])
+	if err != nil {
+		return err
+	}
+
+	counter, ok := utils.ValidateTOTP(secret, code, time.Now(), p.config.Skew)
+	if !ok || counter <= user.MFA.LastCounter {
+		return ErrInvalidMFACode
+	}
+
+	// Moving the counter forward atomically stops a concurrent replay
+	query := bson.M{"_id": user.Id, "mfa.last_counter": bson.M{"$lt": counter}}
+	res, err := p.userCollection.UpdateOne(p.ctx, query, bson.M{"$set": bson.M{"mfa.last_counter": counter}})
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (p *MFAServiceImpl) useRecoveryCode(user *models.DBUser, code string) error {
+	hash := utils.HashToken(normalizeRecoveryCode(code))
+
+	query := bson.M{"_id": user.Id, "mfa.recovery_codes": hash}
+	res, err := p.userCollection.UpdateOne(p.ctx, query, bson.M{"$pull": bson.M{"mfa.recovery_codes": hash}})
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (p *MFAServiceImpl) findUser(userID string) (*models.DBUser, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+
+	var user *models.DBUser
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": obId}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// newRecoveryCodes returns recovery codes formatted like "a1b2c-3d4e5", and
+// the hashes to store in their place.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.RandomHex(5)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = utils.HashToken(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes, which are easy to
+// get wrong when typing a code.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
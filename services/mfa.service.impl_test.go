@@ -0,0 +1,24 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"go_crud/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	for i, code := range codes {
+		assert.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, code)
+		assert.False(t, totpCodePattern.MatchString(code))
+		assert.Equal(t, hashes[i], utils.HashToken(normalizeRecoveryCode(code)))
+		// Typed differently, the code still matches
+		assert.Equal(t, hashes[i], utils.HashToken(normalizeRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", " ")))))
+	}
+}
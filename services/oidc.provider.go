@@ -0,0 +1,322 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// jwksRefreshInterval limits how often keys are fetched again for an
+// unknown key id, as providers rotate keys
+const jwksRefreshInterval = time.Minute
+
+// idTokenAlgorithms are the signature algorithms ID tokens may be signed with.
+var idTokenAlgorithms = map[string]bool{string(jose.RS256): true, string(jose.ES256): true}
+
+// OIDCProviderConfig holds the client registration with an OpenID Connect provider.
+type OIDCProviderConfig struct {
+	Name string
+	// IssuerURL is where /.well-known/openid-configuration is served from
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are asked for besides openid
+	Scopes []string
+}
+
+// OIDCClaims are the claims of a verified ID token.
+type OIDCClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	AuthorizedBy  string       `json:"azp"`
+	ExpiresAt     int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// oidcAudience is the aud claim, a string or a list of strings.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider is the relying party side of the authorization code flow
+// with PKCE. The provider metadata is discovered on first use.
+type OIDCProvider struct {
+	config OIDCProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        jose.JSONWebKeySet
+	keysFetched time.Time
+}
+
+func NewOIDCProvider(config OIDCProviderConfig, client *http.Client) *OIDCProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OIDCProvider{config: config, client: client}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the page of the provider to send the user to.
+func (p *OIDCProvider) AuthCodeURL(state string, nonce string, codeVerifier string) (string, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(append([]string{"openid"}, p.config.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", pkceChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange trades an authorization code for tokens, and returns the claims
+// of the ID token once verified against nonce.
+func (p *OIDCProvider) Exchange(code string, codeVerifier string, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	// Public clients identify themselves in the form, others with basic auth
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%s token endpoint: %w", p.config.Name, err)
+	}
+	if res.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, &Error{ErrCodeUnauthenticated, fmt.Sprintf("%s rejected the sign in: %s %s", p.config.Name, tokens.Error, tokens.ErrorDescription)}
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%s token endpoint returned no ID token", p.config.Name)
+	}
+
+	return p.verifyIDToken(tokens.IDToken, nonce)
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token, OpenID Connect Core section 3.1.3.7.
+func (p *OIDCProvider) verifyIDToken(idToken string, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	var claims OIDCClaims
+	if err := p.parseIDToken(idToken, &claims); err != nil {
+		return nil, &Error{ErrCodeUnauthenticated, fmt.Sprintf("the ID token from %s is invalid: %v", p.config.Name, err)}
+	}
+
+	invalid := func(reason string) error {
+		return &Error{ErrCodeUnauthenticated, fmt.Sprintf("the ID token from %s %s", p.config.Name, reason)}
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != discovery.Issuer:
+		return nil, invalid("has the wrong issuer")
+	case !containsString(claims.Audience, p.config.ClientID):
+		return nil, invalid("is for another client")
+	case len(claims.Audience) > 1 && claims.AuthorizedBy != p.config.ClientID:
+		return nil, invalid("is for another client")
+	case now.After(time.Unix(claims.ExpiresAt, 0).Add(time.Minute)):
+		return nil, invalid("has expired")
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, invalid("does not match the sign in")
+	case claims.Subject == "":
+		return nil, invalid("has no subject")
+	}
+
+	return &claims, nil
+}
+
+// parseIDToken checks the signature of an ID token with the keys of the
+// provider, and decodes its claims into v.
+func (p *OIDCProvider) parseIDToken(idToken string, v interface{}) error {
+	token, err := jwt.ParseSigned(idToken)
+	if err != nil {
+		return err
+	}
+	if len(token.Headers) != 1 {
+		return errors.New("the token must have one signature")
+	}
+
+	header := token.Headers[0]
+	if !idTokenAlgorithms[header.Algorithm] {
+		return fmt.Errorf("unsupported algorithm %q", header.Algorithm)
+	}
+
+	key, err := p.signingKey(header.KeyID)
+	if err != nil {
+		return err
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return fmt.Errorf("the key %q is not for %s", header.KeyID, header.Algorithm)
+	}
+
+	return token.Claims(key.Key, v)
+}
+
+// signingKey finds the public key with keyID, fetching the keys again when
+// the provider has rotated them. Keys for encryption are left out.
+func (p *OIDCProvider) signingKey(keyID string) (*jose.JSONWebKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := findSigningKey(p.keys, keyID); key != nil {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := p.getJSON(p.discovery.JWKSURI, &keys); err != nil {
+		return nil, err
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+
+	if key := findSigningKey(keys, keyID); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+func findSigningKey(keys jose.JSONWebKeySet, keyID string) *jose.JSONWebKey {
+	for _, key := range keys.Key(keyID) {
+		if (key.Use == "" || key.Use == "sig") && key.IsPublic() && key.Valid() {
+			return &key
+		}
+	}
+
+	return nil
+}
+
+func (p *OIDCProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(strings.TrimSuffix(p.config.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	if discovery.Issuer != strings.TrimSuffix(p.config.IssuerURL, "/") && discovery.Issuer != p.config.IssuerURL {
+		return nil, fmt.Errorf("%s: the discovered issuer %q does not match %q", p.config.Name, discovery.Issuer, p.config.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New(p.config.Name + ": the provider metadata is incomplete")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *OIDCProvider) getJSON(url string, v interface{}) error {
+	res, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
+
+// pkceChallenge derives the S256 code challenge of a verifier, RFC 7636.
+func pkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
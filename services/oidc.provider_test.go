@@ -0,0 +1,260 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider for tests. It signs
+// in whoever is in subject, and issues codes bound to the PKCE challenge.
+type mockOIDCServer struct {
+	*httptest.Server
+	clientID     string
+	clientSecret string
+
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	keyID   string
+	subject string
+	email   string
+	// claims override the claims of the next ID tokens
+	claims map[string]interface{}
+	// hmac signs the next ID tokens with the client secret instead of the key
+	hmac  bool
+	codes map[string]url.Values
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	s := &mockOIDCServer{clientID: "go_crud", clientSecret: "s3cret", subject: "248289761001", email: "jane@example.com", codes: map[string]url.Values{}}
+	s.rotateKey(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"jwks_uri":               s.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &s.key.PublicKey, KeyID: s.keyID, Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *mockOIDCServer) rotateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.keyID = fmt.Sprintf("key-%d", time.Now().UnixNano())
+}
+
+func (s *mockOIDCServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != s.clientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	code := fmt.Sprintf("code-%d", len(s.codes))
+	s.codes[code] = query
+	s.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, secret, _ := r.BasicAuth()
+	authorization, ok := s.codes[r.FormValue("code")]
+	delete(s.codes, r.FormValue("code"))
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	switch {
+	case id != s.clientID || secret != s.clientSecret:
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	case !ok || r.FormValue("redirect_uri") != authorization.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.Get("code_challenge"):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":            s.URL,
+		"sub":            s.subject,
+		"aud":            s.clientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          authorization.Get("nonce"),
+		"email":          s.email,
+		"email_verified": true,
+		"name":           "Jane Doe",
+	}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+
+	key := jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: s.key, KeyID: s.keyID}}
+	if s.hmac {
+		key = jose.SigningKey{Algorithm: jose.HS256, Key: jose.JSONWebKey{Key: []byte(s.clientSecret), KeyID: s.keyID}}
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     signTestJWT(key, claims),
+	})
+}
+
+func signTestJWT(key jose.SigningKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(key, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// signIn runs the browser side of a sign in against the mock server, and
+// returns the code and state the callback receives.
+func signIn(t *testing.T, provider *OIDCProvider, state string, nonce string, codeVerifier string) (string, string) {
+	link, err := provider.AuthCodeURL(state, nonce, codeVerifier)
+	assert.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(link)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+
+	callback, err := url.Parse(res.Header.Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/auth/oidc/corp/callback", callback.Path)
+
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func newTestOIDCProvider(server *mockOIDCServer) *OIDCProvider {
+	return NewOIDCProvider(OIDCProviderConfig{
+		Name:         "corp",
+		IssuerURL:    server.URL,
+		ClientID:     server.clientID,
+		ClientSecret: server.clientSecret,
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/corp/callback",
+		Scopes:       []string{"email", "profile"},
+	}, server.Client())
+}
+
+func TestOIDCProviderSignIn(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newTestOIDCProvider(server)
+
+	code, state := signIn(t, provider, "state-1", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	assert.Equal(t, "state-1", state)
+
+	claims, err := provider.Exchange(code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "248289761001", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Jane Doe", claims.Name)
+
+	// Codes are single use
+	_, err = provider.Exchange(code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	assert.Equal(t, ErrCodeUnauthenticated, ErrorCode(err))
+}
+
+func TestOIDCProviderRejects(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newTestOIDCProvider(server)
+	verifier := "verifier-0123456789-0123456789-0123456789"
+
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		hmac     bool
+		verifier string
+		nonce    string
+	}{
+		{"wrong PKCE verifier", nil, false, "another-verifier-0123456789-0123456789", "nonce"},
+		{"replayed ID token", nil, false, verifier, "other-nonce"},
+		{"wrong audience", map[string]interface{}{"aud": "other-client"}, false, verifier, "nonce"},
+		{"wrong issuer", map[string]interface{}{"iss": "https://evil.example.com"}, false, verifier, "nonce"},
+		{"expired", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, false, verifier, "nonce"},
+		{"signed with the client secret", nil, true, verifier, "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.claims, server.hmac = tt.claims, tt.hmac
+			code, _ := signIn(t, provider, "state", "nonce", verifier)
+
+			_, err := provider.Exchange(code, tt.verifier, tt.nonce)
+			assert.Error(t, err)
+			assert.Equal(t, ErrCodeUnauthenticated, ErrorCode(err))
+		})
+	}
+}
+
+func TestOIDCProviderKeyRotation(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newTestOIDCProvider(server)
+	verifier := "verifier-0123456789-0123456789-0123456789"
+
+	code, _ := signIn(t, provider, "state", "nonce", verifier)
+	_, err := provider.Exchange(code, verifier, "nonce")
+	assert.NoError(t, err)
+
+	server.rotateKey(t)
+
+	// Keys are not fetched again within the refresh interval
+	code, _ = signIn(t, provider, "state", "nonce", verifier)
+	_, err = provider.Exchange(code, verifier, "nonce")
+	assert.Error(t, err)
+
+	provider.keysFetched = time.Now().Add(-jwksRefreshInterval)
+	code, _ = signIn(t, provider, "state", "nonce", verifier)
+	_, err = provider.Exchange(code, verifier, "nonce")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,18 @@
+package services
+
+import "go_crud/models"
+
+type OIDCService interface {
+	// Providers lists the names of the configured identity providers
+	Providers() []string
+	// AuthURL starts a sign in with a provider and returns the page to
+	// send the user to
+	AuthURL(provider string) (string, error)
+	// LinkURL starts linking a provider to the signed in user with userID,
+	// and returns the page to send the user to
+	LinkURL(provider string, userID string) (string, error)
+	// Callback finishes a sign in when the provider redirects back, and
+	// returns the user, created on their first sign in or linked through
+	// LinkURL
+	Callback(provider string, code string, state string) (*models.DBUser, error)
+}
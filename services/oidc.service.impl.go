@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrOIDCProviderNotFound = &Error{ErrCodeNotFound, "no identity provider with that name is configured"}
+	ErrInvalidOIDCState     = &Error{ErrCodeUnauthenticated, "the sign in is invalid or has expired, start again"}
+)
+
+func oidcAccountExistsError(email string, provider string) error {
+	return &Error{ErrCodeAlreadyExists, fmt.Sprintf("an account already uses %s, sign in to it and link %s from there", email, provider)}
+}
+
+type OIDCServiceImpl struct {
+	userCollection  Collection
+	stateCollection Collection
+	ctx             context.Context
+	providers       map[string]*OIDCProvider
+	// stateTTL is how long users have to sign in at the provider
+	stateTTL time.Duration
+}
+
+func NewOIDCService(userCollection Collection, stateCollection Collection, ctx context.Context, providers []*OIDCProvider, stateTTL time.Duration) OIDCService {
+	err := createIndexes(ctx, stateCollection, []mongo.IndexModel{
+		{Keys: bson.M{"stateHash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}...)
+	if err != nil {
+		panic(err)
+	}
+
+	// An identity can only be linked to one user
+	err = createIndexes(ctx, userCollection, mongo.IndexModel{
+		Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	byName := map[string]*OIDCProvider{}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCServiceImpl{userCollection, stateCollection, ctx, byName, stateTTL}
+}
+
+func (p *OIDCServiceImpl) Providers() []string {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AuthURL stores the state, nonce and PKCE verifier of a new sign in, and
+// returns the authorization URL of the provider.
+func (p *OIDCServiceImpl) AuthURL(name string) (string, error) {
+	return p.authURL(name, primitive.NilObjectID)
+}
+
+// LinkURL is AuthURL for linking the provider to an existing user, who
+// proves they own the account by being signed in.
+func (p *OIDCServiceImpl) LinkURL(name string, userID string) (string, error) {
+	obId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return "", &Error{ErrCodeInvalid, "invalid user Id"}
+	}
+
+	return p.authURL(name, obId)
+}
+
+func (p *OIDCServiceImpl) authURL(name string, linkUserID primitive.ObjectID) (string, error) {
+	provider, ok := p.providers[name]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	state, stateHash, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := utils.RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	codeVerifier, err := utils.RandomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := provider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	_, err = p.stateCollection.InsertOne(p.ctx, models.OIDCState{
+		StateHash:    stateHash,
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		LinkUserID:   linkUserID,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(p.stateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return link, nil
+}
+
+// Callback uses up the state of the sign in, so the redirect cannot be replayed.
+func (p *OIDCServiceImpl) Callback(name string, code string, state string) (*models.DBUser, error) {
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	query := bson.M{"stateHash": utils.HashToken(state), "provider": name, "expiresAt": bson.M{"$gt": time.Now().UTC()}}
+
+	var signIn models.OIDCState
+	if err := p.stateCollection.FindOneAndDelete(p.ctx, query).Decode(&signIn); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidOIDCState
+		}
+
+		return nil, err
+	}
+
+	claims, err := provider.Exchange(code, signIn.CodeVerifier, signIn.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if !signIn.LinkUserID.IsZero() {
+		return p.linkIdentity(signIn.LinkUserID, name, claims)
+	}
+	return p.findUser(name, claims)
+}
+
+// findUser finds the user of an identity, or creates one on the first sign
+// in. An identity is never linked to an existing account here, even with
+// the same verified email: the owner links it through LinkURL, so a
+// provider cannot take over an account, or skip its second factor.
+func (p *OIDCServiceImpl) findUser(provider string, claims *OIDCClaims) (*models.DBUser, error) {
+	identityQuery := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": claims.Subject}}}
+
+	var user models.DBUser
+	err := p.userCollection.FindOne(p.ctx, identityQuery).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("%s did not share an email address, allow the email scope", provider)}
+	}
+
+	now := time.Now().UTC()
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	request := &models.CreateUserRequest{Name: name, Email: claims.Email, EmailVerified: claims.EmailVerified}
+	if claims.EmailVerified {
+		request.VerifiedAt = &now
+	}
+	newUser := newDBUser(request, "")
+	newUser.Identities = []models.ExternalIdentity{{Provider: provider, Subject: claims.Subject, Email: claims.Email, LinkedAt: now}}
+
+	res, err := p.userCollection.InsertOne(p.ctx, newUser)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, oidcAccountExistsError(claims.Email, provider)
+		}
+		return nil, err
+	}
+
+	if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": res.InsertedID}).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// linkIdentity links an identity to the user with userID, who can then
+// sign in with it. A user has at most one identity per provider.
+func (p *OIDCServiceImpl) linkIdentity(userID primitive.ObjectID, provider string, claims *OIDCClaims) (*models.DBUser, error) {
+	identity := models.ExternalIdentity{Provider: provider, Subject: claims.Subject, Email: claims.Email, LinkedAt: time.Now().UTC()}
+
+	var user models.DBUser
+	query := bson.M{"_id": userID, "identities.provider": bson.M{"$ne": provider}}
+	update := bson.M{"$push": bson.M{"identities": identity}}
+	err := p.userCollection.FindOneAndUpdate(p.ctx, query, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, &Error{ErrCodeAlreadyExists, fmt.Sprintf("this %s account is linked to another user", provider)}
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Linking the same identity again is a sign in
+	query = bson.M{"_id": userID, "identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": claims.Subject}}}
+	if err := p.userCollection.FindOne(p.ctx, query).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &Error{ErrCodeAlreadyExists, fmt.Sprintf("another %s account is linked to the user, or the user no longer exists", provider)}
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
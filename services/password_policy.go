@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go_crud/models"
+	"go_crud/utils"
+)
+
+// PasswordPolicy holds the rules new passwords are checked against.
+type PasswordPolicy struct {
+	MinLength int
+	// MaxBytes caps the length, bcrypt ignores everything past 72 bytes
+	MaxBytes int
+	// MinClasses is how many of lowercase letters, uppercase letters,
+	// digits and symbols a password must contain
+	MinClasses int
+	// Breached holds known breached passwords, it is skipped when nil
+	Breached *utils.BloomFilter
+}
+
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: 8, MaxBytes: 72, MinClasses: 2}
+}
+
+var passwordPolicy = DefaultPasswordPolicy()
+
+// SetPasswordPolicy replaces the policy the services check new passwords against.
+func SetPasswordPolicy(policy *PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// PasswordPolicyError lists the rules a password breaks.
+type PasswordPolicyError struct {
+	Violations []models.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+
+	return "the password " + strings.Join(messages, ", ")
+}
+
+// Check returns a *PasswordPolicyError if password breaks any rule. The
+// email and name of the user it is for can be empty when unknown.
+func (p *PasswordPolicy) Check(password string, email string, name string) error {
+	var violations []models.PasswordViolation
+	add := func(rule string, format string, args ...interface{}) {
+		violations = append(violations, models.PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		add(models.PasswordRuleMinLength, "must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		add(models.PasswordRuleMaxLength, "must be at most %d bytes long", p.MaxBytes)
+	}
+	if passwordClasses(password) < p.MinClasses {
+		add(models.PasswordRuleCharacterClasses, "must contain at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	if email != "" && (strings.EqualFold(password, email) || strings.EqualFold(password, local)) {
+		add(models.PasswordRuleNotEmail, "must not be the email address")
+	}
+	if name != "" && (strings.EqualFold(password, name) || strings.EqualFold(password, strings.ReplaceAll(name, " ", ""))) {
+		add(models.PasswordRuleNotName, "must not be the name")
+	}
+
+	if p.Breached != nil && p.Breached.Test(password) {
+		add(models.PasswordRuleBreached, "appears in a list of breached passwords")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{violations}
+	}
+
+	return nil
+}
+
+// passwordClasses counts the character classes password uses.
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// checkPassword checks password against the configured policy.
+func checkPassword(password string, email string, name string) error {
+	return passwordPolicy.Check(password, email, name)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+	"go_crud/utils"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	breached := utils.NewBloomFilter(10, 0.001)
+	breached.Add("Password1")
+
+	policy := &PasswordPolicy{MinLength: 8, MaxBytes: 72, MinClasses: 3, Breached: breached}
+
+	tests := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{"valid", "correct Horse 1", nil},
+		{"empty", "", []string{models.PasswordRuleMinLength, models.PasswordRuleCharacterClasses}},
+		{"too long", strings.Repeat("aA1", 25), []string{models.PasswordRuleMaxLength}},
+		{"one class", "abcdefghij", []string{models.PasswordRuleCharacterClasses}},
+		{"email", "John.Doe@Example.com", []string{models.PasswordRuleNotEmail}},
+		{"name", "JohnDoe1", []string{models.PasswordRuleNotName}},
+		{"breached", "Password1", []string{models.PasswordRuleBreached}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password, "john.doe@example.com", "JohnDoe1")
+			if tt.rules == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			assert.True(t, errors.As(err, &policyErr))
+			assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+			var rules []string
+			for _, violation := range policyErr.Violations {
+				rules = append(rules, violation.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestUserServiceImpl_CreateUser_WeakPassword(t *testing.T) {
+	userService := MockNewUserService(nil, nil)
+
+	_, err := userService.CreateUser(&models.CreateUserRequest{
+		Name:     "John Doe",
+		Age:      intPointer(30),
+		Email:    "john.doe@example.com",
+		Password: "short",
+		Address:  &models.Address{Formatted: "123 Main St"},
+	})
+
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
@@ -0,0 +1,15 @@
+package services
+
+import "go_crud/models"
+
+type SCIMService interface {
+	FindUsers(filter string, startIndex int, count int) (*models.SCIMListResponse, error)
+	FindUser(id string) (*models.SCIMUser, error)
+	CreateUser(*models.SCIMUser) (*models.SCIMUser, error)
+	ReplaceUser(id string, user *models.SCIMUser) (*models.SCIMUser, error)
+	PatchUser(id string, req *models.SCIMPatchRequest) (*models.SCIMUser, error)
+	DeleteUser(id string) error
+	ServiceProviderConfig() *models.SCIMServiceProviderConfig
+	ResourceTypes() []models.SCIMResourceType
+	Schemas() []models.SCIMSchema
+}
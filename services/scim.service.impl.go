@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// scimMaxResults is the largest page FindUsers returns
+	scimMaxResults = 1000
+	// scimDefaultResults is the page size when the client asks for none
+	scimDefaultResults = 100
+)
+
+// scimDisableReason is the reason given to users disabled through SCIM.
+const scimDisableReason = "Deactivated by the identity provider"
+
+// SCIMServiceImpl exposes the users over SCIM 2.0. Writes go through the
+// user service, so SCIM users follow the same validation, password policy
+// and uniqueness rules, and active goes through the user status service.
+type SCIMServiceImpl struct {
+	userService       UserService
+	userStatusService UserStatusService
+	userCollection    Collection
+	ctx               context.Context
+	baseURL           string
+}
+
+// NewSCIMService creates the SCIM service. baseURL is the URL the SCIM
+// endpoints are served under, such as https://example.com/scim/v2.
+func NewSCIMService(userService UserService, userStatusService UserStatusService, userCollection Collection, ctx context.Context, baseURL string) SCIMService {
+	return &SCIMServiceImpl{userService, userStatusService, userCollection, ctx, strings.TrimSuffix(baseURL, "/")}
+}
+
+// FindUsers finds the users matching a SCIM filter. startIndex counts
+// from 1, a count of 0 only returns the number of users.
+func (p *SCIMServiceImpl) FindUsers(filter string, startIndex int, count int) (*models.SCIMListResponse, error) {
+	query, err := ParseSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+
+	total, err := p.userCollection.CountDocuments(p.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		Resources:    []models.SCIMUser{},
+	}
+	if count == 0 {
+		return res, nil
+	}
+
+	opt := options.Find().SetSort(bson.M{"_id": 1}).SetSkip(int64(startIndex - 1)).SetLimit(int64(count))
+	cursor, err := p.userCollection.Find(p.ctx, query, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(p.ctx)
+
+	for cursor.Next(p.ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		res.Resources = append(res.Resources, scimUser(&user, p.baseURL))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	res.ItemsPerPage = len(res.Resources)
+	return res, nil
+}
+
+func (p *SCIMServiceImpl) FindUser(id string) (*models.SCIMUser, error) {
+	user, err := p.userService.FindUserById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := scimUser(user, p.baseURL)
+	return &resource, nil
+}
+
+// CreateUser creates a user. Users provisioned without a password get a
+// random one, they sign in through the identity provider or reset it.
+func (p *SCIMServiceImpl) CreateUser(resource *models.SCIMUser) (*models.SCIMUser, error) {
+	fields := scimUserFields(resource)
+	if fields.Password == "" {
+		random, err := utils.RandomHex(24)
+		if err != nil {
+			return nil, err
+		}
+		// The prefix covers policies asking for upper case letters or symbols
+		fields.Password = "Scim-" + random
+	}
+
+	req := &models.CreateUserRequest{
+		Name:     fields.Name,
+		Age:      fields.Age,
+		Email:    fields.Email,
+		Password: fields.Password,
+		Address:  fields.Address,
+	}
+	if err := validate(req); err != nil {
+		return nil, scimValueError(err)
+	}
+
+	user, err := p.userService.CreateUser(req)
+	if err != nil {
+		return nil, err
+	}
+	if user, err = p.setActive(user, resource.Active); err != nil {
+		return nil, err
+	}
+
+	created := scimUser(user, p.baseURL)
+	return &created, nil
+}
+
+// ReplaceUser replaces every field of a user, like PUT /api/users/{userId}.
+func (p *SCIMServiceImpl) ReplaceUser(id string, resource *models.SCIMUser) (*models.SCIMUser, error) {
+	fields := scimUserFields(resource)
+	if err := validate(&fields); err != nil {
+		return nil, scimValueError(err)
+	}
+
+	user, err := p.userService.ReplaceUser(id, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if user, err = p.setActive(user, resource.Active); err != nil {
+		return nil, err
+	}
+
+	replaced := scimUser(user, p.baseURL)
+	return &replaced, nil
+}
+
+func (p *SCIMServiceImpl) PatchUser(id string, req *models.SCIMPatchRequest) (*models.SCIMUser, error) {
+	if !containsString(req.Schemas, models.SCIMPatchOpSchema) {
+		return nil, scimInvalid(SCIMTypeInvalidSyntax, "a PATCH request must use the %s schema", models.SCIMPatchOpSchema)
+	}
+
+	patch, active, err := scimUserPatch(req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := p.userService.PatchUser(id, patch)
+	if err != nil {
+		return nil, err
+	}
+	if user, err = p.setActive(user, active); err != nil {
+		return nil, err
+	}
+
+	patched := scimUser(user, p.baseURL)
+	return &patched, nil
+}
+
+func (p *SCIMServiceImpl) DeleteUser(id string) error {
+	return p.userService.DeleteUser(id)
+}
+
+// setActive disables user when active is false and reactivates them when
+// it is true, unless they already are. It returns the user as they are after.
+func (p *SCIMServiceImpl) setActive(user *models.User, active *bool) (*models.User, error) {
+	if active == nil || *active == scimActive(user) {
+		return user, nil
+	}
+
+	if *active {
+		return p.userStatusService.ReactivateUser(user.ID.Hex())
+	}
+	return p.userStatusService.DisableUser(user.ID.Hex(), &models.DisableUserRequest{Reason: scimDisableReason})
+}
+
+// scimValueError gives validation errors the invalidValue SCIM type.
+func scimValueError(err error) error {
+	return &SCIMError{ErrorCode(err), SCIMTypeInvalidValue, err.Error()}
+}
+
+func (p *SCIMServiceImpl) ServiceProviderConfig() *models.SCIMServiceProviderConfig {
+	return &models.SCIMServiceProviderConfig{
+		Schemas:        []string{models.SCIMServiceProviderSchema},
+		Patch:          models.SCIMSupported{Supported: true},
+		Bulk:           models.SCIMBulkSupport{},
+		Filter:         models.SCIMFilterSupport{Supported: true, MaxResults: scimMaxResults},
+		ChangePassword: models.SCIMSupported{Supported: true},
+		Sort:           models.SCIMSupported{},
+		ETag:           models.SCIMSupported{},
+		AuthenticationSchemes: []models.SCIMAuthenticationType{{
+			Type:        "oauthbearertoken",
+			Name:        "API key",
+			Description: "An API key sent as \"Authorization: Bearer <key>\"",
+			Primary:     true,
+		}},
+		Meta: models.SCIMMeta{ResourceType: "ServiceProviderConfig", Location: p.baseURL + "/ServiceProviderConfig"},
+	}
+}
+
+func (p *SCIMServiceImpl) ResourceTypes() []models.SCIMResourceType {
+	return []models.SCIMResourceType{{
+		Schemas:          []string{models.SCIMResourceTypeSchema},
+		ID:               "User",
+		Name:             "User",
+		Endpoint:         "/Users",
+		Description:      "User Account",
+		Schema:           models.SCIMUserSchema,
+		SchemaExtensions: []models.SCIMSchemaExtension{{Schema: models.SCIMUserExtensionSchema}},
+		Meta:             models.SCIMMeta{ResourceType: "ResourceType", Location: p.baseURL + "/ResourceTypes/User"},
+	}}
+}
+
+func (p *SCIMServiceImpl) Schemas() []models.SCIMSchema {
+	str := func(name string, description string, required bool) models.SCIMAttribute {
+		return models.SCIMAttribute{Name: name, Type: "string", Description: description, Required: required, Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+	}
+
+	userName := str("userName", "The email of the user", true)
+	userName.Uniqueness = "server"
+	password := str("password", "The password of the user, checked against the password policy", false)
+	password.Mutability = "writeOnly"
+	password.Returned = "never"
+
+	return []models.SCIMSchema{
+		{
+			Schemas:     []string{models.SCIMSchemaSchema},
+			ID:          models.SCIMUserSchema,
+			Name:        "User",
+			Description: "User Account",
+			Attributes: []models.SCIMAttribute{
+				userName,
+				{Name: "name", Type: "complex", Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []models.SCIMAttribute{
+					str("formatted", "The name of the user", false),
+					str("givenName", "Joined with familyName when formatted is unset", false),
+					str("familyName", "Joined with givenName when formatted is unset", false),
+				}},
+				str("displayName", "The name of the user", false),
+				{Name: "emails", Type: "complex", MultiValued: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []models.SCIMAttribute{
+					str("value", "Only the primary email is kept, as the userName", false),
+					str("type", "", false),
+					{Name: "primary", Type: "boolean", Mutability: "readWrite", Returned: "default"},
+				}},
+				{Name: "addresses", Type: "complex", MultiValued: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: []models.SCIMAttribute{
+					str("formatted", "Only the primary address is kept", false),
+					str("streetAddress", "", false),
+					str("locality", "", false),
+					str("region", "", false),
+					str("postalCode", "", false),
+					str("country", "", false),
+				}},
+				{Name: "active", Type: "boolean", Description: "False for suspended and disabled users, setting it to false disables the user and true reactivates them", Mutability: "readWrite", Returned: "default"},
+				password,
+			},
+			Meta: &models.SCIMMeta{ResourceType: "Schema", Location: p.baseURL + "/Schemas/" + models.SCIMUserSchema},
+		},
+		{
+			Schemas:     []string{models.SCIMSchemaSchema},
+			ID:          models.SCIMUserExtensionSchema,
+			Name:        "go_crud User",
+			Description: "User fields without a core attribute",
+			Attributes: []models.SCIMAttribute{
+				{Name: "age", Type: "integer", Description: "The age of the user, required to create one", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+			},
+			Meta: &models.SCIMMeta{ResourceType: "Schema", Location: p.baseURL + "/Schemas/" + models.SCIMUserExtensionSchema},
+		},
+	}
+}
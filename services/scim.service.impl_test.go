@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type scimUserService struct {
+	UserService
+	user *models.User
+}
+
+func (s *scimUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	return s.user, nil
+}
+
+type MockUserStatusService struct {
+	UserStatusService
+	Disabled    []string
+	Reactivated []string
+}
+
+func (m *MockUserStatusService) DisableUser(id string, req *models.DisableUserRequest) (*models.User, error) {
+	m.Disabled = append(m.Disabled, id)
+	return &models.User{ID: primitive.NewObjectID(), Status: models.UserStatusDisabled, StatusReason: req.Reason}, nil
+}
+
+func (m *MockUserStatusService) ReactivateUser(id string) (*models.User, error) {
+	m.Reactivated = append(m.Reactivated, id)
+	return &models.User{ID: primitive.NewObjectID(), Status: models.UserStatusActive}, nil
+}
+
+func TestSCIMServiceImpl_PatchUser_Active(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		active      bool
+		disabled    bool
+		reactivated bool
+	}{
+		{"Deactivate", models.UserStatusActive, false, true, false},
+		{"Deactivate pending", models.UserStatusPending, false, true, false},
+		{"Reactivate disabled", models.UserStatusDisabled, true, false, true},
+		{"Reactivate suspended", models.UserStatusSuspended, true, false, true},
+		{"Already active", models.UserStatusActive, true, false, false},
+		{"Already inactive", models.UserStatusSuspended, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{ID: primitive.NewObjectID(), Name: "Jane Doe", Email: "jane@example.com", Status: tt.status}
+			statuses := &MockUserStatusService{}
+			scimService := NewSCIMService(&scimUserService{user: user}, statuses, &mongo.Collection{}, context.TODO(), "https://example.com/scim/v2")
+
+			patched, err := scimService.PatchUser(user.ID.Hex(), &models.SCIMPatchRequest{
+				Schemas:    []string{models.SCIMPatchOpSchema},
+				Operations: []models.SCIMPatchOperation{{Op: "replace", Path: "active", Value: tt.active}},
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.active, *patched.Active)
+			assert.Equal(t, tt.disabled, len(statuses.Disabled) == 1)
+			assert.Equal(t, tt.reactivated, len(statuses.Reactivated) == 1)
+		})
+	}
+}
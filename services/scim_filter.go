@@ -0,0 +1,370 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SCIM error types (RFC 7644 section 3.12) returned along with the error codes.
+const (
+	SCIMTypeInvalidFilter = "invalidFilter"
+	SCIMTypeInvalidPath   = "invalidPath"
+	SCIMTypeInvalidValue  = "invalidValue"
+	SCIMTypeInvalidSyntax = "invalidSyntax"
+	SCIMTypeMutability    = "mutability"
+	SCIMTypeNoTarget      = "noTarget"
+	SCIMTypeUniqueness    = "uniqueness"
+)
+
+// SCIMError is a service error that also carries the SCIM error type.
+type SCIMError struct {
+	Code     string
+	ScimType string
+	Message  string
+}
+
+func (e *SCIMError) Error() string {
+	return e.Message
+}
+
+// Unwrap lets ErrorCode read the code of the error.
+func (e *SCIMError) Unwrap() error {
+	return &Error{e.Code, e.Message}
+}
+
+func scimInvalid(scimType string, format string, args ...interface{}) error {
+	return &SCIMError{ErrCodeInvalid, scimType, fmt.Sprintf(format, args...)}
+}
+
+// scimAttributes maps the SCIM attributes filters and patches can use, in
+// lower case, to user fields. Multi-valued attributes stand for their
+// primary value.
+var scimAttributes = map[string]string{
+	"id":                   "_id",
+	"username":             "email",
+	"emails":               "email",
+	"emails.value":         "email",
+	"displayname":          "name",
+	"name":                 "name",
+	"name.formatted":       "name",
+	"addresses":            "address",
+	"addresses.formatted":  "address.formatted",
+	"addresses.locality":   "address.city",
+	"addresses.region":     "address.region",
+	"addresses.postalcode": "address.postal_code",
+	"addresses.country":    "address.country",
+	"age":                  "age",
+	"active":               "active",
+	"password":             "password",
+	"externalid":           "externalId",
+}
+
+// scimAttribute resolves a SCIM attribute path to a key of scimAttributes.
+// Schema URNs are dropped, and so are value filters such as
+// emails[type eq "work"], as users only have one of each.
+func scimAttribute(path string) string {
+	attr := strings.ToLower(strings.TrimSpace(path))
+	for _, schema := range []string{models.SCIMUserSchema, models.SCIMUserExtensionSchema} {
+		schema = strings.ToLower(schema) + ":"
+		if strings.HasPrefix(attr, schema) {
+			attr = strings.TrimPrefix(attr, schema)
+			break
+		}
+	}
+
+	if open := strings.Index(attr, "["); open >= 0 {
+		if end := strings.Index(attr[open:], "]"); end >= 0 {
+			attr = attr[:open] + attr[open+end+1:]
+		}
+	}
+
+	return attr
+}
+
+// scimToken is a token of a SCIM filter: a word, a quoted string or a parenthesis.
+type scimToken struct {
+	text   string
+	quoted bool
+}
+
+// ParseSCIMFilter turns a SCIM filter (RFC 7644 section 3.4.2.2) into a
+// MongoDB query on the users. Every operator is supported, on the
+// attributes of scimAttributes. Strings compare without case, like the
+// userName and emails of the SCIM core schema.
+func ParseSCIMFilter(filter string) (bson.M, error) {
+	if strings.TrimSpace(filter) == "" {
+		return bson.M{}, nil
+	}
+
+	tokens, err := scimFilterTokens(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &scimFilterParser{tokens: tokens}
+	query, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "unexpected %q in the filter", p.tokens[p.pos].text)
+	}
+
+	return query, nil
+}
+
+func scimFilterTokens(filter string) ([]scimToken, error) {
+	var tokens []scimToken
+	for i := 0; i < len(filter); {
+		switch c := filter[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, scimToken{text: string(c)})
+			i++
+		case c == '"':
+			// Find the closing quote, skipping escaped characters
+			end := i + 1
+			for end < len(filter) && filter[end] != '"' {
+				if filter[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(filter) {
+				return nil, scimInvalid(SCIMTypeInvalidFilter, "unterminated string in the filter")
+			}
+
+			var s string
+			if err := json.Unmarshal([]byte(filter[i:end+1]), &s); err != nil {
+				return nil, scimInvalid(SCIMTypeInvalidFilter, "invalid string %s in the filter", filter[i:end+1])
+			}
+			tokens = append(tokens, scimToken{text: s, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && !strings.ContainsRune(" \t()\"", rune(filter[end])) {
+				// Value filters such as emails[type eq "work"] are part of the attribute
+				if filter[end] == '[' {
+					close := strings.IndexByte(filter[end:], ']')
+					if close < 0 {
+						return nil, scimInvalid(SCIMTypeInvalidFilter, "unterminated [ in the filter")
+					}
+					end += close
+				}
+				end++
+			}
+			tokens = append(tokens, scimToken{text: filter[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type scimFilterParser struct {
+	tokens []scimToken
+	pos    int
+}
+
+// keyword reports whether the next token is the unquoted word w, and
+// consumes it if so.
+func (p *scimFilterParser) keyword(w string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, w) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *scimFilterParser) next() (scimToken, error) {
+	if p.pos >= len(p.tokens) {
+		return scimToken{}, scimInvalid(SCIMTypeInvalidFilter, "the filter ends unexpectedly")
+	}
+
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *scimFilterParser) or() (bson.M, error) {
+	query, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		query = bson.M{"$or": bson.A{query, right}}
+	}
+
+	return query, nil
+}
+
+func (p *scimFilterParser) and() (bson.M, error) {
+	query, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		query = bson.M{"$and": bson.A{query, right}}
+	}
+
+	return query, nil
+}
+
+func (p *scimFilterParser) unary() (bson.M, error) {
+	if p.keyword("not") {
+		if !p.keyword("(") {
+			return nil, scimInvalid(SCIMTypeInvalidFilter, "not must be followed by a parenthesis")
+		}
+		query, err := p.group()
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": bson.A{query}}, nil
+	}
+
+	if p.keyword("(") {
+		return p.group()
+	}
+
+	return p.comparison()
+}
+
+// group parses the rest of a parenthesized filter.
+func (p *scimFilterParser) group() (bson.M, error) {
+	query, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword(")") {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "missing ) in the filter")
+	}
+
+	return query, nil
+}
+
+func (p *scimFilterParser) comparison() (bson.M, error) {
+	attr, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if attr.quoted {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "expected an attribute, not %q", attr.text)
+	}
+
+	field, ok := scimAttributes[scimAttribute(attr.text)]
+	if !ok || field == "password" {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "cannot filter on %s", attr.text)
+	}
+	if field == "externalId" {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "externalId is not stored, filter on userName instead")
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	operator := strings.ToLower(op.text)
+
+	if operator == "pr" {
+		switch field {
+		case "active", "_id":
+			return bson.M{}, nil
+		case "age":
+			return bson.M{field: bson.M{"$ne": nil}}, nil
+		default:
+			return bson.M{field: bson.M{"$nin": bson.A{nil, ""}}}, nil
+		}
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	return scimComparison(field, operator, value)
+}
+
+// scimComparison returns the query comparing field with value.
+func scimComparison(field string, operator string, value scimToken) (bson.M, error) {
+	switch field {
+	case "_id":
+		if operator != "eq" && operator != "ne" {
+			return nil, scimInvalid(SCIMTypeInvalidFilter, "id can only be compared with eq or ne")
+		}
+		// Ids that are not object ids match no user
+		id, _ := primitive.ObjectIDFromHex(value.text)
+		if operator == "ne" {
+			return bson.M{field: bson.M{"$ne": id}}, nil
+		}
+		return bson.M{field: id}, nil
+
+	case "active":
+		// Suspended and disabled users are inactive
+		var active bool
+		if err := json.Unmarshal([]byte(value.text), &active); err != nil || value.quoted || (operator != "eq" && operator != "ne") {
+			return nil, scimInvalid(SCIMTypeInvalidFilter, "active can only be compared with eq or ne to true or false")
+		}
+		inactive := bson.A{models.UserStatusSuspended, models.UserStatusDisabled}
+		if active == (operator == "eq") {
+			return bson.M{"status": bson.M{"$nin": inactive}}, nil
+		}
+		return bson.M{"status": bson.M{"$in": inactive}}, nil
+
+	case "age":
+		var age float64
+		if value.quoted || json.Unmarshal([]byte(value.text), &age) != nil {
+			return nil, scimInvalid(SCIMTypeInvalidFilter, "age must be compared with a number")
+		}
+		mongoOp, ok := map[string]string{"eq": "$eq", "ne": "$ne", "gt": "$gt", "ge": "$gte", "lt": "$lt", "le": "$lte"}[operator]
+		if !ok {
+			return nil, scimInvalid(SCIMTypeInvalidFilter, "unsupported operator %q for age", operator)
+		}
+		return bson.M{field: bson.M{mongoOp: age}}, nil
+	}
+
+	if !value.quoted {
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "%s must be compared with a string", field)
+	}
+
+	quoted := regexp.QuoteMeta(value.text)
+	switch operator {
+	case "eq":
+		return bson.M{field: primitive.Regex{Pattern: "^" + quoted + "$", Options: "i"}}, nil
+	case "ne":
+		return bson.M{field: bson.M{"$not": primitive.Regex{Pattern: "^" + quoted + "$", Options: "i"}}}, nil
+	case "co":
+		return bson.M{field: primitive.Regex{Pattern: quoted, Options: "i"}}, nil
+	case "sw":
+		return bson.M{field: primitive.Regex{Pattern: "^" + quoted, Options: "i"}}, nil
+	case "ew":
+		return bson.M{field: primitive.Regex{Pattern: quoted + "$", Options: "i"}}, nil
+	case "gt":
+		return bson.M{field: bson.M{"$gt": value.text}}, nil
+	case "ge":
+		return bson.M{field: bson.M{"$gte": value.text}}, nil
+	case "lt":
+		return bson.M{field: bson.M{"$lt": value.text}}, nil
+	case "le":
+		return bson.M{field: bson.M{"$lte": value.text}}, nil
+	default:
+		return nil, scimInvalid(SCIMTypeInvalidFilter, "unknown operator %q", operator)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		filter string
+		query  bson.M
+	}{
+		{"", bson.M{}},
+		{`userName eq "jane.doe@example.com"`, bson.M{"email": primitive.Regex{Pattern: `^jane\.doe@example\.com$`, Options: "i"}}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName EQ "a"`, bson.M{"email": primitive.Regex{Pattern: "^a$", Options: "i"}}},
+		{`emails[type eq "work"].value sw "jane"`, bson.M{"email": primitive.Regex{Pattern: "^jane", Options: "i"}}},
+		{`id eq "` + id.Hex() + `"`, bson.M{"_id": id}},
+		{`displayName co "o\"b"`, bson.M{"name": primitive.Regex{Pattern: `o"b`, Options: "i"}}},
+		{`addresses pr`, bson.M{"address": bson.M{"$nin": bson.A{nil, ""}}}},
+		{`urn:go_crud:params:scim:schemas:extension:2.0:User:age ge 18`, bson.M{"age": bson.M{"$gte": float64(18)}}},
+		{`active eq true`, bson.M{"status": bson.M{"$nin": bson.A{models.UserStatusSuspended, models.UserStatusDisabled}}}},
+		{
+			`name.formatted ew "doe" and (age lt 30 or not (age pr))`,
+			bson.M{"$and": bson.A{
+				bson.M{"name": primitive.Regex{Pattern: "doe$", Options: "i"}},
+				bson.M{"$or": bson.A{
+					bson.M{"age": bson.M{"$lt": float64(30)}},
+					bson.M{"$nor": bson.A{bson.M{"age": bson.M{"$ne": nil}}}},
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			query, err := ParseSCIMFilter(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.query, query)
+		})
+	}
+}
+
+func TestParseSCIMFilter_Fail(t *testing.T) {
+	tests := []string{
+		`userName`,
+		`userName eq`,
+		`userName eq jane`,
+		`userName xx "jane"`,
+		`nickName eq "jd"`,
+		`externalId eq "123"`,
+		`password eq "secret"`,
+		`age co 3`,
+		`age eq "30"`,
+		`(userName eq "a"`,
+		`userName eq "a" extra`,
+		`userName eq "unterminated`,
+		`not userName eq "a"`,
+	}
+
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			_, err := ParseSCIMFilter(filter)
+			assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+			var scimErr *SCIMError
+			if assert.ErrorAs(t, err, &scimErr) {
+				assert.Equal(t, SCIMTypeInvalidFilter, scimErr.ScimType)
+			}
+		})
+	}
+}
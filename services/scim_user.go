@@ -0,0 +1,322 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"go_crud/models"
+)
+
+// scimUser renders user as a SCIM resource located under baseURL.
+func scimUser(user *models.User, baseURL string) models.SCIMUser {
+	active := scimActive(user)
+	created := user.ID.Timestamp().UTC()
+
+	resource := models.SCIMUser{
+		Schemas:     []string{models.SCIMUserSchema, models.SCIMUserExtensionSchema},
+		ID:          user.ID.Hex(),
+		UserName:    user.Email,
+		Name:        &models.SCIMName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []models.SCIMEmail{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Location:     baseURL + "/Users/" + user.ID.Hex(),
+			Created:      &created,
+		},
+	}
+
+	if user.Address != nil {
+		resource.Addresses = []models.SCIMAddress{{
+			Formatted:     user.Address.String(),
+			StreetAddress: strings.Join(user.Address.Lines, "\n"),
+			Locality:      user.Address.City,
+			Region:        user.Address.Region,
+			PostalCode:    user.Address.PostalCode,
+			Country:       user.Address.Country,
+			Primary:       true,
+		}}
+	}
+	if user.Age != nil {
+		resource.Extension = &models.SCIMUserExtension{Age: user.Age}
+	}
+
+	return resource
+}
+
+// scimActive reports whether user is active in SCIM terms: suspended and
+// disabled users are inactive.
+func scimActive(user *models.User) bool {
+	status := userStatus(user.Status)
+	return status != models.UserStatusSuspended && status != models.UserStatusDisabled
+}
+
+// scimUserFields reads the user fields of a SCIM user. The email is the
+// userName, or the primary email when there is no userName.
+func scimUserFields(resource *models.SCIMUser) models.ReplaceUserRequest {
+	fields := models.ReplaceUserRequest{
+		Email:    resource.UserName,
+		Password: resource.Password,
+		Name:     resource.DisplayName,
+	}
+
+	if fields.Email == "" {
+		fields.Email = primaryEmail(resource.Emails)
+	}
+	if fields.Name == "" && resource.Name != nil {
+		fields.Name = scimName(resource.Name)
+	}
+	if len(resource.Addresses) > 0 {
+		fields.Address = scimAddress(primaryAddress(resource.Addresses))
+	}
+	if resource.Extension != nil {
+		fields.Age = resource.Extension.Age
+	}
+
+	return fields
+}
+
+// scimName reads a SCIM name, joining the given and family names when
+// there is no formatted name.
+func scimName(name *models.SCIMName) string {
+	if name.Formatted != "" {
+		return name.Formatted
+	}
+
+	return strings.TrimSpace(name.GivenName + " " + name.FamilyName)
+}
+
+func primaryEmail(emails []models.SCIMEmail) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+
+	return ""
+}
+
+func primaryAddress(addresses []models.SCIMAddress) models.SCIMAddress {
+	for _, address := range addresses {
+		if address.Primary {
+			return address
+		}
+	}
+
+	return addresses[0]
+}
+
+// scimAddress reads a SCIM address. The street address holds one line per
+// line of the address.
+func scimAddress(address models.SCIMAddress) *models.Address {
+	result := &models.Address{
+		City:       address.Locality,
+		Region:     address.Region,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Formatted:  address.Formatted,
+	}
+	for _, line := range strings.Split(address.StreetAddress, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result.Lines = append(result.Lines, line)
+		}
+	}
+
+	return result
+}
+
+// scimUserPatch turns the operations of a SCIM PATCH into a UserPatch, and
+// the active value they set, if any. The given and family names replace the
+// whole name, as users only have one.
+func scimUserPatch(ops []models.SCIMPatchOperation) (*models.UserPatch, *bool, error) {
+	patch := &models.UserPatch{}
+	var active *bool
+	var givenName, familyName *string
+	nameSet := false
+
+	var apply func(attr string, value interface{}) error
+	apply = func(attr string, value interface{}) error {
+		switch attr {
+		case "username", "emails.value":
+			return scimString(attr, value, &patch.Set.Email)
+		case "emails":
+			var emails []models.SCIMEmail
+			if err := scimDecode(attr, scimList(value), &emails); err != nil {
+				return err
+			}
+			patch.Set.Email = primaryEmail(emails)
+		case "displayname", "name.formatted":
+			nameSet = true
+			return scimString(attr, value, &patch.Set.Name)
+		case "name":
+			var name models.SCIMName
+			if err := scimDecode(attr, value, &name); err != nil {
+				return err
+			}
+			if name.Formatted != "" {
+				nameSet = true
+				patch.Set.Name = name.Formatted
+			}
+			if name.GivenName != "" {
+				givenName = &name.GivenName
+			}
+			if name.FamilyName != "" {
+				familyName = &name.FamilyName
+			}
+		case "name.givenname":
+			givenName = new(string)
+			return scimString(attr, value, givenName)
+		case "name.familyname":
+			familyName = new(string)
+			return scimString(attr, value, familyName)
+		case "addresses":
+			var addresses []models.SCIMAddress
+			if err := scimDecode(attr, scimList(value), &addresses); err != nil {
+				return err
+			}
+			if len(addresses) == 0 {
+				patch.Unset = append(patch.Unset, "address")
+				return nil
+			}
+			patch.Set.Address = scimAddress(primaryAddress(addresses))
+		case "addresses.formatted":
+			if value == "" {
+				patch.Unset = append(patch.Unset, "address")
+				return nil
+			}
+			patch.Set.Address = &models.Address{}
+			return scimString(attr, value, &patch.Set.Address.Formatted)
+		case "age":
+			if value == nil {
+				patch.Unset = append(patch.Unset, "age")
+				return nil
+			}
+			age, ok := value.(float64)
+			if !ok || age != math.Trunc(age) {
+				return scimInvalid(SCIMTypeInvalidValue, "age must be a whole number")
+			}
+			n := int(age)
+			patch.Set.Age = &n
+		case "password":
+			return scimString(attr, value, &patch.Set.Password)
+		case "active":
+			b, ok := value.(bool)
+			if !ok {
+				return scimInvalid(SCIMTypeInvalidValue, "active must be true or false")
+			}
+			active = &b
+		case "externalid":
+			// Not stored
+		case strings.ToLower(models.SCIMUserExtensionSchema):
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return scimInvalid(SCIMTypeInvalidValue, "%s must be an object", models.SCIMUserExtensionSchema)
+			}
+			for name, v := range values {
+				if err := apply(scimAttribute(name), v); err != nil {
+					return err
+				}
+			}
+		default:
+			return scimInvalid(SCIMTypeInvalidPath, "unknown attribute %q", attr)
+		}
+
+		return nil
+	}
+
+	for _, op := range ops {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path != "" {
+				if err := apply(scimAttribute(op.Path), op.Value); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return nil, nil, scimInvalid(SCIMTypeInvalidValue, "an operation without a path needs an object value")
+			}
+			for name, value := range values {
+				if err := apply(scimAttribute(name), value); err != nil {
+					return nil, nil, err
+				}
+			}
+		case "remove":
+			if op.Path == "" {
+				return nil, nil, scimInvalid(SCIMTypeNoTarget, "remove needs a path")
+			}
+
+			attr := scimAttribute(op.Path)
+			field, ok := scimAttributes[attr]
+			if !ok && attr != "name.givenname" && attr != "name.familyname" {
+				return nil, nil, scimInvalid(SCIMTypeInvalidPath, "unknown attribute %q", op.Path)
+			}
+			// Users have one address, so removing any of its parts removes it
+			if strings.HasPrefix(field, "address") {
+				field = "address"
+			}
+			switch field {
+			case "age", "address":
+				patch.Unset = append(patch.Unset, field)
+			case "externalId":
+			default:
+				return nil, nil, scimInvalid(SCIMTypeMutability, "%s is required and cannot be removed", op.Path)
+			}
+		default:
+			return nil, nil, scimInvalid(SCIMTypeInvalidSyntax, "unknown operation %q", op.Op)
+		}
+	}
+
+	if !nameSet && (givenName != nil || familyName != nil) {
+		name := &models.SCIMName{}
+		if givenName != nil {
+			name.GivenName = *givenName
+		}
+		if familyName != nil {
+			name.FamilyName = *familyName
+		}
+		patch.Set.Name = scimName(name)
+	}
+
+	sort.Strings(patch.Unset)
+	return patch, active, nil
+}
+
+// scimString reads a string attribute value into s.
+func scimString(attr string, value interface{}, s *string) error {
+	str, ok := value.(string)
+	if !ok || str == "" {
+		return scimInvalid(SCIMTypeInvalidValue, "%s must be a string that is not empty", attr)
+	}
+
+	*s = str
+	return nil
+}
+
+// scimDecode reads a complex attribute value into v.
+func scimDecode(attr string, value interface{}, v interface{}) error {
+	data, _ := json.Marshal(value)
+	if err := json.Unmarshal(data, v); err != nil {
+		return scimInvalid(SCIMTypeInvalidValue, "invalid value for %s", attr)
+	}
+
+	return nil
+}
+
+// scimList returns value as a list, for clients sending a single value of
+// a multi-valued attribute.
+func scimList(value interface{}) interface{} {
+	if _, ok := value.(map[string]interface{}); ok {
+		return []interface{}{value}
+	}
+
+	return value
+}
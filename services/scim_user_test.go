@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+)
+
+func TestSCIMUserFields(t *testing.T) {
+	var resource models.SCIMUser
+	err := json.Unmarshal([]byte(`{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"externalId": "00u1",
+		"name": {"givenName": "Jane", "familyName": "Doe"},
+		"emails": [{"value": "jane@home.example.com"}, {"value": "jane@example.com", "primary": true}],
+		"addresses": [{"streetAddress": "1 Main St", "locality": "Springfield", "country": "US"}],
+		"urn:go_crud:params:scim:schemas:extension:2.0:User": {"age": 30}
+	}`), &resource)
+	assert.NoError(t, err)
+
+	fields := scimUserFields(&resource)
+	assert.Equal(t, "Jane Doe", fields.Name)
+	assert.Equal(t, "jane@example.com", fields.Email)
+	assert.Equal(t, &models.Address{Lines: []string{"1 Main St"}, City: "Springfield", Country: "US"}, fields.Address)
+	assert.Equal(t, 30, *fields.Age)
+}
+
+func TestSCIMUserPatch(t *testing.T) {
+	var req models.SCIMPatchRequest
+	err := json.Unmarshal([]byte(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "jane@example.com"},
+			{"op": "replace", "path": "name.givenName", "value": "Jane"},
+			{"op": "replace", "path": "name.familyName", "value": "Doe"},
+			{"op": "add", "value": {"active": true, "externalId": "00u1", "urn:go_crud:params:scim:schemas:extension:2.0:User": {"age": 31}}},
+			{"op": "remove", "path": "addresses[type eq \"work\"]"}
+		]
+	}`), &req)
+	assert.NoError(t, err)
+
+	patch, active, err := scimUserPatch(req.Operations)
+	assert.NoError(t, err)
+	assert.True(t, *active)
+	assert.Equal(t, "jane@example.com", patch.Set.Email)
+	assert.Equal(t, "Jane Doe", patch.Set.Name)
+	assert.Equal(t, 31, *patch.Set.Age)
+	assert.Equal(t, []string{"address"}, patch.Unset)
+
+	// The formatted name wins over its parts
+	patch, active, err = scimUserPatch([]models.SCIMPatchOperation{
+		{Op: "replace", Path: "name.givenName", Value: "Jane"},
+		{Op: "replace", Path: "displayName", Value: "Jane D."},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, active)
+	assert.Equal(t, "Jane D.", patch.Set.Name)
+}
+
+func TestSCIMUserPatch_Fail(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       models.SCIMPatchOperation
+		scimType string
+	}{
+		{"active not a boolean", models.SCIMPatchOperation{Op: "replace", Path: "active", Value: "False"}, SCIMTypeInvalidValue},
+		{"remove required", models.SCIMPatchOperation{Op: "remove", Path: "userName"}, SCIMTypeMutability},
+		{"remove without path", models.SCIMPatchOperation{Op: "remove"}, SCIMTypeNoTarget},
+		{"unknown attribute", models.SCIMPatchOperation{Op: "add", Path: "nickName", Value: "JD"}, SCIMTypeInvalidPath},
+		{"unknown op", models.SCIMPatchOperation{Op: "move", Path: "userName"}, SCIMTypeInvalidSyntax},
+		{"empty userName", models.SCIMPatchOperation{Op: "replace", Path: "userName", Value: ""}, SCIMTypeInvalidValue},
+		{"fractional age", models.SCIMPatchOperation{Op: "replace", Path: "age", Value: 30.5}, SCIMTypeInvalidValue},
+		{"no path and no object", models.SCIMPatchOperation{Op: "replace", Value: "x"}, SCIMTypeInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := scimUserPatch([]models.SCIMPatchOperation{tt.op})
+
+			var scimErr *SCIMError
+			if assert.ErrorAs(t, err, &scimErr) {
+				assert.Equal(t, tt.scimType, scimErr.ScimType)
+			}
+		})
+	}
+}
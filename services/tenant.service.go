@@ -0,0 +1,15 @@
+package services
+
+import "go_crud/models"
+
+type TenantService interface {
+	CreateTenant(*models.CreateTenantRequest) (*models.Tenant, error)
+	UpdateTenant(id string, req *models.UpdateTenantRequest) (*models.Tenant, error)
+	FindTenant(id string) (*models.Tenant, error)
+	FindTenants() ([]*models.Tenant, error)
+	// DeleteTenant stops the tenant from being served, its users are kept
+	DeleteTenant(id string) error
+	// ResolveTenant finds the tenant a request is for, which may have been
+	// changed or deleted up to the cache TTL ago
+	ResolveTenant(id string) (*models.Tenant, error)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tenantDatabasePrefix starts the names of the dedicated tenant databases
+const tenantDatabasePrefix = "go_crud_"
+
+var (
+	ErrTenantNotFound = &Error{ErrCodeNotFound, "unknown tenant"}
+	ErrTenantExists   = &Error{ErrCodeAlreadyExists, "a tenant with that Id already exists"}
+
+	// Tenant IDs are used in subdomains and database names, and end before
+	// the first dot of tokens
+	tenantIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+)
+
+// TenantTokenPrefix returns the prefix of the session tokens and API keys
+// issued by the tenant with tenantID, which routes requests carrying them
+// to it. The tokens of the default tenant have none.
+func TenantTokenPrefix(tenantID string) string {
+	if tenantID == "" {
+		return ""
+	}
+	return tenantID + "."
+}
+
+// TokenTenant returns the tenant a session token or API key was issued by,
+// or "" when it has no tenant prefix.
+func TokenTenant(credentials string) string {
+	tenantID, _, ok := strings.Cut(strings.TrimPrefix(credentials, apiKeyPrefix), ".")
+	if !ok {
+		return ""
+	}
+	return tenantID
+}
+
+type cachedTenant struct {
+	tenant    *models.Tenant
+	fetchedAt time.Time
+}
+
+type TenantServiceImpl struct {
+	tenantCollection Collection
+	ctx              context.Context
+	cacheTTL         time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedTenant
+}
+
+// NewTenantService creates the tenant service. ResolveTenant caches the
+// tenants it finds for cacheTTL. Missing ones are looked up every time, so
+// unknown IDs cannot fill the cache.
+func NewTenantService(tenantCollection Collection, ctx context.Context, cacheTTL time.Duration) TenantService {
+	return &TenantServiceImpl{
+		tenantCollection: tenantCollection,
+		ctx:              ctx,
+		cacheTTL:         cacheTTL,
+		cache:            map[string]cachedTenant{},
+	}
+}
+
+func (p *TenantServiceImpl) CreateTenant(req *models.CreateTenantRequest) (*models.Tenant, error) {
+	if !tenantIDPattern.MatchString(req.ID) {
+		return nil, &Error{ErrCodeInvalid, "the tenant Id must be 1 to 32 lowercase letters, digits or dashes"}
+	}
+	if req.ID == models.DefaultTenantID {
+		return nil, &Error{ErrCodeInvalid, "the default tenant Id is reserved"}
+	}
+
+	tenant := models.Tenant{ID: req.ID, Name: req.Name, CreatedAt: time.Now().UTC()}
+	if req.DedicatedDatabase {
+		tenant.Database = tenantDatabasePrefix + req.ID
+	}
+
+	if _, err := p.tenantCollection.InsertOne(p.ctx, tenant); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrTenantExists
+		}
+		return nil, err
+	}
+	p.forget(tenant.ID)
+
+	return &tenant, nil
+}
+
+func (p *TenantServiceImpl) UpdateTenant(id string, req *models.UpdateTenantRequest) (*models.Tenant, error) {
+	var tenant *models.Tenant
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := p.tenantCollection.FindOneAndUpdate(p.ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": req.Name}}, opt).Decode(&tenant); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	p.forget(id)
+
+	return tenant, nil
+}
+
+func (p *TenantServiceImpl) FindTenant(id string) (*models.Tenant, error) {
+	var tenant *models.Tenant
+	if err := p.tenantCollection.FindOne(p.ctx, bson.M{"_id": id}).Decode(&tenant); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+func (p *TenantServiceImpl) FindTenants() ([]*models.Tenant, error) {
+	cursor, err := p.tenantCollection.Find(p.ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	tenants := []*models.Tenant{}
+	if err := cursor.All(p.ctx, &tenants); err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+func (p *TenantServiceImpl) DeleteTenant(id string) error {
+	res, err := p.tenantCollection.DeleteOne(p.ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	p.forget(id)
+
+	if res.DeletedCount == 0 {
+		return ErrTenantNotFound
+	}
+
+	return nil
+}
+
+func (p *TenantServiceImpl) ResolveTenant(id string) (*models.Tenant, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[id]
+	p.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < p.cacheTTL {
+		return cached.tenant, nil
+	}
+
+	tenant, err := p.FindTenant(id)
+	if err != nil {
+		p.forget(id)
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.cache[id] = cachedTenant{tenant, time.Now()}
+	p.mu.Unlock()
+
+	return tenant, nil
+}
+
+// forget drops the tenant from the cache of ResolveTenant, so changes made
+// through this service are seen at once.
+func (p *TenantServiceImpl) forget(id string) {
+	p.mu.Lock()
+	delete(p.cache, id)
+	p.mu.Unlock()
+}
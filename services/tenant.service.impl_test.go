@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenTenant(t *testing.T) {
+	tests := []struct {
+		credentials string
+		tenantID    string
+	}{
+		{"0123abcd", ""},
+		{TenantTokenPrefix("") + "0123abcd", ""},
+		{TenantTokenPrefix("acme") + "0123abcd", "acme"},
+		{"gocrud_0123abcd", ""},
+		{"gocrud_" + TenantTokenPrefix("acme") + "0123abcd", "acme"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.tenantID, TokenTenant(tt.credentials), tt.credentials)
+	}
+}
@@ -5,7 +5,11 @@ import "go_crud/models"
 type UserService interface {
 	CreateUser(*models.CreateUserRequest) (*models.User, error)
 	UpdateUser(string, *models.UpdateUser) (*models.User, error)
-	FindUserById(string) (*models.User, error)
-	FindUsers(page int, limit int) ([]*models.User, error)
+	ReplaceUser(string, *models.ReplaceUserRequest) (*models.User, error)
+	PatchUser(string, *models.UserPatch) (*models.User, error)
+	JSONPatchUser(string, []models.JSONPatchOperation) (*models.User, error)
+	FindUserById(id string, fields ...string) (*models.User, error)
+	FindUsers(page int, limit int, fields ...string) ([]*models.User, error)
+	SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error)
 	DeleteUser(string) error
 }
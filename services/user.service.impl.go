@@ -3,6 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
 
 	"go_crud/models"
 	"go_crud/utils"
@@ -14,38 +19,60 @@ import (
 )
 
 type UserServiceImpl struct {
-	userCollection *mongo.Collection
-	ctx            context.Context
+	userCollection    Collection
+	ctx               context.Context
+	emailVerification EmailVerificationService
+	attributes        AttributeService
+	groups            GroupService
 }
 
-func NewUserService(userCollection *mongo.Collection, ctx context.Context) UserService {
-	// Create a unique index on the "email" field
-	indexModel := mongo.IndexModel{
-		Keys:    bson.M{"email": 1},
-		Options: options.Index().SetUnique(true),
-	}
-	_, err := userCollection.Indexes().CreateOne(ctx, indexModel)
+// NewUserService creates the user service. New users are sent a
+// verification email through emailVerification, unless it is nil. Custom
+// attributes are checked against the definitions of attributes, users
+// cannot have any when it is nil. Users are searched by group through
+// groups, which can be nil when there are none.
+func NewUserService(userCollection Collection, ctx context.Context, emailVerification EmailVerificationService, attributes AttributeService, groups GroupService) UserService {
+	// Create a unique index on the "email" field, unique per tenant when
+	// the collection is shared by several, and a geospatial index on the
+	// location of the address
+	err := createIndexes(ctx, userCollection, []mongo.IndexModel{
+		{Keys: bson.M{"email": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"address.location": "2dsphere"}},
+	}...)
 	if err != nil {
 		// Handle the error if index creation fails
 		panic(err)
 	}
 
-	return &UserServiceImpl{userCollection, ctx}
+	return &UserServiceImpl{userCollection, ctx, emailVerification, attributes, groups}
 }
 
 func (p *UserServiceImpl) CreateUser(user *models.CreateUserRequest) (*models.User, error) {
-	hashPassord, err := utils.HashPassword(user.Password)
+	if err := checkPassword(user.Password, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+	if err := checkAddress(user.Address); err != nil {
+		return nil, err
+	}
+
+	definitions, err := attributeDefinitions(p.attributes)
 	if err != nil {
 		return nil, err
 	}
+	if user.Attributes, err = checkAttributes(definitions, user.Attributes, true); err != nil {
+		return nil, err
+	}
 
-	user.Password = hashPassord
+	hashPassord, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := p.userCollection.InsertOne(p.ctx, user)
+	res, err := p.userCollection.InsertOne(p.ctx, newDBUser(user, hashPassord))
 
 	if err != nil {
 		if er, ok := err.(mongo.WriteException); ok && er.WriteErrors[0].Code == 11000 {
-			return nil, errors.New("user with that email already exists")
+			return nil, duplicateUserError(er.WriteErrors[0].Message)
 		}
 		return nil, err
 	}
@@ -59,49 +86,268 @@ func (p *UserServiceImpl) CreateUser(user *models.CreateUserRequest) (*models.Us
 		return nil, err
 	}
 
+	// The user exists either way, they can ask for the email again
+	if p.emailVerification != nil && !newUser.EmailVerified {
+		if err := p.emailVerification.SendVerification(newUser); err != nil {
+			log.Printf("could not send the verification email to user %s: %v", newUser.ID.Hex(), err)
+		}
+	}
+
 	return newUser, nil
 }
 
+// newDBUser builds the document of a new user, the way every way of
+// creating users stores it. The user is pending until the email is verified.
+func newDBUser(user *models.CreateUserRequest, passwordHash string) *models.DBUser {
+	status := models.UserStatusPending
+	if user.EmailVerified {
+		status = models.UserStatusActive
+	}
+
+	return &models.DBUser{
+		Name:     user.Name,
+		Age:      user.Age,
+		Email:    user.Email,
+		Password: passwordHash,
+		Address:  user.Address,
+		Role:     user.Role,
+
+		Attributes: user.Attributes,
+
+		EmailVerified: user.EmailVerified,
+		VerifiedAt:    user.VerifiedAt,
+
+		Status: status,
+	}
+}
+
+// UpdateUser writes the fields of data that are set. Use PatchUser to remove fields.
 func (p *UserServiceImpl) UpdateUser(id string, data *models.UpdateUser) (*models.User, error) {
+	return p.PatchUser(id, &models.UserPatch{Set: *data})
+}
+
+// ReplaceUser replaces every field of the user, removing the optional
+// fields data leaves out. The password is kept unless data has one.
+func (p *UserServiceImpl) ReplaceUser(id string, data *models.ReplaceUserRequest) (*models.User, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+	return p.patchUser(bson.M{"_id": obId}, replaceUserPatch(data))
+}
+
+func (p *UserServiceImpl) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+	return p.patchUser(bson.M{"_id": obId}, patch)
+}
+
+// JSONPatchUser applies RFC 6902 operations to the user. The result is only
+// written if the user has not changed since it was read, so "test"
+// operations hold for the update.
+func (p *UserServiceImpl) JSONPatchUser(id string, ops []models.JSONPatchOperation) (*models.User, error) {
+	user, err := p.FindUserById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := utils.ApplyJSONPatch(userPatchDocument(user), ops)
+	if errors.Is(err, utils.ErrJSONPatchTestFailed) {
+		return nil, &Error{ErrCodeFailedPrecondition, err.Error()}
+	}
+	if err != nil {
+		return nil, &Error{ErrCodeInvalid, err.Error()}
+	}
+
+	patch, err := userPatchFromDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": user.ID, "name": user.Name, "email": user.Email, "age": user.Age, "address": user.Address}
+	switch {
+	case user.Address == nil:
+		filter["address"] = bson.M{"$in": bson.A{"", nil}}
+	case plainAddress(user.Address):
+		// The address may still be stored as a plain string
+		filter["address"] = bson.M{"$in": bson.A{user.Address, user.Address.Formatted}}
+	}
+	// The order of the attributes is not kept, so they are matched one by
+	// one. Attributes added in the meantime are not noticed.
+	if len(user.Attributes) == 0 {
+		filter["attributes"] = bson.M{"$in": bson.A{bson.M{}, nil}}
+	}
+	for name, value := range user.Attributes {
+		filter["attributes."+name] = value
+	}
+
+	updatedUser, err := p.patchUser(filter, patch)
+	if err == ErrUserNotFound {
+		return nil, &Error{ErrCodeFailedPrecondition, "the user was changed while the patch was applied, retry it"}
+	}
+
+	return updatedUser, err
+}
+
+// patchUser applies patch to the user matching filter and returns the updated user.
+func (p *UserServiceImpl) patchUser(filter bson.M, patch *models.UserPatch) (*models.User, error) {
+	unset := bson.M{}
+	attributesUnset := false
+	for _, field := range patch.Unset {
+		name, _, _ := strings.Cut(field, ".")
+		if !patchFields[name] {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("%s is required and cannot be removed", field)}
+		}
+		unset[field] = ""
+		attributesUnset = attributesUnset || name == "attributes"
+	}
+
+	set := patch.Set
+	if err := validate(&set); err != nil {
+		return nil, err
+	}
+	if err := checkAddress(set.Address); err != nil {
+		return nil, err
+	}
+
+	if set.Attributes != nil || attributesUnset || patch.ReplaceAttributes {
+		definitions, err := attributeDefinitions(p.attributes)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAttributeUnset(definitions, patch.Unset); err != nil {
+			return nil, err
+		}
+		if set.Attributes, err = checkAttributes(definitions, set.Attributes, patch.ReplaceAttributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if set.Password != "" {
+		if err := p.checkNewPassword(filter, &set); err != nil {
+			return nil, err
+		}
 
-	if &data.Password != nil {
 		// Hash the password and update it in the database
-		hashPassword, err := utils.HashPassword(data.Password)
+		hashPassword, err := utils.HashPassword(set.Password)
 		if err != nil {
 			return nil, err
 		}
 
-		data.Password = hashPassword
+		set.Password = hashPassword
 	}
 
-	doc, err := utils.ToDoc(data)
+	doc, err := utils.ToDoc(&set)
 	if err != nil {
 		return nil, err
 	}
+	*doc = append(*doc, attributesDoc(set.Attributes, patch.ReplaceAttributes)...)
+
+	var update interface{}
+	switch {
+	case set.Email != "":
+		update = emailChangePipeline(set.Email, *doc, unset)
+	case len(*doc) > 0 && len(unset) > 0:
+		update = bson.D{{Key: "$set", Value: doc}, {Key: "$unset", Value: unset}}
+	case len(*doc) > 0:
+		update = bson.D{{Key: "$set", Value: doc}}
+	case len(unset) > 0:
+		update = bson.D{{Key: "$unset", Value: unset}}
+	}
 
-	obId, _ := primitive.ObjectIDFromHex(id)
-	query := bson.D{{Key: "_id", Value: obId}}
-	update := bson.D{{Key: "$set", Value: doc}}
-	res := p.userCollection.FindOneAndUpdate(p.ctx, query, update, options.FindOneAndUpdate().SetReturnDocument(1))
+	var res *mongo.SingleResult
+	if update == nil {
+		// Nothing to change
+		res = p.userCollection.FindOne(p.ctx, filter)
+	} else {
+		res = p.userCollection.FindOneAndUpdate(p.ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	}
 
 	var updatedUser *models.User
 	if err := res.Decode(&updatedUser); err != nil {
-		return nil, errors.New("no user with that Id exists")
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, duplicateUserError(err.Error())
+		}
+
+		return nil, err
 	}
 
 	return updatedUser, nil
 }
 
-func (p *UserServiceImpl) FindUserById(id string) (*models.User, error) {
+// checkNewPassword checks the password of set against the policy, comparing
+// it with the name and email the user will have after the update.
+func (p *UserServiceImpl) checkNewPassword(filter bson.M, set *models.UpdateUser) error {
+	email, name := set.Email, set.Name
+	if email == "" || name == "" {
+		var user models.User
+		opt := options.FindOne().SetProjection(bson.M{"name": 1, "email": 1})
+		if err := p.userCollection.FindOne(p.ctx, filter, opt).Decode(&user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		if email == "" {
+			email = user.Email
+		}
+		if name == "" {
+			name = user.Name
+		}
+	}
+
+	return checkPassword(set.Password, email, name)
+}
+
+// emailChangePipeline returns an update pipeline writing set and removing
+// unset, which also resets the email verification if email differs from
+// the current one.
+func emailChangePipeline(email string, set bson.D, unset bson.M) mongo.Pipeline {
+	changed := bson.M{"$ne": bson.A{"$email", email}}
+
+	// Pipeline values are expressions, so strings starting with $ must not
+	// be read as field paths
+	literals := bson.D{}
+	for _, e := range set {
+		literals = append(literals, bson.E{Key: e.Key, Value: bson.M{"$literal": e.Value}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"email_verified": bson.M{"$cond": bson.A{changed, false, "$email_verified"}},
+			"verified_at":    bson.M{"$cond": bson.A{changed, "$$REMOVE", "$verified_at"}},
+		}}},
+		{{Key: "$set", Value: literals}},
+	}
+
+	if len(unset) > 0 {
+		fields := make([]string, 0, len(unset))
+		for field := range unset {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		pipeline = append(pipeline, bson.D{{Key: "$unset", Value: fields}})
+	}
+
+	return pipeline
+}
+
+// FindUserById finds a user, only reading the given fields if any are passed.
+func (p *UserServiceImpl) FindUserById(id string, fields ...string) (*models.User, error) {
 	obId, _ := primitive.ObjectIDFromHex(id)
 
 	query := bson.M{"_id": obId}
 
+	opt := options.FindOne()
+	if projection := userProjection(fields); projection != nil {
+		opt.SetProjection(projection)
+	}
+
 	var user *models.User
 
-	if err := p.userCollection.FindOne(p.ctx, query).Decode(&user); err != nil {
+	if err := p.userCollection.FindOne(p.ctx, query, opt).Decode(&user); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("no document with that Id exists")
+			return nil, ErrUserNotFound
 		}
 
 		return nil, err
@@ -110,7 +356,14 @@ func (p *UserServiceImpl) FindUserById(id string) (*models.User, error) {
 	return user, nil
 }
 
-func (p *UserServiceImpl) FindUsers(page int, limit int) ([]*models.User, error) {
+// FindUsers finds a page of users, only reading the given fields if any are passed.
+func (p *UserServiceImpl) FindUsers(page int, limit int, fields ...string) ([]*models.User, error) {
+	return p.SearchUsers(models.UserFilter{}, page, limit, fields...)
+}
+
+// SearchUsers finds a page of the users matching filter, in the order they
+// were created, only reading the given fields if any are passed.
+func (p *UserServiceImpl) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
 	if page == 0 {
 		page = 1
 	}
@@ -124,7 +377,14 @@ func (p *UserServiceImpl) FindUsers(page int, limit int) ([]*models.User, error)
 	opt := options.FindOptions{}
 	opt.SetLimit(int64(limit))
 	opt.SetSkip(int64(skip))
-	query := bson.M{}
+	if projection := userProjection(fields); projection != nil {
+		opt.SetProjection(projection)
+	}
+	query, sort, err := userSearchQuery(filter, p.attributes, p.groups)
+	if err != nil {
+		return nil, err
+	}
+	opt.SetSort(sort)
 
 	cursor, err := p.userCollection.Find(p.ctx, query, &opt)
 	if err != nil {
@@ -157,6 +417,95 @@ func (p *UserServiceImpl) FindUsers(page int, limit int) ([]*models.User, error)
 	return users, nil
 }
 
+// userSearchQuery returns the query and the order of the users matching
+// filter, checking the custom attributes against the definitions of
+// attributes and finding the members of nested groups through groups.
+func userSearchQuery(filter models.UserFilter, attributes AttributeService, groups GroupService) (bson.M, bson.D, error) {
+	if err := checkUserStatusFilter(filter.Status); err != nil {
+		return nil, nil, err
+	}
+	query := userFilterQuery(filter)
+
+	// Members of the groups nested in the group are members of it too
+	if filter.Group != "" {
+		nested := []primitive.ObjectID{}
+		if groups != nil {
+			var err error
+			if nested, err = groups.NestedGroups(filter.Group); err != nil {
+				if ErrorCode(err) == ErrCodeNotFound {
+					return nil, nil, &Error{ErrCodeInvalid, "no group with the Id " + filter.Group + " exists"}
+				}
+				return nil, nil, err
+			}
+		}
+		query["groups"] = bson.M{"$in": nested}
+	}
+
+	definitions := map[string]*models.Attribute{}
+	if len(filter.Attributes) > 0 || filter.Sort != "" {
+		var err error
+		if definitions, err = attributeDefinitions(attributes); err != nil {
+			return nil, nil, err
+		}
+		if err := attributeFilterQuery(definitions, query, filter); err != nil {
+			return nil, nil, err
+		}
+	}
+	sort, err := userSort(definitions, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return query, sort, nil
+}
+
+// userFilterQuery returns the query selecting the users matching filter.
+func userFilterQuery(filter models.UserFilter) bson.M {
+	query := bson.M{}
+	if filter.Name != "" {
+		query["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(filter.Name), Options: "i"}
+	}
+	if filter.Email != "" {
+		query["email"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.Email) + "$", Options: "i"}
+	}
+
+	age := bson.M{}
+	if filter.MinAge != nil {
+		age["$gte"] = *filter.MinAge
+	}
+	if filter.MaxAge != nil {
+		age["$lte"] = *filter.MaxAge
+	}
+	if len(age) > 0 {
+		query["age"] = age
+	}
+
+	id := bson.M{}
+	if filter.IDs != nil {
+		// IDs that are not object IDs match no user
+		ids := bson.A{}
+		for _, hex := range filter.IDs {
+			if obId, err := primitive.ObjectIDFromHex(hex); err == nil {
+				ids = append(ids, obId)
+			}
+		}
+		id["$in"] = ids
+	}
+	if filter.AfterID != "" {
+		obId, _ := primitive.ObjectIDFromHex(filter.AfterID)
+		id["$gt"] = obId
+	}
+	if len(id) > 0 {
+		query["_id"] = id
+	}
+	if filter.Status != "" {
+		query["status"] = userStatusQuery(filter.Status)
+	}
+	addressFilterQuery(query, filter)
+
+	return query
+}
+
 func (p *UserServiceImpl) DeleteUser(id string) error {
 	obId, _ := primitive.ObjectIDFromHex(id)
 	query := bson.M{"_id": obId}
@@ -167,7 +516,7 @@ func (p *UserServiceImpl) DeleteUser(id string) error {
 	}
 
 	if res.DeletedCount == 0 {
-		return errors.New("no document with that Id exists")
+		return ErrUserNotFound
 	}
 
 	return nil
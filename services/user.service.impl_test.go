@@ -8,18 +8,19 @@ import (
 
 	"bou.ke/monkey"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func MockNewUserService(userCollection *mongo.Collection, ctx context.Context) UserService {
-	patch := monkey.Patch(NewUserService, func(userCollection *mongo.Collection, ctx context.Context) UserService {
-		return &UserServiceImpl{userCollection, ctx} // Return a mock instance
+func MockNewUserService(userCollection Collection, ctx context.Context) UserService {
+	patch := monkey.Patch(NewUserService, func(userCollection Collection, ctx context.Context, emailVerification EmailVerificationService, attributes AttributeService, groups GroupService) UserService {
+		return &UserServiceImpl{userCollection: userCollection, ctx: ctx} // Return a mock instance
 	})
 	defer patch.Unpatch()
 
-	return NewUserService(userCollection, ctx)
+	return NewUserService(userCollection, ctx, nil, nil, nil)
 }
 
 func TestUserServiceImpl_CreateUser_Success(t *testing.T) {
@@ -32,7 +33,7 @@ func TestUserServiceImpl_CreateUser_Success(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "password123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	insertOnePatch := monkey.PatchInstanceMethod(
@@ -74,7 +75,7 @@ func TestUserServiceImpl_CreateUser_Fail(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "password123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	insertOnePatch := monkey.PatchInstanceMethod(
@@ -96,3 +97,37 @@ func TestUserServiceImpl_CreateUser_Fail(t *testing.T) {
 }
 
 // ... Implement other test cases for other methods
+
+func TestUserServiceImpl_UpdateUser_InvalidEmail(t *testing.T) {
+	userService := MockNewUserService(&mongo.Collection{}, context.TODO())
+
+	_, err := userService.UpdateUser(primitive.NewObjectID().Hex(), &models.UpdateUser{Email: "not an email"})
+
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestEmailChangePipeline(t *testing.T) {
+	pipeline := emailChangePipeline("jane@example.com", bson.D{{Key: "name", Value: "$jane"}}, bson.M{"age": ""})
+
+	assert.Len(t, pipeline, 3)
+	assert.Equal(t, bson.D{{Key: "name", Value: bson.M{"$literal": "$jane"}}}, pipeline[1][0].Value)
+	assert.Equal(t, bson.D{{Key: "$unset", Value: []string{"age"}}}, pipeline[2])
+}
+
+func TestUserFilterQuery(t *testing.T) {
+	assert.Equal(t, bson.M{}, userFilterQuery(models.UserFilter{}))
+
+	query := userFilterQuery(models.UserFilter{Name: "o.b", Email: "Jane@Example.com", MinAge: intPointer(18), MaxAge: intPointer(65)})
+	assert.Equal(t, bson.M{
+		"name":  primitive.Regex{Pattern: `o\.b`, Options: "i"},
+		"email": primitive.Regex{Pattern: `^Jane@Example\.com$`, Options: "i"},
+		"age":   bson.M{"$gte": 18, "$lte": 65},
+	}, query)
+
+	id := primitive.NewObjectID()
+	query = userFilterQuery(models.UserFilter{IDs: []string{id.Hex(), "nope"}, AfterID: id.Hex()})
+	assert.Equal(t, bson.M{"_id": bson.M{"$in": bson.A{id}, "$gt": id}}, query)
+
+	query = userFilterQuery(models.UserFilter{Status: models.UserStatusActive})
+	assert.Equal(t, bson.M{"status": bson.M{"$in": bson.A{models.UserStatusActive, nil}}}, query)
+}
@@ -43,7 +43,28 @@ func (m *MockUserService) UpdateUser(id string, data *models.UpdateUser) (*model
 	}, nil
 }
 
-func (m *MockUserService) FindUserById(id string) (*models.User, error) {
+func (m *MockUserService) ReplaceUser(id string, data *models.ReplaceUserRequest) (*models.User, error) {
+	// Implement the ReplaceUser method of the UserService interface
+	// Return a mock replaced user and nil error for testing purposes
+	objID, _ := primitive.ObjectIDFromHex(id)
+	return &models.User{
+		ID:      objID,
+		Name:    data.Name,
+		Age:     data.Age,
+		Email:   data.Email,
+		Address: data.Address,
+	}, nil
+}
+
+func (m *MockUserService) PatchUser(id string, patch *models.UserPatch) (*models.User, error) {
+	return m.UpdateUser(id, &patch.Set)
+}
+
+func (m *MockUserService) JSONPatchUser(id string, ops []models.JSONPatchOperation) (*models.User, error) {
+	return m.FindUserById(id)
+}
+
+func (m *MockUserService) FindUserById(id string, fields ...string) (*models.User, error) {
 	// Implement the FindUserById method of the UserService interface
 	// Return a mock user and nil error for testing purposes
 	objID, _ := primitive.ObjectIDFromHex(id)
@@ -52,11 +73,11 @@ func (m *MockUserService) FindUserById(id string) (*models.User, error) {
 		Name:    "John Doe",
 		Age:     intPointer(30), // Use intPointer(30) to create a pointer to the integer value 30
 		Email:   "john.doe@example.com",
-		Address: "123 Main St",
+		Address: &models.Address{Formatted: "123 Main St"},
 	}, nil
 }
 
-func (m *MockUserService) FindUsers(page int, limit int) ([]*models.User, error) {
+func (m *MockUserService) FindUsers(page int, limit int, fields ...string) ([]*models.User, error) {
 	// Implement the FindUsers method of the UserService interface
 	// Return a mock list of users and nil error for testing purposes
 	users := []*models.User{
@@ -65,19 +86,23 @@ func (m *MockUserService) FindUsers(page int, limit int) ([]*models.User, error)
 			Name:    "John Doe",
 			Age:     intPointer(30), // Use intPointer(30) to create a pointer to the integer value 30
 			Email:   "john.doe@example.com",
-			Address: "123 Main St",
+			Address: &models.Address{Formatted: "123 Main St"},
 		},
 		{
 			ID:      primitive.NewObjectID(),
 			Name:    "Jane Smith",
 			Age:     intPointer(28), // Use intPointer(28) to create a pointer to the integer value 28
 			Email:   "jane.smith@example.com",
-			Address: "456 Oak St",
+			Address: &models.Address{Formatted: "456 Oak St"},
 		},
 	}
 	return users, nil
 }
 
+func (m *MockUserService) SearchUsers(filter models.UserFilter, page int, limit int, fields ...string) ([]*models.User, error) {
+	return m.FindUsers(page, limit, fields...)
+}
+
 func (m *MockUserService) DeleteUser(id string) error {
 	// Implement the DeleteUser method of the UserService interface
 	// For testing purposes, we return nil, indicating success
@@ -100,7 +125,7 @@ func TestCreateUser(t *testing.T) {
 		Age:      intPointer(30),
 		Email:    "john.doe@example.com",
 		Password: "password123",
-		Address:  "123 Main St",
+		Address:  &models.Address{Formatted: "123 Main St"},
 	}
 
 	user, err := mockUserService.CreateUser(userRequest)
@@ -110,7 +135,7 @@ func TestCreateUser(t *testing.T) {
 	assert.Equal(t, "John Doe", user.Name)
 	assert.Equal(t, 30, *user.Age)
 	assert.Equal(t, "john.doe@example.com", user.Email)
-	assert.Equal(t, "123 Main St", user.Address)
+	assert.Equal(t, "123 Main St", user.Address.String())
 }
 
 func TestUpdateUser(t *testing.T) {
@@ -122,7 +147,7 @@ func TestUpdateUser(t *testing.T) {
 		Name:    "Jane Smith",
 		Age:     intPointer(28),
 		Email:   "jane.smith@example.com",
-		Address: "456 Oak St",
+		Address: &models.Address{Formatted: "456 Oak St"},
 	}
 
 	user, err := mockUserService.UpdateUser(userID, updateData)
@@ -132,7 +157,7 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, "Jane Smith", user.Name)
 	assert.Equal(t, 28, *user.Age)
 	assert.Equal(t, "jane.smith@example.com", user.Email)
-	assert.Equal(t, "456 Oak St", user.Address)
+	assert.Equal(t, "456 Oak St", user.Address.String())
 }
 
 func TestFindUserById(t *testing.T) {
@@ -148,7 +173,7 @@ func TestFindUserById(t *testing.T) {
 	assert.Equal(t, "John Doe", user.Name)
 	assert.Equal(t, 30, *user.Age)
 	assert.Equal(t, "john.doe@example.com", user.Email)
-	assert.Equal(t, "123 Main St", user.Address)
+	assert.Equal(t, "123 Main St", user.Address.String())
 }
 
 func TestFindUsers(t *testing.T) {
@@ -0,0 +1,9 @@
+package services
+
+import "go_crud/models"
+
+type UserBatchService interface {
+	BatchCreateUsers(users []models.CreateUserRequest, ordered bool) ([]models.BatchItemResult, error)
+	BatchUpdateUsers(users []models.BatchUpdateUser, ordered bool) ([]models.BatchItemResult, error)
+	BatchDeleteUsers(ids []string, ordered bool) ([]models.BatchItemResult, error)
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserBatchServiceImpl struct {
+	userCollection    Collection
+	ctx               context.Context
+	maxBatchSize      int
+	hashWorkers       int
+	emailVerification EmailVerificationService
+	attributes        AttributeService
+}
+
+// NewUserBatchService creates the batch service. Like the user service, it
+// sends created users a verification email through emailVerification,
+// unless it is nil, and checks custom attributes against the definitions
+// of attributes.
+func NewUserBatchService(userCollection Collection, ctx context.Context, maxBatchSize int, hashWorkers int, emailVerification EmailVerificationService, attributes AttributeService) UserBatchService {
+	return &UserBatchServiceImpl{userCollection, ctx, maxBatchSize, hashWorkers, emailVerification, attributes}
+}
+
+func (p *UserBatchServiceImpl) BatchCreateUsers(users []models.CreateUserRequest, ordered bool) ([]models.BatchItemResult, error) {
+	if err := p.checkSize(len(users)); err != nil {
+		return nil, err
+	}
+
+	definitions, err := attributeDefinitions(p.attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := newUserBatch(len(users), ordered)
+	for i := range users {
+		if err := validate(&users[i]); err != nil {
+			batch.fail(i, err)
+			continue
+		}
+		if users[i].Attributes, err = checkAttributes(definitions, users[i].Attributes, true); err != nil {
+			batch.fail(i, err)
+			continue
+		}
+		if err := checkPassword(users[i].Password, users[i].Email, users[i].Name); err != nil {
+			batch.fail(i, err)
+		}
+	}
+
+	passwords := map[int]string{}
+	for _, i := range batch.pending() {
+		passwords[i] = users[i].Password
+	}
+	hashes := p.hashPasswords(batch, passwords)
+
+	var indexes []int
+	var writes []mongo.WriteModel
+	created := map[int]*models.DBUser{}
+	for _, i := range batch.pending() {
+		newUser := newDBUser(&users[i], hashes[i])
+		newUser.Id = primitive.NewObjectID()
+		batch.results[i].ID = newUser.Id.Hex()
+		created[i] = newUser
+
+		indexes = append(indexes, i)
+		writes = append(writes, mongo.NewInsertOneModel().SetDocument(newUser))
+	}
+
+	if err := p.bulkWrite(batch, indexes, writes, models.BatchItemCreated); err != nil {
+		return nil, err
+	}
+
+	for i := range batch.results {
+		if batch.results[i].Status != models.BatchItemCreated {
+			batch.results[i].ID = ""
+			continue
+		}
+		sendVerification(p.emailVerification, created[i])
+	}
+
+	return batch.results, nil
+}
+
+// sendVerification sends a created user the verification email, like
+// CreateUser. The user exists either way, they can ask for the email again.
+func sendVerification(emailVerification EmailVerificationService, user *models.DBUser) {
+	if emailVerification == nil || user.EmailVerified {
+		return
+	}
+
+	err := emailVerification.SendVerification(&models.User{ID: user.Id, Name: user.Name, Email: user.Email})
+	if err != nil {
+		log.Printf("could not send the verification email to user %s: %v", user.Id.Hex(), err)
+	}
+}
+
+func (p *UserBatchServiceImpl) BatchUpdateUsers(users []models.BatchUpdateUser, ordered bool) ([]models.BatchItemResult, error) {
+	if err := p.checkSize(len(users)); err != nil {
+		return nil, err
+	}
+
+	definitions, err := attributeDefinitions(p.attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := newUserBatch(len(users), ordered)
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		batch.results[i].ID = user.ID
+
+		obId, err := primitive.ObjectIDFromHex(user.ID)
+		if err != nil {
+			batch.fail(i, &Error{ErrCodeInvalid, fmt.Sprintf("invalid user Id %q", user.ID)})
+			continue
+		}
+		ids[i] = obId
+
+		fields := user.UpdateUser
+		fields.Attributes = nil
+		if reflect.ValueOf(fields).IsZero() && len(user.Attributes) == 0 {
+			batch.fail(i, &Error{ErrCodeInvalid, "no fields to update"})
+			continue
+		}
+		if err := validate(&users[i].UpdateUser); err != nil {
+			batch.fail(i, err)
+			continue
+		}
+		if users[i].Attributes, err = checkAttributes(definitions, user.Attributes, false); err != nil {
+			batch.fail(i, err)
+			continue
+		}
+		// Only the name and email in the item are compared with the password
+		if user.Password != "" {
+			if err := checkPassword(user.Password, user.Email, user.Name); err != nil {
+				batch.fail(i, err)
+			}
+		}
+	}
+
+	if err := p.failMissing(batch, ids); err != nil {
+		return nil, err
+	}
+
+	passwords := map[int]string{}
+	for _, i := range batch.pending() {
+		if users[i].Password != "" {
+			passwords[i] = users[i].Password
+		}
+	}
+	for i, hash := range p.hashPasswords(batch, passwords) {
+		users[i].Password = hash
+	}
+
+	docs := make([]*bson.D, len(users))
+	for _, i := range batch.pending() {
+		doc, err := utils.ToDoc(&users[i].UpdateUser)
+		if err != nil {
+			batch.fail(i, err)
+			continue
+		}
+		*doc = append(*doc, attributesDoc(users[i].Attributes, false)...)
+		docs[i] = doc
+	}
+
+	var indexes []int
+	var writes []mongo.WriteModel
+	for _, i := range batch.pending() {
+		var update interface{} = bson.D{{Key: "$set", Value: docs[i]}}
+		if users[i].Email != "" {
+			update = emailChangePipeline(users[i].Email, *docs[i], nil)
+		}
+
+		indexes = append(indexes, i)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": ids[i]}).
+			SetUpdate(update))
+	}
+
+	if err := p.bulkWrite(batch, indexes, writes, models.BatchItemUpdated); err != nil {
+		return nil, err
+	}
+
+	return batch.results, nil
+}
+
+func (p *UserBatchServiceImpl) BatchDeleteUsers(userIds []string, ordered bool) ([]models.BatchItemResult, error) {
+	if err := p.checkSize(len(userIds)); err != nil {
+		return nil, err
+	}
+
+	batch := newUserBatch(len(userIds), ordered)
+	ids := make([]primitive.ObjectID, len(userIds))
+	for i, id := range userIds {
+		batch.results[i].ID = id
+
+		obId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			batch.fail(i, &Error{ErrCodeInvalid, fmt.Sprintf("invalid user Id %q", id)})
+			continue
+		}
+		ids[i] = obId
+	}
+
+	if err := p.failMissing(batch, ids); err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	var writes []mongo.WriteModel
+	for _, i := range batch.pending() {
+		indexes = append(indexes, i)
+		writes = append(writes, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": ids[i]}))
+	}
+
+	if err := p.bulkWrite(batch, indexes, writes, models.BatchItemDeleted); err != nil {
+		return nil, err
+	}
+
+	return batch.results, nil
+}
+
+func (p *UserBatchServiceImpl) checkSize(size int) error {
+	if size == 0 || size > p.maxBatchSize {
+		return &Error{ErrCodeInvalid, fmt.Sprintf("a batch must contain between 1 and %d items", p.maxBatchSize)}
+	}
+
+	return nil
+}
+
+// hashPasswords hashes the passwords of the given items on the worker pool
+// and returns the hashes by item index. Items that cannot be hashed fail.
+func (p *UserBatchServiceImpl) hashPasswords(batch *userBatch, passwords map[int]string) map[int]string {
+	indexes := make([]int, 0, len(passwords))
+	plain := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		indexes = append(indexes, i)
+		plain = append(plain, password)
+	}
+
+	hashed, errs := utils.HashPasswords(plain, p.hashWorkers)
+
+	hashes := map[int]string{}
+	for n, i := range indexes {
+		if errs[n] != nil {
+			batch.fail(i, errs[n])
+			continue
+		}
+		hashes[i] = hashed[n]
+	}
+
+	return hashes
+}
+
+// failMissing fails every pending item whose user does not exist.
+func (p *UserBatchServiceImpl) failMissing(batch *userBatch, ids []primitive.ObjectID) error {
+	pending := batch.pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	lookup := make([]primitive.ObjectID, len(pending))
+	for n, i := range pending {
+		lookup[n] = ids[i]
+	}
+
+	opt := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := p.userCollection.Find(p.ctx, bson.M{"_id": bson.M{"$in": lookup}}, opt)
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(p.ctx)
+
+	found := map[primitive.ObjectID]bool{}
+	for cursor.Next(p.ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		found[user.ID] = true
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	for _, i := range pending {
+		if !found[ids[i]] {
+			batch.fail(i, ErrUserNotFound)
+		}
+	}
+
+	return nil
+}
+
+// bulkWrite runs writes and records their outcome in batch. indexes maps
+// every write to its item in the batch.
+func (p *UserBatchServiceImpl) bulkWrite(batch *userBatch, indexes []int, writes []mongo.WriteModel, status string) error {
+	if len(writes) > 0 {
+		_, err := p.userCollection.BulkWrite(p.ctx, writes, options.BulkWrite().SetOrdered(batch.ordered))
+		if err != nil {
+			var bulkErr mongo.BulkWriteException
+			if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+				return err
+			}
+
+			for _, writeErr := range bulkErr.WriteErrors {
+				batch.fail(indexes[writeErr.Index], bulkWriteError(writeErr))
+			}
+		}
+	}
+
+	// Whatever is still pending was written successfully
+	for _, i := range batch.pending() {
+		batch.results[i].Status = status
+	}
+
+	return nil
+}
+
+func bulkWriteError(err mongo.BulkWriteError) error {
+	if err.Code == 11000 {
+		return duplicateUserError(err.Message)
+	}
+
+	return &Error{ErrCodeInternal, err.Message}
+}
+
+// userBatch tracks the result of every item in a batch request.
+type userBatch struct {
+	results []models.BatchItemResult
+	ordered bool
+}
+
+func newUserBatch(size int, ordered bool) *userBatch {
+	results := make([]models.BatchItemResult, size)
+	for i := range results {
+		results[i].Index = i
+	}
+
+	return &userBatch{results, ordered}
+}
+
+func (b *userBatch) fail(i int, err error) {
+	b.results[i].Status = models.BatchItemFailed
+	b.results[i].Error = &models.BatchItemError{Code: ErrorCode(err), Message: err.Error()}
+}
+
+// pending returns the indexes of the items still to be processed. In an
+// ordered batch every item after the first failure is skipped instead.
+func (b *userBatch) pending() []int {
+	var indexes []int
+	for i := range b.results {
+		switch b.results[i].Status {
+		case "":
+			indexes = append(indexes, i)
+		case models.BatchItemFailed:
+			if b.ordered {
+				for j := i + 1; j < len(b.results); j++ {
+					if b.results[j].Status == "" {
+						b.results[j].Status = models.BatchItemSkipped
+					}
+				}
+				return indexes
+			}
+		}
+	}
+
+	return indexes
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// batchCollection is a Collection whose bulk writes fail the writes at the
+// indexes in Fail with a duplicate key error, and report the upserts at the
+// indexes in Upserted. The writes are kept in Writes.
+type batchCollection struct {
+	Collection
+	Fail     []int
+	Upserted []int64
+	Writes   []mongo.WriteModel
+}
+
+func (c *batchCollection) BulkWrite(ctx context.Context, writes []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	c.Writes = append(c.Writes, writes...)
+	if len(c.Fail) == 0 {
+		upserted := map[int64]interface{}{}
+		for _, i := range c.Upserted {
+			upserted[i] = primitive.NewObjectID()
+		}
+		return &mongo.BulkWriteResult{InsertedCount: int64(len(writes)), UpsertedIDs: upserted}, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	for _, i := range c.Fail {
+		bulkErr.WriteErrors = append(bulkErr.WriteErrors, mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Code: 11000, Message: "duplicate key error"}})
+	}
+	return nil, bulkErr
+}
+
+// MockEmailVerificationService records the users sent a verification email
+type MockEmailVerificationService struct {
+	EmailVerificationService
+	Sent []*models.User
+}
+
+func (m *MockEmailVerificationService) SendVerification(user *models.User) error {
+	m.Sent = append(m.Sent, user)
+	return nil
+}
+
+func TestUserBatchServiceImpl_BatchCreateUsers_TooLarge(t *testing.T) {
+	userBatchService := NewUserBatchService(&mongo.Collection{}, context.TODO(), 1, 1, nil, nil)
+
+	_, err := userBatchService.BatchCreateUsers(make([]models.CreateUserRequest, 2), true)
+
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestUserBatchServiceImpl_BatchCreateUsers_InvalidUnordered(t *testing.T) {
+	userBatchService := NewUserBatchService(&mongo.Collection{}, context.TODO(), 10, 2, nil, nil)
+
+	// Both items miss required fields, so nothing is written
+	results, err := userBatchService.BatchCreateUsers([]models.CreateUserRequest{
+		{Email: "john.doe@example.com"},
+		{Name: "Jane Smith"},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, models.BatchItemFailed, result.Status)
+		assert.Equal(t, ErrCodeInvalid, result.Error.Code)
+		assert.Empty(t, result.ID)
+	}
+}
+
+func TestUserBatchServiceImpl_BatchCreateUsers_SendVerification(t *testing.T) {
+	emailVerification := &MockEmailVerificationService{}
+	userBatchService := NewUserBatchService(&batchCollection{Fail: []int{1}}, context.TODO(), 10, 1, emailVerification, nil)
+
+	age := 30
+	results, err := userBatchService.BatchCreateUsers([]models.CreateUserRequest{
+		{Name: "Jane Doe", Age: &age, Email: "jane.doe@example.com", Password: "correct horse battery", Address: &models.Address{Formatted: "1 Main St"}},
+		{Name: "John Doe", Age: &age, Email: "john.doe@example.com", Password: "correct horse battery", Address: &models.Address{Formatted: "1 Main St"}},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchItemCreated, results[0].Status)
+	assert.Equal(t, models.BatchItemFailed, results[1].Status)
+	// Only the user that was created gets the email
+	if assert.Len(t, emailVerification.Sent, 1) {
+		assert.Equal(t, results[0].ID, emailVerification.Sent[0].ID.Hex())
+		assert.Equal(t, "jane.doe@example.com", emailVerification.Sent[0].Email)
+	}
+}
+
+func TestUserBatchServiceImpl_BatchDeleteUsers_InvalidIdOrdered(t *testing.T) {
+	userBatchService := NewUserBatchService(&mongo.Collection{}, context.TODO(), 10, 1, nil, nil)
+
+	results, err := userBatchService.BatchDeleteUsers([]string{"not-an-id", "64b7f0c2a1b2c3d4e5f60718"}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchItemFailed, results[0].Status)
+	assert.Equal(t, ErrCodeInvalid, results[0].Error.Code)
+	assert.Equal(t, models.BatchItemSkipped, results[1].Status)
+	assert.Equal(t, "64b7f0c2a1b2c3d4e5f60718", results[1].ID)
+}
+
+func TestUserBatch_Pending(t *testing.T) {
+	ordered := newUserBatch(3, true)
+	ordered.fail(1, ErrUserNotFound)
+
+	assert.Equal(t, []int{0}, ordered.pending())
+	assert.Equal(t, models.BatchItemSkipped, ordered.results[2].Status)
+	assert.Equal(t, ErrCodeNotFound, ordered.results[1].Error.Code)
+
+	unordered := newUserBatch(3, false)
+	unordered.fail(1, errors.New("boom"))
+
+	assert.Equal(t, []int{0, 2}, unordered.pending())
+	assert.Equal(t, ErrCodeInternal, unordered.results[1].Error.Code)
+}
+
+func TestUserBatchServiceImpl_BatchUpdateUsers_InvalidEmail(t *testing.T) {
+	userBatchService := NewUserBatchService(&mongo.Collection{}, context.TODO(), 10, 1, nil, nil)
+
+	results, err := userBatchService.BatchUpdateUsers([]models.BatchUpdateUser{
+		{ID: "64b7f0c2a1b2c3d4e5f60718", UpdateUser: models.UpdateUser{Email: "not an email"}},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchItemFailed, results[0].Status)
+	assert.Equal(t, ErrCodeInvalid, results[0].Error.Code)
+}
+
+func TestUserBatchServiceImpl_BatchCreateUsers_Pending(t *testing.T) {
+	collection := &batchCollection{}
+	userBatchService := NewUserBatchService(collection, context.TODO(), 10, 1, nil, nil)
+
+	age := 30
+	results, err := userBatchService.BatchCreateUsers([]models.CreateUserRequest{
+		{Name: "Jane Doe", Age: &age, Email: "jane.doe@example.com", Password: "correct horse battery", Address: &models.Address{Formatted: "1 Main St"}},
+	}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.BatchItemCreated, results[0].Status)
+	// Created users wait for their email to be verified, like single ones
+	if assert.Len(t, collection.Writes, 1) {
+		user := collection.Writes[0].(*mongo.InsertOneModel).Document.(*models.DBUser)
+		assert.Equal(t, results[0].ID, user.Id.Hex())
+		assert.Equal(t, models.UserStatusPending, user.Status)
+	}
+}
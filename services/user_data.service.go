@@ -0,0 +1,14 @@
+package services
+
+import "go_crud/models"
+
+// UserDataService answers subject access and erasure requests. Both are
+// recorded in the audit log.
+type UserDataService interface {
+	// ExportUser gathers everything held about the user
+	ExportUser(userID string, format string, actor models.AuditActor) (*models.UserDataExport, error)
+	// EraseUser deletes the user and the data about them, and anonymises
+	// what is kept for security and audit. The audit log, which only
+	// refers to the user by ID, is kept as their tombstone.
+	EraseUser(userID string, actor models.AuditActor) (*models.UserErasure, error)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserDataCollections are the collections holding data about users.
+type UserDataCollections struct {
+	Users              Collection
+	Groups             Collection
+	Sessions           Collection
+	PasswordResets     Collection
+	EmailVerifications Collection
+	MFAChallenges      Collection
+	LoginHistory       Collection
+	LoginThrottles     Collection
+	APIKeys            Collection
+	Invites            Collection
+	Audit              Collection
+}
+
+type UserDataServiceImpl struct {
+	collections UserDataCollections
+	audit       AuditService
+	ctx         context.Context
+}
+
+func NewUserDataService(collections UserDataCollections, audit AuditService, ctx context.Context) UserDataService {
+	return &UserDataServiceImpl{collections, audit, ctx}
+}
+
+func (p *UserDataServiceImpl) ExportUser(userID string, format string, actor models.AuditActor) (*models.UserDataExport, error) {
+	if format != models.UserDataFormatJSON && format != models.UserDataFormatZIP {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unsupported export format %q", format)}
+	}
+
+	user, err := p.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := p.collections
+	export := &models.UserDataExport{
+		ExportedAt:   time.Now().UTC(),
+		User:         user,
+		Groups:       []*models.Group{},
+		Sessions:     []*models.ExportedSession{},
+		LoginHistory: []*models.LoginAttempt{},
+		APIKeys:      []*models.APIKey{},
+		Invites:      []*models.Invite{},
+		AuditLog:     []*models.AuditEntry{},
+	}
+	byUser := bson.M{"userId": user.ID}
+	newest := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	// Identities are not part of the user the API shows
+	var dbUser models.DBUser
+	opt := options.FindOne().SetProjection(bson.M{"identities": 1})
+	if err := c.Users.FindOne(p.ctx, bson.M{"_id": user.ID}, opt).Decode(&dbUser); err != nil {
+		return nil, err
+	}
+	export.Identities = dbUser.Identities
+	if export.Identities == nil {
+		export.Identities = []models.ExternalIdentity{}
+	}
+
+	if len(user.Groups) > 0 {
+		if err := p.findAll(c.Groups, bson.M{"_id": bson.M{"$in": user.Groups}}, options.Find().SetSort(bson.M{"name": 1}), &export.Groups); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.findAll(c.Sessions, byUser, newest, &export.Sessions); err != nil {
+		return nil, err
+	}
+	if err := p.findAll(c.LoginHistory, byUser, newest, &export.LoginHistory); err != nil {
+		return nil, err
+	}
+	if err := p.findAll(c.APIKeys, byUser, newest, &export.APIKeys); err != nil {
+		return nil, err
+	}
+	if err := p.findAll(c.Invites, bson.M{"$or": bson.A{byUser, bson.M{"email": user.Email}}}, newest, &export.Invites); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, invite := range export.Invites {
+		invite.Status = inviteStatus(invite, now)
+	}
+	if err := p.findAll(c.Audit, byUser, newest, &export.AuditLog); err != nil {
+		return nil, err
+	}
+
+	// The export is only handed out once it is recorded
+	entry, err := p.audit.Record(models.AuditActionUserExported, userID, actor, map[string]interface{}{"format": format})
+	if err != nil {
+		return nil, err
+	}
+	export.AuditLog = append([]*models.AuditEntry{entry}, export.AuditLog...)
+
+	return export, nil
+}
+
+func (p *UserDataServiceImpl) EraseUser(userID string, actor models.AuditActor) (*models.UserErasure, error) {
+	user, err := p.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := p.collections
+	erasure := &models.UserErasure{
+		UserID:     userID,
+		ErasedAt:   time.Now().UTC(),
+		Removed:    map[string]int64{},
+		Anonymised: map[string]int64{},
+	}
+	byUser := bson.M{"userId": user.ID}
+	byUserOrEmail := bson.M{"$or": bson.A{byUser, bson.M{"email": user.Email}}}
+
+	// Tokens and keys of the user are only of use to them
+	for name, collection := range map[string]Collection{
+		"sessions":            c.Sessions,
+		"password_resets":     c.PasswordResets,
+		"email_verifications": c.EmailVerifications,
+		"mfa_challenges":      c.MFAChallenges,
+		"api_keys":            c.APIKeys,
+	} {
+		res, err := collection.DeleteMany(p.ctx, byUser)
+		if err != nil {
+			return nil, err
+		}
+		erasure.Removed[name] = res.DeletedCount
+	}
+
+	res, err := c.LoginThrottles.DeleteOne(p.ctx, bson.M{"_id": emailThrottleKey(user.Email)})
+	if err != nil {
+		return nil, err
+	}
+	erasure.Removed["login_throttles"] = res.DeletedCount
+
+	// Sign ins and invites are kept for security, without who they were
+	anonymise := map[string]struct {
+		collection Collection
+		update     bson.M
+	}{
+		"login_history": {c.LoginHistory, bson.M{"$set": bson.M{"email": "", "ip": "", "userAgent": ""}}},
+		"invites":       {c.Invites, bson.M{"$set": bson.M{"email": ""}}},
+	}
+	for name, a := range anonymise {
+		res, err := a.collection.UpdateMany(p.ctx, byUserOrEmail, a.update)
+		if err != nil {
+			return nil, err
+		}
+		erasure.Anonymised[name] = res.ModifiedCount
+	}
+
+	// Group memberships go with the user document
+	deleted, err := c.Users.DeleteOne(p.ctx, bson.M{"_id": user.ID})
+	if err != nil {
+		return nil, err
+	}
+	erasure.Removed["users"] = deleted.DeletedCount
+
+	details := map[string]interface{}{"removed": erasure.Removed, "anonymised": erasure.Anonymised}
+	if _, err := p.audit.Record(models.AuditActionUserErased, userID, actor, details); err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
+func (p *UserDataServiceImpl) findUser(userID string) (*models.User, error) {
+	obId, _ := primitive.ObjectIDFromHex(userID)
+
+	var user *models.User
+	if err := p.collections.Users.FindOne(p.ctx, bson.M{"_id": obId}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// findAll decodes every document of collection matching query into results.
+func (p *UserDataServiceImpl) findAll(collection Collection, query bson.M, opt *options.FindOptions, results interface{}) error {
+	cursor, err := collection.Find(p.ctx, query, opt)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(p.ctx, results)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go_crud/models"
+)
+
+// WriteUserData writes export to w in format, one of the
+// models.UserDataFormat constants.
+func WriteUserData(w io.Writer, export *models.UserDataExport, format string) error {
+	switch format {
+	case models.UserDataFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	case models.UserDataFormatZIP:
+		return writeUserDataZip(w, export)
+	default:
+		return &Error{ErrCodeInvalid, fmt.Sprintf("unsupported export format %q", format)}
+	}
+}
+
+func writeUserDataZip(w io.Writer, export *models.UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"identities.json", export.Identities},
+		{"groups.json", export.Groups},
+		{"sessions.json", export.Sessions},
+		{"login_history.json", export.LoginHistory},
+		{"api_keys.json", export.APIKeys},
+		{"invites.json", export.Invites},
+		{"audit_log.json", export.AuditLog},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testUserDataExport() *models.UserDataExport {
+	id := primitive.NewObjectID()
+	return &models.UserDataExport{
+		ExportedAt:   time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		User:         &models.User{ID: id, Name: "Jane Doe", Email: "jane@example.com"},
+		Identities:   []models.ExternalIdentity{},
+		Groups:       []*models.Group{},
+		Sessions:     []*models.ExportedSession{{ID: primitive.NewObjectID()}},
+		LoginHistory: []*models.LoginAttempt{{UserID: &id, Email: "jane@example.com", Success: true}},
+		APIKeys:      []*models.APIKey{{Name: "ci", KeyHash: "secret"}},
+		Invites:      []*models.Invite{},
+		AuditLog:     []*models.AuditEntry{{Action: models.AuditActionUserExported, UserID: id}},
+	}
+}
+
+func TestWriteUserData_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteUserData(&buf, testUserDataExport(), models.UserDataFormatJSON))
+
+	var export map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+	assert.Equal(t, "Jane Doe", export["user"].(map[string]interface{})["name"])
+	assert.Len(t, export["loginHistory"], 1)
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestWriteUserData_ZIP(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteUserData(&buf, testUserDataExport(), models.UserDataFormatZIP))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		f, err := file.Open()
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(f)
+		files[file.Name] = string(data)
+	}
+
+	assert.Len(t, files, 8)
+	assert.Contains(t, files["user.json"], `"name": "Jane Doe"`)
+	assert.Contains(t, files["audit_log.json"], models.AuditActionUserExported)
+	assert.Equal(t, "[]\n", files["groups.json"])
+}
+
+func TestWriteUserData_UnknownFormat(t *testing.T) {
+	err := WriteUserData(io.Discard, testUserDataExport(), "pdf")
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
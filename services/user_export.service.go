@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"go_crud/models"
+)
+
+type UserExportService interface {
+	ExportUsers(ctx context.Context, w io.Writer, opts models.ExportUsersOptions) error
+	StartExportJob(opts models.ExportUsersOptions) (*models.ExportJob, error)
+	FindExportJob(id string) (*models.ExportJob, error)
+	ExportJobFile(id string) (string, *models.ExportJob, error)
+}
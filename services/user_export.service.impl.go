@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportJobTTL is how long finished export files are kept for download.
+const exportJobTTL = 24 * time.Hour
+
+var ErrExportJobNotFound = &Error{ErrCodeNotFound, "no export job with that Id exists"}
+
+type UserExportServiceImpl struct {
+	userCollection Collection
+	exportDir      string
+	attributes     AttributeService
+	groups         GroupService
+
+	// Export jobs only live in memory, they are lost on restart
+	mu   sync.Mutex
+	jobs map[string]*models.ExportJob
+}
+
+// NewUserExportService creates the export service, which selects users
+// by custom attribute and group through attributes and groups like the
+// user service.
+func NewUserExportService(userCollection Collection, exportDir string, attributes AttributeService, groups GroupService) UserExportService {
+	return &UserExportServiceImpl{
+		userCollection: userCollection,
+		exportDir:      exportDir,
+		attributes:     attributes,
+		groups:         groups,
+		jobs:           map[string]*models.ExportJob{},
+	}
+}
+
+// ExportUsers streams users from the database cursor into w. Invalid options
+// are reported before anything is written.
+func (p *UserExportServiceImpl) ExportUsers(ctx context.Context, w io.Writer, opts models.ExportUsersOptions) error {
+	_, err := p.exportUsers(ctx, w, opts)
+	return err
+}
+
+func (p *UserExportServiceImpl) exportUsers(ctx context.Context, w io.Writer, opts models.ExportUsersOptions) (int, error) {
+	if err := checkExportOptions(opts); err != nil {
+		return 0, err
+	}
+
+	columns, _ := exportColumns(opts.Columns, opts.Role)
+
+	projection := bson.M{"_id": 1}
+	for _, column := range columns {
+		if column != "id" {
+			projection[column] = 1
+		}
+	}
+
+	query, sort, err := userSearchQuery(opts.Filter, p.attributes, p.groups)
+	if err != nil {
+		return 0, err
+	}
+
+	opt := options.Find().SetProjection(projection).SetSort(sort).SetBatchSize(500)
+	if opts.Limit > 0 {
+		page := opts.Page
+		if page == 0 {
+			page = 1
+		}
+		opt.SetLimit(int64(opts.Limit))
+		opt.SetSkip(int64((page - 1) * opts.Limit))
+	}
+
+	cursor, err := p.userCollection.Find(ctx, query, opt)
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	writer, err := newExportWriter(w, opts.Format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	values := make([]interface{}, len(columns))
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return rows, err
+		}
+
+		for i, column := range columns {
+			values[i] = exportValue(&user, column)
+		}
+
+		if err := writer.WriteRow(values); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return rows, err
+	}
+
+	return rows, writer.Close()
+}
+
+// StartExportJob runs an export in the background, writing it to a file
+// that can be downloaded once the job is done.
+func (p *UserExportServiceImpl) StartExportJob(opts models.ExportUsersOptions) (*models.ExportJob, error) {
+	// Check the options now rather than failing the job later
+	if err := checkExportOptions(opts); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(p.exportDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.ExportJob{
+		ID:        id,
+		Status:    models.ExportJobPending,
+		Format:    opts.Format,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	p.mu.Lock()
+	p.removeExpiredJobs()
+	p.jobs[id] = job
+	p.mu.Unlock()
+
+	go p.runExportJob(job.ID, opts)
+
+	return p.FindExportJob(id)
+}
+
+func (p *UserExportServiceImpl) runExportJob(id string, opts models.ExportUsersOptions) {
+	p.updateJob(id, func(job *models.ExportJob) { job.Status = models.ExportJobRunning })
+
+	rows, err := p.writeExportFile(p.jobFile(id, opts.Format), opts)
+
+	p.updateJob(id, func(job *models.ExportJob) {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		job.Rows = rows
+		job.Status = models.ExportJobDone
+		if err != nil {
+			job.Status = models.ExportJobFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+func (p *UserExportServiceImpl) writeExportFile(path string, opts models.ExportUsersOptions) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := p.exportUsers(context.Background(), file, opts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+
+	return rows, err
+}
+
+// FindExportJob returns a copy of the job, so callers never race with the running export.
+func (p *UserExportServiceImpl) FindExportJob(id string) (*models.ExportJob, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[id]
+	if !ok {
+		return nil, ErrExportJobNotFound
+	}
+
+	found := *job
+	return &found, nil
+}
+
+// ExportJobFile returns the path of the file written by a finished job.
+func (p *UserExportServiceImpl) ExportJobFile(id string) (string, *models.ExportJob, error) {
+	job, err := p.FindExportJob(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if job.Status != models.ExportJobDone {
+		return "", nil, &Error{ErrCodeFailedPrecondition, fmt.Sprintf("export job is %s", job.Status)}
+	}
+
+	return p.jobFile(job.ID, job.Format), job, nil
+}
+
+func (p *UserExportServiceImpl) updateJob(id string, update func(*models.ExportJob)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if job, ok := p.jobs[id]; ok {
+		update(job)
+	}
+}
+
+// removeExpiredJobs forgets finished jobs older than exportJobTTL and
+// deletes their files. The caller must hold p.mu.
+func (p *UserExportServiceImpl) removeExpiredJobs() {
+	for id, job := range p.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > exportJobTTL {
+			os.Remove(p.jobFile(id, job.Format))
+			delete(p.jobs, id)
+		}
+	}
+}
+
+func (p *UserExportServiceImpl) jobFile(id string, format string) string {
+	return filepath.Join(p.exportDir, "users-"+id+"."+format)
+}
+
+func checkExportOptions(opts models.ExportUsersOptions) error {
+	if _, err := exportColumns(opts.Columns, opts.Role); err != nil {
+		return err
+	}
+	if err := checkUserStatusFilter(opts.Filter.Status); err != nil {
+		return err
+	}
+
+	_, err := newExportWriter(io.Discard, opts.Format, nil)
+	return err
+}
+
+// exportColumns checks the requested columns against the fields role may
+// read, defaulting to every exportable column the role may read.
+func exportColumns(requested []string, role string) ([]string, error) {
+	readable := readableFields(role)
+	if len(requested) == 0 {
+		var columns []string
+		for _, column := range models.ExportColumns {
+			if containsString(readable, column) {
+				columns = append(columns, column)
+			}
+		}
+		return columns, nil
+	}
+
+	for _, column := range requested {
+		if !containsString(models.ExportColumns, column) {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unknown column %q", column)}
+		}
+		if !containsString(readable, column) {
+			return nil, &Error{ErrCodePermissionDenied, fmt.Sprintf("not allowed to read the %s field", column)}
+		}
+	}
+
+	return requested, nil
+}
+
+func exportValue(user *models.User, column string) interface{} {
+	switch column {
+	case "id":
+		return user.ID.Hex()
+	case "name":
+		return user.Name
+	case "age":
+		if user.Age == nil {
+			return nil
+		}
+		return *user.Age
+	case "email":
+		return user.Email
+	case "address":
+		// On one line, as the import reads it back
+		return user.Address.String()
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestExportColumns(t *testing.T) {
+	columns, err := exportColumns(nil, models.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ExportColumns, columns)
+
+	columns, err = exportColumns([]string{"id", "name"}, models.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name"}, columns)
+
+	// The password hash can never be exported
+	_, err = exportColumns([]string{"id", "password"}, models.RoleAdmin)
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+	// Roles only export the fields they may read
+	columns, err = exportColumns(nil, models.RoleUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "email"}, columns)
+
+	_, err = exportColumns([]string{"id", "age"}, models.RoleUser)
+	assert.Equal(t, ErrCodePermissionDenied, ErrorCode(err))
+}
+
+func TestExportWriters(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Name: "John Doe", Email: "john.doe@example.com"}
+	columns := []string{"name", "age", "email"}
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = exportValue(user, column)
+	}
+
+	var csvOut bytes.Buffer
+	writer, err := newExportWriter(&csvOut, models.ExportFormatCSV, columns)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRow(values))
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, "name,age,email\nJohn Doe,,john.doe@example.com\n", csvOut.String())
+
+	var ndjsonOut bytes.Buffer
+	writer, err = newExportWriter(&ndjsonOut, models.ExportFormatNDJSON, columns)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRow(values))
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, `{"age":null,"email":"john.doe@example.com","name":"John Doe"}`+"\n", ndjsonOut.String())
+}
+
+func TestUserExportServiceImpl_InvalidOptions(t *testing.T) {
+	userExportService := NewUserExportService(&mongo.Collection{}, t.TempDir(), nil, nil)
+
+	var out bytes.Buffer
+	err := userExportService.ExportUsers(context.TODO(), &out, models.ExportUsersOptions{Format: "pdf"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+	assert.Zero(t, out.Len())
+
+	_, err = userExportService.StartExportJob(models.ExportUsersOptions{Format: models.ExportFormatCSV, Columns: []string{"password"}})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+	_, err = userExportService.StartExportJob(models.ExportUsersOptions{Format: models.ExportFormatCSV, Filter: models.UserFilter{Status: "banned"}})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestUserExportServiceImpl_FindExportJob_NotFound(t *testing.T) {
+	userExportService := NewUserExportService(&mongo.Collection{}, t.TempDir(), nil, nil)
+
+	_, err := userExportService.FindExportJob("missing")
+	assert.Equal(t, ErrExportJobNotFound, err)
+
+	_, _, err = userExportService.ExportJobFile("missing")
+	assert.Equal(t, ErrCodeNotFound, ErrorCode(err))
+}
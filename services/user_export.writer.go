@@ -0,0 +1,78 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go_crud/models"
+	"go_crud/utils"
+)
+
+// exportWriter writes exported users one row at a time.
+type exportWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+func newExportWriter(w io.Writer, format string, columns []string) (exportWriter, error) {
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+
+	switch format {
+	case models.ExportFormatCSV:
+		writer := &csvExportWriter{csv.NewWriter(w)}
+		return writer, writer.WriteRow(header)
+	case models.ExportFormatNDJSON:
+		return &ndjsonExportWriter{json.NewEncoder(w), columns}, nil
+	case models.ExportFormatXLSX:
+		writer, err := utils.NewXLSXWriter(w, "Users")
+		if err != nil {
+			return nil, err
+		}
+		return writer, writer.WriteRow(header)
+	default:
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unsupported export format %q", format)}
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = fmt.Sprint(value)
+		}
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (n *ndjsonExportWriter) WriteRow(values []interface{}) error {
+	row := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		row[n.columns[i]] = value
+	}
+
+	return n.encoder.Encode(row)
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
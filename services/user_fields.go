@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UserFields are the user fields that can be selected with fields=.
+var UserFields = []string{"id", "name", "age", "email", "address", "attributes", "groups"}
+
+// publicUserFields are the fields unauthenticated callers, and roles
+// without an entry in readableUserFields, may read.
+var publicUserFields = []string{"id", "name"}
+
+// readableUserFields lists the fields each role may read.
+var readableUserFields = map[string][]string{
+	models.RoleAdmin:   UserFields,
+	models.RoleService: UserFields,
+	models.RoleUser:    {"id", "name", "email"},
+}
+
+// UserReadFields resolves a comma separated fields= value for a caller with
+// the given role, an empty role only reads the public fields. It returns
+// nil when every field is selected.
+func UserReadFields(role string, query string) ([]string, error) {
+	return selectFields(readableFields(role), query)
+}
+
+// UserListFields resolves fields= like UserReadFields, for a list of users.
+// Lists hold other users than the caller, so roles that cannot manage users
+// only read their public fields.
+func UserListFields(role string, query string) ([]string, error) {
+	return selectFields(listableFields(role), query)
+}
+
+// selectFields resolves a fields= value out of the allowed fields.
+func selectFields(allowed []string, query string) ([]string, error) {
+
+	if strings.TrimSpace(query) == "" {
+		if len(allowed) == len(UserFields) {
+			return nil, nil
+		}
+		return allowed, nil
+	}
+
+	var fields []string
+	selected := map[string]bool{}
+	for _, field := range strings.Split(query, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || selected[field] {
+			continue
+		}
+
+		if !containsString(UserFields, field) {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unknown field %q", field)}
+		}
+		if !containsString(allowed, field) {
+			return nil, &Error{ErrCodePermissionDenied, fmt.Sprintf("not allowed to read the %s field", field)}
+		}
+
+		selected[field] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// readableFields returns the fields a caller with the given role may read.
+func readableFields(role string) []string {
+	if fields, ok := readableUserFields[role]; ok {
+		return fields
+	}
+
+	return publicUserFields
+}
+
+// listableFields returns the fields a caller with the given role may read of
+// the users in a list.
+func listableFields(role string) []string {
+	if !CanManageUsers(role) {
+		return publicUserFields
+	}
+
+	return readableFields(role)
+}
+
+// CheckUserFilter rejects a filter on fields the role may not read of the
+// users it lists, as the users it finds would give the values away.
+func CheckUserFilter(role string, filter models.UserFilter) error {
+	allowed := listableFields(role)
+	for _, field := range filterFields(filter) {
+		if !containsString(allowed, field) {
+			return &Error{ErrCodePermissionDenied, fmt.Sprintf("not allowed to filter on the %s field", field)}
+		}
+	}
+
+	// The status cannot be selected, it only comes with the whole user
+	if filter.Status != "" && len(allowed) != len(UserFields) {
+		return &Error{ErrCodePermissionDenied, "not allowed to filter on the status field"}
+	}
+
+	return nil
+}
+
+// filterFields returns the user fields filter has conditions on.
+func filterFields(filter models.UserFilter) []string {
+	var fields []string
+	if len(filter.IDs) > 0 || filter.AfterID != "" {
+		fields = append(fields, "id")
+	}
+	if filter.Name != "" {
+		fields = append(fields, "name")
+	}
+	if filter.MinAge != nil || filter.MaxAge != nil {
+		fields = append(fields, "age")
+	}
+	if filter.Email != "" {
+		fields = append(fields, "email")
+	}
+	if filter.City != "" || filter.Country != "" || filter.Near != nil {
+		fields = append(fields, "address")
+	}
+	if len(filter.Attributes) > 0 || filter.Sort != "" {
+		fields = append(fields, "attributes")
+	}
+	if filter.Group != "" {
+		fields = append(fields, "groups")
+	}
+
+	return fields
+}
+
+// userProjection returns the Mongo projection selecting fields, or nil for every field.
+func userProjection(fields []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	projection := bson.M{"_id": 0}
+	for _, field := range fields {
+		if field == "id" {
+			projection["_id"] = 1
+			continue
+		}
+		projection[field] = 1
+	}
+
+	return projection
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"go_crud/models"
+)
+
+func TestUserReadFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  string
+		query string
+		want  []string
+		code  string
+	}{
+		{"admin", models.RoleAdmin, "", nil, ""},
+		{"unauthenticated", "", "", []string{"id", "name"}, ""},
+		{"selected", models.RoleAdmin, "name, id,name", []string{"name", "id"}, ""},
+		{"role default", models.RoleUser, "", []string{"id", "name", "email"}, ""},
+		{"unknown role", "guest", "", []string{"id", "name"}, ""},
+		{"unknown field", models.RoleAdmin, "id,password", nil, ErrCodeInvalid},
+		{"unauthenticated hidden field", "", "email", nil, ErrCodePermissionDenied},
+		{"hidden field", models.RoleUser, "id,address", nil, ErrCodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := UserReadFields(tt.role, tt.query)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, ErrorCode(err))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, fields)
+		})
+	}
+}
+
+func TestUserListFields(t *testing.T) {
+	fields, err := UserListFields(models.RoleAdmin, "")
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+
+	// Users only read the public fields of the other users of a list
+	fields, err = UserListFields(models.RoleUser, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name"}, fields)
+
+	_, err = UserListFields(models.RoleUser, "id,email")
+	assert.Equal(t, ErrCodePermissionDenied, ErrorCode(err))
+}
+
+func TestCheckUserFilter(t *testing.T) {
+	filter := models.UserFilter{City: "Amsterdam", Near: &models.GeoNear{Radius: 500}}
+	assert.NoError(t, CheckUserFilter(models.RoleAdmin, filter))
+	assert.NoError(t, CheckUserFilter(models.RoleUser, models.UserFilter{}))
+
+	err := CheckUserFilter(models.RoleUser, filter)
+	assert.Equal(t, ErrCodePermissionDenied, ErrorCode(err))
+	assert.EqualError(t, err, "not allowed to filter on the address field")
+
+	assert.NoError(t, CheckUserFilter(models.RoleUser, models.UserFilter{Name: "jane", IDs: []string{"6ad5bbd9d51f80a6d3792dc9"}}))
+	minAge := 18
+	err = CheckUserFilter(models.RoleUser, models.UserFilter{MinAge: &minAge})
+	assert.EqualError(t, err, "not allowed to filter on the age field")
+	err = CheckUserFilter(models.RoleUser, models.UserFilter{Email: "jane@example.com"})
+	assert.EqualError(t, err, "not allowed to filter on the email field")
+
+	err = CheckUserFilter(models.RoleUser, models.UserFilter{Sort: "-hired"})
+	assert.EqualError(t, err, "not allowed to filter on the attributes field")
+
+	err = CheckUserFilter(models.RoleUser, models.UserFilter{Group: "6ad5bbd9d51f80a6d3792dc9"})
+	assert.EqualError(t, err, "not allowed to filter on the groups field")
+
+	assert.NoError(t, CheckUserFilter(models.RoleService, models.UserFilter{Status: models.UserStatusSuspended}))
+	err = CheckUserFilter(models.RoleUser, models.UserFilter{Status: models.UserStatusSuspended})
+	assert.EqualError(t, err, "not allowed to filter on the status field")
+}
+
+func TestUserProjection(t *testing.T) {
+	assert.Nil(t, userProjection(nil))
+	assert.Equal(t, bson.M{"_id": 0, "name": 1}, userProjection([]string{"name"}))
+	assert.Equal(t, bson.M{"_id": 1, "email": 1}, userProjection([]string{"id", "email"}))
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go_crud/models"
+)
+
+// importRow is a user read from an import file, or the reason it could not be read.
+type importRow struct {
+	Row  int
+	User models.CreateUserRequest
+	Err  error
+}
+
+// importReader reads users from an import file one row at a time. Next
+// returns io.EOF once every row has been read.
+type importReader interface {
+	Next() (*importRow, error)
+}
+
+func newImportReader(r io.Reader, opts models.ImportUsersOptions) (importReader, error) {
+	switch opts.Format {
+	case models.ImportFormatCSV:
+		return newCSVImportReader(r, opts.Columns)
+	case models.ImportFormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	case "":
+		return nil, &Error{ErrCodeInvalid, "the import format could not be detected, set it to csv or ndjson"}
+	default:
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unsupported import format %q", opts.Format)}
+	}
+}
+
+// importFields are the CreateUserRequest fields a column can be mapped to.
+var importFields = map[string]bool{"name": true, "age": true, "email": true, "password": true, "address": true}
+
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVImportReader(r io.Reader, mapping map[string]string) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &Error{ErrCodeInvalid, "the import file is empty"}
+	}
+	if err != nil {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("could not read the header row: %s", err)}
+	}
+
+	// Map every column to a field, unknown columns are ignored
+	columns := make([]string, len(header))
+	known := 0
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if field, ok := mapping[name]; ok {
+			name = field
+		}
+
+		name = strings.ToLower(name)
+		if importFields[name] {
+			columns[i] = name
+			known++
+		}
+	}
+
+	if known == 0 {
+		return nil, &Error{ErrCodeInvalid, "the header row has no columns matching user fields"}
+	}
+
+	return &csvImportReader{reader, columns}, nil
+}
+
+func (c *csvImportReader) Next() (*importRow, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &importRow{Row: parseErr.StartLine, Err: &Error{ErrCodeInvalid, parseErr.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := &importRow{Row: line}
+
+	for i, value := range record {
+		if i >= len(c.columns) {
+			break
+		}
+
+		value = strings.TrimSpace(value)
+		switch c.columns[i] {
+		case "name":
+			row.User.Name = value
+		case "age":
+			if value == "" {
+				continue
+			}
+			age, err := strconv.Atoi(value)
+			if err != nil {
+				row.Err = &Error{ErrCodeInvalid, fmt.Sprintf("age %q is not a number", value)}
+				continue
+			}
+			row.User.Age = &age
+		case "email":
+			row.User.Email = value
+		case "password":
+			row.User.Password = value
+		case "address":
+			if value != "" {
+				row.User.Address = &models.Address{Formatted: value}
+			}
+		}
+	}
+
+	return row, nil
+}
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonImportReader) Next() (*importRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+
+		data := bytes.TrimSpace(n.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &importRow{Row: n.line}
+		if err := json.Unmarshal(data, &row.User); err != nil {
+			row.Err = &Error{ErrCodeInvalid, err.Error()}
+		}
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
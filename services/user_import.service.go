@@ -0,0 +1,11 @@
+package services
+
+import (
+	"io"
+
+	"go_crud/models"
+)
+
+type UserImportService interface {
+	ImportUsers(r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersReport, error)
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go_crud/models"
+	"go_crud/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultImportChunkSize = 500
+
+type UserImportServiceImpl struct {
+	userCollection    Collection
+	ctx               context.Context
+	hashWorkers       int
+	emailVerification EmailVerificationService
+	attributes        AttributeService
+}
+
+// NewUserImportService creates the import service. Like the user service,
+// it sends created users a verification email through emailVerification,
+// which may be nil, and checks custom attributes against the definitions
+// of attributes.
+func NewUserImportService(userCollection Collection, ctx context.Context, hashWorkers int, emailVerification EmailVerificationService, attributes AttributeService) UserImportService {
+	return &UserImportServiceImpl{userCollection, ctx, hashWorkers, emailVerification, attributes}
+}
+
+// userImport holds the state of a single import run.
+type userImport struct {
+	opts   models.ImportUsersOptions
+	report *models.ImportUsersReport
+	// seen holds the emails of earlier rows, so dry runs can report
+	// duplicates within the file
+	seen map[string]bool
+	// definitions are the custom attributes rows are checked against
+	definitions map[string]*models.Attribute
+}
+
+// ImportUsers reads users from r and commits them in chunks. Rows that fail
+// validation or cannot be written are listed in the report, the remaining
+// rows are still imported. If an error stops the import part way through,
+// the report of the chunks committed so far is returned along with it.
+func (p *UserImportServiceImpl) ImportUsers(r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersReport, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = models.ImportOnDuplicateSkip
+	}
+	if opts.OnDuplicate != models.ImportOnDuplicateSkip && opts.OnDuplicate != models.ImportOnDuplicateUpsert {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("onDuplicate must be %q or %q", models.ImportOnDuplicateSkip, models.ImportOnDuplicateUpsert)}
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultImportChunkSize
+	}
+
+	reader, err := newImportReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := attributeDefinitions(p.attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &userImport{
+		opts:        opts,
+		report:      &models.ImportUsersReport{DryRun: opts.DryRun, Rows: []models.ImportRowResult{}},
+		seen:        map[string]bool{},
+		definitions: definitions,
+	}
+
+	var chunk []*importRow
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return run.report, err
+		}
+
+		run.report.Total++
+		if row.Err == nil {
+			row.Err = validate(&row.User)
+		}
+		if row.Err == nil {
+			row.User.Attributes, row.Err = checkAttributes(run.definitions, row.User.Attributes, true)
+		}
+		if row.Err == nil {
+			row.Err = checkPassword(row.User.Password, row.User.Email, row.User.Name)
+		}
+		if row.Err != nil {
+			run.record(row, models.ImportRowFailed, row.Err)
+			continue
+		}
+
+		chunk = append(chunk, row)
+		if len(chunk) == opts.ChunkSize {
+			if err := p.importChunk(run, chunk); err != nil {
+				return run.report, err
+			}
+			chunk = nil
+		}
+	}
+
+	if len(chunk) > 0 {
+		if err := p.importChunk(run, chunk); err != nil {
+			return run.report, err
+		}
+	}
+
+	return run.report, nil
+}
+
+func (p *UserImportServiceImpl) importChunk(run *userImport, chunk []*importRow) error {
+	if run.opts.DryRun {
+		return p.checkChunk(run, chunk)
+	}
+
+	passwords := make([]string, len(chunk))
+	for n, row := range chunk {
+		passwords[n] = row.User.Password
+	}
+	hashes, errs := utils.HashPasswords(passwords, p.hashWorkers)
+
+	var rows []*importRow
+	var users []*models.DBUser
+	var writes []mongo.WriteModel
+	for n, row := range chunk {
+		if errs[n] != nil {
+			run.record(row, models.ImportRowFailed, errs[n])
+			continue
+		}
+
+		user := newDBUser(&row.User, hashes[n])
+		user.Id = primitive.NewObjectID()
+		if run.opts.OnDuplicate == models.ImportOnDuplicateUpsert {
+			update, err := importUpsert(user)
+			if err != nil {
+				run.record(row, models.ImportRowFailed, err)
+				continue
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"email": user.Email}).
+				SetUpdate(update).
+				SetUpsert(true))
+		} else {
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(user))
+		}
+		rows = append(rows, row)
+		users = append(users, user)
+	}
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	failed := map[int]error{}
+	res, err := p.userCollection.BulkWrite(p.ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			return err
+		}
+
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = bulkWriteError(writeErr)
+		}
+	}
+
+	for n, row := range rows {
+		switch err := failed[n]; {
+		case err == nil && run.opts.OnDuplicate == models.ImportOnDuplicateUpsert:
+			if _, inserted := res.UpsertedIDs[int64(n)]; inserted {
+				run.record(row, models.ImportRowCreated, nil)
+				sendVerification(p.emailVerification, users[n])
+			} else {
+				run.record(row, models.ImportRowUpdated, nil)
+			}
+		case err == nil:
+			run.record(row, models.ImportRowCreated, nil)
+			sendVerification(p.emailVerification, users[n])
+		case ErrorCode(err) == ErrCodeAlreadyExists:
+			run.record(row, models.ImportRowSkipped, err)
+		default:
+			run.record(row, models.ImportRowFailed, err)
+		}
+	}
+
+	return nil
+}
+
+// importUpsert returns the update that upserts user by email. Existing
+// users only get their profile updated: they keep their password, status
+// and verified email, so an import cannot take over their account. The
+// attributes of the row are set one by one, the others are kept.
+func importUpsert(user *models.DBUser) (bson.M, error) {
+	doc, err := utils.ToDoc(user)
+	if err != nil {
+		return nil, err
+	}
+
+	set, setOnInsert := bson.D{}, bson.D{}
+	for _, e := range *doc {
+		switch e.Key {
+		case "email":
+			// Inserted from the filter
+		case "name", "age", "address":
+			set = append(set, e)
+		case "attributes":
+			// Set below
+		default:
+			setOnInsert = append(setOnInsert, e)
+		}
+	}
+	set = append(set, attributesDoc(user.Attributes, false)...)
+
+	return bson.M{"$set": set, "$setOnInsert": setOnInsert}, nil
+}
+
+// checkChunk reports what importing chunk would do without writing anything.
+func (p *UserImportServiceImpl) checkChunk(run *userImport, chunk []*importRow) error {
+	emails := make([]string, len(chunk))
+	for n, row := range chunk {
+		emails[n] = row.User.Email
+	}
+
+	opt := options.Find().SetProjection(bson.M{"email": 1})
+	cursor, err := p.userCollection.Find(p.ctx, bson.M{"email": bson.M{"$in": emails}}, opt)
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(p.ctx)
+
+	existing := map[string]bool{}
+	for cursor.Next(p.ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		existing[user.Email] = true
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	for _, row := range chunk {
+		switch {
+		case !existing[row.User.Email] && !run.seen[row.User.Email]:
+			run.record(row, models.ImportRowCreated, nil)
+		case run.opts.OnDuplicate == models.ImportOnDuplicateUpsert:
+			run.record(row, models.ImportRowUpdated, nil)
+		default:
+			run.record(row, models.ImportRowSkipped, ErrEmailExists)
+		}
+	}
+
+	return nil
+}
+
+// record counts the outcome of row and adds it to the report unless it was imported.
+func (run *userImport) record(row *importRow, status string, err error) {
+	report := run.report
+	switch status {
+	case models.ImportRowCreated:
+		report.Created++
+		run.seen[row.User.Email] = true
+	case models.ImportRowUpdated:
+		report.Updated++
+		run.seen[row.User.Email] = true
+	case models.ImportRowSkipped:
+		report.Skipped++
+	case models.ImportRowFailed:
+		report.Failed++
+	}
+
+	if err == nil {
+		return
+	}
+
+	report.Rows = append(report.Rows, models.ImportRowResult{
+		Row:    row.Row,
+		Status: status,
+		Email:  row.User.Email,
+		Error:  &models.BatchItemError{Code: ErrorCode(err), Message: err.Error()},
+	})
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCSVImportReader(t *testing.T) {
+	file := "Full Name,E-mail,Age,Password,Address,Department\n" +
+		"John Doe,john.doe@example.com,30,password123,123 Main St,Sales\n" +
+		"Jane Smith,jane.smith@example.com,abc,password123,456 Oak St,HR\n"
+
+	reader, err := newImportReader(strings.NewReader(file), models.ImportUsersOptions{
+		Format:  models.ImportFormatCSV,
+		Columns: map[string]string{"Full Name": "name", "E-mail": "email"},
+	})
+	assert.NoError(t, err)
+
+	row, err := reader.Next()
+	assert.NoError(t, err)
+	assert.NoError(t, row.Err)
+	assert.Equal(t, 2, row.Row)
+	assert.Equal(t, "John Doe", row.User.Name)
+	assert.Equal(t, "john.doe@example.com", row.User.Email)
+	assert.Equal(t, 30, *row.User.Age)
+	assert.Equal(t, "123 Main St", row.User.Address.String())
+
+	row, err = reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, row.Row)
+	assert.ErrorContains(t, row.Err, "age")
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCSVImportReader_UnknownHeader(t *testing.T) {
+	_, err := newImportReader(strings.NewReader("foo,bar\n1,2\n"), models.ImportUsersOptions{Format: models.ImportFormatCSV})
+
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestNDJSONImportReader(t *testing.T) {
+	file := `{"name": "John Doe", "age": 30, "email": "john.doe@example.com", "password": "password123", "address": "123 Main St"}` +
+		"\n\n" + `{"name": ` + "\n"
+
+	reader, err := newImportReader(strings.NewReader(file), models.ImportUsersOptions{Format: models.ImportFormatNDJSON})
+	assert.NoError(t, err)
+
+	row, err := reader.Next()
+	assert.NoError(t, err)
+	assert.NoError(t, row.Err)
+	assert.Equal(t, 1, row.Row)
+	assert.Equal(t, "John Doe", row.User.Name)
+
+	// Blank lines are skipped but still counted
+	row, err = reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, row.Row)
+	assert.Error(t, row.Err)
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestUserImportServiceImpl_ImportUsers_InvalidRows(t *testing.T) {
+	userImportService := NewUserImportService(&mongo.Collection{}, context.TODO(), 1, nil, nil)
+
+	// Every row misses a required field, so nothing reaches the database
+	file := "name,email,age,password,address\n" +
+		"John Doe,,30,password123,123 Main St\n" +
+		",jane.smith@example.com,28,password123,456 Oak St\n"
+
+	report, err := userImportService.ImportUsers(strings.NewReader(file), models.ImportUsersOptions{Format: models.ImportFormatCSV})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 2, report.Failed)
+	assert.Len(t, report.Rows, 2)
+	assert.Equal(t, 2, report.Rows[0].Row)
+	assert.Equal(t, models.ImportRowFailed, report.Rows[0].Status)
+	assert.Equal(t, ErrCodeInvalid, report.Rows[0].Error.Code)
+	assert.Equal(t, "jane.smith@example.com", report.Rows[1].Email)
+}
+
+type MockAttributeService struct {
+	AttributeService
+	attributes []*models.Attribute
+}
+
+func (m *MockAttributeService) FindAttributes() ([]*models.Attribute, error) {
+	return m.attributes, nil
+}
+
+func TestUserImportServiceImpl_ImportUsers_InvalidAttributes(t *testing.T) {
+	attributes := &MockAttributeService{attributes: []*models.Attribute{
+		{Name: "department", Type: models.AttributeTypeString, Required: true},
+		{Name: "seniority", Type: models.AttributeTypeNumber},
+	}}
+	userImportService := NewUserImportService(&mongo.Collection{}, context.TODO(), 1, nil, attributes)
+
+	file := `{"name": "John Doe", "email": "john.doe@example.com", "age": 30, "address": "123 Main St", "password": "password123", "attributes": {"team": "Sales"}}` + "\n" +
+		`{"name": "Jane Smith", "email": "jane.smith@example.com", "age": 28, "address": "456 Oak St", "password": "password123", "attributes": {"department": "HR", "seniority": "high"}}` + "\n" +
+		`{"name": "Jim Brown", "email": "jim.brown@example.com", "age": 40, "address": "789 Pine St", "password": "password123"}` + "\n"
+
+	report, err := userImportService.ImportUsers(strings.NewReader(file), models.ImportUsersOptions{Format: models.ImportFormatNDJSON})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 3, report.Failed)
+	assert.Len(t, report.Rows, 3)
+	assert.Equal(t, `unknown attribute "team"`, report.Rows[0].Error.Message)
+	assert.Equal(t, ErrCodeInvalid, report.Rows[1].Error.Code)
+	assert.Equal(t, "the department attribute is required", report.Rows[2].Error.Message)
+}
+
+func TestUserImportServiceImpl_ImportUsers_Verification(t *testing.T) {
+	file := "name,email,age,password,address\n" +
+		"John Doe,john.doe@example.com,30,password123,123 Main St\n" +
+		"Jane Smith,jane.smith@example.com,28,password123,456 Oak St\n"
+
+	emailVerification := &MockEmailVerificationService{}
+	userImportService := NewUserImportService(&batchCollection{Fail: []int{1}}, context.TODO(), 1, emailVerification, nil)
+
+	report, err := userImportService.ImportUsers(strings.NewReader(file), models.ImportUsersOptions{Format: models.ImportFormatCSV})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Len(t, emailVerification.Sent, 1)
+	assert.Equal(t, "john.doe@example.com", emailVerification.Sent[0].Email)
+	assert.False(t, emailVerification.Sent[0].ID.IsZero())
+
+	// Upserts only send the email to the users they create, not to the
+	// existing users they update
+	emailVerification = &MockEmailVerificationService{}
+	userImportService = NewUserImportService(&batchCollection{Upserted: []int64{1}}, context.TODO(), 1, emailVerification, nil)
+
+	report, err = userImportService.ImportUsers(strings.NewReader(file), models.ImportUsersOptions{Format: models.ImportFormatCSV, OnDuplicate: models.ImportOnDuplicateUpsert})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Len(t, emailVerification.Sent, 1)
+	assert.Equal(t, "jane.smith@example.com", emailVerification.Sent[0].Email)
+}
+
+func TestUserImportServiceImpl_ImportUsers_BadOptions(t *testing.T) {
+	userImportService := NewUserImportService(&mongo.Collection{}, context.TODO(), 1, nil, nil)
+
+	_, err := userImportService.ImportUsers(strings.NewReader(""), models.ImportUsersOptions{Format: "xml"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+	_, err = userImportService.ImportUsers(strings.NewReader(""), models.ImportUsersOptions{Format: models.ImportFormatCSV, OnDuplicate: "merge"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
+
+func TestImportUpsert(t *testing.T) {
+	user := newDBUser(&models.CreateUserRequest{
+		Name:     "John Doe",
+		Age:      intPointer(30),
+		Email:    "john.doe@example.com",
+		Password: "password123",
+		Address:  &models.Address{Formatted: "123 Main St"},
+	}, "hash")
+
+	update, err := importUpsert(user)
+	assert.NoError(t, err)
+
+	set := update["$set"].(bson.D).Map()
+	assert.Equal(t, "John Doe", set["name"])
+	assert.NotContains(t, set, "email")
+	// An existing account keeps its password and status
+	assert.NotContains(t, set, "password")
+	assert.NotContains(t, set, "status")
+
+	setOnInsert := update["$setOnInsert"].(bson.D).Map()
+	assert.Equal(t, "hash", setOnInsert["password"])
+	assert.Equal(t, models.UserStatusPending, setOnInsert["status"])
+	assert.Equal(t, false, setOnInsert["email_verified"])
+	assert.NotContains(t, setOnInsert, "attributes")
+}
+
+func TestImportUpsert_Attributes(t *testing.T) {
+	user := newDBUser(&models.CreateUserRequest{
+		Name:       "John Doe",
+		Email:      "john.doe@example.com",
+		Attributes: map[string]interface{}{"department": "Sales"},
+	}, "hash")
+
+	update, err := importUpsert(user)
+	assert.NoError(t, err)
+
+	// Only the attributes of the row are set, an existing user keeps the others
+	set := update["$set"].(bson.D).Map()
+	assert.Equal(t, "Sales", set["attributes.department"])
+	assert.NotContains(t, set, "attributes")
+	assert.NotContains(t, update["$setOnInsert"].(bson.D).Map(), "attributes")
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go_crud/models"
+)
+
+// patchFields are the user fields a patch can change. Fields marked true
+// are optional and can be removed.
+var patchFields = map[string]bool{"name": false, "age": true, "email": false, "password": false, "address": true, "attributes": true}
+
+// ParseUserMergePatch turns an RFC 7396 merge patch into a UserPatch. A null
+// member removes the field, members left out are not changed. The custom
+// attributes are merged the same way, one by one.
+func ParseUserMergePatch(body []byte) (*models.UserPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return nil, &Error{ErrCodeInvalid, "the merge patch must be a JSON object"}
+	}
+
+	patch := &models.UserPatch{}
+	set := map[string]json.RawMessage{}
+	for name, value := range members {
+		optional, ok := patchFields[name]
+		if !ok {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("unknown field %q", name)}
+		}
+
+		if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			set[name] = value
+			continue
+		}
+		if !optional {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("%s is required and cannot be removed", name)}
+		}
+		patch.Unset = append(patch.Unset, name)
+	}
+
+	data, _ := json.Marshal(set)
+	if err := json.Unmarshal(data, &patch.Set); err != nil {
+		return nil, &Error{ErrCodeInvalid, err.Error()}
+	}
+
+	// Empty strings would be dropped from the update, so reject them or,
+	// for the address, treat them and empty objects as removing it
+	for name := range set {
+		empty := false
+		switch name {
+		case "name":
+			empty = patch.Set.Name == ""
+		case "email":
+			empty = patch.Set.Email == ""
+		case "password":
+			empty = patch.Set.Password == ""
+		case "address":
+			if patch.Set.Address.IsZero() {
+				patch.Set.Address = nil
+				patch.Unset = append(patch.Unset, name)
+			}
+		case "attributes":
+			for attribute, value := range patch.Set.Attributes {
+				if value == nil {
+					delete(patch.Set.Attributes, attribute)
+					patch.Unset = append(patch.Unset, "attributes."+attribute)
+				}
+			}
+		}
+
+		if empty {
+			return nil, &Error{ErrCodeInvalid, fmt.Sprintf("%s cannot be empty", name)}
+		}
+	}
+
+	sort.Strings(patch.Unset)
+	return patch, nil
+}
+
+// replaceUserPatch returns the patch that replaces every field of a user
+// with the fields of r. The password is only changed if r has one, and the
+// custom attributes if r has any.
+func replaceUserPatch(r *models.ReplaceUserRequest) *models.UserPatch {
+	patch := &models.UserPatch{Set: models.UpdateUser{
+		Name:     r.Name,
+		Age:      r.Age,
+		Email:    r.Email,
+		Password: r.Password,
+		Address:  r.Address,
+	}}
+
+	if r.Age == nil {
+		patch.Unset = append(patch.Unset, "age")
+	}
+	if r.Address.IsZero() {
+		patch.Set.Address = nil
+		patch.Unset = append(patch.Unset, "address")
+	}
+	if r.Attributes != nil {
+		patch.ReplaceAttributes = true
+		if len(r.Attributes) > 0 {
+			patch.Set.Attributes = r.Attributes
+		} else {
+			patch.Unset = append(patch.Unset, "attributes")
+		}
+	}
+
+	return patch
+}
+
+// userPatchDocument is the JSON document JSON Patch operations are applied
+// to. Fields the user does not have are left out and the password, which
+// can only be added, is never included.
+func userPatchDocument(user *models.User) map[string]interface{} {
+	doc := map[string]interface{}{"name": user.Name, "email": user.Email}
+	if user.Age != nil {
+		doc["age"] = float64(*user.Age)
+	}
+	if user.Address != nil {
+		// Decoded like the JSON it was read from, so paths such as
+		// /address/city can be patched
+		var address map[string]interface{}
+		data, _ := json.Marshal(user.Address)
+		_ = json.Unmarshal(data, &address)
+		doc["address"] = address
+	}
+	if len(user.Attributes) > 0 {
+		var attributes map[string]interface{}
+		data, _ := json.Marshal(user.Attributes)
+		_ = json.Unmarshal(data, &attributes)
+		doc["attributes"] = attributes
+	}
+
+	return doc
+}
+
+// userPatchFromDocument checks a patched user document and returns the patch
+// that replaces the user with it.
+func userPatchFromDocument(doc interface{}) (*models.UserPatch, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.ReplaceUserRequest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&user); err != nil {
+		return nil, &Error{ErrCodeInvalid, fmt.Sprintf("the patched user is invalid: %s", err)}
+	}
+
+	if err := validate(&user); err != nil {
+		return nil, err
+	}
+	// Attributes left out of the document were removed
+	if user.Attributes == nil {
+		user.Attributes = map[string]interface{}{}
+	}
+
+	return replaceUserPatch(&user), nil
+}
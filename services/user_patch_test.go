@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+)
+
+func TestParseUserMergePatch(t *testing.T) {
+	patch, err := ParseUserMergePatch([]byte(`{"name": "Jane", "age": null, "address": ""}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", patch.Set.Name)
+	assert.Nil(t, patch.Set.Age)
+	assert.Equal(t, []string{"address", "age"}, patch.Unset)
+
+	patch, err = ParseUserMergePatch([]byte(`{"address": {"city": "Springfield", "country": "US"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Address{City: "Springfield", Country: "US"}, patch.Set.Address)
+	assert.Empty(t, patch.Unset)
+
+	patch, err = ParseUserMergePatch([]byte(`{"address": {}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, patch.Set.Address)
+	assert.Equal(t, []string{"address"}, patch.Unset)
+
+	patch, err = ParseUserMergePatch([]byte(`{"attributes": {"department": "HR", "costCenter": null}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"department": "HR"}, patch.Set.Attributes)
+	assert.Equal(t, []string{"attributes.costCenter"}, patch.Unset)
+	assert.False(t, patch.ReplaceAttributes)
+}
+
+func TestParseUserMergePatch_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not an object", `[]`},
+		{"null", `null`},
+		{"unknown field", `{"nickname": "JD"}`},
+		{"required field removed", `{"email": null}`},
+		{"empty name", `{"name": ""}`},
+		{"invalid age", `{"age": "thirty"}`},
+		{"invalid address", `{"address": 12}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseUserMergePatch([]byte(tt.body))
+			assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+		})
+	}
+}
+
+func TestUserPatchFromDocument(t *testing.T) {
+	user := &models.User{Name: "John Doe", Age: intPointer(30), Email: "john.doe@example.com"}
+
+	doc := userPatchDocument(user)
+	assert.Equal(t, map[string]interface{}{"name": "John Doe", "age": float64(30), "email": "john.doe@example.com"}, doc)
+
+	delete(doc, "age")
+	doc["address"] = "123 Main St"
+	doc["password"] = "password123"
+
+	patch, err := userPatchFromDocument(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, models.UpdateUser{
+		Name:     "John Doe",
+		Email:    "john.doe@example.com",
+		Password: "password123",
+		Address:  &models.Address{Formatted: "123 Main St"},
+	}, patch.Set)
+	// The user has no attributes, so the document has none to keep
+	assert.Equal(t, []string{"age", "attributes"}, patch.Unset)
+	assert.True(t, patch.ReplaceAttributes)
+
+	doc["id"] = "123"
+	_, err = userPatchFromDocument(doc)
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+
+	_, err = userPatchFromDocument(map[string]interface{}{"name": "John Doe"})
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(err))
+}
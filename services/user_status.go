@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrUserDisabled = &Error{ErrCodePermissionDenied, "the account is disabled"}
+
+// userStatusTransitions lists the statuses each status can change to.
+// Suspending a suspended user replaces the reason and end of the suspension.
+var userStatusTransitions = map[string][]string{
+	models.UserStatusPending:   {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDisabled},
+	models.UserStatusActive:    {models.UserStatusSuspended, models.UserStatusDisabled},
+	models.UserStatusSuspended: {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDisabled},
+	models.UserStatusDisabled:  {models.UserStatusActive},
+}
+
+// userStatus returns status, or active for users without one.
+func userStatus(status string) string {
+	if status == "" {
+		return models.UserStatusActive
+	}
+
+	return status
+}
+
+// checkUserStatusFilter checks the status users are filtered by.
+func checkUserStatusFilter(status string) error {
+	if _, ok := userStatusTransitions[status]; status != "" && !ok {
+		return &Error{ErrCodeInvalid, fmt.Sprintf("unknown status %q, use pending, active, suspended or disabled", status)}
+	}
+
+	return nil
+}
+
+// userStatusesTo returns the statuses that can change to status.
+func userStatusesTo(status string) []string {
+	var from []string
+	for _, s := range []string{models.UserStatusPending, models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDisabled} {
+		if containsString(userStatusTransitions[s], status) {
+			from = append(from, s)
+		}
+	}
+
+	return from
+}
+
+// userStatusQuery returns the query value matching users with any of
+// statuses, which includes users without a status for active.
+func userStatusQuery(statuses ...string) bson.M {
+	values := bson.A{}
+	for _, status := range statuses {
+		values = append(values, status)
+		if status == models.UserStatusActive {
+			values = append(values, nil)
+		}
+	}
+
+	return bson.M{"$in": values}
+}
+
+// checkUserStatus returns why a user with status cannot sign in at now,
+// nil when they can. Suspensions that ended count as active even before
+// they are lifted.
+func checkUserStatus(status string, suspendedUntil *time.Time, now time.Time) error {
+	switch status {
+	case models.UserStatusSuspended:
+		if suspendedUntil == nil {
+			return &Error{ErrCodePermissionDenied, "the account is suspended"}
+		}
+		if suspendedUntil.After(now) {
+			return &Error{ErrCodePermissionDenied, "the account is suspended until " + suspendedUntil.UTC().Format(time.RFC3339)}
+		}
+	case models.UserStatusDisabled:
+		return ErrUserDisabled
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"time"
+
+	"go_crud/models"
+)
+
+// UserStatusService moves users between the statuses of their account.
+// Suspended and disabled users cannot sign in, and are signed out.
+type UserStatusService interface {
+	SuspendUser(id string, req *models.SuspendUserRequest) (*models.User, error)
+	DisableUser(id string, req *models.DisableUserRequest) (*models.User, error)
+	// ReactivateUser makes a pending, suspended or disabled user active
+	ReactivateUser(id string) (*models.User, error)
+	// ReactivateExpired lifts the suspensions that ended, returning how
+	// many users were reactivated
+	ReactivateExpired() (int64, error)
+	// RunReactivation calls ReactivateExpired every interval, until the
+	// context of the service is done
+	RunReactivation(interval time.Duration)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserStatusServiceImpl struct {
+	userCollection    Collection
+	sessionCollection Collection
+	ctx               context.Context
+}
+
+func NewUserStatusService(userCollection Collection, sessionCollection Collection, ctx context.Context) UserStatusService {
+	err := createIndexes(ctx, userCollection, mongo.IndexModel{
+		Keys:    bson.D{{Key: "status", Value: 1}, {Key: "suspended_until", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &UserStatusServiceImpl{userCollection, sessionCollection, ctx}
+}
+
+func (p *UserStatusServiceImpl) SuspendUser(id string, req *models.SuspendUserRequest) (*models.User, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, &Error{ErrCodeInvalid, "a suspension needs a reason"}
+	}
+
+	set := bson.M{"status_reason": reason}
+	unset := bson.M{}
+	if req.Until != nil {
+		if !req.Until.After(time.Now()) {
+			return nil, &Error{ErrCodeInvalid, "until must be in the future"}
+		}
+		set["suspended_until"] = req.Until.UTC()
+	} else {
+		unset["suspended_until"] = ""
+	}
+
+	return p.changeStatus(id, models.UserStatusSuspended, set, unset)
+}
+
+func (p *UserStatusServiceImpl) DisableUser(id string, req *models.DisableUserRequest) (*models.User, error) {
+	set := bson.M{}
+	unset := bson.M{"suspended_until": ""}
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		set["status_reason"] = reason
+	} else {
+		unset["status_reason"] = ""
+	}
+
+	return p.changeStatus(id, models.UserStatusDisabled, set, unset)
+}
+
+func (p *UserStatusServiceImpl) ReactivateUser(id string) (*models.User, error) {
+	return p.changeStatus(id, models.UserStatusActive, bson.M{}, bson.M{"status_reason": "", "suspended_until": ""})
+}
+
+// changeStatus moves the user with id to status, if their current status
+// allows it, writing set and unset along.
+func (p *UserStatusServiceImpl) changeStatus(id string, status string, set bson.M, unset bson.M) (*models.User, error) {
+	obId, _ := primitive.ObjectIDFromHex(id)
+	set["status"] = status
+
+	query := bson.M{"_id": obId, "status": userStatusQuery(userStatusesTo(status)...)}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user *models.User
+	if err := p.userCollection.FindOneAndUpdate(p.ctx, query, update, opt).Decode(&user); err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		if err := p.userCollection.FindOne(p.ctx, bson.M{"_id": obId}).Decode(&user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+		return nil, &Error{ErrCodeFailedPrecondition, fmt.Sprintf("a %s user cannot become %s", userStatus(user.Status), status)}
+	}
+
+	if status == models.UserStatusSuspended || status == models.UserStatusDisabled {
+		if _, err := p.sessionCollection.DeleteMany(p.ctx, bson.M{"userId": obId}); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (p *UserStatusServiceImpl) ReactivateExpired() (int64, error) {
+	query := bson.M{"status": models.UserStatusSuspended, "suspended_until": bson.M{"$lte": time.Now().UTC()}}
+	update := bson.M{
+		"$set":   bson.M{"status": models.UserStatusActive},
+		"$unset": bson.M{"status_reason": "", "suspended_until": ""},
+	}
+
+	res, err := p.userCollection.UpdateMany(p.ctx, query, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+func (p *UserStatusServiceImpl) RunReactivation(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := p.ReactivateExpired(); err != nil {
+				log.Printf("could not reactivate the users whose suspension ended: %v", err)
+			}
+		}
+	}()
+}
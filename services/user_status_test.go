@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserStatusesTo(t *testing.T) {
+	assert.Equal(t, []string{models.UserStatusPending, models.UserStatusSuspended, models.UserStatusDisabled}, userStatusesTo(models.UserStatusActive))
+	assert.Equal(t, []string{models.UserStatusPending, models.UserStatusActive, models.UserStatusSuspended}, userStatusesTo(models.UserStatusSuspended))
+	assert.Equal(t, []string{models.UserStatusPending, models.UserStatusActive, models.UserStatusSuspended}, userStatusesTo(models.UserStatusDisabled))
+	// Nothing goes back to pending
+	assert.Empty(t, userStatusesTo(models.UserStatusPending))
+}
+
+func TestUserStatusQuery(t *testing.T) {
+	assert.Equal(t, bson.M{"$in": bson.A{models.UserStatusPending, models.UserStatusActive, nil}}, userStatusQuery(models.UserStatusPending, models.UserStatusActive))
+	assert.Equal(t, bson.M{"$in": bson.A{models.UserStatusDisabled}}, userStatusQuery(models.UserStatusDisabled))
+}
+
+func TestCheckUserStatusFilter(t *testing.T) {
+	assert.NoError(t, checkUserStatusFilter(""))
+	assert.NoError(t, checkUserStatusFilter(models.UserStatusSuspended))
+	assert.Equal(t, ErrCodeInvalid, ErrorCode(checkUserStatusFilter("banned")))
+}
+
+func TestCheckUserStatus(t *testing.T) {
+	now := time.Now().UTC()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	assert.NoError(t, checkUserStatus("", nil, now))
+	assert.NoError(t, checkUserStatus(models.UserStatusPending, nil, now))
+	assert.NoError(t, checkUserStatus(models.UserStatusActive, nil, now))
+
+	err := checkUserStatus(models.UserStatusSuspended, &later, now)
+	assert.Equal(t, ErrCodePermissionDenied, ErrorCode(err))
+	assert.Contains(t, err.Error(), later.Format(time.RFC3339))
+	assert.Equal(t, ErrCodePermissionDenied, ErrorCode(checkUserStatus(models.UserStatusSuspended, nil, now)))
+	// The suspension ended, it just has not been lifted yet
+	assert.NoError(t, checkUserStatus(models.UserStatusSuspended, &earlier, now))
+
+	assert.Equal(t, ErrUserDisabled, checkUserStatus(models.UserStatusDisabled, nil, now))
+}
+
+func TestSCIMUserActive(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Email: "jane@example.com"}
+	assert.True(t, *scimUser(user, "").Active)
+
+	user.Status = models.UserStatusSuspended
+	assert.False(t, *scimUser(user, "").Active)
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+
+	"go_crud/models"
+)
+
+type UserStreamService interface {
+	Watch(ctx context.Context, lastEventID string) (<-chan models.UserEvent, error)
+}
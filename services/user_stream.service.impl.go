@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go_crud/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes returned when a change stream cannot be opened or resumed.
+const (
+	errCodeChangeStreamNotSupported = 40573
+	errCodeInvalidResumeToken       = 260
+	errCodeChangeStreamFatal        = 280
+	errCodeChangeStreamHistoryLost  = 286
+)
+
+type UserStreamServiceImpl struct {
+	userCollection Collection
+	pollInterval   time.Duration
+}
+
+func NewUserStreamService(userCollection Collection, pollInterval time.Duration) UserStreamService {
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	return &UserStreamServiceImpl{userCollection, pollInterval}
+}
+
+// changeEvent is the subset of a change stream document we care about.
+type changeEvent struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *models.User `bson:"fullDocument"`
+}
+
+// Watch returns a channel of user changes which is closed when ctx is done.
+// It uses a change stream on the users collection and falls back to polling
+// when change streams are not available (e.g. on a standalone server).
+func (s *UserStreamServiceImpl) Watch(ctx context.Context, lastEventID string) (<-chan models.UserEvent, error) {
+	events := make(chan models.UserEvent)
+
+	stream, err := s.openChangeStream(ctx, lastEventID)
+	if err != nil && lastEventID != "" && isServerError(err, errCodeInvalidResumeToken, errCodeChangeStreamFatal, errCodeChangeStreamHistoryLost) {
+		// The token can no longer be resumed from, start over and tell the
+		// subscriber to refetch everything it may have missed.
+		stream, err = s.openChangeStream(ctx, "")
+		if err == nil {
+			go s.forwardChangeStream(ctx, stream, events, true)
+			return events, nil
+		}
+	}
+
+	if err != nil {
+		if isServerError(err, errCodeChangeStreamNotSupported) {
+			go s.poll(ctx, events)
+			return events, nil
+		}
+		return nil, err
+	}
+
+	go s.forwardChangeStream(ctx, stream, events, false)
+	return events, nil
+}
+
+func (s *UserStreamServiceImpl) openChangeStream(ctx context.Context, lastEventID string) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+		{{Key: "$project", Value: bson.M{"fullDocument.password": 0}}},
+	}
+
+	opt := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if lastEventID != "" {
+		opt.SetResumeAfter(bson.M{"_data": lastEventID})
+	}
+
+	return s.userCollection.Watch(ctx, pipeline, opt)
+}
+
+func (s *UserStreamServiceImpl) forwardChangeStream(ctx context.Context, stream *mongo.ChangeStream, events chan<- models.UserEvent, reset bool) {
+	defer close(events)
+	defer stream.Close(context.Background())
+
+	if reset && !sendUserEvent(ctx, events, models.UserEvent{Type: models.UserEventReset, Time: time.Now()}) {
+		return
+	}
+
+	for stream.Next(ctx) {
+		var change changeEvent
+		if err := stream.Decode(&change); err != nil {
+			return
+		}
+
+		event := models.UserEvent{
+			ID:     stream.ResumeToken().Lookup("_data").StringValue(),
+			UserID: change.DocumentKey.ID.Hex(),
+			Time:   time.Unix(int64(change.ClusterTime.T), 0).UTC(),
+		}
+
+		switch change.OperationType {
+		case "insert":
+			event.Type = models.UserEventCreated
+			event.User = change.FullDocument
+		case "update", "replace":
+			event.Type = models.UserEventUpdated
+			event.User = change.FullDocument
+		case "delete":
+			event.Type = models.UserEventDeleted
+		default:
+			continue
+		}
+
+		if !sendUserEvent(ctx, events, event) {
+			return
+		}
+	}
+}
+
+// poll diffs the users collection against an in-memory snapshot on every
+// tick. Polled events cannot be resumed, so they carry no resume token.
+func (s *UserStreamServiceImpl) poll(ctx context.Context, events chan<- models.UserEvent) {
+	defer close(events)
+
+	snapshot, err := s.snapshot(ctx)
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	var seq int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		next, err := s.snapshot(ctx)
+		if err != nil {
+			return
+		}
+
+		for _, event := range diffUserSnapshots(snapshot, next, time.Now().UTC()) {
+			seq++
+			event.ID = fmt.Sprintf("poll-%d", seq)
+			if !sendUserEvent(ctx, events, event) {
+				return
+			}
+		}
+
+		snapshot = next
+	}
+}
+
+func (s *UserStreamServiceImpl) snapshot(ctx context.Context) (map[primitive.ObjectID]models.User, error) {
+	opt := options.Find().SetProjection(bson.M{"password": 0})
+
+	cursor, err := s.userCollection.Find(ctx, bson.M{}, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	users := map[primitive.ObjectID]models.User{}
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users[user.ID] = user
+	}
+
+	return users, cursor.Err()
+}
+
+// diffUserSnapshots returns the events that turn prev into next, ordered by user ID.
+func diffUserSnapshots(prev, next map[primitive.ObjectID]models.User, now time.Time) []models.UserEvent {
+	var events []models.UserEvent
+
+	for id, user := range next {
+		user := user
+		old, found := prev[id]
+		switch {
+		case !found:
+			events = append(events, models.UserEvent{Type: models.UserEventCreated, UserID: id.Hex(), User: &user, Time: now})
+		case !reflect.DeepEqual(old, user):
+			events = append(events, models.UserEvent{Type: models.UserEventUpdated, UserID: id.Hex(), User: &user, Time: now})
+		}
+	}
+
+	for id := range prev {
+		if _, found := next[id]; !found {
+			events = append(events, models.UserEvent{Type: models.UserEventDeleted, UserID: id.Hex(), Time: now})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].UserID < events[j].UserID
+	})
+
+	return events
+}
+
+func sendUserEvent(ctx context.Context, events chan<- models.UserEvent, event models.UserEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func isServerError(err error, codes ...int) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
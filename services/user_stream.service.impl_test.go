@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go_crud/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffUserSnapshots(t *testing.T) {
+	kept := primitive.NewObjectID()
+	changed := primitive.NewObjectID()
+	removed := primitive.NewObjectID()
+	added := primitive.NewObjectID()
+
+	prev := map[primitive.ObjectID]models.User{
+		kept:    {ID: kept, Name: "John Doe", Age: intPointer(30)},
+		changed: {ID: changed, Name: "Jane Smith", Age: intPointer(28)},
+		removed: {ID: removed, Name: "Old User"},
+	}
+	next := map[primitive.ObjectID]models.User{
+		kept:    {ID: kept, Name: "John Doe", Age: intPointer(30)},
+		changed: {ID: changed, Name: "Jane Smith", Age: intPointer(29)},
+		added:   {ID: added, Name: "New User"},
+	}
+
+	events := diffUserSnapshots(prev, next, time.Now())
+
+	byUser := map[string]models.UserEvent{}
+	for _, event := range events {
+		byUser[event.UserID] = event
+	}
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, models.UserEventUpdated, byUser[changed.Hex()].Type)
+	assert.Equal(t, 29, *byUser[changed.Hex()].User.Age)
+	assert.Equal(t, models.UserEventDeleted, byUser[removed.Hex()].Type)
+	assert.Nil(t, byUser[removed.Hex()].User)
+	assert.Equal(t, models.UserEventCreated, byUser[added.Hex()].Type)
+	assert.NotContains(t, byUser, kept.Hex())
+}
+
+func TestDiffUserSnapshots_NoChanges(t *testing.T) {
+	id := primitive.NewObjectID()
+	snapshot := map[primitive.ObjectID]models.User{
+		id: {ID: id, Name: "John Doe"},
+	}
+
+	assert.Empty(t, diffUserSnapshots(snapshot, snapshot, time.Now()))
+}
@@ -0,0 +1,13 @@
+package services
+
+import "github.com/gin-gonic/gin/binding"
+
+// validate checks v against its binding tags, using the same rules as
+// gin's ShouldBindJSON.
+func validate(v interface{}) error {
+	if err := binding.Validator.ValidateStruct(v); err != nil {
+		return &Error{ErrCodeInvalid, err.Error()}
+	}
+
+	return nil
+}
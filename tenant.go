@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+
+	"go_crud/models"
+)
+
+// tenantApps builds the app of each tenant on its first request and keeps
+// it for the next ones.
+type tenantApps struct {
+	build func(tenant *models.Tenant) *tenantApp
+
+	mu   sync.Mutex
+	apps map[string]*tenantEntry
+}
+
+type tenantEntry struct {
+	once sync.Once
+	app  *tenantApp
+}
+
+func newTenantApps(build func(tenant *models.Tenant) *tenantApp) *tenantApps {
+	return &tenantApps{build: build, apps: map[string]*tenantEntry{}}
+}
+
+// app returns the app of tenant, the default tenant when nil. Apps are
+// built once, without holding up the requests of other tenants.
+func (a *tenantApps) app(tenant *models.Tenant) *tenantApp {
+	// A tenant deleted and created again may have moved to another database
+	key := ""
+	if tenant != nil {
+		key = tenant.ID + "/" + tenant.Database
+	}
+
+	a.mu.Lock()
+	entry, ok := a.apps[key]
+	if !ok {
+		entry = &tenantEntry{}
+		a.apps[key] = entry
+	}
+	a.mu.Unlock()
+
+	entry.once.Do(func() {
+		// Building again on the next request if this one fails
+		defer func() {
+			if entry.app == nil {
+				a.mu.Lock()
+				delete(a.apps, key)
+				a.mu.Unlock()
+			}
+		}()
+		entry.app = a.build(tenant)
+	})
+	return entry.app
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bufio"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// BloomFilter is a set of strings that can report false positives, but
+// never false negatives, in a fraction of the memory of the strings.
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint64
+}
+
+// NewBloomFilter sizes a filter for n strings at the given false positive rate.
+func NewBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, hashes: hashes}
+}
+
+func (b *BloomFilter) Add(s string) {
+	h1, h2 := bloomHashes(s)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Test reports whether s may have been added.
+func (b *BloomFilter) Test(s string) bool {
+	h1, h2 := bloomHashes(s)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHashes returns the two hashes the bit positions are derived from.
+func bloomHashes(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	io.WriteString(h, s)
+	h1 := h.Sum64()
+
+	h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+
+	return h1, h2
+}
+
+// LoadBloomFilter builds a filter from a file with one string per line.
+// Blank lines are skipped.
+func LoadBloomFilter(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	lines := 0
+	if err := readLines(path, func(string) { lines++ }); err != nil {
+		return nil, err
+	}
+
+	filter := NewBloomFilter(lines, falsePositiveRate)
+	if err := readLines(path, filter.Add); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func readLines(path string, fn func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fn(line)
+		}
+	}
+
+	return scanner.Err()
+}
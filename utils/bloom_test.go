@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		filter.Add(fmt.Sprintf("password%d", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		assert.True(t, filter.Test(fmt.Sprintf("password%d", i)))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Test(fmt.Sprintf("other%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestLoadBloomFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("123456\npassword\n\n  qwerty  \n"), 0o600))
+
+	filter, err := LoadBloomFilter(path, 0.001)
+
+	assert.NoError(t, err)
+	assert.True(t, filter.Test("123456"))
+	assert.True(t, filter.Test("qwerty"))
+	assert.False(t, filter.Test("correct horse battery staple"))
+
+	_, err = LoadBloomFilter(filepath.Join(t.TempDir(), "missing.txt"), 0.001)
+	assert.Error(t, err)
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go_crud/models"
+)
+
+// ErrJSONPatchTestFailed is returned when a "test" operation does not match the document.
+var ErrJSONPatchTestFailed = errors.New("test operation failed")
+
+// ApplyJSONPatch applies RFC 6902 operations to a document decoded from JSON
+// (maps, slices and scalars) and returns the patched document. The patch is
+// applied atomically: if any operation fails, the error is returned and doc
+// is left untouched.
+func ApplyJSONPatch(doc interface{}, ops []models.JSONPatchOperation) (interface{}, error) {
+	doc = deepCopy(doc)
+
+	for i, op := range ops {
+		var err error
+		switch op.Op {
+		case "add":
+			doc, err = patchAdd(doc, op.Path, deepCopy(op.Value))
+		case "remove":
+			doc, _, err = patchRemove(doc, op.Path)
+		case "replace":
+			if doc, _, err = patchRemove(doc, op.Path); err == nil {
+				doc, err = patchAdd(doc, op.Path, deepCopy(op.Value))
+			}
+		case "move":
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				err = fmt.Errorf("cannot move %q into one of its children", op.From)
+				break
+			}
+			var value interface{}
+			if doc, value, err = patchRemove(doc, op.From); err == nil {
+				doc, err = patchAdd(doc, op.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = patchGet(doc, op.From); err == nil {
+				doc, err = patchAdd(doc, op.Path, deepCopy(value))
+			}
+		case "test":
+			var value interface{}
+			if value, err = patchGet(doc, op.Path); err == nil && !reflect.DeepEqual(value, normalize(op.Value)) {
+				err = fmt.Errorf("%w, %q does not match", ErrJSONPatchTestFailed, op.Path)
+			}
+		default:
+			err = fmt.Errorf("unknown op %q", op.Op)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func patchGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+
+	return doc, nil
+}
+
+// patchAdd adds value at pointer and returns the new document.
+func patchAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := patchGet(doc, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i := len(node)
+		if last != "-" {
+			if i, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+
+		node = append(node[:i], append([]interface{}{value}, node[i:]...)...)
+		return replaceAt(doc, tokens[:len(tokens)-1], node), nil
+	default:
+		return nil, fmt.Errorf("cannot add to %q", pointer)
+	}
+
+	return doc, nil
+}
+
+// patchRemove removes the value at pointer and returns the new document and the removed value.
+func patchRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	value, err := patchGet(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parent, _ := patchGet(doc, pointer[:strings.LastIndex(pointer, "/")])
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		delete(node, last)
+	case []interface{}:
+		i, _ := arrayIndex(last, len(node)-1)
+		node = append(node[:i:i], node[i+1:]...)
+		return replaceAt(doc, tokens[:len(tokens)-1], node), value, nil
+	}
+
+	return doc, value, nil
+}
+
+// replaceAt sets the value at the location given by tokens, which must
+// exist, and returns the new document. It is needed because growing or
+// shrinking a slice creates a new slice header.
+func replaceAt(doc interface{}, tokens []string, value interface{}) interface{} {
+	if len(tokens) == 0 {
+		return value
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[tokens[0]] = replaceAt(node[tokens[0]], tokens[1:], value)
+	case []interface{}:
+		i, _ := strconv.Atoi(tokens[0])
+		node[i] = replaceAt(node[i], tokens[1:], value)
+	}
+
+	return doc
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	return i, nil
+}
+
+// deepCopy copies a document decoded from JSON so patches never alias the input.
+func deepCopy(doc interface{}) interface{} {
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for key, value := range node {
+			copied[key] = deepCopy(value)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, value := range node {
+			copied[i] = deepCopy(value)
+		}
+		return copied
+	default:
+		return normalize(doc)
+	}
+}
+
+// normalize turns Go numbers into float64, the type encoding/json decodes numbers into.
+func normalize(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, bool, string, float64, map[string]interface{}, []interface{}:
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
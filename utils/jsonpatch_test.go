@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_crud/models"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add member", `[{"op": "add", "path": "/age", "value": 30}]`, `{"name": "John", "tags": ["a", "b"], "age": 30}`},
+		{"add to array", `[{"op": "add", "path": "/tags/1", "value": "c"}]`, `{"name": "John", "tags": ["a", "c", "b"]}`},
+		{"append to array", `[{"op": "add", "path": "/tags/-", "value": "c"}]`, `{"name": "John", "tags": ["a", "b", "c"]}`},
+		{"remove", `[{"op": "remove", "path": "/tags/0"}]`, `{"name": "John", "tags": ["b"]}`},
+		{"replace", `[{"op": "replace", "path": "/name", "value": "Jane"}]`, `{"name": "Jane", "tags": ["a", "b"]}`},
+		{"move", `[{"op": "move", "from": "/name", "path": "/alias"}]`, `{"alias": "John", "tags": ["a", "b"]}`},
+		{"copy", `[{"op": "copy", "from": "/tags/1", "path": "/tags/0"}]`, `{"name": "John", "tags": ["b", "a", "b"]}`},
+		{"test", `[{"op": "test", "path": "/tags", "value": ["a", "b"]}]`, `{"name": "John", "tags": ["a", "b"]}`},
+		{"escaped path", `[{"op": "add", "path": "/a~1b~0c", "value": null}]`, `{"name": "John", "tags": ["a", "b"], "a/b~c": null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{"name": "John", "tags": []interface{}{"a", "b"}}
+
+			var ops []models.JSONPatchOperation
+			assert.NoError(t, json.Unmarshal([]byte(tt.patch), &ops))
+
+			patched, err := ApplyJSONPatch(doc, ops)
+			assert.NoError(t, err)
+
+			var want interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tt.want), &want))
+			assert.Equal(t, want, patched)
+
+			// The input document is never changed
+			assert.Equal(t, map[string]interface{}{"name": "John", "tags": []interface{}{"a", "b"}}, doc)
+		})
+	}
+}
+
+func TestApplyJSONPatch_Fail(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"missing member", `[{"op": "replace", "path": "/age", "value": 30}]`},
+		{"index out of range", `[{"op": "add", "path": "/tags/3", "value": "c"}]`},
+		{"invalid path", `[{"op": "remove", "path": "name"}]`},
+		{"move into child", `[{"op": "move", "from": "/tags", "path": "/tags/0"}]`},
+		{"unknown op", `[{"op": "merge", "path": "/name"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []models.JSONPatchOperation
+			assert.NoError(t, json.Unmarshal([]byte(tt.patch), &ops))
+
+			_, err := ApplyJSONPatch(map[string]interface{}{"name": "John", "tags": []interface{}{"a", "b"}}, ops)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyJSONPatch_TestFailed(t *testing.T) {
+	ops := []models.JSONPatchOperation{
+		{Op: "replace", Path: "/name", Value: "Jane"},
+		{Op: "test", Path: "/name", Value: "John"},
+	}
+
+	_, err := ApplyJSONPatch(map[string]interface{}{"name": "John"}, ops)
+	assert.True(t, errors.Is(err, ErrJSONPatchTestFailed))
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+// Argon2idHasher hashes passwords with argon2id into PHC strings such as
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>.
+type Argon2idHasher struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2Params are the parameters read back from a hash.
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(hash string, password string) error {
+	params, err := parseArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+func (h *Argon2idHasher) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.memory < h.Memory ||
+		params.iterations < h.Iterations ||
+		params.parallelism < h.Parallelism ||
+		uint32(len(params.salt)) < h.SaltLength ||
+		uint32(len(params.key)) < h.KeyLength
+}
+
+func parseArgon2Hash(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errInvalidArgon2Hash
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, errInvalidArgon2Hash
+	}
+
+	return params, nil
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher hashes passwords with bcrypt. Bcrypt ignores everything past
+// the first 72 bytes of a password.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(hash string, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrPasswordMismatch
+	}
+
+	return err
+}
+
+func (h *BcryptHasher) Handles(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	if !h.Handles(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.Cost
+}
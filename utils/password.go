@@ -1,20 +1,109 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrPasswordMismatch is returned by VerifyPassword when the password is wrong.
+var ErrPasswordMismatch = errors.New("the password does not match")
+
+// PasswordHasher hashes passwords with one algorithm and set of parameters.
+type PasswordHasher interface {
+	// Hash returns the hash of password as a PHC string, or a modular
+	// crypt string for bcrypt.
+	Hash(password string) (string, error)
+	// Verify checks password against a hash made by this algorithm, with
+	// whatever parameters the hash carries.
+	Verify(hash string, password string) error
+	// Handles reports whether hash was made by this algorithm.
+	Handles(hash string) bool
+	// NeedsRehash reports whether hash was made by another algorithm or
+	// with weaker parameters than this hasher uses.
+	NeedsRehash(hash string) bool
+}
+
+var (
+	hasherMu       sync.RWMutex
+	passwordHasher PasswordHasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+	// hashers are every algorithm VerifyPassword can check
+	hashers = []PasswordHasher{&BcryptHasher{}, &Argon2idHasher{}}
+)
+
+// SetPasswordHasher sets the hasher new passwords are hashed with.
+func SetPasswordHasher(hasher PasswordHasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+
+	passwordHasher = hasher
+}
+
+func currentHasher() PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+
+	return passwordHasher
+}
+
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := currentHasher().Hash(password)
 
 	if err != nil {
 		return "", fmt.Errorf("could not hash password %w", err)
 	}
-	return string(hashedPassword), nil
+	return hashedPassword, nil
+}
+
+// HashPasswords hashes passwords on a pool of at most workers goroutines.
+// Hashes and errors are returned in the same order as passwords.
+func HashPasswords(passwords []string, workers int) ([]string, []error) {
+	hashes := make([]string, len(passwords))
+	errs := make([]error, len(passwords))
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(passwords); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hashes[i], errs[i] = HashPassword(passwords[i])
+			}
+		}()
+	}
+
+	for i := range passwords {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return hashes, errs
 }
 
+// VerifyPassword checks candidatePassword against a hash made by any
+// supported algorithm, detected from the hash itself.
 func VerifyPassword(hashedPassword string, candidatePassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(candidatePassword))
+	for _, hasher := range hashers {
+		if hasher.Handles(hashedPassword) {
+			return hasher.Verify(hashedPassword, candidatePassword)
+		}
+	}
+
+	prefix, _, _ := strings.Cut(strings.TrimPrefix(hashedPassword, "$"), "$")
+	return fmt.Errorf("unsupported password hash %q", prefix)
+}
+
+// PasswordNeedsRehash reports whether a hash should be replaced by one made
+// with the current hasher.
+func PasswordNeedsRehash(hashedPassword string) bool {
+	return currentHasher().NeedsRehash(hashedPassword)
 }
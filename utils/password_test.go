@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testArgon2 keeps the parameters small so the tests stay fast
+var testArgon2 = &Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher(t *testing.T) {
+	hash, err := testArgon2.Hash("correct horse")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.NoError(t, testArgon2.Verify(hash, "correct horse"))
+	assert.Equal(t, ErrPasswordMismatch, testArgon2.Verify(hash, "wrong horse"))
+
+	other, err := testArgon2.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestVerifyPasswordDetectsAlgorithm(t *testing.T) {
+	defer SetPasswordHasher(currentHasher())
+
+	bcryptHasher := &BcryptHasher{Cost: 4}
+	bcryptHash, err := bcryptHasher.Hash("secret password")
+	assert.NoError(t, err)
+	argon2Hash, err := testArgon2.Hash("secret password")
+	assert.NoError(t, err)
+
+	for _, hasher := range []PasswordHasher{bcryptHasher, testArgon2} {
+		SetPasswordHasher(hasher)
+		assert.NoError(t, VerifyPassword(bcryptHash, "secret password"))
+		assert.NoError(t, VerifyPassword(argon2Hash, "secret password"))
+		assert.Equal(t, ErrPasswordMismatch, VerifyPassword(bcryptHash, "other password"))
+		assert.Equal(t, ErrPasswordMismatch, VerifyPassword(argon2Hash, "other password"))
+	}
+
+	assert.EqualError(t, VerifyPassword("$scrypt$ln=15$abc$def", "secret password"), `unsupported password hash "scrypt"`)
+	assert.Error(t, VerifyPassword("$argon2id$v=19$m=1024$abc", "secret password"))
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	defer SetPasswordHasher(currentHasher())
+
+	weakBcrypt, _ := (&BcryptHasher{Cost: 4}).Hash("secret password")
+	strongBcrypt, _ := (&BcryptHasher{Cost: 5}).Hash("secret password")
+	argon2Hash, _ := testArgon2.Hash("secret password")
+
+	SetPasswordHasher(&BcryptHasher{Cost: 5})
+	assert.True(t, PasswordNeedsRehash(weakBcrypt))
+	assert.False(t, PasswordNeedsRehash(strongBcrypt))
+	assert.True(t, PasswordNeedsRehash(argon2Hash))
+
+	SetPasswordHasher(testArgon2)
+	assert.True(t, PasswordNeedsRehash(strongBcrypt))
+	assert.False(t, PasswordNeedsRehash(argon2Hash))
+
+	SetPasswordHasher(&Argon2idHasher{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	assert.True(t, PasswordNeedsRehash(argon2Hash))
+}
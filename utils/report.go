@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"go_crud/models"
+)
+
+// WriteImportReportCSV writes the rows of an import report as CSV.
+func WriteImportReportCSV(w io.Writer, report *models.ImportUsersReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"row", "status", "email", "code", "message"}); err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		record := []string{strconv.Itoa(row.Row), row.Status, row.Email, "", ""}
+		if row.Error != nil {
+			record[3] = row.Error.Code
+			record[4] = row.Error.Message
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
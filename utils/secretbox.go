@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// SecretBox encrypts small secrets for storage with AES-256-GCM.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox from a 32 byte key.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretBox{aead}, nil
+}
+
+// Seal encrypts plaintext, binding it to context so it cannot be opened
+// for another one. The nonce is prepended and the result base64 encoded.
+func (b *SecretBox) Seal(plaintext string, context []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), context)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value made by Seal with the same context.
+func (b *SecretBox) Open(sealed string, context []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < b.aead.NonceSize() {
+		return "", errors.New("the sealed value is too short")
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, context)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewToken returns a random opaque token and the hash to store in its place.
+func NewToken() (string, string, error) {
+	token, err := RandomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a token for storage and lookup. Tokens are random, so a
+// fast unsalted hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The last six digits of the RFC 6238 appendix B SHA1 vectors
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, want := range vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPCounter(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfc6238Secret, TOTPCounter(now))
+
+	counter, ok := ValidateTOTP(rfc6238Secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPCounter(now), counter)
+
+	// A code from the previous step is accepted within the skew
+	counter, ok = ValidateTOTP(rfc6238Secret, code, now.Add(TOTPPeriod), 1)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPCounter(now), counter)
+
+	_, ok = ValidateTOTP(rfc6238Secret, code, now.Add(2*TOTPPeriod), 1)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(rfc6238Secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	u, err := url.Parse(TOTPURI("go_crud", "john.doe@example.com", secret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/go_crud:john.doe@example.com", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "go_crud", u.Query().Get("issuer"))
+}
+
+func TestSecretBox(t *testing.T) {
+	_, err := NewSecretBox([]byte("short"))
+	assert.Error(t, err)
+
+	box, err := NewSecretBox([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP", []byte("user-1"))
+	assert.NoError(t, err)
+	assert.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+	opened, err := box.Open(sealed, []byte("user-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", opened)
+
+	// A secret copied to another user cannot be opened
+	_, err = box.Open(sealed, []byte("user-2"))
+	assert.Error(t, err)
+}
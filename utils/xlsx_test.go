@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := NewXLSXWriter(&buf, "Users")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteRow([]interface{}{"name", "age"}))
+	assert.NoError(t, writer.WriteRow([]interface{}{"Tom & Jerry", 30}))
+	assert.NoError(t, writer.WriteRow([]interface{}{"Jane Smith", nil}))
+	assert.NoError(t, writer.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		f, err := file.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(f)
+		parts[file.Name] = string(content)
+	}
+
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Users"`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Tom &amp; Jerry</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>30</v></c>`)
+	assert.NotContains(t, sheet, `r="B3"`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "BA", xlsxColumn(52))
+}